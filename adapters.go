@@ -0,0 +1,82 @@
+package shutter
+
+import "github.com/ptdewey/shutter/internal/snapshots"
+
+// T is the subset of testing.TB that shutter needs to take a snapshot:
+// Helper, Name, Error and Log. *testing.T, *testing.B and *testing.F satisfy
+// it directly; other test runners can be adapted with Adapt.
+type T = snapshots.T
+
+// Benchmark adapts a benchmark, a *testing.B, for taking snapshots.
+//
+// Example:
+//
+//	func BenchmarkRender(b *testing.B) {
+//	    var out string
+//	    for b.Loop() {
+//	        out = render()
+//	    }
+//	    shutter.SnapString(shutter.Benchmark(b), "rendered", out)
+//	}
+//
+// Benchmark and Fuzz accept the methods they need rather than *testing.B and
+// *testing.F, so that package testing, which defines the test flags, is not
+// linked into programs that import shutter.
+func Benchmark(b interface {
+	T
+	ResetTimer()
+}) T {
+	return b
+}
+
+// Fuzz adapts a fuzz test, a *testing.F, for taking snapshots, for example of
+// the seed corpus it is set up with.
+func Fuzz(f interface {
+	T
+	Add(args ...any)
+}) T {
+	return f
+}
+
+// Runner describes a custom test runner for Adapt.
+type Runner struct {
+	// Name identifies the running test. Snapshots are grouped by it.
+	Name string
+	// Error reports a failed snapshot comparison. It is required.
+	Error func(args ...any)
+	// Log reports informational messages. It may be nil.
+	Log func(args ...any)
+}
+
+// Adapt returns a T for a test runner that is not based on package testing.
+//
+// Example:
+//
+//	t := shutter.Adapt(shutter.Runner{
+//	    Name:  spec.FullName(),
+//	    Error: func(args ...any) { spec.Fail(fmt.Sprint(args...)) },
+//	})
+//	shutter.Snap(t, "response", resp)
+func Adapt(r Runner) T {
+	return runnerT{r}
+}
+
+type runnerT struct {
+	r Runner
+}
+
+func (t runnerT) Helper() {}
+
+func (t runnerT) Name() string {
+	return t.r.Name
+}
+
+func (t runnerT) Error(args ...any) {
+	t.r.Error(args...)
+}
+
+func (t runnerT) Log(args ...any) {
+	if t.r.Log != nil {
+		t.r.Log(args...)
+	}
+}
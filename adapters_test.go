@@ -0,0 +1,78 @@
+package shutter_test
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ptdewey/shutter"
+)
+
+// Benchmarks and fuzz tests satisfy T without an adapter.
+var (
+	_ shutter.T = (*testing.B)(nil)
+	_ shutter.T = (*testing.F)(nil)
+)
+
+// Linking package testing registers the test flags, so programs that import
+// shutter outside of tests must not pull it in.
+func TestNoTestingDependency(t *testing.T) {
+	if testing.Short() {
+		t.Skip("runs go list")
+	}
+	cmd := exec.Command("go", "list", "-deps", ".")
+	cmd.Env = append(os.Environ(), "GOWORK=off")
+	out, err := cmd.Output()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, pkg := range strings.Fields(string(out)) {
+		if pkg == "testing" {
+			t.Error("shutter depends on package testing")
+		}
+	}
+}
+
+func TestAdapt(t *testing.T) {
+	var errors, logs []string
+	rt := shutter.Adapt(shutter.Runner{
+		Name:  t.Name(),
+		Error: func(args ...any) { errors = append(errors, fmt.Sprint(args...)) },
+		Log:   func(args ...any) { logs = append(logs, fmt.Sprint(args...)) },
+	})
+
+	shutter.SnapString(rt, "Custom Runner", "snapshot taken through a custom runner")
+
+	if len(errors) != 0 {
+		t.Errorf("unexpected errors: %v", errors)
+	}
+}
+
+func TestAdaptMismatchReportsError(t *testing.T) {
+	var errors []string
+	rt := shutter.Adapt(shutter.Runner{
+		Name:  "TestAdapt",
+		Error: func(args ...any) { errors = append(errors, fmt.Sprint(args...)) },
+	})
+
+	// Same snapshot as TestAdapt, with different content.
+	shutter.SnapString(rt, "Custom Runner", "different content")
+	t.Cleanup(func() {
+		_ = os.Remove(filepath.Join("__snapshots__", "TestAdapt", "custom_runner.snap.new"))
+	})
+
+	if len(errors) != 1 {
+		t.Errorf("expected one mismatch error, got %v", errors)
+	}
+}
+
+func BenchmarkSnapString(b *testing.B) {
+	out := ""
+	for i := 0; i < b.N; i++ {
+		out = fmt.Sprintf("rendered %d", 42)
+	}
+	shutter.SnapString(shutter.Benchmark(b), "Rendered", out)
+}
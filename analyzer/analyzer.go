@@ -0,0 +1,129 @@
+// Package analyzer provides go/analysis analyzers that catch snapshot
+// mistakes at lint time, before a snapshot is recorded.
+//
+// The analyzers can be run with go vet through the shuttervet command:
+//
+//	go install github.com/ptdewey/shutter/analyzer/cmd/shuttervet@latest
+//	go vet -vettool=$(which shuttervet) ./...
+package analyzer
+
+import (
+	"go/ast"
+	"go/types"
+	"strings"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/types/typeutil"
+)
+
+// Analyzers lists every analyzer in this package.
+var Analyzers = []*analysis.Analyzer{Titles, Scrubbers}
+
+// Packages whose Snap functions are checked. freeze re-exports shutter's.
+const (
+	shutterPath = "github.com/ptdewey/shutter"
+	freezePath  = "github.com/ptdewey/shutter/freeze"
+)
+
+// snapFuncs are the functions that record a snapshot. Each takes the test
+// as its first argument and the title as its second.
+var snapFuncs = map[string]bool{
+	"Snap":           true,
+	"SnapMany":       true,
+	"SnapEach":       true,
+	"SnapString":     true,
+	"SnapTemplate":   true,
+	"SnapJSON":       true,
+	"SnapJSONBytes":  true,
+	"SnapJSONReader": true,
+	"SnapJSONValue":  true,
+	"SnapAuto":       true,
+}
+
+// snapCall returns the name of the shutter function call invokes, if it
+// records a snapshot.
+func snapCall(info *types.Info, call *ast.CallExpr) (string, bool) {
+	obj := typeutil.Callee(info, call)
+	if obj == nil {
+		// freeze exposes its functions as variables.
+		if sel, ok := ast.Unparen(call.Fun).(*ast.SelectorExpr); ok {
+			obj = info.Uses[sel.Sel]
+		}
+	}
+	if obj == nil || obj.Pkg() == nil || !snapFuncs[obj.Name()] {
+		return "", false
+	}
+	if path := obj.Pkg().Path(); path != shutterPath && path != freezePath {
+		return "", false
+	}
+	return obj.Name(), true
+}
+
+// optionCalls returns the option constructors called in args, keyed by
+// name: shutter's by their bare name ("ScrubEmail" for shutter.ScrubEmail()),
+// others qualified by package path ("example.com/redact.Secrets"). known is
+// false if some argument is not such a call, so the options passed cannot
+// all be determined.
+func optionCalls(info *types.Info, args []ast.Expr) (options map[string]*ast.CallExpr, known bool) {
+	options = map[string]*ast.CallExpr{}
+	known = true
+	for _, arg := range args {
+		call, ok := ast.Unparen(arg).(*ast.CallExpr)
+		if !ok {
+			known = false
+			continue
+		}
+		var obj types.Object = typeutil.Callee(info, call)
+		if obj == nil {
+			if sel, ok := ast.Unparen(call.Fun).(*ast.SelectorExpr); ok {
+				obj = info.Uses[sel.Sel]
+			}
+		}
+		if obj == nil || obj.Pkg() == nil {
+			known = false
+			continue
+		}
+		switch path := obj.Pkg().Path(); path {
+		case shutterPath, freezePath:
+			options[obj.Name()] = call
+		default:
+			options[path+"."+obj.Name()] = call
+		}
+	}
+	return options, known
+}
+
+// snapshotFileName mirrors how shutter names snapshot files, so titles that
+// differ only in case, spaces, or characters that are unsafe in file names
+// are recognized as the same snapshot.
+func snapshotFileName(title string) string {
+	name := strings.ReplaceAll(strings.ToLower(title), " ", "_")
+	if name == "" {
+		return ""
+	}
+	name = strings.Map(func(r rune) rune {
+		if r < 0x20 || strings.ContainsRune(`<>:"/\|?*`, r) {
+			return '_'
+		}
+		return r
+	}, name)
+	name = strings.TrimRight(name, ". ")
+	if name == "" {
+		return "_"
+	}
+	base, _, _ := strings.Cut(name, ".")
+	if reservedNames[base] {
+		name = base + "_" + name[len(base):]
+	}
+	return name
+}
+
+// reservedNames are the device names Windows reserves, which shutter
+// suffixes with an underscore.
+var reservedNames = map[string]bool{
+	"con": true, "prn": true, "aux": true, "nul": true,
+	"com1": true, "com2": true, "com3": true, "com4": true, "com5": true,
+	"com6": true, "com7": true, "com8": true, "com9": true,
+	"lpt1": true, "lpt2": true, "lpt3": true, "lpt4": true, "lpt5": true,
+	"lpt6": true, "lpt7": true, "lpt8": true, "lpt9": true,
+}
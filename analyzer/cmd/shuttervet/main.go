@@ -0,0 +1,15 @@
+// Command shuttervet runs the shutter analyzers. It can be run on its own or
+// as a go vet tool:
+//
+//	shuttervet ./...
+//	go vet -vettool=$(which shuttervet) ./...
+package main
+
+import (
+	"github.com/ptdewey/shutter/analyzer"
+	"golang.org/x/tools/go/analysis/multichecker"
+)
+
+func main() {
+	multichecker.Main(analyzer.Analyzers...)
+}
@@ -0,0 +1,112 @@
+package analyzer
+
+import (
+	"go/ast"
+	"strings"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/analysis/passes/inspect"
+	"golang.org/x/tools/go/ast/inspector"
+)
+
+// Scrubbers reports snapshot calls that are missing options a team requires,
+// such as redaction of sensitive fields, so secrets are caught before they
+// land in a snapshot file.
+var Scrubbers = &analysis.Analyzer{
+	Name: "shutterscrubbers",
+	Doc: `report snapshot calls missing required options
+
+Each call to one of the -funcs functions (default the SnapJSON functions)
+must pass every
+option listed in -require (default "IgnoreSensitive,ScrubEmail"). Entries are
+separated by commas; alternatives within an entry are separated by "|", so
+"IgnoreSensitive|IgnoreKey" accepts either. Shutter options are named
+without a package, others by package path, e.g. "example.com/redact.Secrets".
+
+Calls whose options cannot all be determined, such as options passed as a
+slice or through variables, are not reported.`,
+	Requires: []*analysis.Analyzer{inspect.Analyzer},
+	Run:      runScrubbers,
+}
+
+var (
+	scrubbedFuncs   = "SnapJSON,SnapJSONBytes,SnapJSONReader,SnapJSONValue"
+	requiredOptions = "IgnoreSensitive,ScrubEmail"
+)
+
+func init() {
+	Scrubbers.Flags.StringVar(&scrubbedFuncs, "funcs", scrubbedFuncs, "comma-separated snapshot functions to check")
+	Scrubbers.Flags.StringVar(&requiredOptions, "require", requiredOptions, `comma-separated options each call must pass; "|" separates alternatives`)
+}
+
+func runScrubbers(pass *analysis.Pass) (any, error) {
+	insp := pass.ResultOf[inspect.Analyzer].(*inspector.Inspector)
+
+	funcs := map[string]bool{}
+	for name := range strings.SplitSeq(scrubbedFuncs, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			funcs[name] = true
+		}
+	}
+	required := parseRequired(requiredOptions)
+
+	filter := []ast.Node{(*ast.CallExpr)(nil)}
+	insp.Preorder(filter, func(n ast.Node) {
+		call := n.(*ast.CallExpr)
+		name, ok := snapCall(pass.TypesInfo, call)
+		if !ok || !funcs[name] || call.Ellipsis.IsValid() {
+			return
+		}
+
+		// Options follow the title and the value(s) being snapshotted.
+		first := 3
+		if name == "SnapTemplate" {
+			first = 4
+		}
+		if len(call.Args) < first {
+			return
+		}
+		options, known := optionCalls(pass.TypesInfo, call.Args[first:])
+		if !known {
+			return
+		}
+
+		var missing []string
+		for _, alternatives := range required {
+			if !anyOption(options, alternatives) {
+				missing = append(missing, strings.Join(alternatives, " or "))
+			}
+		}
+		if len(missing) > 0 {
+			pass.Reportf(call.Pos(), "%s call is missing required option(s): %s", name, strings.Join(missing, ", "))
+		}
+	})
+
+	return nil, nil
+}
+
+// parseRequired splits a -require value into entries of alternatives.
+func parseRequired(value string) [][]string {
+	var required [][]string
+	for entry := range strings.SplitSeq(value, ",") {
+		var alternatives []string
+		for name := range strings.SplitSeq(entry, "|") {
+			if name = strings.TrimSpace(name); name != "" {
+				alternatives = append(alternatives, name)
+			}
+		}
+		if len(alternatives) > 0 {
+			required = append(required, alternatives)
+		}
+	}
+	return required
+}
+
+func anyOption(options map[string]*ast.CallExpr, names []string) bool {
+	for _, name := range names {
+		if _, ok := options[name]; ok {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,28 @@
+package analyzer_test
+
+import (
+	"testing"
+
+	"github.com/ptdewey/shutter/analyzer"
+	"golang.org/x/tools/go/analysis/analysistest"
+)
+
+func TestScrubbers(t *testing.T) {
+	analysistest.Run(t, analysistest.TestData(), analyzer.Scrubbers, "scrubbers")
+}
+
+func TestScrubbersCustom(t *testing.T) {
+	setFlag(t, "funcs", "SnapString,Snap")
+	setFlag(t, "require", "IgnoreSensitive|IgnoreKey, example.com/redact.Secrets")
+	analysistest.Run(t, analysistest.TestData(), analyzer.Scrubbers, "scrubberscustom")
+}
+
+func setFlag(t *testing.T, name, value string) {
+	t.Helper()
+	f := analyzer.Scrubbers.Flags.Lookup(name)
+	previous := f.Value.String()
+	if err := f.Value.Set(value); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = f.Value.Set(previous) })
+}
@@ -0,0 +1,10 @@
+// Package freeze is a stub of the real package for analyzer tests.
+package freeze
+
+import "github.com/ptdewey/shutter"
+
+var (
+	Snap       = shutter.Snap
+	SnapString = shutter.SnapString
+	SnapJSON   = shutter.SnapJSON
+)
@@ -0,0 +1,35 @@
+// Package shutter is a stub of the real package for analyzer tests.
+package shutter
+
+import "io"
+
+type T interface {
+	Helper()
+	Name() string
+	Error(args ...any)
+	Log(args ...any)
+}
+
+type Option interface{}
+
+type Case struct {
+	Name  string
+	Value any
+}
+
+type Template interface{}
+
+func Snap(t T, title string, value any, opts ...Option)                       {}
+func SnapMany(t T, title string, values []any, opts ...Option)                {}
+func SnapEach(t T, title string, cases []Case, opts ...Option)                {}
+func SnapString(t T, title string, content string, opts ...Option)            {}
+func SnapTemplate(t T, title string, tmpl Template, data any, opts ...Option) {}
+func SnapJSON(t T, title string, jsonStr string, opts ...Option)              {}
+func SnapJSONBytes(t T, title string, jsonBytes []byte, opts ...Option)       {}
+func SnapJSONReader(t T, title string, r io.Reader, opts ...Option)           {}
+func SnapJSONValue(t T, title string, v any, opts ...Option)                  {}
+func Variant(name string) Option                                              { return nil }
+func Key(name string) Option                                                  { return nil }
+func ScrubEmail() Option                                                      { return nil }
+func IgnoreSensitive() Option                                                 { return nil }
+func IgnoreKey(keys ...string) Option                                         { return nil }
@@ -0,0 +1,26 @@
+package scrubbers
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/ptdewey/shutter"
+	"github.com/ptdewey/shutter/freeze"
+)
+
+func TestScrubbers(t *testing.T) {
+	shutter.SnapJSON(t, "bare", `{}`)                               // want `SnapJSON call is missing required option\(s\): IgnoreSensitive, ScrubEmail`
+	shutter.SnapJSON(t, "partial", `{}`, shutter.IgnoreSensitive()) // want `missing required option\(s\): ScrubEmail$`
+	freeze.SnapJSON(t, "freeze", `{}`, shutter.ScrubEmail())        // want `missing required option\(s\): IgnoreSensitive$`
+	shutter.SnapJSON(t, "complete", `{}`, shutter.ScrubEmail(), shutter.IgnoreSensitive())
+	shutter.SnapJSONBytes(t, "bytes", []byte(`{}`), shutter.ScrubEmail())              // want `SnapJSONBytes call is missing required option\(s\): IgnoreSensitive$`
+	shutter.SnapJSONReader(t, "reader", strings.NewReader(`{}`), shutter.ScrubEmail()) // want `SnapJSONReader call is missing required option\(s\): IgnoreSensitive$`
+	shutter.SnapJSONValue(t, "value", struct{}{}, shutter.IgnoreSensitive())           // want `SnapJSONValue call is missing required option\(s\): ScrubEmail$`
+	shutter.SnapString(t, "not checked", "text")
+
+	// Options that cannot be determined are not reported.
+	opts := []shutter.Option{shutter.ScrubEmail()}
+	shutter.SnapJSON(t, "slice", `{}`, opts...)
+	scrub := shutter.IgnoreSensitive()
+	shutter.SnapJSON(t, "variable", `{}`, scrub)
+}
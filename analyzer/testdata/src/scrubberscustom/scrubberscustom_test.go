@@ -0,0 +1,15 @@
+package scrubberscustom
+
+import (
+	"testing"
+
+	"example.com/redact"
+	"github.com/ptdewey/shutter"
+)
+
+func TestCustom(t *testing.T) {
+	shutter.SnapString(t, "bare", "text")                   // want `SnapString call is missing required option\(s\): IgnoreSensitive or IgnoreKey, example.com/redact.Secrets`
+	shutter.Snap(t, "value", 1, shutter.IgnoreKey("token")) // want `Snap call is missing required option\(s\): example.com/redact.Secrets$`
+	shutter.SnapString(t, "alternative", "text", shutter.IgnoreKey("token"), redact.Secrets())
+	shutter.SnapString(t, "complete", "text", shutter.IgnoreSensitive(), redact.Secrets())
+}
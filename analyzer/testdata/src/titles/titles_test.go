@@ -0,0 +1,51 @@
+package titles
+
+import (
+	"testing"
+
+	"github.com/ptdewey/shutter"
+	"github.com/ptdewey/shutter/freeze"
+)
+
+const sharedTitle = "shared"
+
+func TestDuplicates(t *testing.T) {
+	shutter.Snap(t, "user", 1)
+	shutter.SnapString(t, "user", "again") // want `duplicate snapshot title "user": already used at .*titles_test.go:13:`
+	shutter.Snap(t, "Admin Case", 1)
+	shutter.Snap(t, "admin_case", 2) // want `duplicate snapshot title "admin_case"`
+	shutter.Snap(t, sharedTitle, 1)
+	freeze.Snap(t, "shared", 2) // want `duplicate snapshot title "shared"`
+	shutter.Snap(t, "GET /users/:id", 1)
+	shutter.Snap(t, "get__users__id", 2) // want `duplicate snapshot title "get__users__id"`
+}
+
+func TestDistinct(t *testing.T) {
+	// The same titles as in TestDuplicates belong to a different test.
+	shutter.Snap(t, "user", 1)
+	shutter.Snap(t, "linux", 1, shutter.Variant("linux"))
+	shutter.Snap(t, "linux", 2, shutter.Variant("darwin"))
+	shutter.Snap(t, "linux", 3)
+	shutter.Snap(t, "schema", 1, shutter.Key("postgres-15"))
+	shutter.Snap(t, "schema", 2, shutter.Key("sqlite"))
+	shutter.Snap(t, "schema", 3, shutter.Key("sqlite")) // want `duplicate snapshot title "schema"`
+}
+
+func TestSubtests(t *testing.T) {
+	shutter.Snap(t, "output", 1)
+	t.Run("first", func(t *testing.T) {
+		shutter.Snap(t, "output", 2)
+		shutter.Snap(t, "output", 3) // want `duplicate snapshot title "output"`
+	})
+	t.Run("second", func(t *testing.T) {
+		shutter.Snap(t, "output", 4)
+	})
+}
+
+func TestNonConstant(t *testing.T) {
+	for _, name := range []string{"a", "b"} {
+		shutter.Snap(t, name, 1) // want `Snap title is not a constant`
+		shutter.Snap(t, "fixed", 1, shutter.Variant(name))
+		shutter.Snap(t, "fixed", 2, shutter.Variant(name))
+	}
+}
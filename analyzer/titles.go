@@ -0,0 +1,123 @@
+package analyzer
+
+import (
+	"go/ast"
+	"go/constant"
+	"go/token"
+	"strings"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/analysis/passes/inspect"
+	"golang.org/x/tools/go/ast/inspector"
+)
+
+// Titles reports snapshot titles used more than once in the same function,
+// where both calls would write the same snapshot file, and titles that are
+// not constants and so cannot be checked.
+var Titles = &analysis.Analyzer{
+	Name: "shuttertitles",
+	Doc: `report duplicate snapshot titles
+
+Two Snap calls in the same function with the same title record the same
+snapshot file for the same test, so the second overwrites the first. Titles
+are compared the way snapshot files are named: case-insensitively, with
+spaces and underscores equal. Calls with different constant Variant or Key
+options do not collide.
+
+Titles that are not constants cannot be checked and are reported too,
+unless -nonconst=false.`,
+	Requires: []*analysis.Analyzer{inspect.Analyzer},
+	Run:      runTitles,
+}
+
+var reportNonConstant bool
+
+func init() {
+	Titles.Flags.BoolVar(&reportNonConstant, "nonconst", true, "report snapshot titles that are not constants")
+}
+
+func runTitles(pass *analysis.Pass) (any, error) {
+	insp := pass.ResultOf[inspect.Analyzer].(*inspector.Inspector)
+
+	// Titles seen so far, per enclosing function. A function literal is its
+	// own scope, since subtests run it under a different test name.
+	seen := map[ast.Node]map[string]token.Pos{}
+
+	filter := []ast.Node{(*ast.CallExpr)(nil)}
+	insp.WithStack(filter, func(n ast.Node, push bool, stack []ast.Node) bool {
+		if !push {
+			return true
+		}
+		call := n.(*ast.CallExpr)
+		name, ok := snapCall(pass.TypesInfo, call)
+		if !ok || len(call.Args) < 2 {
+			return true
+		}
+
+		titleArg := call.Args[1]
+		tv := pass.TypesInfo.Types[titleArg]
+		if tv.Value == nil || tv.Value.Kind() != constant.String {
+			if reportNonConstant {
+				pass.Reportf(titleArg.Pos(), "%s title is not a constant, so duplicate titles cannot be detected", name)
+			}
+			return true
+		}
+		title := constant.StringVal(tv.Value)
+
+		variant, ok := constantVariant(pass, call.Args[2:])
+		if !ok {
+			return true
+		}
+
+		scope := enclosingFunc(stack)
+		if seen[scope] == nil {
+			seen[scope] = map[string]token.Pos{}
+		}
+		key := snapshotFileName(title) + "\x00" + variant
+		if prev, ok := seen[scope][key]; ok {
+			pass.Reportf(titleArg.Pos(), "duplicate snapshot title %q: already used at %s", title, pass.Fset.Position(prev))
+			return true
+		}
+		seen[scope][key] = titleArg.Pos()
+		return true
+	})
+
+	return nil, nil
+}
+
+// constantVariant returns the variant selected by Variant and Key options
+// among args, joined as shutter joins them. It reports false if a variant is
+// not a constant, since such calls cannot be compared.
+func constantVariant(pass *analysis.Pass, args []ast.Expr) (string, bool) {
+	var variants []string
+	for _, arg := range args {
+		call, ok := ast.Unparen(arg).(*ast.CallExpr)
+		if !ok || len(call.Args) != 1 {
+			continue
+		}
+		options, _ := optionCalls(pass.TypesInfo, []ast.Expr{call})
+		_, variant := options["Variant"]
+		_, key := options["Key"]
+		if !variant && !key {
+			continue
+		}
+		tv := pass.TypesInfo.Types[call.Args[0]]
+		if tv.Value == nil || tv.Value.Kind() != constant.String {
+			return "", false
+		}
+		variants = append(variants, constant.StringVal(tv.Value))
+	}
+	return strings.Join(variants, "."), true
+}
+
+// enclosingFunc returns the innermost function declaration or literal in
+// stack.
+func enclosingFunc(stack []ast.Node) ast.Node {
+	for i := len(stack) - 1; i >= 0; i-- {
+		switch stack[i].(type) {
+		case *ast.FuncDecl, *ast.FuncLit:
+			return stack[i]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,12 @@
+package analyzer_test
+
+import (
+	"testing"
+
+	"github.com/ptdewey/shutter/analyzer"
+	"golang.org/x/tools/go/analysis/analysistest"
+)
+
+func TestTitles(t *testing.T) {
+	analysistest.Run(t, analysistest.TestData(), analyzer.Titles, "titles")
+}
@@ -0,0 +1,154 @@
+package shutter_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ptdewey/shutter"
+)
+
+// benchmarkSizes are the input sizes covered by the large-snapshot
+// benchmarks. Sizes above 1MB are skipped with -short.
+var benchmarkSizes = []struct {
+	name string
+	size int
+}{
+	{"1MB", 1 << 20},
+	{"10MB", 10 << 20},
+	{"50MB", 50 << 20},
+}
+
+// everyIteration snapshots on every benchmark iteration. shutter records
+// only the first snapshot of a *testing.B, which would leave the loop
+// measuring nothing.
+type everyIteration struct{ *testing.B }
+
+// tempProject changes into a temporary module so snapshots are not written
+// to the repository.
+func tempProject(tb testing.TB) {
+	tb.Helper()
+	dir := tb.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "go.mod"), []byte("module bench\n"), 0o644); err != nil {
+		tb.Fatal(err)
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		tb.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		tb.Fatal(err)
+	}
+	tb.Cleanup(func() { os.Chdir(wd) })
+}
+
+// acceptAll accepts every pending snapshot in the benchmark project, so the
+// timed loop takes the matching path.
+func acceptAll(b *testing.B) {
+	b.Helper()
+	err := filepath.WalkDir("__snapshots__", func(p string, d os.DirEntry, err error) error {
+		if err != nil || !strings.HasSuffix(p, ".snap.new") {
+			return err
+		}
+		return os.Rename(p, strings.TrimSuffix(p, ".new"))
+	})
+	if err != nil {
+		b.Fatal(err)
+	}
+}
+
+// recorder is a T that ignores errors, used to record the initial snapshot.
+type recorder struct{ *testing.B }
+
+func (recorder) Error(...any) {}
+
+// record runs snap with the new-snapshot box discarded, then accepts it.
+func record(b *testing.B, snap func()) {
+	b.Helper()
+	stdout := os.Stdout
+	devNull, err := os.OpenFile(os.DevNull, os.O_WRONLY, 0)
+	if err != nil {
+		b.Fatal(err)
+	}
+	os.Stdout = devNull
+	snap()
+	os.Stdout = stdout
+	devNull.Close()
+	acceptAll(b)
+}
+
+// largeLines returns lines whose formatted size is about size bytes.
+func largeLines(size int) []any {
+	var lines []any
+	for n := 0; n < size; {
+		line := fmt.Sprintf("line %08d: the quick brown fox jumps over the lazy dog", len(lines))
+		lines = append(lines, line)
+		n += len(line) + 3
+	}
+	return lines
+}
+
+// largeJSON returns a JSON array of records about size bytes long.
+func largeJSON(size int) string {
+	type record struct {
+		ID    int    `json:"id"`
+		Name  string `json:"name"`
+		Email string `json:"email"`
+	}
+	var records []record
+	for n := 0; n < size; n += 72 {
+		records = append(records, record{
+			ID:    len(records),
+			Name:  fmt.Sprintf("user %d", len(records)),
+			Email: fmt.Sprintf("user%d@example.com", len(records)),
+		})
+	}
+	data, err := json.Marshal(records)
+	if err != nil {
+		panic(err)
+	}
+	return string(data)
+}
+
+func BenchmarkSnapManyLarge(b *testing.B) {
+	for _, bs := range benchmarkSizes {
+		b.Run(bs.name, func(b *testing.B) {
+			if testing.Short() && bs.size > 1<<20 {
+				b.Skip("large input skipped with -short")
+			}
+			tempProject(b)
+			values := largeLines(bs.size)
+			record(b, func() { shutter.SnapMany(recorder{b}, "large", values) })
+
+			b.SetBytes(int64(bs.size))
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				shutter.SnapMany(everyIteration{b}, "large", values)
+			}
+		})
+	}
+}
+
+func BenchmarkSnapJSONLarge(b *testing.B) {
+	for _, bs := range benchmarkSizes {
+		b.Run(bs.name, func(b *testing.B) {
+			if testing.Short() && bs.size > 1<<20 {
+				b.Skip("large input skipped with -short")
+			}
+			tempProject(b)
+			input := largeJSON(bs.size)
+			record(b, func() { shutter.SnapJSON(recorder{b}, "large", input, shutter.ScrubEmail()) })
+
+			b.SetBytes(int64(len(input)))
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				shutter.SnapJSON(everyIteration{b}, "large", input, shutter.ScrubEmail())
+			}
+		})
+	}
+}
@@ -6,52 +6,271 @@ import (
 	"os"
 
 	"github.com/ptdewey/shutter"
+	"github.com/ptdewey/shutter/internal/files"
+	"github.com/ptdewey/shutter/internal/pretty"
+	"github.com/ptdewey/shutter/internal/review"
 )
 
 func main() {
 	flag.Usage = func() {
-		fmt.Fprintf(os.Stderr, `Usage: shutter-cli [COMMAND]
+		fmt.Fprintf(os.Stderr, `Usage: shutter-cli [COMMAND] [--yes] [--quiet]
 
 Commands:
   review      Review and accept/reject new snapshots (default)
+  status      List snapshots pending review and corrupted accepted snapshots
+  accept      Accept the snapshot named as printed by a failing test, e.g.
+              accept "TestUsers/admin case"; without a name, like accept-all
   accept-all  Accept all new snapshots
-  reject-all  Reject all new snapshots
+  reject-all  Reject all new snapshots (also: reject)
+  migrate     Move snapshots into per-test directories and the configured format
+  collect-bazel
+              Copy the pending snapshots Bazel tests wrote to their outputs
+              into the workspace, from bazel-testlogs or the given directory
+  restore     Restore a rejected snapshot by name, or list rejected snapshots;
+              with --purge, empty the trash
+  history     List the accepted versions of a snapshot
+  diff        Compare an earlier accepted version with the current snapshot
+  resolve     Resolve git merge conflicts in an accepted snapshot with the
+              configured merge tool
+  prune       Delete accepted snapshots by age, size, or missing test
+  clean       Delete accepted snapshots the last test run did not compare
+              (--from-manifest, recorded with $SHUTTER_MANIFEST=1)
+  audit       Scan accepted snapshots for secrets such as API keys and emails
+  audit-log   List the accepts and rejects recorded with audit_log = true in
+              .shutterconfig, optionally those of titles containing a name
+  flakes      List snapshots whose content changed between runs recorded
+              with $SHUTTER_DETECT_FLAKES
+  report      Summarize pending snapshots per package; with --summary-json,
+              as JSON with short diffs for bots
+  coverage    List the tests of each package that take snapshots and those
+              that do not, and snapshots no Snap call takes anymore
+  regen       Re-run the tests of accepted snapshots matching an optional
+              pattern with $SHUTTER_UPDATE=1 and report what changed
   help        Show this help message
 
+Flags:
+  -y, --yes   Skip the confirmation prompt for accept-all, reject-all,
+              restore --purge and regen
+  -q, --quiet Suppress headers, confirmations and summaries
+  --allow-secrets
+              Accept snapshots even if they look like they contain secrets
+  --suite     Only act on the snapshots of the named suite, as taken with
+              shutter.Suite (review, status, accept, reject, report)
+  --mine      Only act on the snapshots owned by your teams, per CODEOWNERS
+              and the owners and teams settings of .shutterconfig
+  --accessible
+              Screen-reader-friendly output: no color or box drawing, diff
+              lines labeled ADDED:, REMOVED: and CONTEXT: ($SHUTTER_ACCESSIBLE)
+  --legend    Name the accepted and pending sides above each diff
+  --no-metadata
+              Leave out the title, test, options and other metadata lines
+              above each snapshot, e.g. for screenshots or narrow terminals
+  --difftool  Open each snapshot in an external diff tool during review:
+              $SHUTTER_DIFFTOOL, else git difftool (also the d key)
+  --root      Project root to search for snapshots (default: $SHUTTER_ROOT,
+              else the enclosing go.work or go.mod directory)
+  --against   Version for diff to compare against (default: the previous one)
+  --external  Open diff in the external diff tool instead of printing it
+  --summary-json
+              Print report as JSON (schema_version, totals, packages and
+              snapshots with short diffs)
+  --since, --action
+              Only list audit log entries this recent (e.g. 30d) or of this
+              action (accept or reject)
+  --older-than, --larger-than, --orphaned, --dry-run
+              Prune criteria (all given criteria must match), e.g. 180d, 1MB;
+              --dry-run also applies to clean
+
+Exit codes (review, status, accept, reject, accept-all, reject-all):
+  0           No snapshots are pending review
+  1           Snapshots are still pending review
+  2           The command failed, or status found corrupted snapshots
+Other commands, and accept with a name, exit with 0 on success and 2 on
+failure.
+
 Examples:
   shutter              # Start interactive review
   shutter review       # Same as above
-  shutter accept-all   # Accept all new snapshots
-  shutter reject-all   # Reject all new snapshots
+  shutter status -q    # Exit with 1 if snapshots are pending, e.g. in CI
+  shutter accept-all   # Accept all new snapshots (asks for confirmation)
+  shutter reject-all --yes  # Reject all new snapshots without asking
+  shutter accept --suite billing-api  # Accept one suite's snapshots
+  shutter review --mine  # Review the snapshots your teams own
+  shutter accept "TestUsers/user api response"  # Accept one snapshot
+  shutter migrate      # Migrate snapshots to the per-test layout and format
+  bazel test //... ; shutter collect-bazel && shutter review
+  shutter restore TestUsers/admin_case  # Undo a reject
+  shutter restore --purge  # Empty the trash of rejected snapshots
+  shutter history TestUsers/admin_case  # List accepted versions
+  shutter diff TestUsers/admin_case --against v2
+  shutter diff TestUsers/admin_case --external
+  shutter resolve TestUsers/admin_case  # After a conflicting git merge
+  shutter prune --older-than 180d --larger-than 1MB --dry-run
+  shutter prune --orphaned
+  SHUTTER_MANIFEST=1 go test ./... && shutter clean --from-manifest
+  shutter audit        # Check accepted snapshots for leaked secrets
+  shutter audit-log TestUsers --since 90d  # Who changed these baselines
+  SHUTTER_DETECT_FLAKES=3 go test -count=3 ./... && shutter flakes
+  shutter report --summary-json  # Pending changes for a chat or PR bot
+  shutter coverage     # Track snapshot adoption per package
+  shutter regen '^TestRender/'  # After an intentional output change
 `)
 	}
 
-	flag.Parse()
+	var yes, quiet, mine, accessible, legend, noMetadata, orphaned, dryRun, allowSecrets, fromManifest, purge, difftool, external, summaryJSON bool
+	var root, against, olderThan, largerThan, suite, since, action string
+	flag.BoolVar(&yes, "yes", false, "skip confirmation prompts")
+	flag.BoolVar(&yes, "y", false, "skip confirmation prompts")
+	flag.BoolVar(&quiet, "quiet", false, "suppress decorative output")
+	flag.BoolVar(&quiet, "q", false, "suppress decorative output")
+	flag.BoolVar(&allowSecrets, "allow-secrets", false, "accept snapshots that may contain secrets")
+	flag.BoolVar(&accessible, "accessible", pretty.Accessible(), "screen-reader-friendly output")
+	flag.BoolVar(&legend, "legend", false, "name the accepted and pending sides above each diff")
+	flag.BoolVar(&noMetadata, "no-metadata", false, "leave out the metadata lines above each snapshot")
+	flag.StringVar(&root, "root", "", "project root to search for snapshots")
+	flag.StringVar(&suite, "suite", "", "only act on the snapshots of the named suite")
+	flag.BoolVar(&mine, "mine", false, "only act on the snapshots owned by your teams")
+	flag.StringVar(&against, "against", "", "version to diff against")
+	flag.StringVar(&since, "since", "", "only list audit log entries this recent")
+	flag.StringVar(&action, "action", "", "only list audit log entries of this action")
+	flag.StringVar(&olderThan, "older-than", "", "prune snapshots last accepted longer ago than this")
+	flag.StringVar(&largerThan, "larger-than", "", "prune snapshots larger than this")
+	flag.BoolVar(&orphaned, "orphaned", false, "prune snapshots whose test no longer exists")
+	flag.BoolVar(&dryRun, "dry-run", false, "list snapshots to prune without deleting them")
+	flag.BoolVar(&fromManifest, "from-manifest", false, "clean snapshots missing from the last run's manifest")
+	flag.BoolVar(&purge, "purge", false, "empty the trash of rejected snapshots")
+	flag.BoolVar(&difftool, "difftool", false, "open each snapshot in an external diff tool during review")
+	flag.BoolVar(&external, "external", false, "open diff in the configured external diff tool")
+	flag.BoolVar(&summaryJSON, "summary-json", false, "print report as JSON for bots")
 
-	var cmd string
-	if flag.NArg() > 0 {
-		cmd = flag.Arg(0)
+	args := parseArgs(os.Args[1:])
+	var cmd, name string
+	if len(args) > 0 {
+		cmd = args[0]
+	}
+	if len(args) > 1 {
+		name = args[1]
+	}
+	review.SetQuiet(quiet)
+	review.SetAllowSecrets(allowSecrets)
+	review.SetSuite(suite)
+	review.SetMine(mine)
+	pretty.SetAccessible(accessible)
+	if root != "" {
+		os.Setenv(files.RootEnv, root)
+	}
+	if err := review.SetDiffStyle(legend, noMetadata); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(review.ExitError)
 	}
 
 	var err error
 	switch cmd {
 	case "", "review":
-		err = shutter.Review()
+		err = shutter.ReviewWithOptions(shutter.ReviewOptions{DiffTool: difftool})
+	case "status":
+		err = review.Status()
+	case "accept":
+		if name != "" {
+			err = review.AcceptNamed(name)
+			break
+		}
+		err = review.ConfirmAcceptAll(yes)
 	case "accept-all":
-		err = shutter.AcceptAll()
-	case "reject-all":
-		err = shutter.RejectAll()
+		err = review.ConfirmAcceptAll(yes)
+	case "reject", "reject-all":
+		err = review.ConfirmRejectAll(yes)
+	case "migrate":
+		err = shutter.Migrate()
+	case "collect-bazel":
+		err = review.CollectBazel(name)
+	case "restore":
+		if purge {
+			err = review.PurgeTrash(yes)
+			break
+		}
+		err = review.Restore(name)
+	case "history":
+		err = requireName(cmd, name, review.History)
+	case "diff":
+		err = requireName(cmd, name, func(name string) error {
+			if external {
+				return review.DiffExternal(name, against)
+			}
+			return review.Diff(name, against)
+		})
+	case "resolve":
+		err = requireName(cmd, name, review.Resolve)
+	case "prune":
+		err = review.PruneFlags(olderThan, largerThan, orphaned, dryRun, yes)
+	case "clean":
+		err = review.Clean(fromManifest, dryRun, yes)
+	case "audit":
+		err = review.Audit()
+	case "audit-log":
+		err = review.AuditLog(name, since, action)
+	case "flakes":
+		err = review.Flakes()
+	case "report":
+		err = review.Report(summaryJSON)
+	case "coverage":
+		err = review.Coverage()
+	case "regen":
+		err = review.Regen(name, yes)
 	case "help", "-h", "--help":
 		flag.Usage()
 		return
 	default:
 		fmt.Fprintf(os.Stderr, "Unknown command: %s\n\n", cmd)
 		flag.Usage()
-		os.Exit(1)
+		os.Exit(review.ExitError)
 	}
 
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-		os.Exit(1)
 	}
+
+	switch cmd {
+	case "", "review", "status", "accept", "reject", "accept-all", "reject-all":
+		// These commands report whether snapshots are still pending,
+		// except accept with a name, which leaves the others to review.
+		if cmd != "accept" || name == "" {
+			os.Exit(review.ExitCode(err))
+		}
+	}
+	if err != nil {
+		os.Exit(review.ExitError)
+	}
+}
+
+// parseArgs parses flags wherever they appear on the command line, so both
+// "shutter --yes accept-all" and "shutter accept-all --yes" work, and returns
+// the positional arguments. It exits after printing the usage for -h and on
+// invalid flags.
+func parseArgs(args []string) []string {
+	flag.CommandLine.Init(os.Args[0], flag.ContinueOnError)
+	var positional []string
+	for {
+		err := flag.CommandLine.Parse(args)
+		switch {
+		case err == flag.ErrHelp:
+			os.Exit(0)
+		case err != nil:
+			os.Exit(review.ExitError)
+		}
+		args = flag.Args()
+		if len(args) == 0 {
+			return positional
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
+
+// requireName runs fn with name, or fails if no snapshot name was given.
+func requireName(cmd, name string, fn func(string) error) error {
+	if name == "" {
+		return fmt.Errorf("%s requires a snapshot name, e.g. shutter %s TestUsers/admin_case", cmd, cmd)
+	}
+	return fn(name)
 }
@@ -1,8 +1,11 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 	"os"
+	"os/exec"
+	"strconv"
 	"strings"
 
 	"github.com/charmbracelet/bubbles/viewport"
@@ -11,6 +14,7 @@ import (
 	"github.com/ptdewey/shutter/internal/diff"
 	"github.com/ptdewey/shutter/internal/files"
 	"github.com/ptdewey/shutter/internal/pretty"
+	"github.com/ptdewey/shutter/internal/review"
 )
 
 // Styles
@@ -56,27 +60,59 @@ var (
 			Foreground(lipgloss.AdaptiveColor{Light: "8", Dark: "8"})
 )
 
+// The TUI needs a terminal of at least minWidth by minHeight cells; below
+// that it asks for a larger one instead of drawing a garbled layout. Below
+// stackedWidth, the header and footer put their parts on separate lines.
+const (
+	minWidth     = 40
+	minHeight    = 8
+	stackedWidth = 80
+)
+
+// lowBandwidthEnv names the environment variable that enables the
+// low-bandwidth TUI, like the --low-bandwidth flag.
+const lowBandwidthEnv = "SHUTTER_LOW_BANDWIDTH"
+
+// lowBandwidthFPS caps the redraws per second of the low-bandwidth TUI.
+// Key presses arriving in between are drawn together in the next frame.
+const lowBandwidthFPS = 8
+
 type model struct {
 	snapshots    []files.SnapshotInfo
 	current      int
 	newSnap      *files.Snapshot
 	accepted     *files.Snapshot
+	corrupted    bool                // The accepted snapshot does not match its stored digest
+	secrets      *files.SecretsError // Suspected secrets found by the last accept
+	showRaw      bool                // Show the content before scrubbing (v key)
+	owner        string              // Who owns the current snapshot, per CODEOWNERS
 	diffLines    []diff.DiffLine
 	choice       string
 	done         bool
 	err          error
-	acceptedAll  int
-	rejectedAll  int
-	skippedAll   int
+	summary      *review.Summary
 	actionResult string
 	viewport     viewport.Model
 	ready        bool
 	width        int
 	height       int
+	inline       bool // Render in the normal terminal buffer instead of the alt screen
+	lowBandwidth bool // Redraw less often and scroll by half pages, e.g. over SSH
+	quiet        bool
+
+	// The overview lists the pending snapshots grouped by package and by
+	// the test that took them.
+	overview  bool
+	collapsed map[string]bool // Packages and tests whose snapshots are hidden in the overview
+	cursor    int             // Row of the overview the cursor is on
 }
 
+// diffToolMsg reports that the external diff tool opened with the d key
+// exited.
+type diffToolMsg struct{ err error }
+
 func initialModel() (model, error) {
-	snapshots, err := files.ListNewSnapshots()
+	snapshots, err := review.PendingSnapshots()
 	if err != nil {
 		return model{}, err
 	}
@@ -88,6 +124,7 @@ func initialModel() (model, error) {
 	m := model{
 		snapshots: snapshots,
 		current:   0,
+		summary:   review.NewSummary(snapshots),
 	}
 
 	if err := m.loadCurrentSnapshot(); err != nil {
@@ -104,31 +141,71 @@ func (m *model) loadCurrentSnapshot() error {
 	}
 
 	snapshotInfo := m.snapshots[m.current]
+	m.secrets = nil
+	m.showRaw = false
+	m.owner = review.OwnerLabel(snapshotInfo)
 
-	newSnap, err := files.ReadSnapshotFromPath(snapshotInfo.Path)
+	newSnap, err := files.CurrentStorage().Read(snapshotInfo, files.StateNew)
 	if err != nil {
 		return err
 	}
 	m.newSnap = newSnap
 
-	accepted, err := files.ReadSnapshotWithDir(snapshotInfo.Dir, snapshotInfo.Title, "accepted")
+	accepted, err := files.CurrentStorage().Read(snapshotInfo, files.StateAccepted)
 	if err == nil {
+		// Best effort: without git there is simply no commit to show.
+		accepted.Commit, _ = files.LastCommit(accepted.Path)
 		m.accepted = accepted
+		m.corrupted = accepted.Corrupted()
 		diffLines := computeDiffLines(accepted, newSnap)
 		m.diffLines = diffLines
 	} else {
 		m.accepted = nil
+		m.corrupted = false
 		m.diffLines = nil
 	}
 
 	return nil
 }
 
+// advance moves to the next snapshot still remaining after the current one,
+// wrapping around to snapshots passed over by jumping ahead in the
+// overview. The review is done when none remain.
+func (m *model) advance() error {
+	n := len(m.snapshots)
+	for i := 1; i <= n; i++ {
+		next := (m.current + i) % n
+		if m.summary.Outcome(m.snapshots[next]) == review.Remaining {
+			m.current = next
+			return m.loadCurrentSnapshot()
+		}
+	}
+	m.current = n
+	return m.loadCurrentSnapshot()
+}
+
+// remaining returns the snapshots not decided on yet, starting with the
+// current one.
+func (m *model) remaining() []files.SnapshotInfo {
+	var infos []files.SnapshotInfo
+	n := len(m.snapshots)
+	for i := range n {
+		info := m.snapshots[(m.current+i)%n]
+		if m.summary.Outcome(info) == review.Remaining {
+			infos = append(infos, info)
+		}
+	}
+	return infos
+}
+
 func computeDiffLines(old, new *files.Snapshot) []diff.DiffLine {
-	return diff.Histogram(old.Content, new.Content)
+	return diff.Snapshots(old, new)
 }
 
 func (m model) Init() tea.Cmd {
+	if m.inline {
+		return nil
+	}
 	return tea.EnterAltScreen
 }
 
@@ -145,34 +222,73 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 		headerHeight := 1
 		footerHeight := 1
+		if m.stacked() {
+			headerHeight, footerHeight = 2, 2
+		}
 		verticalMarginHeight := headerHeight + footerHeight
 
 		if !m.ready {
-			m.viewport = viewport.New(msg.Width, msg.Height-verticalMarginHeight)
+			m.viewport = viewport.New(msg.Width, max(msg.Height-verticalMarginHeight, 0))
 			m.viewport.YPosition = headerHeight
+			if m.lowBandwidth {
+				m.viewport.MouseWheelEnabled = false
+				m.viewport.KeyMap = lowBandwidthKeyMap()
+			}
 			m.ready = true
 			m.updateViewportContent()
 		} else {
 			m.viewport.Width = msg.Width
-			m.viewport.Height = msg.Height - verticalMarginHeight
+			m.viewport.Height = max(msg.Height-verticalMarginHeight, 0)
+			m.viewport.YPosition = headerHeight
 			m.updateViewportContent()
 		}
 
+	case diffToolMsg:
+		// git difftool exits non-zero when the files differ, so only a
+		// failure to start the tool is an error.
+		var exitErr *exec.ExitError
+		if msg.err != nil && !errors.As(msg.err, &exitErr) {
+			m.err = msg.err
+		}
+
 	case tea.KeyMsg:
+		if m.tooSmall() && msg.String() != "q" && msg.String() != "ctrl+c" && msg.String() != "esc" {
+			// Nothing is shown to act on.
+			return m, nil
+		}
+		if m.overview {
+			return m, m.updateOverview(msg)
+		}
+
 		switch msg.String() {
+		case "o":
+			m.openOverview()
+			return m, nil
+
 		case "q", "ctrl+c", "esc":
 			m.done = true
 			return m, tea.Quit
 
 		case "a":
-			// Accept current snapshot
+			// Accept current snapshot. If it may contain secrets, warn
+			// first and accept only when a is pressed again.
 			snapshotInfo := m.snapshots[m.current]
-			if err := files.AcceptSnapshotInfo(snapshotInfo); err != nil {
+			changed := review.ChangedBytesFor(snapshotInfo)
+			var err error
+			if m.secrets != nil {
+				err = files.CurrentStorage().Accept(snapshotInfo, true)
+			} else {
+				err = review.AcceptSnapshot(snapshotInfo)
+			}
+			var secretsErr *files.SecretsError
+			if errors.As(err, &secretsErr) {
+				m.secrets = secretsErr
+				m.updateViewportContent()
+			} else if err != nil {
 				m.err = err
 			} else {
-				m.acceptedAll++
-				m.current++
-				if err := m.loadCurrentSnapshot(); err != nil {
+				m.summary.Record(snapshotInfo, review.Accepted, changed)
+				if err := m.advance(); err != nil {
 					m.err = err
 				}
 				if m.done {
@@ -184,12 +300,11 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		case "r":
 			// Reject current snapshot
 			snapshotInfo := m.snapshots[m.current]
-			if err := files.RejectSnapshotInfo(snapshotInfo); err != nil {
+			if err := files.CurrentStorage().Reject(snapshotInfo); err != nil {
 				m.err = err
 			} else {
-				m.rejectedAll++
-				m.current++
-				if err := m.loadCurrentSnapshot(); err != nil {
+				m.summary.Record(snapshotInfo, review.Rejected, 0)
+				if err := m.advance(); err != nil {
 					m.err = err
 				}
 				if m.done {
@@ -200,9 +315,8 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 		case "s":
 			// Skip current snapshot
-			m.skippedAll++
-			m.current++
-			if err := m.loadCurrentSnapshot(); err != nil {
+			m.summary.Record(m.snapshots[m.current], review.Skipped, 0)
+			if err := m.advance(); err != nil {
 				m.err = err
 			}
 			if m.done {
@@ -211,32 +325,53 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			m.updateViewportContent()
 
 		case "A":
-			// Accept all remaining
-			for i := m.current; i < len(m.snapshots); i++ {
-				if err := files.AcceptSnapshotInfo(m.snapshots[i]); err != nil {
-					m.err = err
-					break
-				}
-				m.acceptedAll++
-			}
+			// Accept all remaining, leaving any that may contain secrets
+			// pending
+			_, err := review.AcceptEach(m.remaining(), func(info files.SnapshotInfo, changed int) {
+				m.summary.Record(info, review.Accepted, changed)
+			}, nil)
+			m.err = err
 			m.done = true
 			return m, tea.Quit
 
 		case "R":
 			// Reject all remaining
-			for i := m.current; i < len(m.snapshots); i++ {
-				if err := files.RejectSnapshotInfo(m.snapshots[i]); err != nil {
+			for _, snapshotInfo := range m.remaining() {
+				if err := files.CurrentStorage().Reject(snapshotInfo); err != nil {
 					m.err = err
 					break
 				}
-				m.rejectedAll++
+				m.summary.Record(snapshotInfo, review.Rejected, 0)
 			}
 			m.done = true
 			return m, tea.Quit
 
+		case "v":
+			// Toggle between the scrubbed and the raw content, when the
+			// snapshot was taken with KeepRaw
+			if m.newSnap != nil && m.newSnap.RawFingerprint != "" {
+				m.showRaw = !m.showRaw
+				m.updateViewportContent()
+			}
+			return m, nil
+
+		case "d":
+			// Open the current snapshot in the external diff tool,
+			// handing it the terminal until it exits
+			diffTool, err := review.DiffToolCommand(m.snapshots[m.current])
+			if err != nil {
+				m.err = err
+				break
+			}
+			return m, tea.ExecProcess(diffTool, func(err error) tea.Msg {
+				return diffToolMsg{err}
+			})
+
 		case "S":
 			// Skip all remaining
-			m.skippedAll = len(m.snapshots) - m.current
+			for _, snapshotInfo := range m.remaining() {
+				m.summary.Record(snapshotInfo, review.Skipped, 0)
+			}
 			m.done = true
 			return m, tea.Quit
 		}
@@ -249,19 +384,61 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	return m, tea.Batch(cmds...)
 }
 
+// lowBandwidthKeyMap returns the viewport keys of the low-bandwidth TUI.
+// The line-by-line scroll keys move half a page instead, as every line
+// scrolled repaints the whole viewport.
+func lowBandwidthKeyMap() viewport.KeyMap {
+	km := viewport.DefaultKeyMap()
+	km.HalfPageDown.SetKeys(append(km.HalfPageDown.Keys(), km.Down.Keys()...)...)
+	km.HalfPageUp.SetKeys(append(km.HalfPageUp.Keys(), km.Up.Keys()...)...)
+	km.Down.SetEnabled(false)
+	km.Up.SetEnabled(false)
+	return km
+}
+
 func (m *model) updateViewportContent() {
 	if !m.ready {
 		return
 	}
 
+	if m.overview {
+		content, cursorLine := m.renderOverview()
+		m.viewport.SetContent(contentStyle.Render(content))
+		// Keep the cursor in view, allowing for the content's top padding.
+		line := cursorLine + 1
+		if line < m.viewport.YOffset || line >= m.viewport.YOffset+m.viewport.Height {
+			m.viewport.SetYOffset(line - m.viewport.Height/2)
+		}
+		return
+	}
+
 	var b strings.Builder
 
 	// Show diff or new snapshot
-	if m.accepted != nil && m.diffLines != nil {
-		b.WriteString(pretty.DiffSnapshotBox(m.accepted, m.newSnap, m.diffLines, m.width))
+	if m.corrupted {
+		b.WriteString(rejectStyle.Render(review.CorruptedNotice) + "\n\n")
+	}
+	if m.secrets != nil {
+		b.WriteString(rejectStyle.Render("✗ This snapshot may contain secrets:") + "\n")
+		for _, f := range m.secrets.Findings {
+			b.WriteString("    " + f.String() + "\n")
+		}
+		b.WriteString(rejectStyle.Render("Press a again to accept anyway") + "\n\n")
+	}
+	if m.showRaw {
+		b.WriteString(skipStyle.Render("Raw content, before scrubbers and ignore patterns") + "\n")
+		if m.newSnap.RawFingerprint != files.OptionsFingerprint(m.newSnap.Options) {
+			b.WriteString(rejectStyle.Render("✗ Recorded with different options than the snapshot") + "\n")
+		}
+		b.WriteString("\n")
+		raw := *m.newSnap
+		raw.Content = raw.Raw
+		b.WriteString(pretty.NewSnapshotBox(&raw, m.boxWidth()))
+	} else if m.accepted != nil && m.diffLines != nil {
+		b.WriteString(pretty.DiffSnapshotBox(m.accepted, m.newSnap, m.diffLines, m.boxWidth()))
 	} else {
 		if m.newSnap != nil {
-			b.WriteString(pretty.NewSnapshotBox(m.newSnap, m.width))
+			b.WriteString(pretty.NewSnapshotBox(m.newSnap, m.boxWidth()))
 		}
 	}
 
@@ -289,33 +466,78 @@ func (m *model) updateViewportContent() {
 		skipStyle.Render("skip"),
 	)
 	b.WriteString(skipLine)
+	b.WriteString("\n")
+
+	diffToolLine := lipgloss.JoinHorizontal(lipgloss.Left,
+		keyStyle.Render("[d]"),
+		helpTextStyle.Render(" "),
+		helpTextStyle.Render("open in diff tool"),
+	)
+	b.WriteString(diffToolLine)
+	b.WriteString("\n")
+
+	if m.newSnap != nil && m.newSnap.RawFingerprint != "" {
+		view := "show raw content"
+		if m.showRaw {
+			view = "show scrubbed content"
+		}
+		rawLine := lipgloss.JoinHorizontal(lipgloss.Left,
+			keyStyle.Render("[v]"),
+			helpTextStyle.Render(" "),
+			helpTextStyle.Render(view),
+		)
+		b.WriteString(rawLine)
+		b.WriteString("\n")
+	}
+
+	overviewLine := lipgloss.JoinHorizontal(lipgloss.Left,
+		keyStyle.Render("[o]"),
+		helpTextStyle.Render(" "),
+		helpTextStyle.Render("overview by test"),
+	)
+	b.WriteString(overviewLine)
 
 	m.viewport.SetContent(contentStyle.Render(b.String()))
 	m.viewport.GotoTop()
 }
 
+// tooSmall reports whether the terminal is smaller than the TUI needs.
+func (m model) tooSmall() bool {
+	return m.ready && (m.width < minWidth || m.height < minHeight)
+}
+
+// stacked reports whether the terminal is too narrow to fit the parts of
+// the header and footer side by side.
+func (m model) stacked() bool {
+	return m.width < stackedWidth
+}
+
+// boxWidth returns the width of snapshot boxes in the viewport, inside the
+// padding of the content.
+func (m model) boxWidth() int {
+	return m.width - contentStyle.GetHorizontalPadding()
+}
+
+// statusBar renders lines as a status bar spanning the terminal, cutting
+// off what does not fit rather than wrapping it onto more lines.
+func (m model) statusBar(lines ...string) string {
+	for i, line := range lines {
+		lines[i] = statusBarStyle.Width(m.width).Render(lipgloss.NewStyle().MaxWidth(m.width).Render(line))
+	}
+	return lipgloss.JoinVertical(lipgloss.Left, lines...)
+}
+
 func (m model) View() string {
 	if m.done {
 		if len(m.snapshots) == 0 {
 			return pretty.Success("✓ No new snapshots to review\n")
 		}
 
-		// Build summary from counts
-		var summary []string
-		if m.acceptedAll > 0 {
-			summary = append(summary, fmt.Sprintf("✓ Accepted %d", m.acceptedAll))
-		}
-		if m.rejectedAll > 0 {
-			summary = append(summary, fmt.Sprintf("⊘ Rejected %d", m.rejectedAll))
-		}
-		if m.skippedAll > 0 {
-			summary = append(summary, fmt.Sprintf("⊘ Skipped %d", m.skippedAll))
-		}
-
-		if len(summary) > 0 {
-			return pretty.Success(strings.Join(summary, " • ") + "\n")
+		if m.inline && m.quiet {
+			// The final frame stays on screen in inline mode.
+			return ""
 		}
-		return pretty.Success("\n✓ Review complete\n")
+		return m.summary.String()
 	}
 
 	if m.err != nil {
@@ -326,35 +548,41 @@ func (m model) View() string {
 		return "\n  Initializing..."
 	}
 
+	if m.tooSmall() {
+		return fmt.Sprintf("Terminal too small\nNeed %d×%d, have %d×%d\nPress q to quit", minWidth, minHeight, m.width, m.height)
+	}
+
 	// Header
 	snapshotTitle := m.snapshots[m.current].Title // fallback to snapshot title
 	if m.newSnap != nil && m.newSnap.Title != "" {
 		snapshotTitle = m.newSnap.Title
 	}
-	header := lipgloss.JoinHorizontal(
-		lipgloss.Left,
-		titleStyle.Render("Review Snapshots"),
-		counterStyle.Render(fmt.Sprintf("[%d/%d] %s", m.current+1, len(m.snapshots), snapshotTitle)),
-	)
-	headerStyled := statusBarStyle.Width(m.width).Render(header)
+	title := titleStyle.Render("Review Snapshots")
+	counter := counterStyle.Render(fmt.Sprintf("[%d/%d] %s%s%s", m.current+1, len(m.snapshots), snapshotTitle, review.VariantLabel(m.snapshots, m.current), m.owner))
 
 	// Footer with snapshot filename and scroll info
-	snapshotFile := files.SnapshotFileName(m.snapshots[m.current].Title) + ".snap.new"
+	snapshotFile := files.DisplayPath(m.snapshots[m.current].Path)
 	fileInfo := helpStyle.Render(snapshotFile)
 	scrollInfo := fmt.Sprintf("%3.f%%", m.viewport.ScrollPercent()*100)
 	scrollStyled := helpStyle.Render(scrollInfo)
 
-	// Calculate spacing between filename and scroll percentage
-	totalFooterWidth := lipgloss.Width(fileInfo) + lipgloss.Width(scrollStyled)
-	spacing := max(m.width-totalFooterWidth-2, 1)
-
-	// Create footer with filename on left and scroll info on right
-	footer := lipgloss.JoinHorizontal(lipgloss.Bottom,
-		fileInfo,
-		strings.Repeat(" ", spacing),
-		scrollStyled,
-	)
-	footerStyled := statusBarStyle.Width(m.width).Render(footer)
+	var headerStyled, footerStyled string
+	if m.stacked() {
+		headerStyled = m.statusBar(title, counter)
+		footerStyled = m.statusBar(fileInfo, scrollStyled)
+	} else {
+		// Calculate spacing between filename and scroll percentage
+		totalFooterWidth := lipgloss.Width(fileInfo) + lipgloss.Width(scrollStyled)
+		spacing := max(m.width-totalFooterWidth-2, 1)
+
+		// Create footer with filename on left and scroll info on right
+		headerStyled = m.statusBar(lipgloss.JoinHorizontal(lipgloss.Left, title, counter))
+		footerStyled = m.statusBar(lipgloss.JoinHorizontal(lipgloss.Bottom,
+			fileInfo,
+			strings.Repeat(" ", spacing),
+			scrollStyled,
+		))
+	}
 
 	// Viewport content
 	// TODO: it would be nice if we could show the input on the right side?
@@ -369,92 +597,305 @@ func (m model) View() string {
 	)
 }
 
-func acceptAll() error {
-	snapshots, err := files.ListNewSnapshots()
-	if err != nil {
-		return err
+// argAt returns os.Args[i], or "" if there are not enough arguments.
+func argAt(i int) string {
+	if i < len(os.Args) {
+		return os.Args[i]
 	}
+	return ""
+}
 
-	for _, snapshotInfo := range snapshots {
-		if err := files.AcceptSnapshotInfo(snapshotInfo); err != nil {
-			return err
+// flagValue returns the value of a "--name value" or "--name=value" flag.
+func flagValue(args []string, name string) string {
+	for i, arg := range args {
+		if value, ok := strings.CutPrefix(arg, name+"="); ok {
+			return value
+		}
+		if arg == name && i+1 < len(args) {
+			return args[i+1]
 		}
 	}
-
-	fmt.Printf(pretty.Success("✓ Accepted %d snapshot(s)\n"), len(snapshots))
-	return nil
+	return ""
 }
 
-func rejectAll() error {
-	snapshots, err := files.ListNewSnapshots()
-	if err != nil {
-		return err
-	}
-
-	for _, snapshotInfo := range snapshots {
-		if err := files.RejectSnapshotInfo(snapshotInfo); err != nil {
-			return err
+// hasFlag reports whether any of the given flags was passed.
+func hasFlag(args []string, names ...string) bool {
+	for _, arg := range args {
+		for _, name := range names {
+			if arg == name {
+				return true
+			}
 		}
 	}
-
-	fmt.Printf(pretty.Warning("⊘ Rejected %d snapshot(s)\n"), len(snapshots))
-	return nil
+	return false
 }
 
 func main() {
-	if len(os.Args) > 1 {
-		switch os.Args[1] {
-		case "accept-all":
-			if err := acceptAll(); err != nil {
-				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-				os.Exit(1)
-			}
-			return
-		case "reject-all":
-			if err := rejectAll(); err != nil {
-				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-				os.Exit(1)
-			}
-			return
-		case "help", "-h", "--help":
-			fmt.Println(`Usage: shutter-tui [COMMAND]
+	quiet := hasFlag(os.Args[1:], "--quiet", "-q")
+	yes := hasFlag(os.Args[1:], "--yes", "-y")
+	review.SetQuiet(quiet)
+	review.SetAllowSecrets(hasFlag(os.Args[1:], "--allow-secrets"))
+	review.SetSuite(flagValue(os.Args[1:], "--suite"))
+	review.SetMine(hasFlag(os.Args[1:], "--mine"))
+	if hasFlag(os.Args[1:], "--accessible") {
+		pretty.SetAccessible(true)
+	}
+	if root := flagValue(os.Args[1:], "--root"); root != "" {
+		os.Setenv(files.RootEnv, root)
+	}
+	if err := review.SetDiffStyle(hasFlag(os.Args[1:], "--legend"), hasFlag(os.Args[1:], "--no-metadata")); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(review.ExitError)
+	}
+
+	cmd := argAt(1)
+	if strings.HasPrefix(cmd, "-") && cmd != "-h" && cmd != "--help" {
+		cmd = ""
+	}
+
+	var err error
+	switch cmd {
+	case "", "review":
+		lowBandwidth, _ := strconv.ParseBool(os.Getenv(lowBandwidthEnv))
+		err = runTUI(quiet, hasFlag(os.Args[1:], "--inline", "--no-altscreen"), lowBandwidth || hasFlag(os.Args[1:], "--low-bandwidth"))
+	case "status":
+		err = review.Status()
+	case "accept":
+		if name := argAt(2); name != "" && !strings.HasPrefix(name, "-") {
+			err = review.AcceptNamed(name)
+			break
+		}
+		err = review.ConfirmAcceptAll(yes)
+	case "accept-all":
+		err = review.ConfirmAcceptAll(yes)
+	case "reject", "reject-all":
+		err = review.ConfirmRejectAll(yes)
+	case "migrate":
+		err = review.Migrate()
+	case "collect-bazel":
+		var testlogs string
+		if len(os.Args) > 2 && !strings.HasPrefix(os.Args[2], "-") {
+			testlogs = os.Args[2]
+		}
+		err = review.CollectBazel(testlogs)
+	case "restore":
+		if hasFlag(os.Args[2:], "--purge") {
+			err = review.PurgeTrash(yes)
+			break
+		}
+		err = review.Restore(argAt(2))
+	case "history", "diff", "resolve":
+		name := argAt(2)
+		switch {
+		case name == "" || strings.HasPrefix(name, "-"):
+			err = fmt.Errorf("%s requires a snapshot name, e.g. shutter %s TestUsers/admin_case", cmd, cmd)
+		case cmd == "history":
+			err = review.History(name)
+		case cmd == "resolve":
+			err = review.Resolve(name)
+		case hasFlag(os.Args[3:], "--external"):
+			err = review.DiffExternal(name, flagValue(os.Args[3:], "--against"))
+		default:
+			err = review.Diff(name, flagValue(os.Args[3:], "--against"))
+		}
+	case "prune":
+		err = review.PruneFlags(
+			flagValue(os.Args[2:], "--older-than"),
+			flagValue(os.Args[2:], "--larger-than"),
+			hasFlag(os.Args[2:], "--orphaned"),
+			hasFlag(os.Args[2:], "--dry-run"),
+			yes,
+		)
+	case "clean":
+		err = review.Clean(hasFlag(os.Args[2:], "--from-manifest"), hasFlag(os.Args[2:], "--dry-run"), yes)
+	case "audit":
+		err = review.Audit()
+	case "audit-log":
+		var name string
+		if len(os.Args) > 2 && !strings.HasPrefix(os.Args[2], "-") {
+			name = os.Args[2]
+		}
+		err = review.AuditLog(name, flagValue(os.Args[2:], "--since"), flagValue(os.Args[2:], "--action"))
+	case "flakes":
+		err = review.Flakes()
+	case "report":
+		err = review.Report(hasFlag(os.Args[2:], "--summary-json"))
+	case "coverage":
+		err = review.Coverage()
+	case "regen":
+		var pattern string
+		if len(os.Args) > 2 && !strings.HasPrefix(os.Args[2], "-") {
+			pattern = os.Args[2]
+		}
+		err = review.Regen(pattern, yes)
+	case "help", "-h", "--help":
+		fmt.Println(`Usage: shutter-tui [COMMAND] [--yes] [--quiet] [--inline]
 
 Commands:
   review      Review and accept/reject new snapshots (default)
+  status      List snapshots pending review and corrupted accepted snapshots
+  accept      Accept the snapshot named as printed by a failing test, e.g.
+              accept "TestUsers/admin case"; without a name, like accept-all
   accept-all  Accept all new snapshots
-  reject-all  Reject all new snapshots
+  reject-all  Reject all new snapshots (also: reject)
+  migrate     Move snapshots into per-test directories and the configured format
+  collect-bazel
+              Copy the pending snapshots Bazel tests wrote to their outputs
+              into the workspace, from bazel-testlogs or the given directory
+  restore     Restore a rejected snapshot by name, or list rejected snapshots;
+              with --purge, empty the trash
+  history     List the accepted versions of a snapshot
+  diff        Compare an earlier accepted version with the current snapshot
+  resolve     Resolve git merge conflicts in an accepted snapshot with the
+              configured merge tool
+  prune       Delete accepted snapshots by age, size, or missing test
+  clean       Delete accepted snapshots the last test run did not compare
+              (--from-manifest, recorded with $SHUTTER_MANIFEST=1)
+  audit       Scan accepted snapshots for secrets such as API keys and emails
+  audit-log   List the accepts and rejects recorded with audit_log = true in
+              .shutterconfig, optionally those of titles containing a name
+  flakes      List snapshots whose content changed between runs recorded
+              with $SHUTTER_DETECT_FLAKES
+  report      Summarize pending snapshots per package; with --summary-json,
+              as JSON with short diffs for bots
+  coverage    List the tests of each package that take snapshots and those
+              that do not, and snapshots no Snap call takes anymore
+  regen       Re-run the tests of accepted snapshots matching an optional
+              pattern with $SHUTTER_UPDATE=1 and report what changed
   help        Show this help message
 
+Flags:
+  -y, --yes   Skip the confirmation prompt for accept-all, reject-all,
+              restore --purge and regen
+  -q, --quiet Suppress headers, confirmations and summaries
+  --allow-secrets
+              Accept snapshots even if they look like they contain secrets
+  --suite     Only act on the snapshots of the named suite, as taken with
+              shutter.Suite (review, status, accept, reject, report)
+  --mine      Only act on the snapshots owned by your teams, per CODEOWNERS
+              and the owners and teams settings of .shutterconfig
+  --accessible
+              Screen-reader-friendly output: no color or box drawing, diff
+              lines labeled ADDED:, REMOVED: and CONTEXT: ($SHUTTER_ACCESSIBLE)
+  --legend    Name the accepted and pending sides above each diff
+  --no-metadata
+              Leave out the title, test, options and other metadata lines
+              above each snapshot, e.g. for screenshots or narrow terminals
+  --inline, --no-altscreen
+              Review in the normal terminal buffer, keeping the scrollback
+  --low-bandwidth
+              For slow connections such as SSH: redraw less often, scroll by
+              half pages, and leave the mouse alone ($SHUTTER_LOW_BANDWIDTH)
+  --root      Project root to search for snapshots (default: $SHUTTER_ROOT,
+              else the enclosing go.work or go.mod directory)
+  --against   Version for diff to compare against (default: the previous one)
+  --external  Open diff in the external diff tool instead of printing it
+  --summary-json
+              Print report as JSON (schema_version, totals, packages and
+              snapshots with short diffs)
+  --since, --action
+              Only list audit log entries this recent (e.g. 30d) or of this
+              action (accept or reject)
+  --older-than, --larger-than, --orphaned, --dry-run
+              Prune criteria (all given criteria must match), e.g. 180d, 1MB;
+              --dry-run also applies to clean
+
+Exit codes (review, status, accept, reject, accept-all, reject-all):
+  0           No snapshots are pending review
+  1           Snapshots are still pending review
+  2           The command failed, or status found corrupted snapshots
+Other commands, and accept with a name, exit with 0 on success and 2 on
+failure.
+
 Interactive Controls:
-  a           Accept current snapshot
+  a           Accept current snapshot (press again to accept one that may
+              contain secrets)
   r           Reject current snapshot
   s           Skip current snapshot
-  A           Accept all remaining snapshots
+  A           Accept all remaining snapshots, except any that may contain
+              secrets
   R           Reject all remaining snapshots
   S           Skip all remaining snapshots
+  v           Toggle between the scrubbed content and the raw content, for
+              snapshots taken with KeepRaw or $SHUTTER_KEEP_RAW=1
   q           Quit`)
-			return
+		return
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown command: %s (see shutter help)\n", cmd)
+		os.Exit(review.ExitError)
+	}
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+	}
+
+	switch cmd {
+	case "", "review", "status", "accept", "reject", "accept-all", "reject-all":
+		// These commands report whether snapshots are still pending,
+		// except accept with a name, which leaves the others to review.
+		if name := argAt(2); cmd != "accept" || name == "" || strings.HasPrefix(name, "-") {
+			os.Exit(review.ExitCode(err))
 		}
 	}
+	if err != nil {
+		os.Exit(review.ExitError)
+	}
+}
+
+// runTUI runs the interactive review and prints its summary afterwards,
+// unless quiet is set. With inline, the review is rendered in the normal
+// terminal buffer so earlier output stays in the scrollback. With
+// lowBandwidth, it is redrawn at most lowBandwidthFPS times a second and
+// the mouse is not captured, so a slow connection is not flooded with
+// frames and mouse reports. The review lock is held for the whole session.
+func runTUI(quiet, inline, lowBandwidth bool) error {
+	unlock, err := files.LockReview()
+	if err != nil {
+		return err
+	}
+	defer unlock()
 
 	m, err := initialModel()
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-		os.Exit(1)
+		return err
 	}
 
 	if m.done && len(m.snapshots) == 0 {
-		fmt.Println(m.View())
-		return
+		if !quiet {
+			fmt.Println(m.View())
+		}
+		return nil
 	}
 
-	p := tea.NewProgram(
-		m,
-		tea.WithAltScreen(),
-		tea.WithMouseCellMotion(),
-	)
-	if _, err := p.Run(); err != nil {
-		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-		os.Exit(1)
+	m.inline = inline
+	m.lowBandwidth = lowBandwidth
+	m.quiet = quiet
+	opts := []tea.ProgramOption{tea.WithAltScreen(), tea.WithMouseCellMotion()}
+	if inline {
+		// Mouse capture would stop the terminal from scrolling and selecting.
+		opts = nil
+	}
+	if lowBandwidth {
+		opts = []tea.ProgramOption{tea.WithFPS(lowBandwidthFPS)}
+		if !inline {
+			opts = append(opts, tea.WithAltScreen())
+		}
 	}
+	p := tea.NewProgram(m, opts...)
+	final, err := p.Run()
+	if err != nil {
+		return err
+	}
+
+	// The alt screen is cleared on exit, so print the summary afterwards.
+	// Inline, it is already on screen as the final frame.
+	if fm, ok := final.(model); ok && fm.done {
+		if fm.err != nil {
+			return fm.err
+		}
+		if !quiet && !inline {
+			fmt.Print(fm.View())
+		}
+	}
+	return nil
 }
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/ptdewey/shutter/internal/review"
+)
+
+// overviewRow is one line of the overview: the header of a package group,
+// the header of a test group within it when test is set, or, when index is
+// not -1, the snapshot m.snapshots[index] within the group of its test.
+type overviewRow struct {
+	pkg   string
+	test  string
+	index int
+}
+
+// group returns the key of the innermost group the row belongs to, by which
+// it is collapsed.
+func (r overviewRow) group() string {
+	if r.test == "" {
+		return r.pkg
+	}
+	return testGroup(r.pkg, r.test)
+}
+
+func testGroup(pkg, test string) string {
+	return pkg + "\x00" + test
+}
+
+// overviewRows returns the lines of the overview: each package with the
+// tests that took its snapshots below it, and each test with its snapshots,
+// unless the group is collapsed.
+func (m *model) overviewRows() []overviewRow {
+	var rows []overviewRow
+	for _, pkg := range m.summary.Packages() {
+		rows = append(rows, overviewRow{pkg: pkg, index: -1})
+		if m.collapsed[pkg] {
+			continue
+		}
+		for _, test := range m.summary.Tests(pkg) {
+			rows = append(rows, overviewRow{pkg: pkg, test: test, index: -1})
+			if m.collapsed[testGroup(pkg, test)] {
+				continue
+			}
+			for i, info := range m.snapshots {
+				if review.PackageOf(info) == pkg && review.TestOf(info) == test {
+					rows = append(rows, overviewRow{pkg: pkg, test: test, index: i})
+				}
+			}
+		}
+	}
+	return rows
+}
+
+// openOverview shows the overview with the cursor on the current snapshot,
+// expanding its group if needed.
+func (m *model) openOverview() {
+	m.overview = true
+	pkg := review.PackageOf(m.snapshots[m.current])
+	delete(m.collapsed, pkg)
+	delete(m.collapsed, testGroup(pkg, review.TestOf(m.snapshots[m.current])))
+	for i, row := range m.overviewRows() {
+		if row.index == m.current {
+			m.cursor = i
+		}
+	}
+	m.updateViewportContent()
+}
+
+// updateOverview handles a key press in the overview.
+func (m *model) updateOverview(msg tea.KeyMsg) tea.Cmd {
+	rows := m.overviewRows()
+	row := rows[m.cursor]
+
+	switch msg.String() {
+	case "ctrl+c":
+		m.done = true
+		return tea.Quit
+	case "o", "esc", "q":
+		m.overview = false
+	case "up", "k":
+		m.cursor = max(m.cursor-1, 0)
+	case "down", "j":
+		m.cursor = min(m.cursor+1, len(rows)-1)
+	case "left", "h":
+		// Collapse the group the cursor is in and move onto its header.
+		if m.collapsed == nil {
+			m.collapsed = map[string]bool{}
+		}
+		m.collapsed[row.group()] = true
+		m.cursor = m.groupRow(row.group())
+	case "right", "l":
+		delete(m.collapsed, row.group())
+	case "enter", " ":
+		if row.index == -1 {
+			if m.collapsed[row.group()] {
+				delete(m.collapsed, row.group())
+			} else {
+				if m.collapsed == nil {
+					m.collapsed = map[string]bool{}
+				}
+				m.collapsed[row.group()] = true
+			}
+			break
+		}
+		// Jump to the snapshot. Decided snapshots no longer have a
+		// pending file to show.
+		if m.summary.Outcome(m.snapshots[row.index]) != review.Remaining {
+			break
+		}
+		m.current = row.index
+		if err := m.loadCurrentSnapshot(); err != nil {
+			m.err = err
+		}
+		m.overview = false
+	}
+	m.updateViewportContent()
+	return nil
+}
+
+// groupRow returns the row of the header of the group with key group.
+func (m *model) groupRow(group string) int {
+	for i, row := range m.overviewRows() {
+		if row.index == -1 && row.group() == group {
+			return i
+		}
+	}
+	return 0
+}
+
+// renderOverview renders the overview and returns it with the line the
+// cursor is on.
+func (m *model) renderOverview() (string, int) {
+	var b strings.Builder
+	cursorLine := 0
+	for i, row := range m.overviewRows() {
+		marker := "  "
+		if i == m.cursor {
+			marker = keyStyle.Render("› ")
+			cursorLine = i
+		}
+		b.WriteString(marker)
+
+		if row.index == -1 {
+			counts := m.summary.PackageCounts(row.pkg)
+			indent, name := "", row.pkg
+			if row.test != "" {
+				counts = m.summary.TestCounts(row.pkg, row.test)
+				indent, name = "  ", row.test
+			}
+			arrow := "▾"
+			if m.collapsed[row.group()] {
+				arrow = "▸"
+			}
+			progress := fmt.Sprintf("%d/%d reviewed", counts.Reviewed(), counts.Total())
+			if counts.Remaining == 0 {
+				progress = acceptStyle.Render(progress)
+			} else {
+				progress = helpTextStyle.Render(progress)
+			}
+			style := titleStyle.UnsetPadding()
+			if row.test != "" {
+				style = style.UnsetBold()
+			}
+			b.WriteString(indent + style.Render(arrow+" "+name) + "  " + progress + "\n")
+			continue
+		}
+
+		info := m.snapshots[row.index]
+		var status string
+		switch m.summary.Outcome(info) {
+		case review.Accepted:
+			status = acceptStyle.Render("✓")
+		case review.Rejected:
+			status = rejectStyle.Render("✗")
+		case review.Skipped:
+			status = skipStyle.Render("⊘")
+		default:
+			status = helpTextStyle.Render("…")
+		}
+		// The test is shown in the header above.
+		title := strings.TrimPrefix(info.Title, row.test+"/") + review.VariantLabel(m.snapshots, row.index)
+		if row.index == m.current {
+			title = counterStyle.UnsetPadding().Bold(true).Render(title)
+		}
+		b.WriteString("      " + status + " " + title + "\n")
+	}
+
+	b.WriteString("\n" + helpTextStyle.Render("↑/↓ move  enter open/toggle  ←/→ collapse/expand  o back"))
+	return b.String(), cursorLine
+}
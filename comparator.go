@@ -0,0 +1,46 @@
+package shutter
+
+// Comparator decides whether the content of a snapshot matches the accepted
+// snapshot, in place of string equality. Both are passed as they are
+// stored: formatted, scrubbed and normalized.
+type Comparator interface {
+	Equal(accepted, actual string) bool
+}
+
+// ComparatorFunc adapts a function to the Comparator interface.
+type ComparatorFunc func(accepted, actual string) bool
+
+// Equal calls f(accepted, actual).
+func (f ComparatorFunc) Equal(accepted, actual string) bool {
+	return f(accepted, actual)
+}
+
+// comparatorSetting compares snapshots with a Comparator.
+type comparatorSetting struct {
+	comparator Comparator
+}
+
+func (c *comparatorSetting) isOption() {}
+
+func (c *comparatorSetting) apply(cfg *snapConfig) {
+	cfg.comparator = c.comparator
+}
+
+// CompareWith matches a snapshot with its accepted version when c reports
+// them equal, for content whose meaning survives textual changes, such as
+// JSON compared semantically, lines compared as a set, or images compared
+// by similarity. Content that is equal as a string always matches. A
+// mismatch is stored, reviewed and shown as a diff like any other.
+//
+// Example:
+//
+//	sameLines := shutter.ComparatorFunc(func(accepted, actual string) bool {
+//	    a, b := strings.Split(accepted, "\n"), strings.Split(actual, "\n")
+//	    slices.Sort(a)
+//	    slices.Sort(b)
+//	    return slices.Equal(a, b)
+//	})
+//	shutter.SnapString(t, "hosts", strings.Join(hosts, "\n"), shutter.CompareWith(sameLines))
+func CompareWith(c Comparator) Option {
+	return &comparatorSetting{comparator: c}
+}
@@ -0,0 +1,101 @@
+// Package freeze is a compatibility shim for code written against the
+// original freeze import path. Every identifier forwards to its shutter
+// counterpart, so snapshots produced through this package are identical to
+// those produced by shutter directly.
+//
+// Deprecated: import github.com/ptdewey/shutter instead. This package will be
+// removed in a future release.
+package freeze
+
+import "github.com/ptdewey/shutter"
+
+// Option is an alias for shutter.Option.
+//
+// Deprecated: use shutter.Option.
+type Option = shutter.Option
+
+// Scrubber is an alias for shutter.Scrubber.
+//
+// Deprecated: use shutter.Scrubber.
+type Scrubber = shutter.Scrubber
+
+// IgnorePattern is an alias for shutter.IgnorePattern.
+//
+// Deprecated: use shutter.IgnorePattern.
+type IgnorePattern = shutter.IgnorePattern
+
+// The snapshot functions are declared as variables rather than wrappers so
+// that no extra frame sits between the test and shutter. This keeps caller
+// file detection pointing at the test file.
+var (
+	// Deprecated: use shutter.Snap.
+	Snap = shutter.Snap
+	// Deprecated: use shutter.SnapMany.
+	SnapMany = shutter.SnapMany
+	// Deprecated: use shutter.SnapString.
+	SnapString = shutter.SnapString
+	// Deprecated: use shutter.SnapJSON.
+	SnapJSON = shutter.SnapJSON
+	// Deprecated: use shutter.Helper.
+	Helper = shutter.Helper
+)
+
+// Review tools.
+var (
+	// Deprecated: use shutter.Review.
+	Review = shutter.Review
+	// Deprecated: use shutter.AcceptAll.
+	AcceptAll = shutter.AcceptAll
+	// Deprecated: use shutter.RejectAll.
+	RejectAll = shutter.RejectAll
+)
+
+// Scrubbers.
+var (
+	// Deprecated: use shutter.ScrubRegex.
+	ScrubRegex = shutter.ScrubRegex
+	// Deprecated: use shutter.ScrubExact.
+	ScrubExact = shutter.ScrubExact
+	// Deprecated: use shutter.ScrubUUID.
+	ScrubUUID = shutter.ScrubUUID
+	// Deprecated: use shutter.ScrubTimestamp.
+	ScrubTimestamp = shutter.ScrubTimestamp
+	// Deprecated: use shutter.ScrubEmail.
+	ScrubEmail = shutter.ScrubEmail
+	// Deprecated: use shutter.ScrubUnixTimestamp.
+	ScrubUnixTimestamp = shutter.ScrubUnixTimestamp
+	// Deprecated: use shutter.ScrubIP.
+	ScrubIP = shutter.ScrubIP
+	// Deprecated: use shutter.ScrubCreditCard.
+	ScrubCreditCard = shutter.ScrubCreditCard
+	// Deprecated: use shutter.ScrubJWT.
+	ScrubJWT = shutter.ScrubJWT
+	// Deprecated: use shutter.ScrubDate.
+	ScrubDate = shutter.ScrubDate
+	// Deprecated: use shutter.ScrubAPIKey.
+	ScrubAPIKey = shutter.ScrubAPIKey
+	// Deprecated: use shutter.ScrubWith.
+	ScrubWith = shutter.ScrubWith
+)
+
+// Ignore patterns.
+var (
+	// Deprecated: use shutter.IgnoreKeyValue.
+	IgnoreKeyValue = shutter.IgnoreKeyValue
+	// Deprecated: use shutter.IgnoreKeyPattern.
+	IgnoreKeyPattern = shutter.IgnoreKeyPattern
+	// Deprecated: use shutter.IgnoreKey.
+	IgnoreKey = shutter.IgnoreKey
+	// Deprecated: use shutter.IgnoreKeyMatching.
+	IgnoreKeyMatching = shutter.IgnoreKeyMatching
+	// Deprecated: use shutter.IgnoreSensitive.
+	IgnoreSensitive = shutter.IgnoreSensitive
+	// Deprecated: use shutter.IgnoreValue.
+	IgnoreValue = shutter.IgnoreValue
+	// Deprecated: use shutter.IgnoreWith.
+	IgnoreWith = shutter.IgnoreWith
+	// Deprecated: use shutter.IgnoreEmpty.
+	IgnoreEmpty = shutter.IgnoreEmpty
+	// Deprecated: use shutter.IgnoreNull.
+	IgnoreNull = shutter.IgnoreNull
+)
@@ -0,0 +1,20 @@
+package freeze_test
+
+import (
+	"testing"
+
+	"github.com/ptdewey/shutter/freeze"
+)
+
+func TestFreezeSnap(t *testing.T) {
+	freeze.Snap(t, "Freeze Compatibility", map[string]any{
+		"id":    "550e8400-e29b-41d4-a716-446655440000",
+		"email": "user@example.com",
+	}, freeze.ScrubUUID(), freeze.ScrubEmail())
+}
+
+func TestFreezeSnapJSON(t *testing.T) {
+	freeze.SnapJSON(t, "Freeze JSON Compatibility", `{"name": "alice", "password": "secret"}`,
+		freeze.IgnoreSensitive(),
+	)
+}
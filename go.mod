@@ -4,4 +4,9 @@ go 1.23.12
 
 toolchain go1.25.2
 
-require github.com/kortschak/utter v1.7.0
+require (
+	github.com/kortschak/utter v1.7.0
+	github.com/mattn/go-runewidth v0.0.16
+)
+
+require github.com/rivo/uniseg v0.2.0 // indirect
@@ -1,9 +1,12 @@
 package shutter
 
 import (
+	"fmt"
 	"regexp"
 	"slices"
 	"strings"
+
+	"github.com/ptdewey/shutter/internal/transform"
 )
 
 // exactKeyValueIgnore ignores exact key-value matches.
@@ -14,6 +17,16 @@ type exactKeyValueIgnore struct {
 
 func (e *exactKeyValueIgnore) isOption() {}
 
+// String leaves out the ignored value unless it is "*", since it may be the
+// very value kept out of the snapshot.
+func (e *exactKeyValueIgnore) String() string {
+	value := "…"
+	if e.value == "*" {
+		value = quoteArg(e.value)
+	}
+	return fmt.Sprintf("IgnoreKeyValue(%s, %s)", quoteArg(e.key), value)
+}
+
 func (e *exactKeyValueIgnore) ShouldIgnore(key, value string) bool {
 	return e.key == key && (e.value == "*" || e.value == value)
 }
@@ -44,6 +57,17 @@ type regexKeyValueIgnore struct {
 
 func (r *regexKeyValueIgnore) isOption() {}
 
+func (r *regexKeyValueIgnore) String() string {
+	var keyPattern, valuePattern string
+	if r.keyPattern != nil {
+		keyPattern = r.keyPattern.String()
+	}
+	if r.valuePattern != nil {
+		valuePattern = r.valuePattern.String()
+	}
+	return fmt.Sprintf("IgnoreKeyPattern(%s, %s)", quoteArg(keyPattern), quoteArg(valuePattern))
+}
+
 func (r *regexKeyValueIgnore) ShouldIgnore(key, value string) bool {
 	keyMatch := r.keyPattern == nil || r.keyPattern.MatchString(key)
 	valueMatch := r.valuePattern == nil || r.valuePattern.MatchString(value)
@@ -77,11 +101,14 @@ func IgnoreKeyPattern(keyPattern, valuePattern string) IgnorePattern {
 
 // keyOnlyIgnore ignores any key matching the pattern, regardless of value.
 type keyOnlyIgnore struct {
+	name string
 	keys []string
 }
 
 func (k *keyOnlyIgnore) isOption() {}
 
+func (k *keyOnlyIgnore) String() string { return k.name }
+
 func (k *keyOnlyIgnore) ShouldIgnore(key, value string) bool {
 	return slices.Contains(k.keys, key)
 }
@@ -98,6 +125,7 @@ func (k *keyOnlyIgnore) ShouldIgnore(key, value string) bool {
 //	)
 func IgnoreKey(keys ...string) IgnorePattern {
 	return &keyOnlyIgnore{
+		name: "IgnoreKey(" + quoteArgs(keys) + ")",
 		keys: keys,
 	}
 }
@@ -109,6 +137,10 @@ type regexKeyIgnore struct {
 
 func (r *regexKeyIgnore) isOption() {}
 
+func (r *regexKeyIgnore) String() string {
+	return "IgnoreKeyMatching(" + quoteArg(r.pattern.String()) + ")"
+}
+
 func (r *regexKeyIgnore) ShouldIgnore(key, value string) bool {
 	return r.pattern.MatchString(key)
 }
@@ -148,6 +180,7 @@ var sensitiveKeys = []string{
 //	)
 func IgnoreSensitive() IgnorePattern {
 	return &keyOnlyIgnore{
+		name: "IgnoreSensitive()",
 		keys: sensitiveKeys,
 	}
 }
@@ -159,6 +192,10 @@ type valueOnlyIgnore struct {
 
 func (v *valueOnlyIgnore) isOption() {}
 
+// String leaves out the ignored values, which may be the very values kept
+// out of the snapshot.
+func (v *valueOnlyIgnore) String() string { return "IgnoreValue(…)" }
+
 func (v *valueOnlyIgnore) ShouldIgnore(key, value string) bool {
 	return slices.Contains(v.values, value)
 }
@@ -179,13 +216,51 @@ func IgnoreValue(values ...string) IgnorePattern {
 	}
 }
 
+// regexValueIgnore ignores values matching a regex pattern.
+type regexValueIgnore struct {
+	pattern *regexp.Regexp
+}
+
+func (r *regexValueIgnore) isOption() {}
+
+func (r *regexValueIgnore) String() string {
+	return "IgnoreValueMatching(" + quoteArg(r.pattern.String()) + ")"
+}
+
+func (r *regexValueIgnore) ShouldIgnore(key, value string) bool {
+	return r.pattern.MatchString(value)
+}
+
+// IgnoreValueMatching creates an ignore pattern that ignores values matching
+// the given regex pattern, regardless of their keys. Numbers, booleans and
+// null are matched as written in the JSON, and objects and arrays by their
+// JSON encoding, so anchor the pattern to avoid dropping whole objects that
+// merely contain a matching value.
+//
+// This option only works with SnapJSON.
+//
+// Example:
+//
+//	shutter.SnapJSON(t, "response", jsonStr,
+//	    shutter.IgnoreValueMatching(`^[0-9A-HJKMNP-TV-Z]{26}$`), // ULIDs
+//	)
+func IgnoreValueMatching(pattern string) IgnorePattern {
+	re := regexp.MustCompile(pattern)
+	return &regexValueIgnore{
+		pattern: re,
+	}
+}
+
 // customIgnore allows users to provide a custom ignore function.
 type customIgnore struct {
+	name       string
 	ignoreFunc func(key, value string) bool
 }
 
 func (c *customIgnore) isOption() {}
 
+func (c *customIgnore) String() string { return c.name }
+
 func (c *customIgnore) ShouldIgnore(key, value string) bool {
 	return c.ignoreFunc(key, value)
 }
@@ -205,6 +280,7 @@ func (c *customIgnore) ShouldIgnore(key, value string) bool {
 //	)
 func IgnoreWith(ignoreFunc func(key, value string) bool) IgnorePattern {
 	return &customIgnore{
+		name:       "IgnoreWith(func)",
 		ignoreFunc: ignoreFunc,
 	}
 }
@@ -219,9 +295,12 @@ func IgnoreWith(ignoreFunc func(key, value string) bool) IgnorePattern {
 //	    shutter.IgnoreEmpty(),
 //	)
 func IgnoreEmpty() IgnorePattern {
-	return IgnoreWith(func(key, value string) bool {
-		return strings.TrimSpace(value) == ""
-	})
+	return &customIgnore{
+		name: "IgnoreEmpty()",
+		ignoreFunc: func(key, value string) bool {
+			return strings.TrimSpace(value) == ""
+		},
+	}
 }
 
 // IgnoreNull ignores fields with null/nil values (represented as "null" in JSON).
@@ -234,7 +313,110 @@ func IgnoreEmpty() IgnorePattern {
 //	    shutter.IgnoreNull(),
 //	)
 func IgnoreNull() IgnorePattern {
-	return IgnoreWith(func(key, value string) bool {
-		return value == "null" || value == "<nil>"
-	})
+	return &customIgnore{
+		name: "IgnoreNull()",
+		ignoreFunc: func(key, value string) bool {
+			return value == "null" || value == "<nil>"
+		},
+	}
+}
+
+// jsonTypes are the type names accepted by IgnoreType.
+var jsonTypes = []string{"object", "array", "string", "number", "boolean", "null"}
+
+// typeIgnore ignores values of a JSON type, optionally only below some
+// paths.
+type typeIgnore struct {
+	name  string
+	typ   string
+	paths []string
+}
+
+func (i *typeIgnore) isOption() {}
+
+func (i *typeIgnore) String() string { return i.name }
+
+// ShouldIgnore always reports false: the string form of a value does not
+// tell its JSON type. SnapJSON uses shouldIgnoreField instead.
+func (i *typeIgnore) ShouldIgnore(key, value string) bool {
+	return false
+}
+
+func (i *typeIgnore) shouldIgnoreField(f transform.Field) bool {
+	return f.Type() == i.typ && underPaths(f.Path, i.paths)
+}
+
+// underPaths reports whether the field at path lies below one of paths, or
+// paths is empty.
+func underPaths(path string, paths []string) bool {
+	if len(paths) == 0 {
+		return true
+	}
+	for _, p := range paths {
+		if strings.HasPrefix(path, p+".") {
+			return true
+		}
+	}
+	return false
+}
+
+// IgnoreType creates an ignore pattern that ignores values of the given
+// JSON type: "object", "array", "string", "number", "boolean" or "null".
+// Given paths, only fields below them are ignored. A path names an object by
+// its dotted keys from the document root, such as "data.stats"; array
+// elements share the path of their array. IgnoreType panics on an unknown
+// type.
+//
+// This option only works with SnapJSON.
+//
+// Example:
+//
+//	shutter.SnapJSON(t, "schema", jsonStr,
+//	    shutter.IgnoreType("object", "definitions"),
+//	)
+func IgnoreType(typ string, paths ...string) IgnorePattern {
+	if !slices.Contains(jsonTypes, typ) {
+		panic(fmt.Sprintf("IgnoreType: unknown JSON type %q", typ))
+	}
+	return &typeIgnore{
+		name:  "IgnoreType(" + quoteArgs(append([]string{typ}, paths...)) + ")",
+		typ:   typ,
+		paths: paths,
+	}
+}
+
+// IgnoreNumbers ignores fields with numeric values, or only those below the
+// given paths (see IgnoreType).
+//
+// This option only works with SnapJSON.
+//
+// Example:
+//
+//	shutter.SnapJSON(t, "dashboard", jsonStr,
+//	    shutter.IgnoreNumbers("metrics"),
+//	)
+func IgnoreNumbers(paths ...string) IgnorePattern {
+	return &typeIgnore{
+		name:  "IgnoreNumbers(" + quoteArgs(paths) + ")",
+		typ:   "number",
+		paths: paths,
+	}
+}
+
+// IgnoreBooleans ignores fields with boolean values, or only those below the
+// given paths (see IgnoreType).
+//
+// This option only works with SnapJSON.
+//
+// Example:
+//
+//	shutter.SnapJSON(t, "settings", jsonStr,
+//	    shutter.IgnoreBooleans("features"),
+//	)
+func IgnoreBooleans(paths ...string) IgnorePattern {
+	return &typeIgnore{
+		name:  "IgnoreBooleans(" + quoteArgs(paths) + ")",
+		typ:   "boolean",
+		paths: paths,
+	}
 }
@@ -1,6 +1,7 @@
 package shutter_test
 
 import (
+	"strings"
 	"testing"
 
 	"github.com/ptdewey/shutter"
@@ -171,6 +172,17 @@ func TestIgnoreValues(t *testing.T) {
 			opts:  []shutter.Option{shutter.IgnoreNull()},
 			title: "Ignore Null Values",
 		},
+		{
+			name: "matching_values",
+			json: `{
+				"id": "01ARZ3NDEKTSV4RRFFQ69G5FAV",
+				"name": "John Doe",
+				"parent": {"id": "01BX5ZZKBKACTAV9WEVGEMMVRZ", "kind": "team"},
+				"note": "created from 01ARZ3NDEKTSV4RRFFQ69G5FAV"
+			}`,
+			opts:  []shutter.Option{shutter.IgnoreValueMatching(`^[0-9A-HJKMNP-TV-Z]{26}$`)},
+			title: "Ignore Matching Values",
+		},
 	}
 
 	for _, tt := range tests {
@@ -282,3 +294,54 @@ func TestComplexRealWorldExample(t *testing.T) {
 		shutter.ScrubJWT(),
 	)
 }
+
+func TestIgnoreTypes(t *testing.T) {
+	jsonStr := `{
+		"name": "checkout",
+		"enabled": true,
+		"metrics": {"p50": 12.5, "p99": 480, "unit": "ms", "sampled": false},
+		"limits": {"rps": 100},
+		"schema": {"type": "object", "properties": {"id": {"type": "string"}}}
+	}`
+
+	shutter.SnapJSON(t, "Ignore By Type", jsonStr,
+		shutter.IgnoreNumbers("metrics"),
+		shutter.IgnoreBooleans(),
+		shutter.IgnoreType("object", "schema"),
+	)
+
+	defer func() {
+		if recover() == nil {
+			t.Error("expected IgnoreType to panic on an unknown type")
+		}
+	}()
+	shutter.IgnoreType("integer")
+}
+
+func TestWithin(t *testing.T) {
+	jsonStr := `{
+		"created": 1699999999,
+		"note": null,
+		"data": {
+			"total": 2,
+			"items": [
+				{"id": 1, "updated": 1700000000, "deleted_at": null, "stats": {"views": 10, "label": "a"}},
+				{"id": 2, "updated": 1700000500, "deleted_at": null, "stats": {"views": 12, "label": "b"}}
+			]
+		}
+	}`
+
+	shutter.SnapJSON(t, "Scoped Options", jsonStr,
+		shutter.Within("data.items",
+			shutter.IgnoreNull(),
+			shutter.ScrubUnixTimestamp(),
+			shutter.Within("stats", shutter.IgnoreNumbers()),
+		),
+	)
+
+	rt := &recordingT{T: t}
+	shutter.SnapString(rt, "Scoped String", "1699999999", shutter.Within("data", shutter.ScrubUnixTimestamp()))
+	if len(rt.errors) != 1 || !strings.Contains(rt.errors[0], "not supported with SnapString") {
+		t.Errorf("expected Within to be rejected by SnapString, got %v", rt.errors)
+	}
+}
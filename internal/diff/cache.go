@@ -0,0 +1,156 @@
+package diff
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+)
+
+// CacheEnv names the environment variable that sets the directory of the
+// diff cache, by default shutter-diffs in the temp directory. "off"
+// disables it.
+const CacheEnv = "SHUTTER_DIFF_CACHE"
+
+// cacheVersion is part of every cache key, so diffs computed by an older
+// algorithm are not reused. Bump it whenever the diff output changes.
+const cacheVersion = "1"
+
+// cacheMinBytes is the combined size of old and new content from which a
+// diff is cached on disk. Smaller diffs are cheaper to compute than to
+// read back.
+const cacheMinBytes = 32 << 10
+
+// cacheTTL is how long a diff stays on disk after it was last written; a
+// review session rarely lasts longer.
+const cacheTTL = 24 * time.Hour
+
+// cache holds the diffs computed in this process, by cache key.
+var cache struct {
+	sync.Mutex
+	diffs  map[string][]DiffLine
+	pruned bool // Expired entries were removed from the cache directory
+}
+
+// cached returns the diff of old and new under strategy, computing it with
+// compute the first time. Diffs are shared between processes through a
+// directory of JSON files, so the diff a failing test printed is not
+// computed again by the review that follows it, or by the next review.
+func cached(strategy, old, new string, compute func() []DiffLine) []DiffLine {
+	sum := sha256.Sum256([]byte(cacheVersion + "\x00" + strategy + "\x00" + old + "\x00" + new))
+	key := hex.EncodeToString(sum[:])
+
+	cache.Lock()
+	lines, ok := cache.diffs[key]
+	cache.Unlock()
+	if ok {
+		return slices.Clone(lines)
+	}
+
+	dir := cacheDir()
+	onDisk := dir != "" && len(old)+len(new) >= cacheMinBytes
+	if onDisk {
+		lines, ok = readCached(filepath.Join(dir, key+".json"))
+	}
+	if !ok {
+		lines = compute()
+		if onDisk {
+			// Best effort: without a cache the diff is simply computed again.
+			_ = writeCached(dir, key, lines)
+		}
+	}
+
+	cache.Lock()
+	defer cache.Unlock()
+	if cache.diffs == nil {
+		cache.diffs = map[string][]DiffLine{}
+	}
+	cache.diffs[key] = lines
+	return slices.Clone(lines)
+}
+
+// cacheDir returns the directory of the diff cache, or "" if it is
+// disabled with CacheEnv.
+func cacheDir() string {
+	switch dir := os.Getenv(CacheEnv); dir {
+	case "off":
+		return ""
+	case "":
+		return filepath.Join(os.TempDir(), "shutter-diffs")
+	default:
+		return dir
+	}
+}
+
+// readCached reads the diff cached at path, if it is there and has not
+// expired.
+func readCached(path string) ([]DiffLine, bool) {
+	info, err := os.Stat(path)
+	if err != nil || time.Since(info.ModTime()) > cacheTTL {
+		return nil, false
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, false
+	}
+	var lines []DiffLine
+	if err := json.Unmarshal(data, &lines); err != nil {
+		return nil, false
+	}
+	return lines, true
+}
+
+// writeCached stores lines in dir under key, replacing the file
+// atomically so a concurrent reader never sees part of it. The first
+// write of a process also removes expired entries.
+func writeCached(dir, key string, lines []DiffLine) error {
+	data, err := json.Marshal(lines)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+	pruneCache(dir)
+
+	tmp, err := os.CreateTemp(dir, "diff-*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), filepath.Join(dir, key+".json"))
+}
+
+// pruneCache removes the expired entries of the cache in dir, once per
+// process.
+func pruneCache(dir string) {
+	cache.Lock()
+	pruned := cache.pruned
+	cache.pruned = true
+	cache.Unlock()
+	if pruned {
+		return
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return
+	}
+	for _, entry := range entries {
+		if info, err := entry.Info(); err == nil && time.Since(info.ModTime()) > cacheTTL {
+			_ = os.Remove(filepath.Join(dir, entry.Name()))
+		}
+	}
+}
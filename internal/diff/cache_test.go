@@ -0,0 +1,58 @@
+package diff
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/ptdewey/shutter/internal/files"
+)
+
+func TestSnapshotsCached(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv(CacheEnv, dir)
+
+	old := &files.Snapshot{Content: strings.Repeat("unchanged line\n", 4000) + "old\n"}
+	new := &files.Snapshot{Content: strings.Repeat("unchanged line\n", 4000) + "new\n"}
+
+	computed := 0
+	compute := func() []DiffLine {
+		computed++
+		return Histogram(old.Content, new.Content)
+	}
+	want := cached("", old.Content, new.Content, compute)
+	if got := cached("", old.Content, new.Content, compute); !reflect.DeepEqual(got, want) || computed != 1 {
+		t.Errorf("expected the diff to be computed once, computed %d times", computed)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil || len(entries) != 1 {
+		t.Fatalf("expected one cached diff in %s, got %v (%v)", dir, entries, err)
+	}
+
+	// A later process finds the diff on disk.
+	cache.Lock()
+	cache.diffs = nil
+	cache.Unlock()
+	if got := cached("", old.Content, new.Content, compute); !reflect.DeepEqual(got, want) || computed != 1 {
+		t.Errorf("expected the diff to be read from disk, computed %d times", computed)
+	}
+	if got := Snapshots(old, new); !reflect.DeepEqual(got, want) {
+		t.Errorf("Snapshots differs from the cached diff")
+	}
+
+	// The JSON strategy is cached separately.
+	if got := cached(files.ContentJSON, old.Content, new.Content, compute); computed != 2 || !reflect.DeepEqual(got, want) {
+		t.Errorf("expected a separate entry per strategy, computed %d times", computed)
+	}
+
+	// Small diffs stay in memory only.
+	small := filepath.Join(dir, "small")
+	t.Setenv(CacheEnv, small)
+	cached("", "a\n", "b\n", func() []DiffLine { return Histogram("a\n", "b\n") })
+	if _, err := os.Stat(small); !os.IsNotExist(err) {
+		t.Errorf("expected no cache directory for a small diff")
+	}
+}
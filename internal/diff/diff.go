@@ -64,7 +64,10 @@ NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE OF THIS
 SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
 */
 
-import "strconv"
+import (
+	"strconv"
+	"strings"
+)
 
 type DiffKind int
 
@@ -129,21 +132,26 @@ type opCode struct {
 // case.  sequenceMatcher is quadratic time for the worst case and has
 // expected-case behavior dependent in a complicated way on how many
 // elements the sequences have in common; best case time is linear.
+//
+// Lines are interned in a lineTable and the matcher works on their IDs.
+// Rather than a map from every distinct line to its indices in b, b2jStart
+// and b2jIndex store those indices in one flat slice, grouped by line ID.
 type sequenceMatcher struct {
-	a              []string
-	b              []string
-	b2j            map[string][]int
+	lines          *lineTable
+	a              []int
+	b              []int
+	b2jStart       []int
+	b2jIndex       []int
 	IsJunk         func(string) bool
 	autoJunk       bool
-	bJunk          map[string]struct{}
+	bJunk          map[int]struct{}
 	matchingBlocks []match
-	fullBCount     map[string]int
-	bPopular       map[string]struct{}
+	bPopular       map[int]struct{}
 	opCodes        []opCode
 }
 
 func newMatcher(a, b []string) *sequenceMatcher {
-	m := sequenceMatcher{autoJunk: true}
+	m := sequenceMatcher{autoJunk: true, lines: newLineTable()}
 	m.setSeqs(a, b)
 	return &m
 }
@@ -156,60 +164,74 @@ func (m *sequenceMatcher) setSeqs(a, b []string) {
 
 // Set the first sequence to be compared.
 func (m *sequenceMatcher) setSeq1(a []string) {
-	m.a = a
+	m.a = m.lines.intern(a)
 	m.matchingBlocks, m.opCodes = nil, nil
 }
 
 // Set the second sequence to be compared.
 func (m *sequenceMatcher) setSeq2(b []string) {
-	m.b = b
-	m.matchingBlocks, m.opCodes, m.fullBCount = nil, nil, nil
+	m.b = m.lines.intern(b)
+	m.matchingBlocks, m.opCodes = nil, nil
 	m.chainB()
 }
 
 func (m *sequenceMatcher) chainB() {
-	// Populate line -> index mapping
-	b2j := map[string][]int{}
-	for i, elt := range m.b {
-		indices := b2j[elt]
-		indices = append(indices, i)
-		b2j[elt] = indices
+	// Count occurrences of each line in b. counts[id+1] holds the count for
+	// id, so the prefix sums below give each line's range in b2jIndex.
+	counts := make([]int, m.lines.len()+1)
+	for _, elt := range m.b {
+		counts[elt+1]++
 	}
 
 	// Purge junk elements
-	m.bJunk = map[string]struct{}{}
+	m.bJunk = map[int]struct{}{}
 	if m.IsJunk != nil {
-		junk := m.bJunk
-		for elt := range b2j {
-			if m.IsJunk(elt) {
-				junk[elt] = struct{}{}
+		for elt := 0; elt < m.lines.len(); elt++ {
+			if counts[elt+1] > 0 && m.IsJunk(m.lines.line(elt)) {
+				m.bJunk[elt] = struct{}{}
+				counts[elt+1] = 0
 			}
 		}
-		for elt := range junk { // separate loop avoids separate list of keys
-			delete(b2j, elt)
-		}
 	}
 
 	// Purge popular elements that are not junk
-	popular := map[string]struct{}{}
+	popular := map[int]struct{}{}
 	n := len(m.b)
 	if m.autoJunk && n >= 200 {
 		ntest := n/100 + 1
-		for s, indices := range b2j {
-			if len(indices) > ntest {
-				popular[s] = struct{}{}
+		for elt := 0; elt < m.lines.len(); elt++ {
+			if counts[elt+1] > ntest {
+				popular[elt] = struct{}{}
+				counts[elt+1] = 0
 			}
 		}
-		for s := range popular {
-			delete(b2j, s)
-		}
 	}
 	m.bPopular = popular
-	m.b2j = b2j
+
+	// Populate line -> index mapping
+	for elt := 1; elt < len(counts); elt++ {
+		counts[elt] += counts[elt-1]
+	}
+	m.b2jStart = counts
+	m.b2jIndex = make([]int, counts[len(counts)-1])
+	next := make([]int, m.lines.len())
+	copy(next, counts)
+	for i, elt := range m.b {
+		if m.b2jStart[elt+1] > m.b2jStart[elt] {
+			m.b2jIndex[next[elt]] = i
+			next[elt]++
+		}
+	}
 }
 
-func (m *sequenceMatcher) isBJunk(s string) bool {
-	_, ok := m.bJunk[s]
+// b2j returns the indices in b of the line id, in increasing order. Junk
+// and popular lines have none.
+func (m *sequenceMatcher) b2j(id int) []int {
+	return m.b2jIndex[m.b2jStart[id]:m.b2jStart[id+1]]
+}
+
+func (m *sequenceMatcher) isBJunk(id int) bool {
+	_, ok := m.bJunk[id]
 	return ok
 }
 
@@ -218,10 +240,11 @@ func (m *sequenceMatcher) findLongestMatch(alo, ahi, blo, bhi int) match {
 	besti, bestj, bestsize := alo, blo, 0
 
 	// find longest junk-free match
-	j2len := map[int]int{}
+	// j2len and newj2len are reused across rows rather than reallocated.
+	j2len, newj2len := map[int]int{}, map[int]int{}
 	for i := alo; i != ahi; i++ {
-		newj2len := map[int]int{}
-		for _, j := range m.b2j[m.a[i]] {
+		clear(newj2len)
+		for _, j := range m.b2j(m.a[i]) {
 			if j < blo {
 				continue
 			}
@@ -234,7 +257,7 @@ func (m *sequenceMatcher) findLongestMatch(alo, ahi, blo, bhi int) match {
 				besti, bestj, bestsize = i-k+1, j-k+1, k
 			}
 		}
-		j2len = newj2len
+		j2len, newj2len = newj2len, j2len
 	}
 
 	// Extend the best by non-junk elements on each end
@@ -342,11 +365,27 @@ func (m *sequenceMatcher) getOpCodes() []opCode {
 func Histogram(old, new string) []DiffLine {
 	oldLines := splitLines(old)
 	newLines := splitLines(new)
+	return diffLines(oldLines, newLines, oldLines, newLines)
+}
 
-	matcher := newMatcher(oldLines, newLines)
+// diffLines diffs oldLines against newLines, matching lines by their keys
+// rather than their text. Lines whose keys match are shared and shown as
+// they are in newLines.
+func diffLines(oldLines, newLines, oldKeys, newKeys []string) []DiffLine {
+	matcher := newMatcher(oldKeys, newKeys)
 	opcodes := matcher.getOpCodes()
 
+	size := 0
+	for _, op := range opcodes {
+		size += op.I2 - op.I1
+		if op.Tag != opEqual {
+			size += op.J2 - op.J1
+		}
+	}
 	var result []DiffLine
+	if size > 0 {
+		result = make([]DiffLine, 0, size)
+	}
 
 	for _, op := range opcodes {
 		switch op.Tag {
@@ -354,7 +393,7 @@ func Histogram(old, new string) []DiffLine {
 			for i := op.I1; i < op.I2; i++ {
 				newIdx := i + (op.J1 - op.I1)
 				result = append(result, DiffLine{
-					Line:      oldLines[i],
+					Line:      newLines[newIdx],
 					Kind:      DiffShared,
 					OldNumber: i + 1,
 					NewNumber: newIdx + 1,
@@ -408,22 +447,11 @@ func splitLines(s string) []string {
 
 // splitLinesKeepNewline splits on newlines but removes the newlines themselves
 func splitLinesKeepNewline(s string) []string {
-	var lines []string
-	var current string
-
-	for _, char := range s {
-		if char == '\n' {
-			lines = append(lines, current)
-			current = ""
-		} else {
-			current += string(char)
-		}
+	lines := strings.Split(s, "\n")
+	// A trailing newline ends the last line rather than starting a new one.
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
 	}
-
-	if current != "" {
-		lines = append(lines, current)
-	}
-
 	return lines
 }
 
@@ -1,6 +1,8 @@
 package diff_test
 
 import (
+	"fmt"
+	"strings"
 	"testing"
 
 	"github.com/ptdewey/shutter/internal/diff"
@@ -188,3 +190,61 @@ func TestHistogramWithEmptyLines(t *testing.T) {
 		t.Error("expected non-empty diff result")
 	}
 }
+
+func TestJSONIgnoresCommas(t *testing.T) {
+	old := "{\n  \"a\": 1\n}"
+	new := "{\n  \"a\": 1,\n  \"b\": 2\n}"
+
+	var changed []string
+	for _, dl := range diff.JSON(old, new) {
+		if dl.Kind != diff.DiffShared {
+			changed = append(changed, dl.Line)
+		}
+	}
+	if len(changed) != 1 || changed[0] != `  "b": 2` {
+		t.Errorf("expected only the appended field to change, got %q", changed)
+	}
+}
+
+// largeInput returns about size bytes of distinct lines, like a large
+// rendered snapshot.
+func largeInput(size int) string {
+	var sb strings.Builder
+	sb.Grow(size + 64)
+	for i := 0; sb.Len() < size; i++ {
+		fmt.Fprintf(&sb, "line %08d: the quick brown fox jumps over the lazy dog\n", i)
+	}
+	return sb.String()
+}
+
+// benchmarkSizes are the snapshot sizes covered by the large-input
+// benchmarks. Sizes above 1MB are skipped with -short.
+var benchmarkSizes = []struct {
+	name string
+	size int
+}{
+	{"1MB", 1 << 20},
+	{"10MB", 10 << 20},
+	{"50MB", 50 << 20},
+}
+
+func BenchmarkHistogram(b *testing.B) {
+	for _, bs := range benchmarkSizes {
+		b.Run(bs.name, func(b *testing.B) {
+			if testing.Short() && bs.size > 1<<20 {
+				b.Skip("large input skipped with -short")
+			}
+			old := largeInput(bs.size)
+			// Change one line in the middle.
+			mid := strings.Index(old[len(old)/2:], "\n") + len(old)/2 + 1
+			new := old[:mid] + "changed\n" + old[mid:]
+
+			b.SetBytes(int64(len(old)))
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				diff.Histogram(old, new)
+			}
+		})
+	}
+}
@@ -0,0 +1,71 @@
+package diff
+
+import "hash/maphash"
+
+// lineTable interns lines as small integer IDs, so the sequence matcher
+// compares and indexes integers instead of keeping every distinct line as a
+// map key. Lines are looked up by their 64-bit hash; the line itself is only
+// compared to confirm a hash match, so colliding lines still get distinct
+// IDs.
+type lineTable struct {
+	seed maphash.Seed
+	// ids maps a line hash to the ID of the first line with that hash.
+	ids map[uint64]int
+	// lines holds the line for each ID.
+	lines []string
+	// collisions holds lines whose hash was already taken by a different
+	// line. It stays empty in practice.
+	collisions map[string]int
+}
+
+func newLineTable() *lineTable {
+	return &lineTable{seed: maphash.MakeSeed(), ids: map[uint64]int{}}
+}
+
+// intern returns the IDs of lines, assigning new IDs to lines not seen
+// before.
+func (t *lineTable) intern(lines []string) []int {
+	ids := make([]int, len(lines))
+	for i, line := range lines {
+		ids[i] = t.id(line)
+	}
+	return ids
+}
+
+func (t *lineTable) id(line string) int {
+	h := maphash.String(t.seed, line)
+	id, ok := t.ids[h]
+	if !ok {
+		id = t.add(line)
+		t.ids[h] = id
+		return id
+	}
+	if t.lines[id] == line {
+		return id
+	}
+
+	if id, ok := t.collisions[line]; ok {
+		return id
+	}
+	if t.collisions == nil {
+		t.collisions = map[string]int{}
+	}
+	id = t.add(line)
+	t.collisions[line] = id
+	return id
+}
+
+func (t *lineTable) add(line string) int {
+	t.lines = append(t.lines, line)
+	return len(t.lines) - 1
+}
+
+// line returns the line interned as id.
+func (t *lineTable) line(id int) string {
+	return t.lines[id]
+}
+
+// len returns the number of distinct lines interned.
+func (t *lineTable) len() int {
+	return len(t.lines)
+}
@@ -0,0 +1,43 @@
+package diff
+
+import (
+	"hash/maphash"
+	"testing"
+)
+
+func TestLineTableIntern(t *testing.T) {
+	table := newLineTable()
+	ids := table.intern([]string{"a", "b", "a", ""})
+
+	if ids[0] != ids[2] {
+		t.Errorf("equal lines got different IDs: %v", ids)
+	}
+	if ids[0] == ids[1] || ids[0] == ids[3] || ids[1] == ids[3] {
+		t.Errorf("distinct lines share an ID: %v", ids)
+	}
+	if table.len() != 3 {
+		t.Errorf("expected 3 distinct lines, got %d", table.len())
+	}
+	if got := table.line(ids[1]); got != "b" {
+		t.Errorf("expected line %q for ID %d, got %q", "b", ids[1], got)
+	}
+}
+
+func TestLineTableHashCollision(t *testing.T) {
+	table := newLineTable()
+	a := table.id("a")
+
+	// Make "c" hash to the slot already taken by "a".
+	table.ids[maphash.String(table.seed, "c")] = a
+
+	c := table.id("c")
+	if c == a {
+		t.Fatalf("colliding lines share ID %d", a)
+	}
+	if again := table.id("c"); again != c {
+		t.Errorf("expected colliding line to keep ID %d, got %d", c, again)
+	}
+	if again := table.id("a"); again != a {
+		t.Errorf("expected line %q to keep ID %d, got %d", "a", a, again)
+	}
+}
@@ -0,0 +1,40 @@
+package diff
+
+import (
+	"strings"
+
+	"github.com/ptdewey/shutter/internal/files"
+)
+
+// Snapshots computes the diff shown for a change from old to new, using the
+// strategy for the content type recorded in new. JSON is compared
+// structurally; everything else line by line, as Histogram does. Diffs are
+// cached by content, so showing the same change again is cheap.
+func Snapshots(old, new *files.Snapshot) []DiffLine {
+	if new.ContentType == files.ContentJSON {
+		return cached(files.ContentJSON, old.Content, new.Content, func() []DiffLine {
+			return JSON(old.Content, new.Content)
+		})
+	}
+	return cached("", old.Content, new.Content, func() []DiffLine {
+		return Histogram(old.Content, new.Content)
+	})
+}
+
+// JSON diffs two indented JSON documents line by line, ignoring the commas
+// that separate members and elements. Appending a field then marks only the
+// new field as added, not also the line before it that gained a comma.
+func JSON(old, new string) []DiffLine {
+	oldLines := splitLines(old)
+	newLines := splitLines(new)
+	return diffLines(oldLines, newLines, trimCommas(oldLines), trimCommas(newLines))
+}
+
+// trimCommas returns lines with any trailing comma removed.
+func trimCommas(lines []string) []string {
+	keys := make([]string, len(lines))
+	for i, line := range lines {
+		keys[i] = strings.TrimSuffix(line, ",")
+	}
+	return keys
+}
@@ -0,0 +1,181 @@
+package files
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/user"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// AuditLogFile is where accepted and rejected snapshots are recorded when
+// audit_log is enabled in ConfigFile, relative to the project root (or the
+// go.work directory in a workspace). Each line is a JSON-encoded AuditEntry,
+// oldest first.
+const AuditLogFile = ".shutter/audit.log"
+
+// Audit log actions.
+const (
+	AuditAccept = "accept"
+	AuditReject = "reject"
+)
+
+// AuditEntry records one accept or reject of a snapshot.
+type AuditEntry struct {
+	Time   time.Time `json:"time"`
+	Action string    `json:"action"`
+	User   string    `json:"user"`
+	Title  string    `json:"title"` // Path relative to its __snapshots__ dir, without extension
+	Path   string    `json:"path"`  // Pending file, relative to the project root
+
+	// Before is the digest of the accepted content before the action,
+	// empty for a new snapshot. After is the digest of the pending content
+	// that was accepted or rejected.
+	Before string `json:"before,omitempty"`
+	After  string `json:"after,omitempty"`
+}
+
+// ReadAuditLog returns the entries of the project's audit log, oldest first.
+// A project without an audit log has no entries.
+func ReadAuditLog() ([]AuditEntry, error) {
+	root, err := workspaceRoot()
+	if err != nil {
+		return nil, err
+	}
+
+	path := filepath.Join(root, AuditLogFile)
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var entries []AuditEntry
+	scanner := bufio.NewScanner(f)
+	for n := 1; scanner.Scan(); n++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var entry AuditEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return nil, fmt.Errorf("invalid audit log %s:%d: %w", DisplayPath(path), n, err)
+		}
+		entries = append(entries, entry)
+	}
+
+	return entries, scanner.Err()
+}
+
+// auditTarget holds what an audit entry records about a pending snapshot,
+// read before the snapshot is accepted or rejected.
+type auditTarget struct {
+	info   SnapshotInfo
+	before string
+	after  string
+}
+
+// prepareAudit reads the digests of the pending snapshot info and its
+// accepted counterpart, or returns nil if audit_log is disabled.
+func prepareAudit(info SnapshotInfo) (*auditTarget, error) {
+	cfg, err := LoadConfig()
+	if err != nil {
+		return nil, err
+	}
+	if !cfg.AuditLog {
+		return nil, nil
+	}
+
+	target := &auditTarget{info: info}
+	if pending, err := ReadSnapshotFromPath(info.Path); err == nil {
+		target.after = ContentDigest(pending.Content)
+	}
+	if accepted, err := CurrentStorage().Read(info, StateAccepted); err == nil {
+		target.before = ContentDigest(accepted.Content)
+	}
+	return target, nil
+}
+
+// record appends an entry for action to the audit log. A nil target, from
+// a disabled audit log, records nothing.
+func (a *auditTarget) record(action string) error {
+	if a == nil {
+		return nil
+	}
+	root, err := workspaceRoot()
+	if err != nil {
+		return err
+	}
+
+	path := filepath.ToSlash(a.info.Path)
+	if abs, err := filepath.Abs(a.info.Path); err == nil {
+		if rel, err := filepath.Rel(root, abs); err == nil {
+			path = filepath.ToSlash(rel)
+		}
+	}
+
+	line, err := json.Marshal(AuditEntry{
+		Time:   time.Now().UTC(),
+		Action: action,
+		User:   auditUser(root),
+		Title:  a.info.Title,
+		Path:   path,
+		Before: a.before,
+		After:  a.after,
+	})
+	if err != nil {
+		return err
+	}
+
+	logPath := filepath.Join(root, AuditLogFile)
+	if err := os.MkdirAll(filepath.Dir(logPath), 0755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(logPath, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// auditUser names who is accepting or rejecting snapshots: the git author
+// configured for the project in dir, as "Name <email>", or else the login
+// name.
+func auditUser(dir string) string {
+	name, email := gitConfig(dir, "user.name"), gitConfig(dir, "user.email")
+	switch {
+	case name != "" && email != "":
+		return fmt.Sprintf("%s <%s>", name, email)
+	case email != "":
+		return email
+	case name != "":
+		return name
+	}
+
+	if u, err := user.Current(); err == nil {
+		return u.Username
+	}
+	return "unknown"
+}
+
+// gitConfig returns the git setting key as seen from dir, or "" if it is
+// unset or git is unavailable.
+func gitConfig(dir, key string) string {
+	cmd := exec.Command("git", "config", key)
+	cmd.Dir = dir
+	out, err := cmd.Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(out))
+}
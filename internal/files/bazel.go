@@ -0,0 +1,208 @@
+package files
+
+import (
+	"archive/zip"
+	"bufio"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// BazelOutputsDir is the directory of a test's undeclared outputs that
+// pending snapshots are written to under bazel test, laid out like the
+// workspace: shutter/<package>/__snapshots__/<key>.snap.new.
+const BazelOutputsDir = "shutter"
+
+// bazelPackage returns the package of the test target bazel test is
+// running, such as internal/render for //internal/render:render_test, and
+// false when the tests do not run under Bazel.
+func bazelPackage() (string, bool) {
+	target := os.Getenv("TEST_TARGET")
+	if target == "" || os.Getenv("TEST_SRCDIR") == "" {
+		return "", false
+	}
+	if i := strings.Index(target, "//"); i >= 0 {
+		target = target[i+len("//"):]
+	}
+	pkg, _, _ := strings.Cut(target, ":")
+	return pkg, true
+}
+
+// bazelPendingDir returns the __snapshots__ directory in the undeclared
+// outputs of the running Bazel test, where pending snapshots are written:
+// the runfiles tree a test sees is not the source tree, and is read-only in
+// most sandboxes.
+func bazelPendingDir() (string, bool) {
+	outputs := os.Getenv("TEST_UNDECLARED_OUTPUTS_DIR")
+	pkg, ok := bazelPackage()
+	if outputs == "" || !ok {
+		return "", false
+	}
+	return filepath.Join(outputs, BazelOutputsDir, filepath.FromSlash(pkg), snapshotDirName), true
+}
+
+// UnderBazel reports whether pending snapshots are written to the undeclared
+// outputs of a Bazel test, to be brought back with CollectBazelOutputs.
+func UnderBazel() bool {
+	_, ok := bazelPendingDir()
+	return ok
+}
+
+// acceptedReadPath returns the path the accepted snapshot with key is read
+// from. Under Bazel it is looked up in the runfiles, so snapshots declared as
+// data of the test are found wherever the test runs.
+func acceptedReadPath(key string) string {
+	fileName := getSnapshotFileName(key, StateAccepted)
+	if pkg, ok := bazelPackage(); ok {
+		if p, ok := runfile(path.Join(pkg, snapshotDirName, filepath.ToSlash(fileName))); ok {
+			return p
+		}
+	}
+	return filepath.Join(snapshotDirName, fileName)
+}
+
+// runfile returns the path of the workspace file rel in the runfiles of the
+// running Bazel test: in the runfiles directory, or, where there is none,
+// as listed by the runfiles manifest.
+func runfile(rel string) (string, bool) {
+	rel = path.Join(os.Getenv("TEST_WORKSPACE"), rel)
+	for _, dir := range []string{os.Getenv("RUNFILES_DIR"), os.Getenv("TEST_SRCDIR")} {
+		if dir == "" {
+			continue
+		}
+		if p := filepath.Join(dir, filepath.FromSlash(rel)); fileExists(p) {
+			return p, true
+		}
+	}
+
+	manifest := os.Getenv("RUNFILES_MANIFEST_FILE")
+	if manifest == "" {
+		return "", false
+	}
+	f, err := os.Open(manifest)
+	if err != nil {
+		return "", false
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if name, target, ok := strings.Cut(scanner.Text(), " "); ok && name == rel {
+			return target, true
+		}
+	}
+	return "", false
+}
+
+func fileExists(p string) bool {
+	info, err := os.Stat(p)
+	return err == nil && !info.IsDir()
+}
+
+// CollectBazelOutputs copies the snapshots Bazel tests wrote to their
+// undeclared outputs into the workspace, where review finds them. It reads
+// both the outputs.zip files Bazel creates by default and unzipped
+// test.outputs directories (--nozip_undeclared_test_outputs) below
+// testlogs, by default the bazel-testlogs directory of the workspace. The
+// workspace is the one bazel run was started in, else the project root. It
+// returns the paths written.
+func CollectBazelOutputs(testlogs string) ([]string, error) {
+	root := os.Getenv("BUILD_WORKSPACE_DIRECTORY")
+	if root == "" {
+		var err error
+		if root, err = workspaceRoot(); err != nil {
+			return nil, err
+		}
+	}
+	if testlogs == "" {
+		testlogs = filepath.Join(root, "bazel-testlogs")
+	}
+	testlogs, err := filepath.EvalSymlinks(testlogs)
+	if err != nil {
+		return nil, err
+	}
+
+	var collected []string
+	collect := func(name string, open func() (io.ReadCloser, error)) error {
+		rel, ok := strings.CutPrefix(filepath.ToSlash(name), BazelOutputsDir+"/")
+		if !ok || !filepath.IsLocal(rel) || !strings.Contains("/"+rel, "/"+snapshotDirName+"/") {
+			return nil
+		}
+		r, err := open()
+		if err != nil {
+			return err
+		}
+		defer r.Close()
+
+		dest := filepath.Join(root, filepath.FromSlash(rel))
+		if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+			return err
+		}
+		w, err := os.Create(dest)
+		if err != nil {
+			return err
+		}
+		if _, err := io.Copy(w, r); err != nil {
+			w.Close()
+			return err
+		}
+		if err := w.Close(); err != nil {
+			return err
+		}
+		collected = append(collected, dest)
+		return nil
+	}
+
+	err = filepath.WalkDir(testlogs, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		switch {
+		case d.IsDir() && d.Name() == "test.outputs":
+			return collectDir(p, collect)
+		case d.Name() == "outputs.zip" && filepath.Base(filepath.Dir(p)) == "test.outputs":
+			return collectZip(p, collect)
+		}
+		return nil
+	})
+	return collected, err
+}
+
+// collectDir passes the files below an unzipped test.outputs directory to
+// collect, by their path relative to it. The outputs.zip in it, if any, is
+// left to the caller.
+func collectDir(dir string, collect func(string, func() (io.ReadCloser, error)) error) error {
+	return filepath.WalkDir(filepath.Join(dir, BazelOutputsDir), func(p string, d fs.DirEntry, err error) error {
+		if os.IsNotExist(err) && p == filepath.Join(dir, BazelOutputsDir) {
+			return filepath.SkipDir
+		}
+		if err != nil || d.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		return collect(rel, func() (io.ReadCloser, error) { return os.Open(p) })
+	})
+}
+
+// collectZip passes the files in an outputs.zip to collect.
+func collectZip(p string, collect func(string, func() (io.ReadCloser, error)) error) error {
+	zr, err := zip.OpenReader(p)
+	if err != nil {
+		return err
+	}
+	defer zr.Close()
+	for _, f := range zr.File {
+		if f.FileInfo().IsDir() {
+			continue
+		}
+		if err := collect(f.Name, f.Open); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,162 @@
+package files
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// ConfigFile holds settings for the shutter command line tools, one
+// "key = value" per line, in the project root (or the go.work directory in a
+// workspace). A file of the same name in the user's config directory, such
+// as ~/.config/shutter/.shutterconfig, applies to every project; settings in
+// the project file take precedence. Blank lines and lines starting with "#"
+// are ignored.
+const ConfigFile = ".shutterconfig"
+
+// Config holds the settings read from ConfigFile.
+type Config struct {
+	// DiffTool is the command that opens two snapshot files side by side
+	// (diff_tool). Its arguments may refer to the files as {{.Old}} and
+	// {{.New}}.
+	DiffTool string
+
+	// MergeTool is the command that resolves merge conflicts in a snapshot
+	// file (merge_tool). Its arguments may refer to {{.Base}}, {{.Ours}},
+	// {{.Theirs}} and {{.Merged}}.
+	MergeTool string
+
+	// AuditLog records every accept and reject in AuditLogFile
+	// (audit_log = true).
+	AuditLog bool
+
+	// Owners assigns owners to the snapshots under a path, in addition to
+	// those in CODEOWNERS (owners = internal/billing/ @acme/billing). The
+	// setting may be repeated; later rules take precedence.
+	Owners []OwnerRule
+
+	// Teams lists the owners the current user belongs to, such as their
+	// handle and teams, for review --mine (teams = @alice @acme/billing).
+	Teams []string
+
+	// Extension is the file extension of accepted snapshots (extension =
+	// .golden), DefaultExtension if empty. Pending snapshots add ".new".
+	Extension string
+
+	// Header is the header style of accepted snapshots (header = json), one
+	// of the Header constants; empty means HeaderYAML.
+	Header string
+
+	// DiffLegend adds a legend naming the accepted and pending sides above
+	// snapshot diffs (diff_legend = true).
+	DiffLegend bool
+
+	// DiffGutter holds the characters marking removed, added and unchanged
+	// lines in snapshot diffs (diff_gutter = < > :), or nil for the
+	// defaults.
+	DiffGutter []string
+
+	// Remote keeps accepted snapshots in a bucket instead of on disk
+	// (remote = s3://bucket/prefix or gs://bucket/prefix); see
+	// RemoteStorage.
+	Remote string
+}
+
+// LoadConfig reads the user's and then the project's ConfigFile. Missing
+// files are skipped; an unknown setting is an error.
+func LoadConfig() (Config, error) {
+	var cfg Config
+	var paths []string
+	if dir, err := os.UserConfigDir(); err == nil {
+		paths = append(paths, filepath.Join(dir, "shutter", ConfigFile))
+	}
+	if base, err := workspaceRoot(); err == nil {
+		paths = append(paths, filepath.Join(base, ConfigFile))
+	}
+	for _, path := range paths {
+		if err := cfg.readFile(path); err != nil {
+			return Config{}, err
+		}
+	}
+	return cfg, nil
+}
+
+// readFile applies the settings in the config file at path, if it exists.
+func (c *Config) readFile(path string) error {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			return fmt.Errorf("%s:%d: expected key = value", DisplayPath(path), n)
+		}
+		value = strings.TrimSpace(value)
+		switch strings.TrimSpace(key) {
+		case "diff_tool":
+			c.DiffTool = value
+		case "merge_tool":
+			c.MergeTool = value
+		case "audit_log":
+			on, err := strconv.ParseBool(value)
+			if err != nil {
+				return fmt.Errorf("%s:%d: audit_log must be true or false", DisplayPath(path), n)
+			}
+			c.AuditLog = on
+		case "owners":
+			rule, err := parseOwnerRule(value)
+			if err != nil {
+				return fmt.Errorf("%s:%d: %w", DisplayPath(path), n, err)
+			}
+			c.Owners = append(c.Owners, rule)
+		case "extension":
+			if !strings.HasPrefix(value, ".") || len(value) < 2 || strings.ContainsAny(value, `/\`) || strings.HasSuffix(value, pendingSuffix) {
+				return fmt.Errorf("%s:%d: extension must be a file extension such as .golden", DisplayPath(path), n)
+			}
+			c.Extension = value
+		case "header":
+			if value != HeaderYAML && value != HeaderJSON && value != HeaderNone {
+				return fmt.Errorf("%s:%d: header must be %s, %s or %s", DisplayPath(path), n, HeaderYAML, HeaderJSON, HeaderNone)
+			}
+			c.Header = value
+		case "diff_legend":
+			on, err := strconv.ParseBool(value)
+			if err != nil {
+				return fmt.Errorf("%s:%d: diff_legend must be true or false", DisplayPath(path), n)
+			}
+			c.DiffLegend = on
+		case "diff_gutter":
+			gutter := strings.Fields(value)
+			if len(gutter) != 3 || slices.ContainsFunc(gutter, func(g string) bool { return utf8.RuneCountInString(g) != 1 }) {
+				return fmt.Errorf("%s:%d: diff_gutter must be three characters, for removed, added and unchanged lines", DisplayPath(path), n)
+			}
+			c.DiffGutter = gutter
+		case "remote":
+			if _, err := parseRemote(value); err != nil {
+				return fmt.Errorf("%s:%d: %w", DisplayPath(path), n, err)
+			}
+			c.Remote = value
+		case "teams":
+			c.Teams = append(c.Teams, strings.FieldsFunc(value, isListSeparator)...)
+		default:
+			return fmt.Errorf("%s:%d: unknown setting %q", DisplayPath(path), n, strings.TrimSpace(key))
+		}
+	}
+	return scanner.Err()
+}
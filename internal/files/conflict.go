@@ -0,0 +1,65 @@
+package files
+
+import "strings"
+
+// Git merge conflict markers, each starting a line. The base marker only
+// appears with merge.conflictStyle diff3 or zdiff3.
+const (
+	conflictOurs   = "<<<<<<<"
+	conflictBase   = "|||||||"
+	conflictSplit  = "======="
+	conflictTheirs = ">>>>>>>"
+)
+
+// HasConflicts reports whether content holds a hunk marked up by git as a
+// merge conflict.
+func HasConflicts(content string) bool {
+	start := false
+	for _, line := range strings.SplitAfter(content, "\n") {
+		switch {
+		case strings.HasPrefix(line, conflictOurs):
+			start = true
+		case start && strings.HasPrefix(line, conflictTheirs):
+			return true
+		}
+	}
+	return false
+}
+
+// SplitConflicts splits content with merge conflicts into the three versions
+// git merged: the common ancestor, ours and theirs. Lines outside conflicts
+// belong to all three. A conflict without a base section contributes
+// nothing to base.
+func SplitConflicts(content string) (base, ours, theirs string) {
+	const (
+		common = iota
+		inOurs
+		inBase
+		inTheirs
+	)
+	var b, o, t strings.Builder
+	state := common
+	for _, line := range strings.SplitAfter(content, "\n") {
+		switch {
+		case state == common && strings.HasPrefix(line, conflictOurs):
+			state = inOurs
+		case state == inOurs && strings.HasPrefix(line, conflictBase):
+			state = inBase
+		case (state == inOurs || state == inBase) && strings.TrimRight(line, "\r\n") == conflictSplit:
+			state = inTheirs
+		case state == inTheirs && strings.HasPrefix(line, conflictTheirs):
+			state = common
+		case state == common:
+			b.WriteString(line)
+			o.WriteString(line)
+			t.WriteString(line)
+		case state == inOurs:
+			o.WriteString(line)
+		case state == inBase:
+			b.WriteString(line)
+		case state == inTheirs:
+			t.WriteString(line)
+		}
+	}
+	return b.String(), o.String(), t.String()
+}
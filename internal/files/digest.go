@@ -0,0 +1,59 @@
+package files
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+// digestPrefix names the hash function used for content digests, so the
+// algorithm can change without misreading older snapshots.
+const digestPrefix = "sha256:"
+
+// ContentDigest returns the digest stored in a snapshot's header for
+// content.
+func ContentDigest(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return digestPrefix + hex.EncodeToString(sum[:])
+}
+
+// Corrupted reports whether the snapshot's stored digest no longer matches
+// its content, e.g. after a manual edit or a bad merge. Snapshots written
+// before digests were stored have none and are never corrupted, and neither
+// are snapshots with Regions, which are edited on purpose.
+func (s *Snapshot) Corrupted() bool {
+	return s.Digest != "" && !s.Regions && s.Digest != ContentDigest(s.Content)
+}
+
+// Normalize replaces the snapshot's content with normalize(content). A
+// stored digest that matched the original content is updated to match the
+// normalized content, so normalizing does not make the snapshot corrupted.
+func (s *Snapshot) Normalize(normalize func(string) string) {
+	normalized := normalize(s.Content)
+	if s.Digest != "" && s.Digest == ContentDigest(s.Content) {
+		s.Digest = ContentDigest(normalized)
+	}
+	s.Content = normalized
+}
+
+// SameContent reports whether a and b have the same content. When both
+// carry a digest only the digests are compared, so neither content needs to
+// be read again, so a corrupted snapshot must be ruled out first. The digest
+// of a snapshot with Regions is not trusted.
+func SameContent(a, b *Snapshot) bool {
+	if a.Digest != "" && b.Digest != "" && !a.Regions && !b.Regions {
+		return a.Digest == b.Digest
+	}
+	return a.Content == b.Content
+}
+
+// ListCorruptedSnapshots returns the accepted snapshots in the project whose
+// content does not match their stored digest.
+func ListCorruptedSnapshots() ([]SnapshotInfo, error) {
+	var corrupted []SnapshotInfo
+	err := walkAccepted(func(info SnapshotInfo, snap *Snapshot) {
+		if snap.Corrupted() {
+			corrupted = append(corrupted, info)
+		}
+	})
+	return corrupted, err
+}
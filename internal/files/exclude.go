@@ -0,0 +1,68 @@
+package files
+
+import (
+	"bufio"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// IgnoreFile lists directories snapshot discovery should not descend into,
+// one glob per line, in the project root (or the go.work directory in a
+// workspace). Blank lines and lines starting with "#" are ignored.
+const IgnoreFile = ".shutterignore"
+
+// ExcludeEnv names the environment variable holding additional exclude
+// globs, separated by commas, e.g. SHUTTER_EXCLUDE="bazel-*,build".
+const ExcludeEnv = "SHUTTER_EXCLUDE"
+
+// excludePatterns returns the exclude globs from IgnoreFile and ExcludeEnv.
+// A glob without a "/" matches a directory name at any depth, such as
+// "bazel-*"; one with a "/" matches a path relative to the searched root,
+// such as "tools/generated". A trailing "/" is allowed and ignored.
+func excludePatterns() []string {
+	var patterns []string
+	if base, err := workspaceRoot(); err == nil {
+		if f, err := os.Open(filepath.Join(base, IgnoreFile)); err == nil {
+			scanner := bufio.NewScanner(f)
+			for scanner.Scan() {
+				patterns = appendPattern(patterns, scanner.Text())
+			}
+			f.Close()
+		}
+	}
+	for _, pattern := range strings.Split(os.Getenv(ExcludeEnv), ",") {
+		patterns = appendPattern(patterns, pattern)
+	}
+	return patterns
+}
+
+func appendPattern(patterns []string, line string) []string {
+	line = strings.TrimSpace(line)
+	if line == "" || strings.HasPrefix(line, "#") {
+		return patterns
+	}
+	line = strings.TrimPrefix(line, "/")
+	line = strings.TrimSuffix(line, "/")
+	if line == "" {
+		return patterns
+	}
+	return append(patterns, line)
+}
+
+// excluded reports whether the directory at rel, a slash-separated path
+// relative to the searched root, matches one of patterns.
+func excluded(patterns []string, rel string) bool {
+	name := path.Base(rel)
+	for _, pattern := range patterns {
+		target := name
+		if strings.Contains(pattern, "/") {
+			target = rel
+		}
+		if ok, _ := path.Match(pattern, target); ok {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,59 @@
+package files
+
+import (
+	"fmt"
+	"os"
+)
+
+// ContentPath returns the file holding the content of an externally stored
+// snapshot (see Snapshot.External), next to the snapshot file at
+// snapshotPath. Marking these files binary, or tracking them with Git LFS,
+// in .gitattributes keeps large content out of git diff:
+//
+//	*.snap.content binary
+func ContentPath(snapshotPath string) string {
+	return snapshotPath + ".content"
+}
+
+// writeContent stores the content of snap in the content file of the
+// snapshot file at snapshotPath when snap is stored externally, and removes
+// a content file left over from an earlier external version otherwise.
+func writeContent(snap *Snapshot, snapshotPath string) error {
+	if !snap.External {
+		return removeContent(snapshotPath)
+	}
+	return os.WriteFile(ContentPath(snapshotPath), []byte(snap.Content), 0644)
+}
+
+// readContent loads the content of an externally stored snapshot read from
+// snapshotPath.
+func readContent(snap *Snapshot, snapshotPath string) error {
+	if !snap.External {
+		return nil
+	}
+	data, err := os.ReadFile(ContentPath(snapshotPath))
+	if err != nil {
+		return fmt.Errorf("failed to read content of %s: %w", DisplayPath(snapshotPath), err)
+	}
+	snap.Content = string(data)
+	return nil
+}
+
+// moveContent moves the content file of the snapshot file at from to
+// belong to to, if there is one, replacing any content file of to.
+func moveContent(from, to string) error {
+	err := os.Rename(ContentPath(from), ContentPath(to))
+	if os.IsNotExist(err) {
+		return removeContent(to)
+	}
+	return err
+}
+
+// removeContent deletes the content file of the snapshot file at
+// snapshotPath, if there is one.
+func removeContent(snapshotPath string) error {
+	if err := os.Remove(ContentPath(snapshotPath)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
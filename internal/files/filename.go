@@ -0,0 +1,50 @@
+package files
+
+import "strings"
+
+// reservedNames are the device names Windows reserves regardless of
+// extension, so con.snap cannot be created either.
+var reservedNames = map[string]bool{
+	"con": true, "prn": true, "aux": true, "nul": true,
+	"com1": true, "com2": true, "com3": true, "com4": true, "com5": true,
+	"com6": true, "com7": true, "com8": true, "com9": true,
+	"lpt1": true, "lpt2": true, "lpt3": true, "lpt4": true, "lpt5": true,
+	"lpt6": true, "lpt7": true, "lpt8": true, "lpt9": true,
+}
+
+// sanitizeSegment makes s safe to use as a single path segment on every
+// platform. Path separators, characters Windows does not allow in file
+// names, and control characters become underscores; trailing dots, which
+// Windows drops, are removed; and reserved device names get an underscore
+// appended. A segment left empty, such as "..", becomes a single underscore.
+func sanitizeSegment(s string) string {
+	if s == "" {
+		return ""
+	}
+	s = strings.Map(func(r rune) rune {
+		if r < 0x20 || strings.ContainsRune(`<>:"/\|?*`, r) {
+			return '_'
+		}
+		return r
+	}, s)
+	s = strings.TrimRight(s, ". ")
+	if s == "" {
+		return "_"
+	}
+
+	base, _, _ := strings.Cut(s, ".")
+	if reservedNames[strings.ToLower(strings.TrimRight(base, " "))] {
+		s = base + "_" + s[len(base):]
+	}
+	return s
+}
+
+// sanitizeTestName sanitizes each segment of a test name, keeping the
+// slashes that separate subtests.
+func sanitizeTestName(testName string) string {
+	segments := strings.Split(testName, "/")
+	for i, segment := range segments {
+		segments[i] = sanitizeSegment(segment)
+	}
+	return strings.Join(segments, "/")
+}
@@ -3,8 +3,22 @@ package files
 import (
 	"fmt"
 	"os"
+	"path"
 	"path/filepath"
+	"slices"
 	"strings"
+
+	"github.com/ptdewey/shutter/internal/secrets"
+)
+
+// Content types recorded in a snapshot's header, naming the format of its
+// content.
+const (
+	ContentText = "text"
+	ContentANSI = "ansi" // Text with ANSI escape sequences
+	ContentJSON = "json"
+	ContentYAML = "yaml"
+	ContentXML  = "xml"
 )
 
 type Snapshot struct {
@@ -13,17 +27,106 @@ type Snapshot struct {
 	Test     string
 	FileName string
 	Content  string
+	Variant  string
+
+	// Suite is the name of the suite the snapshot was taken in, if any, so
+	// the snapshots of a suite can be reviewed as a group.
+	Suite string
+
+	// ContentType is the format of Content, one of the Content constants,
+	// as set by the function that took the snapshot. It is empty for
+	// snapshots written before it was recorded.
+	ContentType string
+
+	// Options names the scrubbers and ignore patterns applied to Content
+	// when the snapshot was taken, in order. Each is stored on its own
+	// option line in the header.
+	Options []string
+
+	// External stores Content in a sibling file (see ContentPath) instead of
+	// after the header, leaving only the header and its digest in the
+	// snapshot file. Reading the snapshot loads the content back.
+	External bool
+
+	// Stale explains why the accepted snapshot a pending one would replace
+	// is a stale baseline, such as its age, so review can point it out. It
+	// is only written to pending snapshots and dropped on accept.
+	Stale string
+
+	// IgnoreLines lists the lines of Content left out of comparison, as
+	// line numbers and ranges such as "12,40-42". It is added to accepted
+	// snapshots by hand and carried over to the pending snapshots that
+	// would replace them.
+	IgnoreLines string
+
+	// Regions marks an accepted snapshot whose Content has <<regex>>
+	// regions, edited in by hand, that match any text matching the
+	// expression (see transform.MatchRegions). Its digest is not checked,
+	// since it predates the edit. It is not carried over to new versions,
+	// which hold the actual text.
+	Regions bool
+
+	// Raw is the content before scrubbers and ignore patterns were applied,
+	// kept so review can show what scrubbing hides. RawFingerprint is the
+	// OptionsFingerprint of the options it was recorded under, and is empty
+	// when there is no raw content. Raw is stored in a sibling file (see
+	// RawPath) of pending snapshots only, and dropped on accept and reject.
+	Raw            string
+	RawFingerprint string
+
+	// Digest is the content digest stored in the header when the snapshot
+	// was read (see ContentDigest). Serialize always writes the digest of
+	// the current content, so this field is not written back.
+	Digest string
+
+	// Path is the file the snapshot was read from or last saved to. It is
+	// not part of the serialized snapshot.
+	Path string
+
+	// Commit is the last git commit that touched the file at Path, when it
+	// has been looked up with LastCommit. It is not part of the serialized
+	// snapshot.
+	Commit *Commit
 }
 
 func (s *Snapshot) Serialize() string {
 	header := fmt.Sprintf(
-		"---\ntitle: %s\ntest_name: %s\nfile_name: %s\nversion: %s\n---\n",
+		"---\ntitle: %s\ntest_name: %s\nfile_name: %s\nversion: %s\n",
 		s.Title, s.Test, s.FileName, s.Version,
 	)
-	return header + s.Content
+	if s.Variant != "" {
+		header += fmt.Sprintf("variant: %s\n", s.Variant)
+	}
+	if s.Suite != "" {
+		header += fmt.Sprintf("suite: %s\n", s.Suite)
+	}
+	if s.ContentType != "" {
+		header += fmt.Sprintf("content_type: %s\n", s.ContentType)
+	}
+	for _, opt := range s.Options {
+		header += fmt.Sprintf("option: %s\n", opt)
+	}
+	if s.IgnoreLines != "" {
+		header += fmt.Sprintf("ignore_lines: %s\n", s.IgnoreLines)
+	}
+	if s.Regions {
+		header += "regions: true\n"
+	}
+	if s.Stale != "" {
+		header += fmt.Sprintf("stale: %s\n", s.Stale)
+	}
+	if s.RawFingerprint != "" {
+		header += fmt.Sprintf("raw: %s\n", s.RawFingerprint)
+	}
+	header += fmt.Sprintf("digest: %s\n", ContentDigest(s.Content))
+	if s.External {
+		return header + "external: true\n---\n"
+	}
+	return header + "---\n" + s.Content
 }
 
 func Deserialize(raw string) (*Snapshot, error) {
+	raw = normalizeHeader(raw)
 	parts := strings.SplitN(raw, "---\n", 3)
 	if len(parts) < 3 {
 		return nil, fmt.Errorf("invalid snapshot format")
@@ -36,7 +139,7 @@ func Deserialize(raw string) (*Snapshot, error) {
 		Content: content,
 	}
 
-	for line := range strings.SplitSeq(header, "\n") {
+	for _, line := range strings.Split(header, "\n") {
 		line = strings.TrimSpace(line)
 		if line == "" {
 			continue
@@ -57,18 +160,56 @@ func Deserialize(raw string) (*Snapshot, error) {
 			snap.FileName = value
 		case "version":
 			snap.Version = value
+		case "variant":
+			snap.Variant = value
+		case "suite":
+			snap.Suite = value
+		case "content_type":
+			snap.ContentType = value
+		case "option":
+			snap.Options = append(snap.Options, value)
+		case "digest":
+			snap.Digest = value
+		case "external":
+			snap.External = value == "true"
+		case "ignore_lines":
+			snap.IgnoreLines = value
+		case "regions":
+			snap.Regions = value == "true"
+		case "stale":
+			snap.Stale = value
+		case "raw":
+			snap.RawFingerprint = value
 		}
 	}
 
 	return snap, nil
 }
 
+// normalizeHeader converts a header with CRLF line endings, as left by a
+// checkout on Windows, to LF. The content is left as it is.
+func normalizeHeader(raw string) string {
+	if !strings.HasPrefix(raw, "---\r\n") {
+		return raw
+	}
+	end := strings.Index(raw[len("---\r\n"):], "\r\n---\r\n")
+	if end < 0 {
+		return raw
+	}
+	end += len("---\r\n") + len("\r\n---\r\n")
+	return strings.ReplaceAll(raw[:end], "\r\n", "\n") + raw[end:]
+}
+
+// snapshotDirName is the directory, relative to a test's package, its
+// snapshots are kept in.
+const snapshotDirName = "__snapshots__"
+
 // getSnapshotDir finds the nearest __snapshots__ directory relative to the caller,
 // creating one if it doesn't exist. This is used when creating new snapshots.
 func getSnapshotDir() (string, error) {
 	// NOTE: maybe this could be configurable?
 	// Storing snapshots in root may be desirable in some cases
-	snapshotDir := "__snapshots__"
+	snapshotDir := snapshotDirName
 	if err := os.MkdirAll(snapshotDir, 0755); err != nil {
 		return "", err
 	}
@@ -76,35 +217,19 @@ func getSnapshotDir() (string, error) {
 	return snapshotDir, nil
 }
 
-// findAllSnapshotDirs recursively finds all __snapshots__ directories starting from root
-func findAllSnapshotDirs(root string) ([]string, error) {
-	var snapshotDirs []string
-
-	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			return err
-		}
-
-		// Skip hidden directories and common ignore paths
-		if info.IsDir() && len(info.Name()) > 0 && info.Name()[0] == '.' {
-			return filepath.SkipDir
-		}
-		if info.IsDir() && (info.Name() == "node_modules" || info.Name() == "vendor") {
-			return filepath.SkipDir
-		}
-
-		if info.IsDir() && info.Name() == "__snapshots__" {
-			snapshotDirs = append(snapshotDirs, path)
-		}
-
-		return nil
-	})
-
-	return snapshotDirs, err
+// SnapshotDir returns the __snapshots__ directory used for new snapshots,
+// creating it if it doesn't exist.
+func SnapshotDir() (string, error) {
+	return getSnapshotDir()
 }
 
-// findProjectRoot finds the root of the project by looking for go.mod
+// findProjectRoot finds the root of the project by looking for go.mod, unless
+// RootEnv sets it explicitly.
 func findProjectRoot() (string, error) {
+	if root, ok, err := explicitRoot(); ok {
+		return root, err
+	}
+
 	cwd, err := os.Getwd()
 	if err != nil {
 		return "", err
@@ -126,58 +251,159 @@ func findProjectRoot() (string, error) {
 	}
 }
 
-// TODO: make this use the snapshot title rather than the test name
+// SnapshotFileName converts a snapshot title into the base name used for its
+// file, without any extension. The name is safe on every platform: slashes
+// and characters Windows does not allow become underscores, so a title such
+// as "GET /users/:id" is stored as get__users__id rather than in nested
+// directories.
 func SnapshotFileName(snapTitle string) string {
-	return strings.ReplaceAll(strings.ToLower(snapTitle), " ", "_")
+	return sanitizeSegment(titleReplacer.Replace(strings.ToLower(snapTitle)))
 }
 
-// getSnapshotFileName returns the filename for a snapshot based on test name and state
-func getSnapshotFileName(snapTitle string, state string) string {
-	baseName := SnapshotFileName(snapTitle)
-	switch state {
-	case "accepted":
-		return baseName + ".snap"
-	case "new":
-		return baseName + ".snap.new"
+// variantSeparator separates the title of a snapshot from its variant in
+// file names. SnapshotFileName never produces it, so a title can never name
+// the file of another title's variant.
+const variantSeparator = "~"
+
+var titleReplacer = strings.NewReplacer(" ", "_", variantSeparator, "_")
+
+// SnapshotKey returns the path of a snapshot relative to its __snapshots__
+// directory, without any extension. Snapshots are grouped into a directory per
+// test (subtests nest further) so that two tests using the same title do not
+// share a file. An empty test name yields the legacy flat layout.
+func SnapshotKey(testName, snapTitle string) string {
+	if testName == "" {
+		return SnapshotFileName(snapTitle)
+	}
+	return path.Join(sanitizeTestName(testName), SnapshotFileName(snapTitle))
+}
+
+// VariantKey returns the key of a snapshot variant. Variants are stored next
+// to each other with the variant inserted before the extension
+// (admin_case~linux.snap). An empty variant yields SnapshotKey.
+func VariantKey(testName, snapTitle, variant string) string {
+	key := SnapshotKey(testName, snapTitle)
+	if variant == "" {
+		return key
+	}
+	return key + variantSeparator + SnapshotFileName(variant)
+}
+
+// Key returns the snapshot's path relative to its __snapshots__ directory.
+func (s *Snapshot) Key() string {
+	return VariantKey(s.Test, s.Title, s.Variant)
+}
+
+// State identifies which file of a snapshot is meant.
+type State int
+
+const (
+	StateAccepted State = iota // The accepted snapshot (.snap)
+	StateNew                   // A snapshot pending review (.snap.new)
+)
+
+// Extension returns the file extension used for snapshots in state s, as
+// configured for the project (see Config.Extension).
+func (s State) Extension() string {
+	switch s {
+	case StateAccepted:
+		return projectFormat().extension
+	case StateNew:
+		return projectFormat().extension + pendingSuffix
+	default:
+		panic(fmt.Sprintf("invalid snapshot state: %d", int(s)))
+	}
+}
+
+func (s State) String() string {
+	switch s {
+	case StateAccepted:
+		return "accepted"
+	case StateNew:
+		return "new"
 	default:
-		return baseName + "." + state
+		return fmt.Sprintf("State(%d)", int(s))
 	}
 }
 
+// getSnapshotFileName returns the filename for a snapshot key and state
+func getSnapshotFileName(key string, state State) string {
+	return filepath.FromSlash(key) + state.Extension()
+}
+
 // getSnapshotPath returns the full path for a snapshot file
-func getSnapshotPath(snapTitle string, state string) (string, error) {
+func getSnapshotPath(testName, snapTitle string, state State) (string, error) {
 	snapshotDir, err := getSnapshotDir()
 	if err != nil {
 		return "", err
 	}
 
-	fileName := getSnapshotFileName(snapTitle, state)
+	fileName := getSnapshotFileName(SnapshotKey(testName, snapTitle), state)
 	return filepath.Join(snapshotDir, fileName), nil
 }
 
-func SaveSnapshot(snap *Snapshot, state string) error {
-	snapshotDir, err := getSnapshotDir()
-	if err != nil {
-		return err
+// SaveSnapshot writes snap to the __snapshots__ directory of the current
+// package. Under bazel test, pending snapshots are written to the test's
+// undeclared outputs instead (see CollectBazelOutputs).
+func SaveSnapshot(snap *Snapshot, state State) error {
+	snapshotDir, underBazel := bazelPendingDir()
+	if !underBazel || state != StateNew {
+		var err error
+		if snapshotDir, err = getSnapshotDir(); err != nil {
+			return err
+		}
 	}
 
-	fileName := getSnapshotFileName(snap.Title, state)
+	fileName := getSnapshotFileName(snap.Key(), state)
 	filePath := filepath.Join(snapshotDir, fileName)
 
 	if err := os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
 		return err
 	}
 
-	return os.WriteFile(filePath, []byte(snap.Serialize()), 0644)
+	if state == StateAccepted && projectFormat().header == HeaderNone {
+		// Without a header nothing points to a content file.
+		snap.External = false
+	}
+	if err := writeContent(snap, filePath); err != nil {
+		return err
+	}
+	if err := writeRaw(snap, filePath); err != nil {
+		return err
+	}
+	if err := writeFileAtomic(filePath, []byte(snap.EncodeFile(filePath))); err != nil {
+		return err
+	}
+	snap.Path = filePath
+	return nil
 }
 
-func ReadSnapshot(snapTitle string, state string) (*Snapshot, error) {
-	snapshotDir, err := getSnapshotDir()
+// writeFileAtomic writes data to path through a temporary file in the same
+// directory, so that a failed or interrupted write never leaves a partial
+// file at path.
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
 	if err != nil {
-		return nil, err
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(0644); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
 	}
+	return os.Rename(tmp.Name(), path)
+}
 
-	return ReadSnapshotWithDir(snapshotDir, snapTitle, state)
+func ReadSnapshot(testName, snapTitle string, state State) (*Snapshot, error) {
+	return ReadSnapshotWithDir(snapshotDirName, testName, snapTitle, state)
 }
 
 // ReadSnapshotFromPath reads a snapshot directly from a full file path
@@ -187,55 +413,126 @@ func ReadSnapshotFromPath(filePath string) (*Snapshot, error) {
 		return nil, err
 	}
 
-	return Deserialize(string(data))
+	snap, err := DecodeFile(filePath, string(data))
+	if err != nil {
+		return nil, err
+	}
+	if err := readContent(snap, filePath); err != nil {
+		return nil, err
+	}
+	if err := readRaw(snap, filePath); err != nil {
+		return nil, err
+	}
+	snap.Path = filePath
+	return snap, nil
 }
 
 // ReadSnapshotWithDir reads a snapshot from a specific directory
-func ReadSnapshotWithDir(snapshotDir, snapTitle string, state string) (*Snapshot, error) {
-	fileName := getSnapshotFileName(snapTitle, state)
+func ReadSnapshotWithDir(snapshotDir, testName, snapTitle string, state State) (*Snapshot, error) {
+	fileName := getSnapshotFileName(SnapshotKey(testName, snapTitle), state)
 	filePath := filepath.Join(snapshotDir, fileName)
 
-	data, err := os.ReadFile(filePath)
-	if err != nil {
-		return nil, err
+	return ReadSnapshotFromPath(filePath)
+}
+
+// ReadAccepted reads the accepted snapshot for a test and title. Snapshots
+// still stored in the legacy flat layout are used as a fallback, provided
+// they were recorded by the same test.
+func ReadAccepted(testName, snapTitle string) (*Snapshot, error) {
+	return ReadAcceptedVariant(testName, snapTitle, "")
+}
+
+// ReadAcceptedVariant reads the accepted snapshot for a test, title, and
+// variant. Only snapshots without a variant fall back to the legacy layout.
+func ReadAcceptedVariant(testName, snapTitle, variant string) (*Snapshot, error) {
+	snap, err := ReadSnapshotFromPath(acceptedReadPath(VariantKey(testName, snapTitle, variant)))
+	if err == nil || testName == "" || variant != "" {
+		return snap, err
 	}
 
-	return Deserialize(string(data))
+	legacy, legacyErr := ReadSnapshot("", snapTitle, StateAccepted)
+	if legacyErr != nil || (legacy.Test != "" && legacy.Test != testName) {
+		return nil, err
+	}
+	return legacy, nil
 }
 
-func ReadAccepted(snapTitle string) (*Snapshot, error) {
-	return ReadSnapshot(snapTitle, "snap")
+func ReadNew(testName, snapTitle string) (*Snapshot, error) {
+	return ReadSnapshot(testName, snapTitle, StateNew)
 }
 
-func ReadNew(snapTitle string) (*Snapshot, error) {
-	return ReadSnapshot(snapTitle, "new")
+// DisplayPath returns path relative to the working directory when it lies
+// beneath it, so that paths shown to users stay short.
+func DisplayPath(path string) string {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return path
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return path
+	}
+	rel, err := filepath.Rel(cwd, abs)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return path
+	}
+	return rel
 }
 
 // SnapshotInfo contains metadata about a snapshot file including its full path
 type SnapshotInfo struct {
-	Title string // The snapshot title (used as identifier)
-	Path  string // Full path to the snapshot file
-	Dir   string // Directory containing the snapshot
+	Title   string // The snapshot title (used as identifier)
+	Path    string // Full path to the snapshot file
+	Dir     string // Directory containing the snapshot
+	Variant string // The snapshot's variant, if any, when listed as pending
+	Test    string // The test that took the snapshot, when listed as pending
+
+	// group identifies the snapshots that are variants of one title.
+	group string
+	// source is the snapshot being taken, when the info was made for it
+	// rather than listed (see SnapshotInfoFor).
+	source *Snapshot
+}
+
+// AcceptedPath returns the path the snapshot is written to once accepted.
+func (info SnapshotInfo) AcceptedPath() string {
+	return strings.TrimSuffix(info.Path, StateNew.Extension()) + StateAccepted.Extension()
 }
 
 func ListNewSnapshots() ([]SnapshotInfo, error) {
-	projectRoot, err := findProjectRoot()
+	snapshotDirs, err := projectSnapshotDirs()
 	if err != nil {
 		return nil, err
 	}
+	return listNewSnapshots(snapshotDirs), nil
+}
 
-	snapshotDirs, err := findAllSnapshotDirs(projectRoot)
+// ListNewSnapshotsIn lists the pending snapshots in the __snapshots__
+// directories under dir instead of the whole project.
+func ListNewSnapshotsIn(dir string) ([]SnapshotInfo, error) {
+	absDir, err := filepath.Abs(dir)
 	if err != nil {
 		return nil, err
 	}
+	if _, err := os.Stat(absDir); err != nil {
+		return nil, err
+	}
 
+	snapshotDirs, err := findAllSnapshotDirs(absDir)
+	if err != nil {
+		return nil, err
+	}
+	return listNewSnapshots(snapshotDirs), nil
+}
+
+func listNewSnapshots(snapshotDirs []string) []SnapshotInfo {
 	var newSnapshots []SnapshotInfo
 	for _, dir := range snapshotDirs {
 		walkErr := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
 			if err != nil {
 				return err
 			}
-			if info.IsDir() || !strings.HasSuffix(info.Name(), ".snap.new") {
+			if info.IsDir() || !strings.HasSuffix(info.Name(), StateNew.Extension()) {
 				return nil
 			}
 			rel, err := filepath.Rel(dir, path)
@@ -243,9 +540,9 @@ func ListNewSnapshots() ([]SnapshotInfo, error) {
 				return err
 			}
 			// Title is the path relative to the __snapshots__ dir, with the
-			// .snap.new extension removed. Snapshots saved with titles
-			// containing "/" live in nested subdirectories.
-			title := strings.TrimSuffix(filepath.ToSlash(rel), ".snap.new")
+			// .snap.new extension removed. Snapshots are nested under a
+			// directory per test, and subtests and groups nest further.
+			title := strings.TrimSuffix(filepath.ToSlash(rel), StateNew.Extension())
 			newSnapshots = append(newSnapshots, SnapshotInfo{
 				Title: title,
 				Path:  path,
@@ -259,59 +556,374 @@ func ListNewSnapshots() ([]SnapshotInfo, error) {
 		}
 	}
 
-	return newSnapshots, nil
+	return groupVariants(newSnapshots, ReadSnapshotFromPath)
+}
+
+// FilterSuite returns the snapshots taken in the named suite, reading each
+// snapshot's header from the current Storage.
+func FilterSuite(snapshots []SnapshotInfo, name string) ([]SnapshotInfo, error) {
+	var selected []SnapshotInfo
+	for _, info := range snapshots {
+		snap, err := CurrentStorage().Read(info, StateNew)
+		if err != nil {
+			return nil, err
+		}
+		if snap.Suite == name {
+			selected = append(selected, info)
+		}
+	}
+	return selected, nil
 }
 
-// AcceptSnapshotInfo accepts a snapshot using SnapshotInfo
+// FindPending returns the pending snapshots named name: by their title as
+// listed by ListNewSnapshots (TestUsers/admin_case), by test name and title
+// as printed by failing tests (TestUsers/admin case), or by title alone.
+// The pending variants of a snapshot are all returned. It is an error if no
+// pending snapshot matches, or if snapshots of several tests do.
+func FindPending(name string) ([]SnapshotInfo, error) {
+	pending, err := ListNewSnapshots()
+	if err != nil {
+		return nil, err
+	}
+
+	var matches []SnapshotInfo
+	owners := make(map[string]bool)
+	for _, info := range pending {
+		snap, err := ReadSnapshotFromPath(info.Path)
+		if err != nil {
+			return nil, err
+		}
+		key := info.Title
+		if snap.Variant != "" {
+			key = strings.TrimSuffix(key, variantSeparator+SnapshotFileName(snap.Variant))
+		}
+		if key != name && snap.Test+"/"+snap.Title != name && snap.Title != name {
+			continue
+		}
+		matches = append(matches, info)
+		owners[info.Dir+"\x00"+snap.Test+"\x00"+snap.Title] = true
+	}
+
+	switch {
+	case len(matches) == 0:
+		return nil, fmt.Errorf("no pending snapshot named %q", name)
+	case len(owners) > 1:
+		var paths []string
+		for _, info := range matches {
+			paths = append(paths, DisplayPath(info.Path))
+		}
+		slices.Sort(paths)
+		return nil, fmt.Errorf("%q matches several pending snapshots, use its test name and title instead: %s", name, strings.Join(paths, ", "))
+	}
+	return matches, nil
+}
+
+// groupVariants orders snapshots so that the variants of one title follow
+// each other, where the first of them was found, starting with the snapshot
+// without a variant. File names alone can't tell a variant from a title
+// containing a dot, so the variant is read from each snapshot's header
+// with read.
+func groupVariants(snapshots []SnapshotInfo, read func(path string) (*Snapshot, error)) []SnapshotInfo {
+	var order []string
+	groups := make(map[string][]SnapshotInfo)
+	for _, info := range snapshots {
+		info.group = info.Path
+		if snap, err := read(info.Path); err == nil {
+			info.Variant = snap.Variant
+			info.Test = snap.Test
+			info.group = info.Dir + "\x00" + SnapshotKey(snap.Test, snap.Title)
+		}
+		if _, ok := groups[info.group]; !ok {
+			order = append(order, info.group)
+		}
+		groups[info.group] = append(groups[info.group], info)
+	}
+
+	grouped := make([]SnapshotInfo, 0, len(snapshots))
+	for _, group := range order {
+		variants := groups[group]
+		slices.SortStableFunc(variants, func(a, b SnapshotInfo) int {
+			return strings.Compare(a.Variant, b.Variant)
+		})
+		grouped = append(grouped, variants...)
+	}
+	return grouped
+}
+
+// VariantPosition reports the position of snapshots[i] among the variants
+// of its title, counting from 1, and how many of them are listed. Snapshots
+// without other variants are 1 of 1. snapshots must be ordered as returned
+// by ListNewSnapshots.
+func VariantPosition(snapshots []SnapshotInfo, i int) (int, int) {
+	group := snapshots[i].group
+	first, last := i, i
+	for first > 0 && group != "" && snapshots[first-1].group == group {
+		first--
+	}
+	for last < len(snapshots)-1 && group != "" && snapshots[last+1].group == group {
+		last++
+	}
+	return i - first + 1, last - first + 1
+}
+
+// AcceptedSnapshot is an accepted snapshot found by ListAccepted.
+type AcceptedSnapshot struct {
+	Info     SnapshotInfo
+	Snapshot *Snapshot
+}
+
+// ListAccepted returns every readable accepted snapshot in the project.
+func ListAccepted() ([]AcceptedSnapshot, error) {
+	var accepted []AcceptedSnapshot
+	err := walkAccepted(func(info SnapshotInfo, snap *Snapshot) {
+		accepted = append(accepted, AcceptedSnapshot{Info: info, Snapshot: snap})
+	})
+	return accepted, err
+}
+
+// walkAccepted calls fn for each readable accepted snapshot in the project.
+func walkAccepted(fn func(SnapshotInfo, *Snapshot)) error {
+	snapshotDirs, err := projectSnapshotDirs()
+	if err != nil {
+		return err
+	}
+
+	for _, dir := range snapshotDirs {
+		walkErr := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if info.IsDir() || !strings.HasSuffix(info.Name(), StateAccepted.Extension()) {
+				return nil
+			}
+
+			snap, err := ReadSnapshotFromPath(path)
+			if err != nil {
+				return nil
+			}
+			rel, err := filepath.Rel(dir, path)
+			if err != nil {
+				return err
+			}
+			fn(SnapshotInfo{
+				Title: strings.TrimSuffix(filepath.ToSlash(rel), StateAccepted.Extension()),
+				Path:  path,
+				Dir:   dir,
+			}, snap)
+			return nil
+		})
+		if walkErr != nil {
+			return walkErr
+		}
+	}
+	return nil
+}
+
+// ReadAcceptedInfo reads the accepted counterpart of a pending snapshot. Like
+// ReadAccepted, it falls back to the legacy flat layout when the accepted
+// file has not been migrated yet.
+func ReadAcceptedInfo(info SnapshotInfo) (*Snapshot, error) {
+	accepted, err := ReadSnapshotFromPath(info.AcceptedPath())
+	if err == nil {
+		return accepted, nil
+	}
+
+	newSnap, newErr := ReadSnapshotFromPath(info.Path)
+	if newErr != nil || newSnap.Test == "" || newSnap.Variant != "" {
+		return nil, err
+	}
+
+	legacy, legacyErr := ReadSnapshotWithDir(info.Dir, "", newSnap.Title, StateAccepted)
+	if legacyErr != nil || (legacy.Test != "" && legacy.Test != newSnap.Test) {
+		return nil, err
+	}
+	return legacy, nil
+}
+
+// AcceptSnapshotInfo accepts a snapshot using SnapshotInfo. A snapshot whose
+// content looks like it contains secrets is not accepted; a *SecretsError
+// lists what was found.
 func AcceptSnapshotInfo(info SnapshotInfo) error {
-	newPath := info.Path
-	acceptedPath := filepath.Join(info.Dir, getSnapshotFileName(info.Title, "accepted"))
+	return acceptSnapshotInfo(info, true)
+}
 
-	data, err := os.ReadFile(newPath)
+// ForceAcceptSnapshotInfo accepts a snapshot without scanning it for
+// secrets, for when a reviewer has confirmed the findings are harmless.
+func ForceAcceptSnapshotInfo(info SnapshotInfo) error {
+	return acceptSnapshotInfo(info, false)
+}
+
+func acceptSnapshotInfo(info SnapshotInfo, scan bool) error {
+	data, err := os.ReadFile(info.Path)
+	if err != nil {
+		return err
+	}
+	audit, err := prepareAudit(info)
 	if err != nil {
 		return err
 	}
 
-	if err := os.WriteFile(acceptedPath, data, 0644); err != nil {
+	snap, err := Deserialize(string(data))
+	if err == nil {
+		if err := readContent(snap, info.Path); err != nil {
+			return err
+		}
+		if scan {
+			if findings := secrets.Scan(snap.Content); len(findings) > 0 {
+				return &SecretsError{Path: info.Path, Findings: findings}
+			}
+		}
+		if err := recordHistory(info.AcceptedPath(), snap.Content); err != nil {
+			return err
+		}
+		header := projectFormat().header
+		if snap.Stale != "" || snap.RawFingerprint != "" || header != HeaderYAML {
+			// Accepting renews the baseline, and raw content is only kept
+			// for review.
+			snap.Stale = ""
+			snap.RawFingerprint = ""
+			if header == HeaderNone && snap.External {
+				snap.External = false
+				if err := removeContent(info.Path); err != nil {
+					return err
+				}
+			}
+			data = []byte(snap.EncodeFile(info.AcceptedPath()))
+		}
+	}
+
+	if err := moveContent(info.Path, info.AcceptedPath()); err != nil {
+		return err
+	}
+	if err := os.WriteFile(info.AcceptedPath(), data, 0644); err != nil {
+		return err
+	}
+
+	if snap != nil {
+		removeLegacySnapshot(info.Dir, snap)
+	}
+	if err := removeRaw(info.Path); err != nil {
+		return err
+	}
+	if err := os.Remove(info.Path); err != nil {
 		return err
 	}
 
-	return os.Remove(newPath)
+	return audit.record(AuditAccept)
 }
 
-func AcceptSnapshot(snapTitle string) error {
-	newPath, err := getSnapshotPath(snapTitle, "new")
+func AcceptSnapshot(testName, snapTitle string) error {
+	newPath, err := getSnapshotPath(testName, snapTitle, StateNew)
 	if err != nil {
 		return err
 	}
 
-	acceptedPath, err := getSnapshotPath(snapTitle, "accepted")
+	snapshotDir, err := getSnapshotDir()
+	if err != nil {
+		return err
+	}
+
+	return AcceptSnapshotInfo(SnapshotInfo{
+		Title: SnapshotKey(testName, snapTitle),
+		Path:  newPath,
+		Dir:   snapshotDir,
+	})
+}
+
+// RejectSnapshotInfo rejects a snapshot using SnapshotInfo. The rejected
+// file is moved into the trash so it can be restored with RestoreSnapshot.
+func RejectSnapshotInfo(info SnapshotInfo) error {
+	audit, err := prepareAudit(info)
 	if err != nil {
 		return err
 	}
+	if err := trashSnapshot(info.Path); err != nil {
+		return err
+	}
+
+	return audit.record(AuditReject)
+}
 
-	data, err := os.ReadFile(newPath)
+func RejectSnapshot(testName, snapTitle string) error {
+	filePath, err := getSnapshotPath(testName, snapTitle, StateNew)
 	if err != nil {
 		return err
 	}
 
-	if err := os.WriteFile(acceptedPath, data, 0644); err != nil {
+	snapshotDir, err := getSnapshotDir()
+	if err != nil {
 		return err
 	}
 
-	return os.Remove(newPath)
+	return RejectSnapshotInfo(SnapshotInfo{
+		Title: SnapshotKey(testName, snapTitle),
+		Path:  filePath,
+		Dir:   snapshotDir,
+	})
 }
 
-// RejectSnapshotInfo rejects a snapshot using SnapshotInfo
-func RejectSnapshotInfo(info SnapshotInfo) error {
-	return os.Remove(info.Path)
+// removeLegacySnapshot deletes the flat-layout accepted file superseded by
+// snap, if one exists and was recorded by the same test.
+func removeLegacySnapshot(snapshotDir string, snap *Snapshot) {
+	if snap.Test == "" || snap.Variant != "" {
+		return
+	}
+
+	legacyPath := filepath.Join(snapshotDir, getSnapshotFileName(SnapshotFileName(snap.Title), StateAccepted))
+	legacy, err := ReadSnapshotFromPath(legacyPath)
+	if err != nil || legacy.Test != snap.Test {
+		return
+	}
+	_ = os.Remove(legacyPath)
+	_ = removeContent(legacyPath)
 }
 
-func RejectSnapshot(snapTitle string) error {
-	filePath, err := getSnapshotPath(snapTitle, "new")
+// MigrateLegacySnapshots moves accepted snapshots stored in the flat layout
+// (__snapshots__/<title>.snap) into the per-test layout, using the test name
+// recorded in each file's header. Files without a test name, or whose
+// destination already exists, are left in place. It returns the new paths of
+// the migrated files.
+func MigrateLegacySnapshots() ([]string, error) {
+	snapshotDirs, err := projectSnapshotDirs()
 	if err != nil {
-		return err
+		return nil, err
+	}
+
+	var migrated []string
+	for _, dir := range snapshotDirs {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			return migrated, err
+		}
+
+		for _, entry := range entries {
+			if entry.IsDir() || !strings.HasSuffix(entry.Name(), StateAccepted.Extension()) {
+				continue
+			}
+
+			oldPath := filepath.Join(dir, entry.Name())
+			snap, err := ReadSnapshotFromPath(oldPath)
+			if err != nil || snap.Test == "" {
+				continue
+			}
+
+			newPath := filepath.Join(dir, getSnapshotFileName(snap.Key(), StateAccepted))
+			if _, err := os.Stat(newPath); err == nil {
+				continue
+			}
+
+			if err := os.MkdirAll(filepath.Dir(newPath), 0755); err != nil {
+				return migrated, err
+			}
+			if err := moveContent(oldPath, newPath); err != nil {
+				return migrated, err
+			}
+			if err := os.Rename(oldPath, newPath); err != nil {
+				return migrated, err
+			}
+			migrated = append(migrated, newPath)
+		}
 	}
 
-	return os.Remove(filePath)
+	return migrated, nil
 }
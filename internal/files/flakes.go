@@ -0,0 +1,161 @@
+package files
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// FlakeEntry is the content a snapshot produced in one run, recorded while
+// flake detection is enabled.
+type FlakeEntry struct {
+	Digest     string    `json:"digest"`
+	ObservedAt time.Time `json:"observed_at"`
+	Content    string    `json:"content"`
+}
+
+// FlakesPath returns the file in which the runs of the snapshot accepted at
+// acceptedPath are recorded. Each line of the file is a JSON-encoded
+// FlakeEntry, oldest first.
+func FlakesPath(acceptedPath string) string {
+	return acceptedPath + ".flakes"
+}
+
+// ReadFlakes returns the runs recorded for the snapshot accepted at
+// acceptedPath, oldest first.
+func ReadFlakes(acceptedPath string) ([]FlakeEntry, error) {
+	f, err := os.Open(FlakesPath(acceptedPath))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var entries []FlakeEntry
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 64*1024*1024)
+	for scanner.Scan() {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var entry FlakeEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return nil, fmt.Errorf("invalid flakes file %s: %w", FlakesPath(acceptedPath), err)
+		}
+		entries = append(entries, entry)
+	}
+
+	return entries, scanner.Err()
+}
+
+// RecordRun records the content snap produced in this run, keeping the
+// most recent limit runs.
+func RecordRun(snap *Snapshot, limit int) error {
+	snapshotDir, err := getSnapshotDir()
+	if err != nil {
+		return err
+	}
+	acceptedPath := filepath.Join(snapshotDir, getSnapshotFileName(snap.Key(), StateAccepted))
+
+	entries, err := ReadFlakes(acceptedPath)
+	if err != nil {
+		return err
+	}
+	entries = append(entries, FlakeEntry{
+		Digest:     ContentDigest(snap.Content),
+		ObservedAt: time.Now().UTC(),
+		Content:    snap.Content,
+	})
+	if len(entries) > limit {
+		entries = entries[len(entries)-limit:]
+	}
+
+	var sb strings.Builder
+	for _, entry := range entries {
+		line, err := json.Marshal(entry)
+		if err != nil {
+			return err
+		}
+		sb.Write(line)
+		sb.WriteByte('\n')
+	}
+
+	if err := os.MkdirAll(filepath.Dir(acceptedPath), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(FlakesPath(acceptedPath), []byte(sb.String()), 0644)
+}
+
+// Flake is a snapshot that produced more than one distinct content across
+// its recorded runs.
+type Flake struct {
+	Info SnapshotInfo
+	Runs int
+	// Contents are the distinct contents, in the order first observed.
+	Contents []string
+}
+
+// ListFlakySnapshots returns the snapshots in the project whose recorded
+// runs produced more than one distinct content.
+func ListFlakySnapshots() ([]Flake, error) {
+	snapshotDirs, err := projectSnapshotDirs()
+	if err != nil {
+		return nil, err
+	}
+
+	var flakes []Flake
+	for _, dir := range snapshotDirs {
+		walkErr := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if info.IsDir() || !strings.HasSuffix(info.Name(), StateAccepted.Extension()+".flakes") {
+				return nil
+			}
+
+			acceptedPath := strings.TrimSuffix(path, ".flakes")
+			entries, err := ReadFlakes(acceptedPath)
+			if err != nil {
+				return err
+			}
+
+			seen := make(map[string]bool)
+			var contents []string
+			for _, entry := range entries {
+				if !seen[entry.Digest] {
+					seen[entry.Digest] = true
+					contents = append(contents, entry.Content)
+				}
+			}
+			if len(contents) < 2 {
+				return nil
+			}
+
+			rel, err := filepath.Rel(dir, acceptedPath)
+			if err != nil {
+				return err
+			}
+			flakes = append(flakes, Flake{
+				Info: SnapshotInfo{
+					Title: strings.TrimSuffix(filepath.ToSlash(rel), StateAccepted.Extension()),
+					Path:  acceptedPath,
+					Dir:   dir,
+				},
+				Runs:     len(entries),
+				Contents: contents,
+			})
+			return nil
+		})
+		if walkErr != nil {
+			return flakes, walkErr
+		}
+	}
+
+	return flakes, nil
+}
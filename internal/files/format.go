@@ -0,0 +1,272 @@
+package files
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// DefaultExtension is the file extension of accepted snapshots unless the
+// project configures another one (see Config.Extension).
+const DefaultExtension = ".snap"
+
+// pendingSuffix is added to the extension of accepted snapshots to name the
+// snapshots pending review.
+const pendingSuffix = ".new"
+
+// Header styles of accepted snapshot files, set by the header setting of
+// ConfigFile. Pending snapshots always have a YAML header, as review needs
+// the metadata it holds.
+const (
+	HeaderYAML = "yaml" // Metadata between "---" lines before the content
+	HeaderJSON = "json" // Metadata as a JSON object before the content
+	HeaderNone = "none" // The content alone, so the file can be a fixture
+)
+
+// format is the extension and header style of a project's snapshots.
+type format struct {
+	extension string
+	header    string
+}
+
+// formats caches the format of each project root, as it is needed for
+// every snapshot file name.
+var formats struct {
+	sync.Mutex
+	byRoot map[string]format
+}
+
+// projectFormat returns the snapshot format configured for the current
+// project. A config file that cannot be read leaves the defaults in place;
+// the command line tools report its errors.
+func projectFormat() format {
+	f := format{extension: DefaultExtension, header: HeaderYAML}
+	root, err := workspaceRoot()
+	if err != nil {
+		return f
+	}
+
+	formats.Lock()
+	defer formats.Unlock()
+	if cached, ok := formats.byRoot[root]; ok {
+		return cached
+	}
+	if cfg, err := LoadConfig(); err == nil {
+		if cfg.Extension != "" {
+			f.extension = cfg.Extension
+		}
+		if cfg.Header != "" {
+			f.header = cfg.Header
+		}
+	}
+	if formats.byRoot == nil {
+		formats.byRoot = map[string]format{}
+	}
+	formats.byRoot[root] = f
+	return f
+}
+
+// isPending reports whether path names a snapshot pending review.
+func isPending(path string) bool {
+	return strings.HasSuffix(path, pendingSuffix)
+}
+
+// jsonHeader is the header of a snapshot in the HeaderJSON style, with the
+// keys of the YAML header.
+type jsonHeader struct {
+	Title          string   `json:"title"`
+	Test           string   `json:"test_name,omitempty"`
+	FileName       string   `json:"file_name,omitempty"`
+	Version        string   `json:"version,omitempty"`
+	Variant        string   `json:"variant,omitempty"`
+	Suite          string   `json:"suite,omitempty"`
+	ContentType    string   `json:"content_type,omitempty"`
+	Options        []string `json:"options,omitempty"`
+	IgnoreLines    string   `json:"ignore_lines,omitempty"`
+	Regions        bool     `json:"regions,omitempty"`
+	Stale          string   `json:"stale,omitempty"`
+	RawFingerprint string   `json:"raw,omitempty"`
+	Digest         string   `json:"digest"`
+	External       bool     `json:"external,omitempty"`
+}
+
+// EncodeFile returns the contents of the snapshot file at path: the
+// snapshot in the header style of the project if it is accepted, or as
+// Serialize writes it if it is pending. Without a header the content is
+// always written to the file itself, even if the snapshot is External.
+func (s *Snapshot) EncodeFile(path string) string {
+	if isPending(path) {
+		return s.Serialize()
+	}
+	switch projectFormat().header {
+	case HeaderJSON:
+		header, _ := json.MarshalIndent(jsonHeader{
+			Title:          s.Title,
+			Test:           s.Test,
+			FileName:       s.FileName,
+			Version:        s.Version,
+			Variant:        s.Variant,
+			Suite:          s.Suite,
+			ContentType:    s.ContentType,
+			Options:        s.Options,
+			IgnoreLines:    s.IgnoreLines,
+			Regions:        s.Regions,
+			Stale:          s.Stale,
+			RawFingerprint: s.RawFingerprint,
+			Digest:         ContentDigest(s.Content),
+			External:       s.External,
+		}, "", "  ")
+		if s.External {
+			return string(header) + "\n"
+		}
+		return string(header) + "\n" + s.Content
+	case HeaderNone:
+		return s.Content
+	default:
+		return s.Serialize()
+	}
+}
+
+// DecodeFile parses raw, the contents of the snapshot file at path, in the
+// header style EncodeFile writes for it. Accepted snapshots with a YAML
+// header, written before the project changed its style, are still read.
+func DecodeFile(path, raw string) (*Snapshot, error) {
+	if isPending(path) {
+		return Deserialize(raw)
+	}
+	switch projectFormat().header {
+	case HeaderJSON:
+		if strings.HasPrefix(raw, "{") {
+			return deserializeJSON(raw)
+		}
+	case HeaderNone:
+		return headerless(path, raw), nil
+	}
+	return Deserialize(raw)
+}
+
+func deserializeJSON(raw string) (*Snapshot, error) {
+	dec := json.NewDecoder(strings.NewReader(raw))
+	var h jsonHeader
+	if err := dec.Decode(&h); err != nil {
+		return nil, fmt.Errorf("invalid snapshot header: %w", err)
+	}
+	content := strings.TrimPrefix(raw[dec.InputOffset():], "\r")
+	return &Snapshot{
+		Title:          h.Title,
+		Test:           h.Test,
+		FileName:       h.FileName,
+		Version:        h.Version,
+		Variant:        h.Variant,
+		Suite:          h.Suite,
+		ContentType:    h.ContentType,
+		Options:        h.Options,
+		IgnoreLines:    h.IgnoreLines,
+		Regions:        h.Regions,
+		Stale:          h.Stale,
+		RawFingerprint: h.RawFingerprint,
+		Digest:         h.Digest,
+		External:       h.External,
+		Content:        strings.TrimPrefix(content, "\n"),
+	}, nil
+}
+
+// headerless returns the snapshot of a file without a header. Its test and
+// title are taken from its path below the __snapshots__ directory; the
+// other metadata, including the digest, is not known.
+func headerless(path, raw string) *Snapshot {
+	key := strings.TrimSuffix(filepath.ToSlash(path), projectFormat().extension)
+	if i := strings.LastIndex(key, "__snapshots__/"); i >= 0 {
+		key = key[i+len("__snapshots__/"):]
+	}
+	snap := &Snapshot{Title: key, Content: raw}
+	if i := strings.LastIndex(key, "/"); i >= 0 {
+		snap.Test, snap.Title = key[:i], key[i+1:]
+	}
+	return snap
+}
+
+// MigrateSnapshotFormat rewrites the accepted snapshots of the project that
+// have a YAML header in the configured extension and header style, moving
+// their history along. Snapshots whose destination already exists are left
+// in place. It returns the paths of the rewritten files.
+func MigrateSnapshotFormat() ([]string, error) {
+	f := projectFormat()
+	if f.extension == DefaultExtension && f.header == HeaderYAML {
+		return nil, nil
+	}
+	snapshotDirs, err := projectSnapshotDirs()
+	if err != nil {
+		return nil, err
+	}
+
+	var migrated []string
+	for _, dir := range snapshotDirs {
+		err := filepath.WalkDir(dir, func(oldPath string, d os.DirEntry, err error) error {
+			if err != nil || d.IsDir() {
+				return err
+			}
+			base := strings.TrimSuffix(oldPath, f.extension)
+			if base == oldPath {
+				base = strings.TrimSuffix(oldPath, DefaultExtension)
+			}
+			newPath := base + f.extension
+			if base == oldPath || (newPath == oldPath && f.header == HeaderYAML) {
+				return nil
+			}
+
+			data, err := os.ReadFile(oldPath)
+			if err != nil {
+				return err
+			}
+			raw := normalizeHeader(string(data))
+			if !strings.HasPrefix(raw, "---\n") {
+				return nil
+			}
+			if newPath != oldPath {
+				if _, err := os.Stat(newPath); err == nil {
+					return nil
+				}
+			}
+			snap, err := Deserialize(raw)
+			if err != nil {
+				return nil
+			}
+			if err := readContent(snap, oldPath); err != nil {
+				return err
+			}
+
+			if f.header == HeaderNone {
+				snap.External = false
+			}
+			if err := removeContent(oldPath); err != nil {
+				return err
+			}
+			if err := writeContent(snap, newPath); err != nil {
+				return err
+			}
+			if err := os.WriteFile(newPath, []byte(snap.EncodeFile(newPath)), 0644); err != nil {
+				return err
+			}
+			if newPath != oldPath {
+				if err := os.Remove(oldPath); err != nil {
+					return err
+				}
+				if history := HistoryPath(oldPath); history != "" {
+					if err := os.Rename(history, HistoryPath(newPath)); err != nil && !os.IsNotExist(err) {
+						return err
+					}
+				}
+			}
+			migrated = append(migrated, newPath)
+			return nil
+		})
+		if err != nil {
+			return migrated, err
+		}
+	}
+	return migrated, nil
+}
@@ -0,0 +1,57 @@
+package files
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Commit describes a git commit that touched a snapshot file.
+type Commit struct {
+	Hash    string
+	Author  string
+	Date    time.Time
+	Subject string
+}
+
+// String formats the commit for display, e.g.
+// "Jane Doe, 2024-05-01 — Update golden output (1a2b3c4)".
+func (c *Commit) String() string {
+	return fmt.Sprintf("%s, %s — %s (%s)", c.Author, c.Date.Format("2006-01-02"), c.Subject, c.Hash)
+}
+
+// LastCommit returns the most recent git commit that touched the file at
+// path. It returns nil without an error when the file has never been
+// committed, and an error when git is unavailable or path is not inside a git
+// repository.
+func LastCommit(path string) (*Commit, error) {
+	cmd := exec.Command("git", "log", "-1", "--format=%h%x00%an%x00%aI%x00%s", "--", filepath.Base(path))
+	cmd.Dir = filepath.Dir(path)
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("git log %s: %w: %s", path, err, strings.TrimSpace(stderr.String()))
+	}
+
+	fields := strings.SplitN(strings.TrimSpace(string(out)), "\x00", 4)
+	if len(fields) != 4 {
+		return nil, nil
+	}
+
+	date, err := time.Parse(time.RFC3339, fields[2])
+	if err != nil {
+		return nil, fmt.Errorf("git log %s: invalid date %q", path, fields[2])
+	}
+
+	return &Commit{
+		Hash:    fields[0],
+		Author:  fields[1],
+		Date:    date,
+		Subject: fields[3],
+	}, nil
+}
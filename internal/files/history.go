@@ -0,0 +1,173 @@
+package files
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// HistoryLimit is the number of accepted versions kept per snapshot.
+const HistoryLimit = 10
+
+// HistoryDir is where the accepted versions of snapshots are kept, relative
+// to the project root (or the go.work directory in a workspace), so that
+// they do not grow the committed __snapshots__ directories.
+const HistoryDir = ".shutter/history"
+
+// HistoryEntry is one accepted version of a snapshot.
+type HistoryEntry struct {
+	Version    int       `json:"version"`
+	AcceptedAt time.Time `json:"accepted_at"`
+	Content    string    `json:"content"`
+}
+
+// HistoryPath returns the history file of an accepted snapshot: its path
+// relative to the project root, under HistoryDir, with a .history suffix.
+// Each line of the file is a JSON-encoded HistoryEntry, oldest first.
+// Snapshots outside the project have no history, and HistoryPath returns "".
+func HistoryPath(acceptedPath string) string {
+	root, err := workspaceRoot()
+	if err != nil {
+		return ""
+	}
+	absPath, err := filepath.Abs(acceptedPath)
+	if err != nil {
+		return ""
+	}
+	rel, err := filepath.Rel(root, absPath)
+	if err != nil || !filepath.IsLocal(rel) {
+		return ""
+	}
+	return filepath.Join(root, HistoryDir, rel+".history")
+}
+
+// ReadHistory returns the recorded versions of the accepted snapshot at
+// acceptedPath, oldest first. A snapshot without history has no entries.
+func ReadHistory(acceptedPath string) ([]HistoryEntry, error) {
+	path := HistoryPath(acceptedPath)
+	if path == "" {
+		return nil, nil
+	}
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var entries []HistoryEntry
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 64*1024*1024)
+	for scanner.Scan() {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var entry HistoryEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return nil, fmt.Errorf("invalid history file %s: %w", path, err)
+		}
+		entries = append(entries, entry)
+	}
+
+	return entries, scanner.Err()
+}
+
+// recordHistory appends content as the newest version of the accepted
+// snapshot at acceptedPath, keeping at most HistoryLimit versions. When the
+// snapshot predates its history, the currently accepted content is recorded
+// first so it is not lost.
+func recordHistory(acceptedPath, content string) error {
+	path := HistoryPath(acceptedPath)
+	if path == "" {
+		return nil
+	}
+	entries, err := ReadHistory(acceptedPath)
+	if err != nil {
+		return err
+	}
+
+	if len(entries) == 0 {
+		if previous, err := ReadSnapshotFromPath(acceptedPath); err == nil {
+			entries = append(entries, HistoryEntry{Version: 1, Content: previous.Content})
+		}
+	}
+
+	if len(entries) > 0 && entries[len(entries)-1].Content == content {
+		return nil
+	}
+
+	next := 1
+	if len(entries) > 0 {
+		next = entries[len(entries)-1].Version + 1
+	}
+	entries = append(entries, HistoryEntry{
+		Version:    next,
+		AcceptedAt: time.Now().UTC(),
+		Content:    content,
+	})
+	if len(entries) > HistoryLimit {
+		entries = entries[len(entries)-HistoryLimit:]
+	}
+
+	var sb strings.Builder
+	for _, entry := range entries {
+		line, err := json.Marshal(entry)
+		if err != nil {
+			return err
+		}
+		sb.Write(line)
+		sb.WriteByte('\n')
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, []byte(sb.String()), 0644)
+}
+
+// FindAccepted resolves name to the path of an accepted snapshot in the
+// project. name is either a snapshot key relative to its __snapshots__
+// directory (TestUsers/admin_case) or a path to the .snap file. A key found
+// in several packages is an error.
+func FindAccepted(name string) (string, error) {
+	if strings.HasSuffix(name, StateAccepted.Extension()) {
+		if _, err := os.Stat(name); err != nil {
+			return "", err
+		}
+		return filepath.Abs(name)
+	}
+
+	snapshotDirs, err := projectSnapshotDirs()
+	if err != nil {
+		return "", err
+	}
+
+	var matches []string
+	for _, dir := range snapshotDirs {
+		candidate := filepath.Join(dir, filepath.FromSlash(name)+StateAccepted.Extension())
+		if _, err := os.Stat(candidate); err == nil {
+			matches = append(matches, candidate)
+		}
+	}
+
+	switch len(matches) {
+	case 0:
+		return "", fmt.Errorf("no accepted snapshot named %q", name)
+	case 1:
+		return matches[0], nil
+	default:
+		var paths []string
+		for _, match := range matches {
+			paths = append(paths, DisplayPath(match))
+		}
+		sort.Strings(paths)
+		return "", fmt.Errorf("%q matches snapshots in several packages, use a path instead: %s", name, strings.Join(paths, ", "))
+	}
+}
@@ -0,0 +1,54 @@
+package files
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// parseLineRanges parses line numbers and ranges such as "12,40-42" into
+// the set of lines they cover.
+func parseLineRanges(spec string) (map[int]bool, error) {
+	lines := make(map[int]bool)
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		from, to, isRange := strings.Cut(part, "-")
+		first, err := strconv.Atoi(strings.TrimSpace(from))
+		last := first
+		if err == nil && isRange {
+			last, err = strconv.Atoi(strings.TrimSpace(to))
+		}
+		if err != nil || first < 1 || last < first {
+			return nil, fmt.Errorf("invalid ignore_lines %q: bad line or range %q", spec, part)
+		}
+		for line := first; line <= last; line++ {
+			lines[line] = true
+		}
+	}
+	return lines, nil
+}
+
+// SameIgnoringLines reports whether content equals the content of the
+// accepted snapshot s apart from the lines listed in its IgnoreLines. Both
+// must have the same number of lines. It returns an error if IgnoreLines is
+// malformed.
+func (s *Snapshot) SameIgnoringLines(content string) (bool, error) {
+	if s.IgnoreLines == "" {
+		return false, nil
+	}
+	ignored, err := parseLineRanges(s.IgnoreLines)
+	if err != nil {
+		return false, err
+	}
+	accepted := strings.Split(s.Content, "\n")
+	lines := strings.Split(content, "\n")
+	if len(accepted) != len(lines) {
+		return false, nil
+	}
+	for i := range lines {
+		if !ignored[i+1] && lines[i] != accepted[i] {
+			return false, nil
+		}
+	}
+	return true, nil
+}
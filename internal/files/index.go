@@ -0,0 +1,294 @@
+package files
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// IndexFile caches the directory tree searched for snapshots, relative to the
+// project root (or the go.work directory in a workspace). It is shared by
+// every tool that lists snapshots, so in large trees only directories that
+// changed since the last scan are read again.
+const IndexFile = ".shutter/index.json"
+
+// indexVersion is bumped whenever the index format changes; indexes written
+// with another version are ignored.
+const indexVersion = 1
+
+type snapshotIndex struct {
+	Version int                   `json:"version"`
+	Exclude []string              `json:"exclude,omitempty"` // Exclude globs the directories were read with
+	Dirs    map[string]indexedDir `json:"dirs"`
+}
+
+// racyWindow is how recently a directory may have changed for its
+// modification time to be untrustworthy, covering coarse file system
+// timestamps.
+const racyWindow = 2 * time.Second
+
+// indexedDir records the subdirectories of a directory as of its
+// modification time. Adding, removing, or renaming an entry updates the
+// time, so a directory whose time is unchanged still has the same
+// subdirectories and does not need to be read again.
+type indexedDir struct {
+	ModTime int64    `json:"mod_time"`
+	Subdirs []string `json:"subdirs,omitempty"`
+}
+
+// skipDir reports whether snapshot discovery ignores the directory name:
+// hidden directories and dependency trees.
+func skipDir(name string) bool {
+	return strings.HasPrefix(name, ".") || name == "node_modules" || name == "vendor"
+}
+
+// findAllSnapshotDirs returns the __snapshots__ directories under root. It
+// stats every directory but only reads the ones that changed since the
+// index was written. The index is best effort: when it cannot be read or
+// written, every directory is read.
+func findAllSnapshotDirs(root string) ([]string, error) {
+	indexPath := ""
+	if base, err := workspaceRoot(); err == nil {
+		indexPath = filepath.Join(base, filepath.FromSlash(IndexFile))
+	}
+	exclude := excludePatterns()
+	index := readIndex(indexPath)
+	if !slices.Equal(index.Exclude, exclude) {
+		// Subdirectories were recorded under different exclusions.
+		index = readIndex("")
+		index.Exclude = exclude
+	}
+
+	scan := &indexScan{
+		root:    root,
+		exclude: exclude,
+		cached:  index.Dirs,
+		seen:    map[string]indexedDir{},
+	}
+	if err := scan.run(); err != nil {
+		return nil, err
+	}
+
+	if indexPath != "" && scan.changed(root) {
+		for dir := range index.Dirs {
+			if within(dir, root) {
+				delete(index.Dirs, dir)
+			}
+		}
+		for dir, entry := range scan.seen {
+			index.Dirs[dir] = entry
+		}
+		_ = writeIndex(indexPath, index)
+	}
+	return scan.snapshotDirs, nil
+}
+
+// scanWorkers bounds how many directories are read at once. Discovery is
+// bound by file system latency rather than CPU, so it runs more readers than
+// there are processors.
+func scanWorkers() int {
+	return 4 * runtime.GOMAXPROCS(0)
+}
+
+// indexScan walks a tree with a fixed set of workers, reusing the
+// subdirectories recorded for directories that have not changed.
+type indexScan struct {
+	root    string
+	exclude []string
+	cached  map[string]indexedDir // Read-only during the scan
+
+	mu           sync.Mutex // Guards the fields below
+	more         sync.Cond  // Signaled when queue grows or pending drops to zero
+	queue        []string   // Directories waiting to be visited
+	pending      int        // Directories queued or being visited
+	seen         map[string]indexedDir
+	snapshotDirs []string
+	reads        int
+	err          error
+}
+
+// run scans the tree under root and returns the first error encountered.
+// snapshotDirs is sorted in the order filepath.Walk would visit them.
+func (s *indexScan) run() error {
+	s.more.L = &s.mu
+	s.queue = []string{s.root}
+	s.pending = 1
+
+	var wg sync.WaitGroup
+	for range scanWorkers() {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.work()
+		}()
+	}
+	wg.Wait()
+
+	slices.SortFunc(s.snapshotDirs, compareWalkOrder)
+	return s.err
+}
+
+// work visits queued directories until every directory has been visited.
+func (s *indexScan) work() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for {
+		for len(s.queue) == 0 && s.pending > 0 {
+			s.more.Wait()
+		}
+		if s.pending == 0 {
+			return
+		}
+		dir := s.queue[len(s.queue)-1]
+		s.queue = s.queue[:len(s.queue)-1]
+
+		s.mu.Unlock()
+		entry, isSnapshots, read, err := s.read(dir, dir == s.root)
+		s.mu.Lock()
+
+		s.visited(dir, entry, isSnapshots, read, err)
+		s.pending--
+		if s.pending == 0 {
+			s.more.Broadcast()
+		}
+	}
+}
+
+// visited records dir and queues its subdirectories. __snapshots__
+// directories are not descended into. s.mu must be held.
+func (s *indexScan) visited(dir string, entry indexedDir, isSnapshots, read bool, err error) {
+	switch {
+	case err != nil:
+		if s.err == nil {
+			s.err = err
+		}
+		return
+	case s.err != nil:
+		return
+	case isSnapshots:
+		s.snapshotDirs = append(s.snapshotDirs, dir)
+		return
+	}
+	if read {
+		s.reads++
+	}
+	s.seen[dir] = entry
+
+	for _, sub := range entry.Subdirs {
+		s.queue = append(s.queue, filepath.Join(dir, sub))
+		s.pending++
+		s.more.Signal()
+	}
+}
+
+// read returns the index entry for dir, reading the directory only if it
+// changed since the cached entry was recorded.
+func (s *indexScan) read(dir string, isRoot bool) (entry indexedDir, isSnapshots, read bool, err error) {
+	info, err := os.Stat(dir)
+	if err != nil {
+		if !isRoot && os.IsNotExist(err) {
+			// Removed since its parent was read.
+			return indexedDir{}, false, false, nil
+		}
+		return indexedDir{}, false, false, err
+	}
+
+	if info.Name() == "__snapshots__" {
+		return indexedDir{}, true, false, nil
+	}
+
+	entry, ok := s.cached[dir]
+	if ok && entry.ModTime == info.ModTime().UnixNano() {
+		return entry, false, false, nil
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return indexedDir{}, false, false, err
+	}
+
+	entry = indexedDir{ModTime: info.ModTime().UnixNano()}
+	if time.Since(info.ModTime()) < racyWindow {
+		// Another change within the file system's timestamp
+		// granularity would go unnoticed, so read it again next time.
+		entry.ModTime = 0
+	}
+	for _, e := range entries {
+		if e.IsDir() && !s.skip(filepath.Join(dir, e.Name())) {
+			entry.Subdirs = append(entry.Subdirs, e.Name())
+		}
+	}
+	return entry, false, true, nil
+}
+
+// compareWalkOrder orders paths element by element, as filepath.Walk visits
+// them, so "a/b" sorts before "a-b".
+func compareWalkOrder(a, b string) int {
+	return slices.Compare(strings.Split(a, string(filepath.Separator)), strings.Split(b, string(filepath.Separator)))
+}
+
+// skip reports whether dir is left out of discovery, either by skipDir or
+// by an exclude glob.
+func (s *indexScan) skip(dir string) bool {
+	if skipDir(filepath.Base(dir)) {
+		return true
+	}
+	rel, err := filepath.Rel(s.root, dir)
+	return err == nil && excluded(s.exclude, filepath.ToSlash(rel))
+}
+
+// changed reports whether the scan read any directory or dropped one that
+// the index still has, i.e. whether the index needs to be rewritten.
+func (s *indexScan) changed(root string) bool {
+	if s.reads > 0 {
+		return true
+	}
+	for dir := range s.cached {
+		if within(dir, root) {
+			if _, ok := s.seen[dir]; !ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// within reports whether path is dir or lies beneath it.
+func within(path, dir string) bool {
+	return path == dir || strings.HasPrefix(path, dir+string(filepath.Separator))
+}
+
+func readIndex(path string) snapshotIndex {
+	empty := snapshotIndex{Version: indexVersion, Dirs: map[string]indexedDir{}}
+	if path == "" {
+		return empty
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return empty
+	}
+	var index snapshotIndex
+	if err := json.Unmarshal(data, &index); err != nil || index.Version != indexVersion || index.Dirs == nil {
+		return empty
+	}
+	return index
+}
+
+// writeIndex replaces the index file atomically, so concurrent readers never
+// see a partial index.
+func writeIndex(path string, index snapshotIndex) error {
+	data, err := json.Marshal(index)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return writeFileAtomic(path, data)
+}
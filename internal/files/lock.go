@@ -0,0 +1,126 @@
+package files
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// ReviewLockFile is held while snapshots are reviewed, relative to the
+// project root (or the go.work directory in a workspace).
+const ReviewLockFile = ".shutter/review.lock"
+
+// ReviewLockedError is returned by LockReview when another review session
+// holds the lock.
+type ReviewLockedError struct {
+	Path  string    // The lock file
+	PID   int       // The process holding the lock
+	Host  string    // The host the process runs on
+	Since time.Time // When the lock was taken
+}
+
+func (e *ReviewLockedError) Error() string {
+	return fmt.Sprintf(
+		"another review session is in progress (pid %d on %s, started %s ago); wait for it to finish, or delete %s if it has ended",
+		e.PID, e.Host, time.Since(e.Since).Round(time.Second), DisplayPath(e.Path),
+	)
+}
+
+// LockReview takes the review lock of the project, so that two sessions do
+// not accept and reject the same snapshots at once. It fails with a
+// *ReviewLockedError if another session holds the lock. A lock left behind
+// by a session on this host that has since exited is taken over. Call
+// unlock when the session ends.
+func LockReview() (unlock func(), err error) {
+	root, err := workspaceRoot()
+	if err != nil {
+		return nil, err
+	}
+	path := filepath.Join(root, ReviewLockFile)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+
+	host, _ := os.Hostname()
+	for {
+		f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+		if err == nil {
+			_, err = fmt.Fprintf(f, "pid: %d\nhost: %s\nsince: %s\n", os.Getpid(), host, time.Now().UTC().Format(time.RFC3339))
+			if closeErr := f.Close(); err == nil {
+				err = closeErr
+			}
+			if err != nil {
+				_ = os.Remove(path)
+				return nil, err
+			}
+			return func() {
+				_ = os.Remove(path)
+				// Leave no empty .shutter directory behind.
+				_ = os.Remove(filepath.Dir(path))
+			}, nil
+		}
+		if !errors.Is(err, os.ErrExist) {
+			return nil, err
+		}
+
+		held, err := readReviewLock(path)
+		if errors.Is(err, os.ErrNotExist) {
+			// Released in the meantime.
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if held.Host != host || processAlive(held.PID) {
+			return nil, held
+		}
+		if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return nil, err
+		}
+	}
+}
+
+// readReviewLock reads the holder of the review lock at path.
+func readReviewLock(path string) (*ReviewLockedError, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	held := &ReviewLockedError{Path: path}
+	for _, line := range strings.Split(string(data), "\n") {
+		key, value, _ := strings.Cut(line, ": ")
+		switch key {
+		case "pid":
+			held.PID, _ = strconv.Atoi(value)
+		case "host":
+			held.Host = value
+		case "since":
+			held.Since, _ = time.Parse(time.RFC3339, value)
+		}
+	}
+	return held, nil
+}
+
+// processAlive reports whether the process with the given pid is running
+// on this host.
+func processAlive(pid int) bool {
+	if pid <= 0 {
+		return false
+	}
+	p, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	if runtime.GOOS == "windows" {
+		// FindProcess fails on Windows if there is no such process.
+		return true
+	}
+	err = p.Signal(syscall.Signal(0))
+	return err == nil || errors.Is(err, syscall.EPERM)
+}
@@ -0,0 +1,227 @@
+package files
+
+import (
+	"bufio"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// ManifestName is the file in each __snapshots__ directory that lists the
+// snapshots compared by the last test run that recorded a manifest, one
+// snapshot key per line.
+const ManifestName = ".manifest"
+
+// manifest tracks which manifests this process has started. The first
+// snapshot recorded by a test binary replaces the manifest left by the
+// previous run; later ones append to it.
+var manifest struct {
+	sync.Mutex
+	started map[string]bool
+}
+
+// RecordInManifest adds the snapshot compared against to the manifest of
+// the __snapshots__ directory: accepted, when one was read, which may be a
+// legacy flat-layout file, and otherwise the file snap would be accepted as.
+func RecordInManifest(snap, accepted *Snapshot) error {
+	snapshotDir, key, err := comparedKey(snap, accepted)
+	if err != nil {
+		return err
+	}
+
+	manifest.Lock()
+	defer manifest.Unlock()
+
+	if err := os.MkdirAll(snapshotDir, 0755); err != nil {
+		return err
+	}
+	path := filepath.Join(snapshotDir, ManifestName)
+	flags := os.O_WRONLY | os.O_CREATE | os.O_APPEND
+	if !manifest.started[path] {
+		flags |= os.O_TRUNC
+	}
+	f, err := os.OpenFile(path, flags, 0644)
+	if err != nil {
+		return err
+	}
+	if manifest.started == nil {
+		manifest.started = make(map[string]bool)
+	}
+	manifest.started[path] = true
+
+	if _, err := f.WriteString(key + "\n"); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// comparedKey returns the absolute __snapshots__ directory of snap and the
+// key of the snapshot compared against in it, as recorded in manifests.
+func comparedKey(snap, accepted *Snapshot) (snapshotDir, key string, err error) {
+	if snapshotDir, err = filepath.Abs(snapshotDirName); err != nil {
+		return "", "", err
+	}
+
+	key = snap.Key()
+	if accepted != nil && accepted.Path != "" {
+		acceptedPath, err := filepath.Abs(accepted.Path)
+		if err != nil {
+			return "", "", err
+		}
+		rel, err := filepath.Rel(snapshotDir, acceptedPath)
+		if err != nil {
+			return "", "", err
+		}
+		// An accepted snapshot read from Bazel runfiles may lie elsewhere.
+		if filepath.IsLocal(rel) {
+			key = strings.TrimSuffix(filepath.ToSlash(rel), StateAccepted.Extension())
+		}
+	}
+	return snapshotDir, key, nil
+}
+
+// compared holds the keys of the snapshots compared in this process while
+// tracking is enabled with TrackCompared.
+var compared struct {
+	sync.Mutex
+	enabled bool
+	keys    map[string]bool
+}
+
+// TrackCompared makes RecordCompared remember the snapshots compared from
+// now on, for UncomparedSnapshots.
+func TrackCompared() {
+	compared.Lock()
+	defer compared.Unlock()
+	compared.enabled = true
+}
+
+// RecordCompared remembers the snapshot compared against, as
+// RecordInManifest does, if TrackCompared was called.
+func RecordCompared(snap, accepted *Snapshot) error {
+	compared.Lock()
+	defer compared.Unlock()
+	if !compared.enabled {
+		return nil
+	}
+
+	snapshotDir, key, err := comparedKey(snap, accepted)
+	if err != nil {
+		return err
+	}
+	if compared.keys == nil {
+		compared.keys = make(map[string]bool)
+	}
+	compared.keys[filepath.Join(snapshotDir, filepath.FromSlash(key))] = true
+	return nil
+}
+
+// UncomparedSnapshots returns the accepted snapshots of the current
+// package that were not compared since TrackCompared was called.
+// Benchmark snapshots and stored fuzz inputs, which an ordinary test run
+// does not take, are left out, as are the variants of compared titles.
+func UncomparedSnapshots() ([]string, error) {
+	snapshotDir, err := filepath.Abs(snapshotDirName)
+	if err != nil {
+		return nil, err
+	}
+
+	compared.Lock()
+	defer compared.Unlock()
+
+	// Other variants of a compared title are in use on other platforms or
+	// backends.
+	titles := make(map[string]bool)
+	for key := range compared.keys {
+		titles[titleKey(filepath.ToSlash(key))] = true
+	}
+
+	var paths []string
+	err = filepath.Walk(snapshotDir, func(path string, info os.FileInfo, err error) error {
+		if os.IsNotExist(err) && path == snapshotDir {
+			return filepath.SkipDir
+		}
+		if err != nil {
+			return err
+		}
+		if info.IsDir() || !strings.HasSuffix(info.Name(), StateAccepted.Extension()) {
+			return nil
+		}
+		key := strings.TrimSuffix(path, StateAccepted.Extension())
+		if compared.keys[key] || titles[titleKey(filepath.ToSlash(key))] {
+			return nil
+		}
+		if snap, err := ReadSnapshotFromPath(path); err == nil && manifestExempt(snap.Test) {
+			return nil
+		}
+		paths = append(paths, path)
+		return nil
+	})
+	return paths, err
+}
+
+// readManifest returns the snapshot keys listed in the manifest of
+// snapshotDir, and false if the directory has no manifest.
+func readManifest(snapshotDir string) (map[string]bool, bool, error) {
+	f, err := os.Open(filepath.Join(snapshotDir, ManifestName))
+	if os.IsNotExist(err) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	defer f.Close()
+
+	keys := make(map[string]bool)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if key := strings.TrimSpace(scanner.Text()); key != "" {
+			keys[key] = true
+		}
+	}
+	return keys, true, scanner.Err()
+}
+
+// titleKey returns key without the variant in its file name, if any.
+func titleKey(key string) string {
+	dir, file := path.Split(key)
+	file, _, _ = strings.Cut(file, variantSeparator)
+	return dir + file
+}
+
+// manifestUse describes what the manifest of a __snapshots__ directory says
+// is still in use.
+type manifestUse struct {
+	keys   map[string]bool // The snapshots compared
+	titles map[string]bool // Their keys without variants
+	tests  map[string]bool // The directories of the tests that compared any
+}
+
+func newManifestUse(keys map[string]bool) manifestUse {
+	use := manifestUse{keys: keys, titles: map[string]bool{}, tests: map[string]bool{}}
+	for key := range keys {
+		use.titles[titleKey(key)] = true
+		use.tests[path.Dir(key)] = true
+	}
+	return use
+}
+
+// unused reports whether the accepted snapshot with key is out of use. A
+// snapshot is only out of use if its test compared other snapshots in the
+// run, so the snapshots of a skipped test are kept, and if no variant of its
+// title was compared, so the variants of other platforms or backends are
+// kept.
+func (u manifestUse) unused(key string) bool {
+	return !u.keys[key] && !u.titles[titleKey(key)] && u.tests[path.Dir(key)]
+}
+
+// manifestExempt reports whether a snapshot recorded by testName is kept
+// regardless of the manifest, because an ordinary test run does not take
+// it: benchmark snapshots, and fuzz inputs stored while fuzzing.
+func manifestExempt(testName string) bool {
+	return strings.HasPrefix(testName, "Benchmark") ||
+		(strings.HasPrefix(testName, "Fuzz") && strings.Contains(testName, "/fuzz/"))
+}
@@ -0,0 +1,203 @@
+package files
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// codeownersFiles are the places GitHub and GitLab look for a CODEOWNERS
+// file, relative to the repository root, in the order they are tried.
+var codeownersFiles = []string{".github/CODEOWNERS", "CODEOWNERS", "docs/CODEOWNERS", ".gitlab/CODEOWNERS"}
+
+// OwnerRule assigns owners to the files matching a CODEOWNERS pattern.
+type OwnerRule struct {
+	Pattern string
+	Owners  []string
+
+	re *regexp.Regexp
+}
+
+// Owners maps snapshot files to the owners responsible for them.
+type Owners struct {
+	root  string
+	rules []OwnerRule
+}
+
+// LoadOwners reads the owner rules of the project: those of its CODEOWNERS
+// file, followed by the owners settings of ConfigFile. As in CODEOWNERS, the
+// last rule matching a file decides its owners.
+func LoadOwners() (*Owners, error) {
+	root, err := workspaceRoot()
+	if err != nil {
+		return nil, err
+	}
+
+	owners := &Owners{root: root}
+	for _, name := range codeownersFiles {
+		rules, err := readCodeowners(filepath.Join(root, filepath.FromSlash(name)))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		owners.rules = rules
+		break
+	}
+
+	cfg, err := LoadConfig()
+	if err != nil {
+		return nil, err
+	}
+	owners.rules = append(owners.rules, cfg.Owners...)
+	return owners, nil
+}
+
+// readCodeowners parses the CODEOWNERS file at path. Lines naming a pattern
+// without owners are kept, as they remove the owners of earlier rules.
+func readCodeowners(path string) ([]OwnerRule, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var rules []OwnerRule
+	scanner := bufio.NewScanner(f)
+	for n := 1; scanner.Scan(); n++ {
+		line := scanner.Text()
+		if i := strings.Index(line, " #"); i >= 0 {
+			line = line[:i]
+		}
+		line = strings.TrimSpace(line)
+		// GitLab section headers such as [Billing] are not rules.
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, "[") || strings.HasPrefix(line, "^[") {
+			continue
+		}
+		rule, err := parseOwnerRule(line)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", DisplayPath(path), n, err)
+		}
+		rules = append(rules, rule)
+	}
+	return rules, scanner.Err()
+}
+
+// parseOwnerRule parses a CODEOWNERS line: a pattern followed by owners.
+func parseOwnerRule(line string) (OwnerRule, error) {
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return OwnerRule{}, fmt.Errorf("expected a pattern followed by owners")
+	}
+	re, err := ownerPattern(fields[0])
+	if err != nil {
+		return OwnerRule{}, fmt.Errorf("invalid owners pattern %q: %w", fields[0], err)
+	}
+	return OwnerRule{Pattern: fields[0], Owners: fields[1:], re: re}, nil
+}
+
+// ownerPattern compiles a CODEOWNERS pattern, which follows .gitignore
+// rules: a pattern with a leading or inner "/" is relative to the root, one
+// without matches at any depth, "*" and "?" stay within a path segment,
+// "**" spans segments, and a pattern naming a directory covers everything
+// inside it.
+func ownerPattern(pattern string) (*regexp.Regexp, error) {
+	anchored := strings.Contains(strings.TrimSuffix(pattern, "/"), "/")
+	pattern = strings.Trim(pattern, "/")
+
+	var sb strings.Builder
+	sb.WriteString("^")
+	if !anchored {
+		sb.WriteString("(?:.*/)?")
+	}
+	for i := 0; i < len(pattern); i++ {
+		switch c := pattern[i]; {
+		case strings.HasPrefix(pattern[i:], "**/"):
+			sb.WriteString("(?:.*/)?")
+			i += 2
+		case strings.HasPrefix(pattern[i:], "**"):
+			sb.WriteString(".*")
+			i++
+		case c == '*':
+			sb.WriteString("[^/]*")
+		case c == '?':
+			sb.WriteString("[^/]")
+		case c == '\\' && i+1 < len(pattern):
+			i++
+			sb.WriteString(regexp.QuoteMeta(pattern[i : i+1]))
+		default:
+			sb.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	sb.WriteString("(?:/.*)?$")
+	return regexp.Compile(sb.String())
+}
+
+// Of returns the owners of the file at path, or nil if it has none.
+func (o *Owners) Of(path string) []string {
+	if o == nil {
+		return nil
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil
+	}
+	rel, err := filepath.Rel(o.root, abs)
+	if err != nil {
+		return nil
+	}
+	rel = filepath.ToSlash(rel)
+
+	for i := len(o.rules) - 1; i >= 0; i-- {
+		rule := o.rules[i]
+		if rule.re == nil {
+			rule.re, err = ownerPattern(rule.Pattern)
+			if err != nil {
+				continue
+			}
+		}
+		if rule.re.MatchString(rel) {
+			return rule.Owners
+		}
+	}
+	return nil
+}
+
+// OwnedBy reports whether any of the owners of the file at path is one of
+// identities. Owners and identities are compared without regard to case,
+// as GitHub handles and emails are.
+func (o *Owners) OwnedBy(path string, identities []string) bool {
+	for _, owner := range o.Of(path) {
+		for _, identity := range identities {
+			if strings.EqualFold(owner, identity) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// MyTeams returns the owners the current user counts as: the teams setting
+// of ConfigFile and the git user.email of the project.
+func MyTeams() ([]string, error) {
+	cfg, err := LoadConfig()
+	if err != nil {
+		return nil, err
+	}
+	teams := cfg.Teams
+	if root, err := workspaceRoot(); err == nil {
+		if email := gitConfig(root, "user.email"); email != "" {
+			teams = append(teams, email)
+		}
+	}
+	return teams, nil
+}
+
+// isListSeparator separates the items of a list setting.
+func isListSeparator(r rune) bool {
+	return r == ',' || r == ' ' || r == '\t'
+}
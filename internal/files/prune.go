@@ -0,0 +1,199 @@
+package files
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// PruneCriteria selects accepted snapshots for pruning. A snapshot is
+// selected when it meets every criterion that is set.
+type PruneCriteria struct {
+	OlderThan  time.Duration // Last accepted longer ago than this
+	LargerThan int64         // Larger than this many bytes, including external content
+	Orphaned   bool          // Recorded by a test that no longer exists
+	Untouched  bool          // Not compared by the run that wrote the manifest
+	Now        time.Time     // Reference time for OlderThan; defaults to time.Now
+}
+
+func (c PruneCriteria) empty() bool {
+	return c.OlderThan <= 0 && c.LargerThan <= 0 && !c.Orphaned && !c.Untouched
+}
+
+// PruneCandidate is an accepted snapshot selected by PruneCriteria.
+type PruneCandidate struct {
+	Path       string
+	Test       string
+	Size       int64
+	AcceptedAt time.Time
+	Orphaned   bool
+	Untouched  bool
+}
+
+// FindPruneCandidates returns the accepted snapshots in the project that meet
+// criteria. At least one criterion must be set.
+func FindPruneCandidates(criteria PruneCriteria) ([]PruneCandidate, error) {
+	if criteria.empty() {
+		return nil, fmt.Errorf("no prune criteria given")
+	}
+	if criteria.Now.IsZero() {
+		criteria.Now = time.Now()
+	}
+
+	snapshotDirs, err := projectSnapshotDirs()
+	if err != nil {
+		return nil, err
+	}
+
+	tests := newTestIndex()
+	var candidates []PruneCandidate
+	for _, dir := range snapshotDirs {
+		var use manifestUse
+		if criteria.Untouched {
+			keys, ok, err := readManifest(dir)
+			if err != nil {
+				return nil, err
+			}
+			if !ok {
+				// Without a manifest nothing is known to be untouched.
+				continue
+			}
+			use = newManifestUse(keys)
+		}
+
+		walkErr := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if info.IsDir() || !strings.HasSuffix(info.Name(), StateAccepted.Extension()) {
+				return nil
+			}
+
+			candidate := PruneCandidate{Path: path, Size: info.Size()}
+			if content, err := os.Stat(ContentPath(path)); err == nil {
+				candidate.Size += content.Size()
+			}
+			if criteria.Untouched {
+				rel, err := filepath.Rel(dir, path)
+				if err != nil {
+					return err
+				}
+				if !use.unused(strings.TrimSuffix(filepath.ToSlash(rel), StateAccepted.Extension())) {
+					return nil
+				}
+				candidate.Untouched = true
+			}
+			if criteria.LargerThan > 0 && candidate.Size <= criteria.LargerThan {
+				return nil
+			}
+
+			snap, err := ReadSnapshotFromPath(path)
+			if err != nil {
+				return nil
+			}
+			candidate.Test = snap.Test
+			if criteria.Untouched && manifestExempt(snap.Test) {
+				return nil
+			}
+
+			if criteria.Orphaned {
+				candidate.Orphaned = snap.Test != "" && !tests.defines(filepath.Dir(dir), snap.Test)
+				if !candidate.Orphaned {
+					return nil
+				}
+			}
+
+			candidate.AcceptedAt = acceptedAt(path, info.ModTime())
+			if criteria.OlderThan > 0 && criteria.Now.Sub(candidate.AcceptedAt) <= criteria.OlderThan {
+				return nil
+			}
+
+			candidates = append(candidates, candidate)
+			return nil
+		})
+		if walkErr != nil {
+			return nil, walkErr
+		}
+	}
+
+	return candidates, nil
+}
+
+// PruneSnapshot deletes an accepted snapshot together with its history,
+// recorded runs, and externally stored content.
+func PruneSnapshot(path string) error {
+	if err := os.Remove(path); err != nil {
+		return err
+	}
+	for _, sidecar := range []string{HistoryPath(path), FlakesPath(path), ContentPath(path)} {
+		if sidecar == "" {
+			continue
+		}
+		if err := os.Remove(sidecar); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// AcceptedAt estimates when the accepted snapshot at path was last
+// accepted, like prune does; see acceptedAt.
+func AcceptedAt(path string) (time.Time, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return acceptedAt(path, info.ModTime()), nil
+}
+
+// acceptedAt estimates when the snapshot at path was last accepted: from its
+// history if recorded, else from the last git commit touching it, else from
+// the file's modification time.
+func acceptedAt(path string, modTime time.Time) time.Time {
+	if entries, err := ReadHistory(path); err == nil && len(entries) > 0 {
+		if last := entries[len(entries)-1].AcceptedAt; !last.IsZero() {
+			return last
+		}
+	}
+	if commit, err := LastCommit(path); err == nil && commit != nil {
+		return commit.Date
+	}
+	return modTime
+}
+
+// testFuncPattern matches top-level test, benchmark, fuzz and example
+// function declarations.
+var testFuncPattern = regexp.MustCompile(`(?m)^func\s+((?:Test|Benchmark|Fuzz|Example)\w*)\s*\(`)
+
+// testIndex caches the test functions declared in each package directory.
+type testIndex map[string]map[string]bool
+
+func newTestIndex() testIndex {
+	return testIndex{}
+}
+
+// defines reports whether the package in dir declares the top-level test of
+// testName. Subtest names ("TestUsers/admin") are matched by their parent.
+func (idx testIndex) defines(dir, testName string) bool {
+	funcs, ok := idx[dir]
+	if !ok {
+		funcs = map[string]bool{}
+		testFiles, _ := filepath.Glob(filepath.Join(dir, "*_test.go"))
+		for _, file := range testFiles {
+			data, err := os.ReadFile(file)
+			if err != nil {
+				continue
+			}
+			for _, m := range testFuncPattern.FindAllStringSubmatch(string(data), -1) {
+				funcs[m[1]] = true
+			}
+		}
+		idx[dir] = funcs
+	}
+
+	top, _, _ := strings.Cut(testName, "/")
+	return funcs[top]
+}
@@ -0,0 +1,61 @@
+package files
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// RawPath returns the file holding the raw content of a pending snapshot
+// (see Snapshot.Raw), next to the snapshot file at snapshotPath.
+func RawPath(snapshotPath string) string {
+	return snapshotPath + ".raw"
+}
+
+// OptionsFingerprint identifies the options applied to a snapshot, as listed
+// in Snapshot.Options, so raw content can be matched with the options it
+// was recorded under.
+func OptionsFingerprint(options []string) string {
+	sum := sha256.Sum256([]byte(strings.Join(options, "\n")))
+	return hex.EncodeToString(sum[:])[:12]
+}
+
+// writeRaw stores the raw content of snap in the raw file of the snapshot
+// file at snapshotPath, and removes a raw file left over from an earlier
+// version if snap has none.
+func writeRaw(snap *Snapshot, snapshotPath string) error {
+	if snap.RawFingerprint == "" {
+		return removeRaw(snapshotPath)
+	}
+	return os.WriteFile(RawPath(snapshotPath), []byte(snap.Raw), 0644)
+}
+
+// readRaw loads the raw content of a snapshot read from snapshotPath. A
+// missing raw file, as after a rejected snapshot is restored, leaves the
+// snapshot without raw content.
+func readRaw(snap *Snapshot, snapshotPath string) error {
+	if snap.RawFingerprint == "" {
+		return nil
+	}
+	data, err := os.ReadFile(RawPath(snapshotPath))
+	if os.IsNotExist(err) {
+		snap.RawFingerprint = ""
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read raw content of %s: %w", DisplayPath(snapshotPath), err)
+	}
+	snap.Raw = string(data)
+	return nil
+}
+
+// removeRaw deletes the raw file of the snapshot file at snapshotPath, if
+// there is one.
+func removeRaw(snapshotPath string) error {
+	if err := os.Remove(RawPath(snapshotPath)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,465 @@
+package files
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ptdewey/shutter/internal/secrets"
+)
+
+// Environment variables configuring the bucket of a remote setting.
+// Without the key variables, the AWS ones (AWS_ACCESS_KEY_ID,
+// AWS_SECRET_ACCESS_KEY and AWS_SESSION_TOKEN) are used. For Google Cloud
+// Storage they hold an HMAC key.
+const (
+	RemoteEndpointEnv  = "SHUTTER_REMOTE_ENDPOINT" // Such as http://localhost:9000 for MinIO
+	RemoteAccessKeyEnv = "SHUTTER_REMOTE_ACCESS_KEY"
+	RemoteSecretKeyEnv = "SHUTTER_REMOTE_SECRET_KEY"
+)
+
+// remoteTimeout bounds each request to the bucket.
+const remoteTimeout = 30 * time.Second
+
+// remote is a parsed remote setting: s3://bucket/prefix or
+// gs://bucket/prefix.
+type remote struct {
+	scheme string
+	bucket string
+	prefix string
+}
+
+func parseRemote(value string) (remote, error) {
+	u, err := url.Parse(value)
+	if err != nil || (u.Scheme != "s3" && u.Scheme != "gs") || u.Host == "" || u.RawQuery != "" {
+		return remote{}, fmt.Errorf("remote must be s3://bucket/prefix or gs://bucket/prefix")
+	}
+	return remote{scheme: u.Scheme, bucket: u.Host, prefix: strings.Trim(u.Path, "/")}, nil
+}
+
+// RemoteStorage keeps accepted snapshots in an S3 or Google Cloud Storage
+// bucket, so large baselines do not have to be committed. Pending snapshots
+// stay on disk, where tests write them and review finds them, so only
+// accepting them reaches the bucket.
+//
+// Each accepted snapshot is an object named after its module, package and
+// key, below the prefix of the remote setting, such as
+// prefix/github.com/acme/api/internal/render/TestLogo/png.snap. Its content
+// is always stored in the object itself. History, pruning and audits only
+// cover accepted snapshots on disk.
+type RemoteStorage struct {
+	FileStorage
+
+	remote remote
+	bucket *bucket
+
+	mu      sync.Mutex
+	objects map[string][]byte // Objects read or written by this process, by name
+}
+
+// NewRemoteStorage returns the RemoteStorage of a remote setting, such as
+// s3://acme-baselines/shutter. The bucket is not contacted until a snapshot
+// is read or accepted.
+func NewRemoteStorage(setting string) (*RemoteStorage, error) {
+	r, err := parseRemote(setting)
+	if err != nil {
+		return nil, err
+	}
+	return &RemoteStorage{remote: r, bucket: newBucket(r), objects: map[string][]byte{}}, nil
+}
+
+func (r *RemoteStorage) Read(info SnapshotInfo, state State) (*Snapshot, error) {
+	if state == StateNew {
+		return r.FileStorage.Read(info, state)
+	}
+	accepted := info.AcceptedPath()
+	data, err := r.get(accepted)
+	if err != nil {
+		return nil, err
+	}
+	snap, err := DecodeFile(accepted, string(data))
+	if err != nil {
+		return nil, err
+	}
+	snap.Path = accepted
+	return snap, nil
+}
+
+func (r *RemoteStorage) Write(snap *Snapshot, state State) error {
+	if state == StateNew {
+		return r.FileStorage.Write(snap, state)
+	}
+	accepted := filepath.Join(snapshotDirName, getSnapshotFileName(snap.Key(), state))
+	if err := r.put(accepted, snap); err != nil {
+		return err
+	}
+	snap.Path = accepted
+	return nil
+}
+
+func (r *RemoteStorage) Accept(info SnapshotInfo, force bool) error {
+	snap, err := ReadSnapshotFromPath(info.Path)
+	if err != nil {
+		return err
+	}
+	if !force {
+		if findings := secrets.Scan(snap.Content); len(findings) > 0 {
+			return &SecretsError{Path: info.Path, Findings: findings}
+		}
+	}
+	audit, err := prepareAudit(info)
+	if err != nil {
+		return err
+	}
+
+	if err := r.put(info.AcceptedPath(), snap); err != nil {
+		return err
+	}
+	if err := removeContent(info.Path); err != nil {
+		return err
+	}
+	if err := removeRaw(info.Path); err != nil {
+		return err
+	}
+	if err := os.Remove(info.Path); err != nil {
+		return err
+	}
+	return audit.record(AuditAccept)
+}
+
+// put uploads snap as the accepted snapshot at path, renewing its baseline
+// like accepting it on disk does.
+func (r *RemoteStorage) put(path string, snap *Snapshot) error {
+	accepted := *snap
+	accepted.Stale, accepted.Raw, accepted.RawFingerprint = "", "", ""
+	accepted.External = false
+	data := []byte(accepted.EncodeFile(path))
+
+	name, err := r.objectName(path)
+	if err != nil {
+		return err
+	}
+	if err := r.bucket.put(name, data); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	r.objects[name] = data
+	r.mu.Unlock()
+	return nil
+}
+
+// get downloads the accepted snapshot at path. One that is not in the
+// bucket is an error wrapping fs.ErrNotExist.
+func (r *RemoteStorage) get(path string) ([]byte, error) {
+	name, err := r.objectName(path)
+	if err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	data, ok := r.objects[name]
+	r.mu.Unlock()
+	if ok {
+		return data, nil
+	}
+
+	data, err = r.bucket.get(name)
+	if err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	r.objects[name] = data
+	r.mu.Unlock()
+	return data, nil
+}
+
+// objectName returns the name of the object holding the accepted snapshot
+// at path: the remote prefix, module path, package directory and the path
+// below __snapshots__.
+func (r *RemoteStorage) objectName(snapshotPath string) (string, error) {
+	abs, err := filepath.Abs(snapshotPath)
+	if err != nil {
+		return "", err
+	}
+	abs = filepath.ToSlash(abs)
+	sep := "/" + snapshotDirName + "/"
+	i := strings.LastIndex(abs, sep)
+	if i < 0 {
+		return "", fmt.Errorf("%s is not in a %s directory", DisplayPath(snapshotPath), snapshotDirName)
+	}
+	pkgDir, key := filepath.FromSlash(abs[:i]), abs[i+len(sep):]
+
+	modDir, module, err := findModule(pkgDir)
+	if err != nil {
+		return "", err
+	}
+	pkg, err := filepath.Rel(modDir, pkgDir)
+	if err != nil {
+		return "", err
+	}
+	if pkg == "." {
+		pkg = ""
+	}
+	return path.Join(r.remote.prefix, module, filepath.ToSlash(pkg), key), nil
+}
+
+// findModule returns the directory and path of the module containing dir.
+func findModule(dir string) (string, string, error) {
+	for d := dir; ; {
+		if module, err := readModulePath(filepath.Join(d, "go.mod")); err == nil {
+			return d, module, nil
+		} else if !os.IsNotExist(err) {
+			return "", "", err
+		}
+		parent := filepath.Dir(d)
+		if parent == d {
+			return "", "", fmt.Errorf("no go.mod found above %s", DisplayPath(dir))
+		}
+		d = parent
+	}
+}
+
+// readModulePath returns the path in the module directive of a go.mod file.
+func readModulePath(goMod string) (string, error) {
+	f, err := os.Open(goMod)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if module, ok := strings.CutPrefix(line, "module "); ok {
+			if i := strings.Index(module, "//"); i >= 0 {
+				module = module[:i]
+			}
+			return unquoteWorkPath(strings.TrimSpace(module)), nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return "", err
+	}
+	return "", fmt.Errorf("%s has no module directive", DisplayPath(goMod))
+}
+
+// bucket talks to an S3 bucket, or a Google Cloud Storage bucket through
+// its S3 compatible XML API, signing requests with AWS Signature Version 4.
+type bucket struct {
+	endpoint string
+	name     string
+	region   string
+	creds    credentials
+	client   *http.Client
+}
+
+type credentials struct {
+	accessKey string
+	secretKey string
+	token     string
+}
+
+func newBucket(r remote) *bucket {
+	b := &bucket{name: r.bucket, client: &http.Client{Timeout: remoteTimeout}}
+	if r.scheme == "gs" {
+		b.region = "auto"
+		b.endpoint = "https://storage.googleapis.com"
+	} else {
+		b.region = firstNonEmpty(os.Getenv("AWS_REGION"), os.Getenv("AWS_DEFAULT_REGION"), "us-east-1")
+		b.endpoint = "https://s3." + b.region + ".amazonaws.com"
+	}
+	if endpoint := os.Getenv(RemoteEndpointEnv); endpoint != "" {
+		b.endpoint = strings.TrimSuffix(endpoint, "/")
+	}
+
+	if access := os.Getenv(RemoteAccessKeyEnv); access != "" {
+		b.creds = credentials{accessKey: access, secretKey: os.Getenv(RemoteSecretKeyEnv)}
+	} else {
+		b.creds = credentials{
+			accessKey: os.Getenv("AWS_ACCESS_KEY_ID"),
+			secretKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+			token:     os.Getenv("AWS_SESSION_TOKEN"),
+		}
+	}
+	return b
+}
+
+// firstNonEmpty returns the first of values that is not empty.
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+func (b *bucket) get(name string) ([]byte, error) {
+	resp, err := b.do(http.MethodGet, name, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return nil, &fs.PathError{Op: "read", Path: b.url(name), Err: fs.ErrNotExist}
+	case resp.StatusCode == http.StatusForbidden:
+		// Without s3:ListBucket, S3 answers 403 rather than 404 for a
+		// missing object, which cannot be told apart from a denied read.
+		return nil, fmt.Errorf("%w (reading snapshots that are not in the bucket yet needs s3:ListBucket permission)", b.statusError(http.MethodGet, name, resp.Status, data))
+	case resp.StatusCode != http.StatusOK:
+		return nil, b.statusError(http.MethodGet, name, resp.Status, data)
+	case err != nil:
+		return nil, fmt.Errorf("failed to read %s: %w", b.url(name), err)
+	}
+	return data, nil
+}
+
+func (b *bucket) put(name string, data []byte) error {
+	resp, err := b.do(http.MethodPut, name, data)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return b.statusError(http.MethodPut, name, resp.Status, body)
+	}
+	return nil
+}
+
+func (b *bucket) do(method, name string, body []byte) (*http.Response, error) {
+	if b.creds.accessKey == "" || b.creds.secretKey == "" {
+		return nil, fmt.Errorf("remote snapshots need credentials: set %s and %s", RemoteAccessKeyEnv, RemoteSecretKeyEnv)
+	}
+	u, err := url.Parse(b.endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("invalid remote endpoint %q: %w", b.endpoint, err)
+	}
+	u.Path = "/" + b.name + "/" + name
+	u.RawPath = escapePath(u.Path)
+
+	req, err := http.NewRequest(method, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	signV4(req, body, b.creds, b.region, time.Now())
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("remote snapshot storage: %w", err)
+	}
+	return resp, nil
+}
+
+func (b *bucket) url(name string) string {
+	return b.endpoint + "/" + b.name + "/" + name
+}
+
+// statusError reports a failed request with the message of the error
+// document the bucket answered with, if any.
+func (b *bucket) statusError(method, name, status string, body []byte) error {
+	msg := strings.TrimSpace(string(body))
+	if start, end := strings.Index(msg, "<Message>"), strings.Index(msg, "</Message>"); start >= 0 && end > start {
+		msg = msg[start+len("<Message>") : end]
+	}
+	if msg == "" {
+		return fmt.Errorf("%s %s: %s", method, b.url(name), status)
+	}
+	return fmt.Errorf("%s %s: %s: %s", method, b.url(name), status, msg)
+}
+
+// signV4 adds the date, payload hash and Authorization headers of AWS
+// Signature Version 4 to req. Every header already set on req is signed.
+func signV4(req *http.Request, body []byte, creds credentials, region string, now time.Time) {
+	const service = "s3"
+	now = now.UTC()
+	amzDate := now.Format("20060102T150405Z")
+	day := now.Format("20060102")
+	payload := sha256.Sum256(body)
+	payloadHash := hex.EncodeToString(payload[:])
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	if creds.token != "" {
+		req.Header.Set("X-Amz-Security-Token", creds.token)
+	}
+
+	host := req.Host
+	if host == "" {
+		host = req.URL.Host
+	}
+	headers := map[string]string{"host": host}
+	for name, values := range req.Header {
+		headers[strings.ToLower(name)] = strings.TrimSpace(strings.Join(values, ","))
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		escapePath(req.URL.Path),
+		req.URL.Query().Encode(),
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+
+	scope := day + "/" + region + "/" + service + "/aws4_request"
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(requestHash[:])
+
+	key := hmacSHA256([]byte("AWS4"+creds.secretKey), day)
+	key = hmacSHA256(key, region)
+	key = hmacSHA256(key, service)
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		creds.accessKey, scope, signedHeaders, signature))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// escapePath percent-encodes every byte of p but unreserved characters and
+// slashes, as Signature Version 4 requires.
+func escapePath(p string) string {
+	var sb strings.Builder
+	for i := 0; i < len(p); i++ {
+		c := p[i]
+		if 'A' <= c && c <= 'Z' || 'a' <= c && c <= 'z' || '0' <= c && c <= '9' || strings.IndexByte("-_.~/", c) >= 0 {
+			sb.WriteByte(c)
+		} else {
+			fmt.Fprintf(&sb, "%%%02X", c)
+		}
+	}
+	return sb.String()
+}
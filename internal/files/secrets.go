@@ -0,0 +1,41 @@
+package files
+
+import (
+	"fmt"
+
+	"github.com/ptdewey/shutter/internal/secrets"
+)
+
+// SecretsError is returned by AcceptSnapshotInfo when a snapshot looks like
+// it contains secrets. The snapshot stays pending; ForceAcceptSnapshotInfo
+// accepts it anyway.
+type SecretsError struct {
+	Path     string
+	Findings []secrets.Finding
+}
+
+func (e *SecretsError) Error() string {
+	msg := fmt.Sprintf("%s may contain secrets: %s", DisplayPath(e.Path), e.Findings[0])
+	if more := len(e.Findings) - 1; more > 0 {
+		msg += fmt.Sprintf(" (and %d more)", more)
+	}
+	return msg
+}
+
+// AuditResult lists the suspected secrets in an accepted snapshot.
+type AuditResult struct {
+	Info     SnapshotInfo
+	Findings []secrets.Finding
+}
+
+// AuditSnapshots scans every accepted snapshot in the project for suspected
+// secrets and returns those with findings.
+func AuditSnapshots() ([]AuditResult, error) {
+	var results []AuditResult
+	err := walkAccepted(func(info SnapshotInfo, snap *Snapshot) {
+		if findings := secrets.Scan(snap.Content); len(findings) > 0 {
+			results = append(results, AuditResult{Info: info, Findings: findings})
+		}
+	})
+	return results, err
+}
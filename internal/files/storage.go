@@ -0,0 +1,226 @@
+package files
+
+import (
+	"io/fs"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/ptdewey/shutter/internal/idmap"
+	"github.com/ptdewey/shutter/internal/secrets"
+)
+
+// Storage keeps the snapshots of a project. The review tools and the
+// snapshot functions reach snapshots through the current Storage, so
+// baselines can be kept somewhere other than the filesystem, such as in
+// memory for tests or in object storage shared between machines.
+type Storage interface {
+	// List returns the snapshots pending review.
+	List() ([]SnapshotInfo, error)
+
+	// Read returns the pending snapshot info describes, or with
+	// StateAccepted the accepted snapshot it would replace. A snapshot
+	// that does not exist is an error satisfying os.IsNotExist.
+	Read(info SnapshotInfo, state State) (*Snapshot, error)
+
+	// Write saves snap as a pending or accepted snapshot, setting its Path.
+	Write(snap *Snapshot, state State) error
+
+	// Accept replaces the accepted snapshot with the pending one info
+	// describes. Unless force is set, a snapshot that looks like it
+	// contains secrets is left pending and a *SecretsError returned.
+	Accept(info SnapshotInfo, force bool) error
+
+	// Reject discards the pending snapshot info describes.
+	Reject(info SnapshotInfo) error
+}
+
+var (
+	storageMu sync.Mutex
+	storage   Storage            // Set with SetStorage, overriding the project's
+	projects  map[string]Storage // The Storage configured for each project root
+)
+
+// CurrentStorage returns the Storage snapshots are kept in: the one set with
+// SetStorage, else a RemoteStorage if the project configures a remote, else
+// FileStorage.
+func CurrentStorage() Storage {
+	storageMu.Lock()
+	defer storageMu.Unlock()
+	if storage != nil {
+		return storage
+	}
+
+	root, err := workspaceRoot()
+	if err != nil {
+		return FileStorage{}
+	}
+	if s, ok := projects[root]; ok {
+		return s
+	}
+	var s Storage = FileStorage{}
+	// A config file that cannot be read leaves snapshots on disk; the
+	// command line tools report its errors.
+	if cfg, err := LoadConfig(); err == nil && cfg.Remote != "" {
+		if remote, err := NewRemoteStorage(cfg.Remote); err == nil {
+			s = remote
+		}
+	}
+	if projects == nil {
+		projects = map[string]Storage{}
+	}
+	projects[root] = s
+	return s
+}
+
+// SetStorage replaces the Storage snapshots are kept in and returns the
+// previous one, so it can be restored. Setting nil restores the Storage the
+// project configures.
+func SetStorage(s Storage) Storage {
+	storageMu.Lock()
+	defer storageMu.Unlock()
+	prev := storage
+	storage = s
+	return prev
+}
+
+// SnapshotInfoFor describes the pending snapshot snap is saved as, in the
+// __snapshots__ directory of the current package.
+func SnapshotInfoFor(snap *Snapshot) SnapshotInfo {
+	return SnapshotInfo{
+		Title:   snap.Key(),
+		Path:    filepath.Join(snapshotDirName, getSnapshotFileName(snap.Key(), StateNew)),
+		Dir:     snapshotDirName,
+		Variant: snap.Variant,
+		source:  snap,
+	}
+}
+
+// FileStorage keeps snapshots in __snapshots__ directories next to the
+// packages that take them. It is the default Storage.
+type FileStorage struct{}
+
+func (FileStorage) List() ([]SnapshotInfo, error) {
+	return ListNewSnapshots()
+}
+
+func (FileStorage) Read(info SnapshotInfo, state State) (*Snapshot, error) {
+	switch {
+	case state == StateNew:
+		return ReadSnapshotFromPath(info.Path)
+	case info.source != nil:
+		// The test and title find accepted snapshots in the legacy layout.
+		return ReadAcceptedVariant(info.source.Test, info.source.Title, info.source.Variant)
+	default:
+		return ReadAcceptedInfo(info)
+	}
+}
+
+func (FileStorage) Write(snap *Snapshot, state State) error {
+	return SaveSnapshot(snap, state)
+}
+
+func (FileStorage) Accept(info SnapshotInfo, force bool) error {
+	return acceptSnapshotInfo(info, !force)
+}
+
+func (FileStorage) Reject(info SnapshotInfo) error {
+	return RejectSnapshotInfo(info)
+}
+
+// MemoryStorage keeps snapshots in memory, by the paths FileStorage would
+// write them to. Nothing is written to disk, not even the __snapshots__
+// directory.
+type MemoryStorage struct {
+	mu        sync.Mutex
+	snapshots map[string]Snapshot
+	ids       *idmap.Store
+}
+
+// NewMemoryStorage returns an empty MemoryStorage.
+func NewMemoryStorage() *MemoryStorage {
+	return &MemoryStorage{snapshots: map[string]Snapshot{}, ids: idmap.NewMemory()}
+}
+
+// IDs returns the placeholder numbers of mapped scrubbers, kept with the
+// snapshots instead of in an ids.json file.
+func (m *MemoryStorage) IDs() *idmap.Store {
+	return m.ids
+}
+
+func (m *MemoryStorage) List() ([]SnapshotInfo, error) {
+	m.mu.Lock()
+	var infos []SnapshotInfo
+	for path, snap := range m.snapshots {
+		if isPending(path) {
+			infos = append(infos, SnapshotInfo{
+				Title: snap.Key(),
+				Path:  path,
+				Dir:   filepath.Dir(strings.TrimSuffix(path, getSnapshotFileName(snap.Key(), StateNew))),
+			})
+		}
+	}
+	m.mu.Unlock()
+
+	slices.SortFunc(infos, func(a, b SnapshotInfo) int { return strings.Compare(a.Path, b.Path) })
+	return groupVariants(infos, func(path string) (*Snapshot, error) {
+		return m.Read(SnapshotInfo{Path: path}, StateNew)
+	}), nil
+}
+
+func (m *MemoryStorage) Read(info SnapshotInfo, state State) (*Snapshot, error) {
+	path := info.Path
+	if state == StateAccepted {
+		path = info.AcceptedPath()
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	snap, ok := m.snapshots[path]
+	if !ok {
+		return nil, &fs.PathError{Op: "read", Path: path, Err: fs.ErrNotExist}
+	}
+	return &snap, nil
+}
+
+func (m *MemoryStorage) Write(snap *Snapshot, state State) error {
+	snap.Path = filepath.Join(snapshotDirName, getSnapshotFileName(snap.Key(), state))
+	snap.Digest = ContentDigest(snap.Content)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.snapshots[snap.Path] = *snap
+	return nil
+}
+
+func (m *MemoryStorage) Accept(info SnapshotInfo, force bool) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	snap, ok := m.snapshots[info.Path]
+	if !ok {
+		return &fs.PathError{Op: "accept", Path: info.Path, Err: fs.ErrNotExist}
+	}
+	if !force {
+		if findings := secrets.Scan(snap.Content); len(findings) > 0 {
+			return &SecretsError{Path: info.Path, Findings: findings}
+		}
+	}
+	delete(m.snapshots, info.Path)
+	snap.Path = info.AcceptedPath()
+	snap.Stale, snap.Raw, snap.RawFingerprint = "", "", ""
+	m.snapshots[snap.Path] = snap
+	return nil
+}
+
+func (m *MemoryStorage) Reject(info SnapshotInfo) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.snapshots[info.Path]; !ok {
+		return &fs.PathError{Op: "reject", Path: info.Path, Err: fs.ErrNotExist}
+	}
+	delete(m.snapshots, info.Path)
+	return nil
+}
@@ -0,0 +1,248 @@
+package files
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// TrashDir is where rejected snapshots are kept, relative to the project root
+// (or the go.work directory in a workspace).
+const TrashDir = ".shutter/trash"
+
+// trashStampFormat names the per-rejection directory inside the trash. It
+// sorts chronologically and is safe to use as a file name.
+const trashStampFormat = "20060102-150405.000000000"
+
+// TrashMaxAge is how long rejected snapshots are kept in the trash. Older
+// rejections are removed whenever another snapshot is rejected.
+const TrashMaxAge = 30 * 24 * time.Hour
+
+// TrashedSnapshot is a rejected snapshot held in the trash.
+type TrashedSnapshot struct {
+	Title      string    // Path relative to its __snapshots__ dir, without extension
+	Path       string    // Location of the file inside the trash
+	Original   string    // Location the file was rejected from
+	RejectedAt time.Time // When the snapshot was rejected
+}
+
+// trashSnapshot moves a rejected snapshot file, and its content file if it
+// is stored externally, into the trash, preserving its path relative to the
+// project root under a timestamped directory. Its raw content is deleted.
+func trashSnapshot(path string) error {
+	root, err := workspaceRoot()
+	if err != nil {
+		return err
+	}
+	// Raw content may hold what scrubbers removed, so it is not kept.
+	if err := removeRaw(path); err != nil {
+		return err
+	}
+
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return err
+	}
+
+	rel, err := filepath.Rel(root, absPath)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		// Nowhere sensible to keep files from outside the project.
+		if err := removeContent(path); err != nil {
+			return err
+		}
+		return os.Remove(path)
+	}
+
+	stamp := time.Now().UTC().Format(trashStampFormat)
+	dest := filepath.Join(root, TrashDir, stamp, rel)
+	if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+		return err
+	}
+
+	if err := moveContent(absPath, dest); err != nil {
+		return err
+	}
+	if err := os.Rename(absPath, dest); err != nil {
+		return err
+	}
+	_, err = PurgeTrash(TrashMaxAge)
+	return err
+}
+
+// PurgeTrash deletes the rejections older than maxAge from the trash, or all
+// of them if maxAge is 0, and returns how many snapshots it deleted.
+func PurgeTrash(maxAge time.Duration) (int, error) {
+	root, err := workspaceRoot()
+	if err != nil {
+		return 0, err
+	}
+
+	trashDir := filepath.Join(root, TrashDir)
+	entries, err := os.ReadDir(trashDir)
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	purged := 0
+	for _, entry := range entries {
+		rejectedAt, err := time.Parse(trashStampFormat, entry.Name())
+		if !entry.IsDir() || err != nil || (maxAge > 0 && time.Since(rejectedAt) <= maxAge) {
+			continue
+		}
+
+		stampDir := filepath.Join(trashDir, entry.Name())
+		walkErr := filepath.Walk(stampDir, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if !info.IsDir() && strings.HasSuffix(info.Name(), StateNew.Extension()) {
+				purged++
+			}
+			return nil
+		})
+		if walkErr != nil {
+			return purged, walkErr
+		}
+		if err := os.RemoveAll(stampDir); err != nil {
+			return purged, err
+		}
+	}
+	return purged, nil
+}
+
+// ListTrash returns the snapshots in the trash, most recently rejected first.
+func ListTrash() ([]TrashedSnapshot, error) {
+	root, err := workspaceRoot()
+	if err != nil {
+		return nil, err
+	}
+
+	trashDir := filepath.Join(root, TrashDir)
+	entries, err := os.ReadDir(trashDir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var trashed []TrashedSnapshot
+	for _, entry := range entries {
+		rejectedAt, err := time.Parse(trashStampFormat, entry.Name())
+		if !entry.IsDir() || err != nil {
+			continue
+		}
+
+		stampDir := filepath.Join(trashDir, entry.Name())
+		walkErr := filepath.Walk(stampDir, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if info.IsDir() || !strings.HasSuffix(info.Name(), StateNew.Extension()) {
+				return nil
+			}
+			rel, err := filepath.Rel(stampDir, path)
+			if err != nil {
+				return err
+			}
+			trashed = append(trashed, TrashedSnapshot{
+				Title:      trashTitle(rel),
+				Path:       path,
+				Original:   filepath.Join(root, rel),
+				RejectedAt: rejectedAt,
+			})
+			return nil
+		})
+		if walkErr != nil {
+			return nil, walkErr
+		}
+	}
+
+	sort.SliceStable(trashed, func(i, j int) bool {
+		return trashed[i].RejectedAt.After(trashed[j].RejectedAt)
+	})
+
+	return trashed, nil
+}
+
+// trashTitle derives a snapshot title from a project-relative path in the
+// same way ListNewSnapshots does: relative to the __snapshots__ dir, with the
+// .snap.new extension removed.
+func trashTitle(rel string) string {
+	rel = filepath.ToSlash(rel)
+	if i := strings.LastIndex(rel, "__snapshots__/"); i >= 0 {
+		rel = rel[i+len("__snapshots__/"):]
+	}
+	return strings.TrimSuffix(rel, StateNew.Extension())
+}
+
+// RestoreSnapshot moves the most recently rejected snapshot matching name
+// back to where it was rejected from, so it can be reviewed again. name is
+// either a snapshot title (TestUsers/admin_case) or the path of the rejected
+// file. A name that matches snapshots from different packages is an error.
+func RestoreSnapshot(name string) (*TrashedSnapshot, error) {
+	trashed, err := ListTrash()
+	if err != nil {
+		return nil, err
+	}
+
+	var matches []TrashedSnapshot
+	originals := map[string]bool{}
+	for _, t := range trashed {
+		display := filepath.ToSlash(DisplayPath(t.Original))
+		if t.Title == name || display == filepath.ToSlash(name) {
+			matches = append(matches, t)
+			originals[t.Original] = true
+		}
+	}
+
+	if len(matches) == 0 {
+		return nil, fmt.Errorf("no rejected snapshot named %q in %s", name, TrashDir)
+	}
+	if len(originals) > 1 {
+		var paths []string
+		for path := range originals {
+			paths = append(paths, DisplayPath(path))
+		}
+		sort.Strings(paths)
+		return nil, fmt.Errorf("%q matches snapshots in several packages, restore by path instead: %s", name, strings.Join(paths, ", "))
+	}
+
+	latest := matches[0]
+	if _, err := os.Stat(latest.Original); err == nil {
+		return nil, fmt.Errorf("cannot restore %q: %s already exists", name, DisplayPath(latest.Original))
+	}
+
+	if err := os.MkdirAll(filepath.Dir(latest.Original), 0755); err != nil {
+		return nil, err
+	}
+	if err := moveContent(latest.Path, latest.Original); err != nil {
+		return nil, err
+	}
+	if err := os.Rename(latest.Path, latest.Original); err != nil {
+		return nil, err
+	}
+
+	root, err := workspaceRoot()
+	if err == nil {
+		removeEmptyDirs(filepath.Dir(latest.Path), filepath.Join(root, TrashDir))
+	}
+	return &latest, nil
+}
+
+// removeEmptyDirs removes dir and its parents while they are empty, stopping
+// before stop.
+func removeEmptyDirs(dir, stop string) {
+	for dir != stop && strings.HasPrefix(dir, stop+string(filepath.Separator)) {
+		if err := os.Remove(dir); err != nil {
+			return
+		}
+		dir = filepath.Dir(dir)
+	}
+}
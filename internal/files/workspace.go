@@ -0,0 +1,229 @@
+package files
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// RootEnv names the environment variable that sets the project root
+// explicitly, bypassing go.mod and go.work discovery. Relative paths are
+// resolved against the current directory.
+const RootEnv = "SHUTTER_ROOT"
+
+// explicitRoot returns the absolute project root set by RootEnv, if any.
+func explicitRoot() (string, bool, error) {
+	root := os.Getenv(RootEnv)
+	if root == "" {
+		return "", false, nil
+	}
+
+	abs, err := filepath.Abs(root)
+	if err != nil {
+		return "", true, err
+	}
+	info, err := os.Stat(abs)
+	if err != nil {
+		return "", true, fmt.Errorf("%s: %w", RootEnv, err)
+	}
+	if !info.IsDir() {
+		return "", true, fmt.Errorf("%s: %s is not a directory", RootEnv, root)
+	}
+	return abs, true, nil
+}
+
+// projectRoots returns the directories searched for snapshots. Inside a
+// go.work workspace these are the workspace's member modules; otherwise it
+// is the project root found by findProjectRoot. RootEnv overrides both.
+func projectRoots() ([]string, error) {
+	if root, ok, err := explicitRoot(); ok {
+		if err != nil {
+			return nil, err
+		}
+		return []string{root}, nil
+	}
+	if members, ok := findWorkspaceModules(); ok {
+		return members, nil
+	}
+
+	root, err := findProjectRoot()
+	if err != nil {
+		return nil, err
+	}
+	return []string{root}, nil
+}
+
+// workspaceRoot returns the directory that holds project-wide state such as
+// the trash: the go.work directory inside a workspace, else the project root.
+func workspaceRoot() (string, error) {
+	if root, ok, err := explicitRoot(); ok {
+		return root, err
+	}
+	if workFile := findWorkFile(); workFile != "" {
+		return filepath.Dir(workFile), nil
+	}
+	return findProjectRoot()
+}
+
+// projectSnapshotDirs returns every __snapshots__ directory under the project
+// roots, without duplicates when workspace members are nested.
+func projectSnapshotDirs() ([]string, error) {
+	roots, err := projectRoots()
+	if err != nil {
+		return nil, err
+	}
+
+	seen := map[string]bool{}
+	var dirs []string
+	for _, root := range roots {
+		found, err := findAllSnapshotDirs(root)
+		if err != nil {
+			return nil, err
+		}
+		for _, dir := range found {
+			if !seen[dir] {
+				seen[dir] = true
+				dirs = append(dirs, dir)
+			}
+		}
+	}
+	return dirs, nil
+}
+
+// ListTestFiles returns the _test.go files of the project, keyed by
+// package directory. Directories are skipped as in snapshot discovery, and
+// so are testdata directories.
+func ListTestFiles() (map[string][]string, error) {
+	roots, err := projectRoots()
+	if err != nil {
+		return nil, err
+	}
+	exclude := excludePatterns()
+
+	testFiles := map[string][]string{}
+	seen := map[string]bool{}
+	for _, root := range roots {
+		err := filepath.WalkDir(root, func(path string, d os.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.IsDir() {
+				rel, _ := filepath.Rel(root, path)
+				if path != root && (skipDir(d.Name()) || d.Name() == "testdata" || d.Name() == "__snapshots__" || excluded(exclude, filepath.ToSlash(rel))) {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if strings.HasSuffix(d.Name(), "_test.go") && !seen[path] {
+				seen[path] = true
+				dir := filepath.Dir(path)
+				testFiles[dir] = append(testFiles[dir], path)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return testFiles, nil
+}
+
+// findWorkspaceModules returns the absolute paths of the member modules of
+// the go.work workspace governing the current directory, if any.
+func findWorkspaceModules() ([]string, bool) {
+	workFile := findWorkFile()
+	if workFile == "" {
+		return nil, false
+	}
+
+	uses, err := parseWorkUses(workFile)
+	if err != nil || len(uses) == 0 {
+		return nil, false
+	}
+
+	workDir := filepath.Dir(workFile)
+	members := make([]string, 0, len(uses))
+	for _, use := range uses {
+		if !filepath.IsAbs(use) {
+			use = filepath.Join(workDir, use)
+		}
+		members = append(members, filepath.Clean(use))
+	}
+	return members, true
+}
+
+// findWorkFile returns the go.work file governing the current directory, or
+// "" if there is none. Like the go command, it honors GOWORK, including
+// GOWORK=off.
+func findWorkFile() string {
+	switch workFile := os.Getenv("GOWORK"); workFile {
+	case "off":
+		return ""
+	case "":
+		cwd, err := os.Getwd()
+		if err != nil {
+			return ""
+		}
+		return findUp(cwd, "go.work")
+	default:
+		return workFile
+	}
+}
+
+// findUp returns the path of name in dir or its closest ancestor, or "".
+func findUp(dir, name string) string {
+	for {
+		candidate := filepath.Join(dir, name)
+		if _, err := os.Stat(candidate); err == nil {
+			return candidate
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// parseWorkUses returns the module directories listed in the use directives
+// of a go.work file, in both the single-line and block forms.
+func parseWorkUses(workFile string) ([]string, error) {
+	f, err := os.Open(workFile)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var uses []string
+	inBlock := false
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.Index(line, "//"); i >= 0 {
+			line = line[:i]
+		}
+		line = strings.TrimSpace(line)
+
+		switch {
+		case inBlock && line == ")":
+			inBlock = false
+		case inBlock && line != "":
+			uses = append(uses, unquoteWorkPath(line))
+		case line == "use (":
+			inBlock = true
+		case strings.HasPrefix(line, "use "):
+			uses = append(uses, unquoteWorkPath(strings.TrimSpace(strings.TrimPrefix(line, "use "))))
+		}
+	}
+	return uses, scanner.Err()
+}
+
+func unquoteWorkPath(s string) string {
+	if unquoted, err := strconv.Unquote(s); err == nil {
+		return unquoted
+	}
+	return s
+}
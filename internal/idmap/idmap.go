@@ -0,0 +1,120 @@
+package idmap
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// FileName is the name of the sidecar file, stored in the __snapshots__
+// directory, that records identifier assignments.
+const FileName = "ids.json"
+
+// Store assigns stable numbers to scrubbed values, per label. Values are
+// recorded as SHA-256 digests so the sidecar file never contains the
+// original data.
+type Store struct {
+	mu     sync.Mutex
+	path   string // Empty for a store kept in memory only
+	loaded bool
+	labels map[string]map[string]int
+}
+
+var (
+	storesMu sync.Mutex
+	stores   = map[string]*Store{}
+)
+
+// ForDir returns the shared store backed by the sidecar file in dir.
+func ForDir(dir string) *Store {
+	path := filepath.Join(dir, FileName)
+
+	storesMu.Lock()
+	defer storesMu.Unlock()
+
+	if s, ok := stores[path]; ok {
+		return s
+	}
+	s := &Store{path: path}
+	stores[path] = s
+	return s
+}
+
+// NewMemory returns a store that is not backed by a file, for snapshots
+// kept in memory.
+func NewMemory() *Store {
+	return &Store{}
+}
+
+// Lookup returns the number assigned to value under label, assigning the
+// next free number if the value has not been seen before. New assignments
+// are written to disk immediately; if that fails the assignment is still
+// kept for the rest of the process and the error is returned.
+func (s *Store) Lookup(label, value string) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.loaded {
+		if err := s.load(); err != nil {
+			return 0, err
+		}
+	}
+
+	digest := hash(value)
+	ids, ok := s.labels[label]
+	if !ok {
+		ids = map[string]int{}
+		s.labels[label] = ids
+	}
+	if n, ok := ids[digest]; ok {
+		return n, nil
+	}
+
+	n := len(ids) + 1
+	ids[digest] = n
+	return n, s.save()
+}
+
+func (s *Store) load() error {
+	s.labels = map[string]map[string]int{}
+	if s.path == "" {
+		s.loaded = true
+		return nil
+	}
+
+	data, err := os.ReadFile(s.path)
+	if os.IsNotExist(err) {
+		s.loaded = true
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, &s.labels); err != nil {
+		return err
+	}
+	s.loaded = true
+	return nil
+}
+
+func (s *Store) save() error {
+	if s.path == "" {
+		return nil
+	}
+	data, err := json.MarshalIndent(s.labels, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(s.path, append(data, '\n'), 0644)
+}
+
+func hash(value string) string {
+	sum := sha256.Sum256([]byte(value))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,68 @@
+package idmap_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ptdewey/shutter/internal/idmap"
+)
+
+func TestLookupAssignsStableNumbers(t *testing.T) {
+	store := idmap.ForDir(t.TempDir())
+
+	tests := []struct {
+		label string
+		value string
+		want  int
+	}{
+		{"UUID", "a", 1},
+		{"UUID", "b", 2},
+		{"UUID", "a", 1},
+		{"USER", "a", 1},
+	}
+
+	for _, tt := range tests {
+		got, err := store.Lookup(tt.label, tt.value)
+		if err != nil {
+			t.Fatalf("Lookup(%q, %q) failed: %v", tt.label, tt.value, err)
+		}
+		if got != tt.want {
+			t.Errorf("Lookup(%q, %q) = %d, want %d", tt.label, tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestLookupPersistsAcrossStores(t *testing.T) {
+	dir := t.TempDir()
+
+	if _, err := idmap.ForDir(dir).Lookup("UUID", "first"); err != nil {
+		t.Fatalf("Lookup failed: %v", err)
+	}
+	if _, err := idmap.ForDir(dir).Lookup("UUID", "second"); err != nil {
+		t.Fatalf("Lookup failed: %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, idmap.FileName))
+	if err != nil {
+		t.Fatalf("failed to read sidecar: %v", err)
+	}
+	if strings.Contains(string(data), "first") || strings.Contains(string(data), "second") {
+		t.Errorf("sidecar should not contain raw values:\n%s", data)
+	}
+
+	// Simulate a later run by copying the sidecar into a fresh directory,
+	// which is backed by a new store that must load it from disk.
+	nextRun := t.TempDir()
+	if err := os.WriteFile(filepath.Join(nextRun, idmap.FileName), data, 0644); err != nil {
+		t.Fatalf("failed to copy sidecar: %v", err)
+	}
+	got, err := idmap.ForDir(nextRun).Lookup("UUID", "second")
+	if err != nil {
+		t.Fatalf("Lookup failed: %v", err)
+	}
+	if got != 2 {
+		t.Errorf("expected persisted number 2, got %d", got)
+	}
+}
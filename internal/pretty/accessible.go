@@ -0,0 +1,92 @@
+package pretty
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/ptdewey/shutter/internal/diff"
+	"github.com/ptdewey/shutter/internal/files"
+)
+
+// AccessibleEnv names the environment variable that enables accessible
+// output, e.g. SHUTTER_ACCESSIBLE=1.
+const AccessibleEnv = "SHUTTER_ACCESSIBLE"
+
+var accessible = envAccessible()
+
+func envAccessible() bool {
+	v, err := strconv.ParseBool(os.Getenv(AccessibleEnv))
+	return err == nil && v
+}
+
+// SetAccessible switches screen-reader-friendly output on or off. Accessible
+// output has no color, box-drawing characters, or status glyphs; diff lines
+// are labeled with "ADDED:", "REMOVED:", and "CONTEXT:" instead.
+func SetAccessible(on bool) {
+	accessible = on
+}
+
+// Accessible reports whether accessible output is enabled.
+func Accessible() bool {
+	return accessible
+}
+
+// statusGlyphs are the symbols that lead status messages, e.g. "✓ Snapshot
+// accepted". They carry no information the words don't, so accessible output
+// drops them.
+var statusGlyphs = []string{"✓ ", "✗ ", "⊘ ", "… "}
+
+// plainStatus removes a leading status glyph from s, keeping any leading
+// newlines.
+func plainStatus(s string) string {
+	trimmed := strings.TrimLeft(s, "\n")
+	for _, glyph := range statusGlyphs {
+		if rest, ok := strings.CutPrefix(trimmed, glyph); ok {
+			return s[:len(s)-len(trimmed)] + rest
+		}
+	}
+	return s
+}
+
+// diffLabels name each kind of diff line in accessible output.
+var diffLabels = map[diff.DiffKind]string{
+	diff.DiffOld:    "REMOVED",
+	diff.DiffNew:    "ADDED",
+	diff.DiffShared: "CONTEXT",
+}
+
+// accessibleDiff renders a diff as labeled lines, one per diff line, with
+// the line number each line has in the old or new snapshot.
+func accessibleDiff(old, newSnapshot *files.Snapshot, diffLines []diff.DiffLine) string {
+	var sb strings.Builder
+	sb.WriteString("Snapshot Diff\n\n")
+	writeDiffHeader(&sb, old, newSnapshot)
+
+	for _, dl := range diffLines {
+		number := dl.NewNumber
+		if dl.Kind == diff.DiffOld {
+			number = dl.OldNumber
+		}
+		sb.WriteString(fmt.Sprintf("%s: line %d: %s\n", diffLabels[dl.Kind], number, dl.Line))
+	}
+
+	sb.WriteString("End of snapshot diff\n")
+	return sb.String()
+}
+
+// accessibleNewSnapshot renders a new snapshot with every line labeled as
+// added.
+func accessibleNewSnapshot(snap *files.Snapshot) string {
+	var sb strings.Builder
+	sb.WriteString("New Snapshot\n\n")
+	writeNewSnapshotHeader(&sb, snap)
+
+	for i, line := range strings.Split(snap.Content, "\n") {
+		sb.WriteString(fmt.Sprintf("%s: line %d: %s\n", diffLabels[diff.DiffNew], i+1, line))
+	}
+
+	sb.WriteString("End of new snapshot\n")
+	return sb.String()
+}
@@ -2,12 +2,64 @@ package pretty
 
 import (
 	"fmt"
+	"path/filepath"
+	"slices"
+	"strconv"
 	"strings"
 
 	"github.com/ptdewey/shutter/internal/diff"
 	"github.com/ptdewey/shutter/internal/files"
 )
 
+// BoxStyle tunes how snapshot boxes are drawn, e.g. for screenshots in docs
+// or for narrow terminals.
+type BoxStyle struct {
+	// Legend adds lines naming the two sides of a diff, "- accepted" and
+	// "+ pending", above it.
+	Legend bool
+
+	// Removed, Added and Context are the gutter characters of removed,
+	// added and unchanged lines. Empty ones keep "-", "+" and "│".
+	Removed string
+	Added   string
+	Context string
+
+	// HideMetadata leaves out the title, test, options and other metadata
+	// lines above a snapshot.
+	HideMetadata bool
+}
+
+var boxStyle BoxStyle
+
+// SetBoxStyle sets the style of the boxes drawn by DiffSnapshotBox and
+// NewSnapshotBox.
+func SetBoxStyle(style BoxStyle) {
+	boxStyle = style
+}
+
+// gutter holds the characters that mark each kind of line in a box.
+type gutter struct {
+	removed, added, context string
+}
+
+var defaultGutter = gutter{removed: "-", added: "+", context: "│"}
+
+// gutter returns the gutter characters of s, with defaults for those not
+// set.
+func (s BoxStyle) gutter() gutter {
+	g := defaultGutter
+	if s.Removed != "" {
+		g.removed = s.Removed
+	}
+	if s.Added != "" {
+		g.added = s.Added
+	}
+	if s.Context != "" {
+		g.context = s.Context
+	}
+	return g
+}
+
 func NewSnapshotBox(snap *files.Snapshot, width ...int) string {
 	w := TerminalWidth()
 	if len(width) > 0 && width[0] > 0 {
@@ -16,18 +68,50 @@ func NewSnapshotBox(snap *files.Snapshot, width ...int) string {
 	return newSnapshotBoxInternal(snap, w)
 }
 
+// minNumberedWidth is the narrowest box that shows line numbers. Narrower
+// boxes leave them out to keep room for the content.
+const minNumberedWidth = 60
+
+// writeBar writes the top or bottom bar of a box, with corner above or
+// below the gutter separator.
+func writeBar(sb *strings.Builder, gutterWidth int, corner string, rest int) {
+	sb.WriteString(strings.Repeat("─", gutterWidth) + corner + strings.Repeat("─", max(rest, 0)) + "\n")
+}
+
 // calculateLineNumWidth returns the width needed to display line numbers
 func calculateLineNumWidth(maxLineNum int) int {
-	return len(fmt.Sprintf("%d", maxLineNum))
+	return len(strconv.Itoa(maxLineNum))
+}
+
+// padNumber right-aligns n in a column of width, like fmt's "%*d".
+func padNumber(n, width int) string {
+	num := strconv.Itoa(n)
+	if len(num) >= width {
+		return num
+	}
+	return strings.Repeat(" ", width-len(num)) + num
+}
+
+// writeRow writes one row of a box: the columns separated by spaces,
+// indented by two spaces.
+func writeRow(sb *strings.Builder, columns ...string) {
+	sb.WriteString("  ")
+	for i, col := range columns {
+		if i > 0 {
+			sb.WriteByte(' ')
+		}
+		sb.WriteString(col)
+	}
+	sb.WriteByte('\n')
 }
 
 // formatColoredLine applies color to a line based on diff kind
-func formatColoredLine(line string, kind diff.DiffKind) string {
+func formatColoredLine(p painter, line string, kind diff.DiffKind) string {
 	switch kind {
 	case diff.DiffOld:
-		return Red(line)
+		return p.paint(line, colorRed)
 	case diff.DiffNew:
-		return Green(line)
+		return p.paint(line, colorGreen)
 	case diff.DiffShared:
 		return line
 	default:
@@ -35,16 +119,21 @@ func formatColoredLine(line string, kind diff.DiffKind) string {
 	}
 }
 
-func DiffSnapshotBox(old, newSnapshot *files.Snapshot, diffLines []diff.DiffLine, widthOpt ...int) string {
-	width := TerminalWidth()
-	if len(widthOpt) > 0 && widthOpt[0] > 0 {
-		width = widthOpt[0]
+// snapshotPath returns the path a reviewer should look at for snap. Pending
+// snapshots are shown as the accepted file they will become. Snapshots that
+// were never written to disk fall back to their path within __snapshots__.
+func snapshotPath(snap *files.Snapshot) string {
+	if snap.Path == "" {
+		return filepath.Join("__snapshots__", filepath.FromSlash(snap.Key())) + files.StateAccepted.Extension()
 	}
-	snapshotFileName := files.SnapshotFileName(newSnapshot.Title) + ".snap"
-
-	var sb strings.Builder
-	sb.WriteString("─── " + "Snapshot Diff " + strings.Repeat("─", width-15) + "\n\n")
+	return strings.TrimSuffix(files.DisplayPath(snap.Path), ".new")
+}
 
+// writeDiffHeader writes the metadata shown above a snapshot diff.
+func writeDiffHeader(sb *strings.Builder, old, newSnapshot *files.Snapshot) {
+	if boxStyle.HideMetadata {
+		return
+	}
 	// TODO: maybe make helper functions for this, swap coloring between the key and the value
 	// TODO: maybe show the snapshot file name in gray next to the "a/r/s" options
 	// (i.e. "a accept -> snap_file_name.snap", "reject" w/strikethrough?, skip, keeps "*snap.new")
@@ -52,12 +141,79 @@ func DiffSnapshotBox(old, newSnapshot *files.Snapshot, diffLines []diff.DiffLine
 		sb.WriteString(Blue("  title: ") + newSnapshot.Title + "\n")
 	}
 	sb.WriteString(Blue("  test: ") + newSnapshot.Test + "\n")
-	sb.WriteString(Blue("  file: ") + snapshotFileName + "\n")
+	if newSnapshot.Variant != "" {
+		sb.WriteString(Blue("  variant: ") + newSnapshot.Variant + "\n")
+	}
+	writeOptions(sb, newSnapshot)
+	if !slices.Equal(old.Options, newSnapshot.Options) {
+		sb.WriteString(Blue("  options changed: ") + optionChanges(old.Options, newSnapshot.Options) + "\n")
+	}
+	sb.WriteString(Blue("  file: ") + snapshotPath(newSnapshot) + "\n")
+	if old.Commit != nil {
+		sb.WriteString(Blue("  accepted: ") + old.Commit.String() + "\n")
+	}
+	if newSnapshot.Stale != "" {
+		sb.WriteString(Yellow("  stale baseline: ") + newSnapshot.Stale + "\n")
+	}
 	sb.WriteString("\n")
-	// sb.WriteString(Red("  - old snapshot\n"))
-	// sb.WriteString(Green("  + new snapshot\n"))
-	// sb.WriteString("\n")
+}
+
+// writeOptions writes the scrubbers and ignore patterns a snapshot was taken
+// with, if any.
+func writeOptions(sb *strings.Builder, snap *files.Snapshot) {
+	if len(snap.Options) > 0 {
+		sb.WriteString(Blue("  options: ") + strings.Join(snap.Options, ", ") + "\n")
+	}
+}
+
+// optionChanges describes how the options of a snapshot changed since it was
+// accepted, so a reviewer can tell a diff caused by a new or removed
+// scrubber from one caused by changed data.
+func optionChanges(old, new []string) string {
+	var changes []string
+	for _, opt := range new {
+		if !slices.Contains(old, opt) {
+			changes = append(changes, Green("added "+opt))
+		}
+	}
+	for _, opt := range old {
+		if !slices.Contains(new, opt) {
+			changes = append(changes, Red("removed "+opt))
+		}
+	}
+	if len(changes) == 0 {
+		return "reordered"
+	}
+	return strings.Join(changes, ", ")
+}
+
+func DiffSnapshotBox(old, newSnapshot *files.Snapshot, diffLines []diff.DiffLine, widthOpt ...int) string {
+	width := TerminalWidth()
+	if len(widthOpt) > 0 && widthOpt[0] > 0 {
+		width = widthOpt[0]
+	}
+	if accessible {
+		return accessibleDiff(old, newSnapshot, diffLines)
+	}
+
+	var sb strings.Builder
+	sb.WriteString("─── " + "Snapshot Diff " + strings.Repeat("─", max(width-15, 0)) + "\n\n")
+	writeDiffHeader(&sb, old, newSnapshot)
+	g := boxStyle.gutter()
+	if boxStyle.Legend {
+		sb.WriteString(Red("  "+g.removed+" accepted") + "\n")
+		sb.WriteString(Green("  "+g.added+" pending") + "\n\n")
+	}
+
+	writeDiffRows(&sb, newPainter(), g, diffLines, width, newSnapshot.ContentType, nil)
 
+	return sb.String()
+}
+
+// writeDiffRows writes the rows of a diff box, from its top bar to its bottom
+// bar, colored with p and marked with the characters of g. If shown is not
+// nil, the lines it marks false are collapsed into a row counting them.
+func writeDiffRows(sb *strings.Builder, p painter, g gutter, diffLines []diff.DiffLine, width int, contentType string, shown []bool) {
 	// Calculate max line numbers for proper spacing
 	maxOldNum := 0
 	maxNewNum := 0
@@ -76,143 +232,179 @@ func DiffSnapshotBox(old, newSnapshot *files.Snapshot, diffLines []diff.DiffLine
 	}
 	lineNumWidth := calculateLineNumWidth(maxLineNum)
 
+	// The gutter holds 2 spaces padding, 2 line number columns and a space
+	// after each.
+	gutterWidth := (lineNumWidth * 2) + 4
+	numbered := width >= minNumberedWidth
+	if !numbered {
+		gutterWidth = 2
+	}
+	row := func(leftNum, rightNum, prefix, text string) {
+		if numbered {
+			writeRow(sb, leftNum, rightNum, prefix, text)
+		} else {
+			writeRow(sb, prefix, text)
+		}
+	}
+
 	// Top bar with corner (account for both line number columns)
-	topBar := strings.Repeat("─", (lineNumWidth*2)+4) + "┬" +
-		strings.Repeat("─", width-(lineNumWidth*2)-1) + "\n"
-	sb.WriteString(topBar)
+	writeBar(sb, gutterWidth, "┬", width-gutterWidth+3)
 
-	for _, dl := range diffLines {
+	// Wrap long lines instead of truncating
+	// Account for: the gutter + prefix + space
+	maxContentWidth := width - gutterWidth - 4
+	if maxContentWidth < 20 {
+		maxContentWidth = 20
+	}
+
+	blank := strings.Repeat(" ", lineNumWidth)
+	for i := 0; i < len(diffLines); i++ {
+		if shown != nil && !shown[i] {
+			// Collapse the run of hidden lines into one row.
+			n := 0
+			for ; i < len(diffLines) && !shown[i]; i++ {
+				n++
+			}
+			i--
+			row(blank, blank, "┆", p.paint(fmt.Sprintf("… %d unchanged line(s)", n), colorGray))
+			continue
+		}
+		dl := diffLines[i]
 		var leftNum, rightNum, prefix, formatted string
+		text := diffText(dl.Line, dl.Kind, contentType)
 
 		// FIX: line number coloring is the same between old and new lines
 		switch dl.Kind {
 		case diff.DiffOld:
 			// For removed lines: show old line number on left, space on right, red -
-			leftNum = Red(fmt.Sprintf("%*d", lineNumWidth, dl.OldNumber))
-			rightNum = strings.Repeat(" ", lineNumWidth)
-			prefix = Red("-")
-			formatted = Red(dl.Line)
+			leftNum = p.paint(padNumber(dl.OldNumber, lineNumWidth), colorRed)
+			rightNum = blank
+			prefix = p.paint(g.removed, colorRed)
+			formatted = p.paint(text, colorRed)
 		case diff.DiffNew:
 			// For added lines: space on left, new line number on right, green +
-			leftNum = strings.Repeat(" ", lineNumWidth)
-			rightNum = Green(fmt.Sprintf("%*d", lineNumWidth, dl.NewNumber))
-			prefix = Green("+")
-			formatted = Green(dl.Line)
+			leftNum = blank
+			rightNum = p.paint(padNumber(dl.NewNumber, lineNumWidth), colorGreen)
+			prefix = p.paint(g.added, colorGreen)
+			formatted = p.paint(text, colorGreen)
 		case diff.DiffShared:
 			// For shared lines: show line number centered, │ separator (not gray)
-			leftNum = strings.Repeat(" ", lineNumWidth)
-			rightNum = Gray(fmt.Sprintf("%*d", lineNumWidth, dl.NewNumber))
-			prefix = "│"
-			formatted = dl.Line
-		}
-
-		// Wrap long lines instead of truncating
-		// Account for: 2 spaces padding + 2 line number columns + 2 spaces between + prefix + space
-		maxContentWidth := width - (lineNumWidth * 2) - 8
-		if maxContentWidth < 20 {
-			maxContentWidth = 20
+			leftNum = blank
+			rightNum = p.paint(padNumber(dl.NewNumber, lineNumWidth), colorGray)
+			prefix = g.context
+			formatted = highlight(p, text, contentType)
 		}
 
-		if len(dl.Line) > maxContentWidth {
+		chunks := wrapDisplay(text, maxContentWidth)
+		if len(chunks) > 1 {
 			// Emit wrapped chunks with proper gutter alignment
-			line := dl.Line
-			first := true
-			for len(line) > 0 {
-				chunk := line
-				if len(chunk) > maxContentWidth {
-					chunk = line[:maxContentWidth]
-					line = line[maxContentWidth:]
+			for i, chunk := range chunks {
+				coloredChunk := formatColoredLine(p, chunk, dl.Kind)
+				if i == 0 {
+					row(leftNum, rightNum, prefix, coloredChunk)
 				} else {
-					line = ""
-				}
-				coloredChunk := formatColoredLine(chunk, dl.Kind)
-				if first {
-					display := fmt.Sprintf("%s %s %s %s", leftNum, rightNum, prefix, coloredChunk)
-					sb.WriteString(fmt.Sprintf("  %s\n", display))
-					first = false
-				} else {
-					pad := strings.Repeat(" ", lineNumWidth)
-					display := fmt.Sprintf("%s %s %s %s", pad, pad, "│", coloredChunk)
-					sb.WriteString(fmt.Sprintf("  %s\n", display))
+					row(blank, blank, g.context, coloredChunk)
 				}
 			}
 		} else {
-			display := fmt.Sprintf("%s %s %s %s", leftNum, rightNum, prefix, formatted)
-			sb.WriteString(fmt.Sprintf("  %s\n", display))
+			row(leftNum, rightNum, prefix, formatted)
 		}
 	}
 
 	// Bottom bar with corner (account for both line number columns)
-	bottomBar := strings.Repeat("─", (lineNumWidth*2)+4) + "┴" +
-		strings.Repeat("─", width-(lineNumWidth*2)-1) + "\n"
-	sb.WriteString(bottomBar)
-
-	return sb.String()
+	writeBar(sb, gutterWidth, "┴", width-gutterWidth+3)
 }
 
 func newSnapshotBoxInternal(snap *files.Snapshot, width int) string {
+	if accessible {
+		return accessibleNewSnapshot(snap)
+	}
 
 	var sb strings.Builder
-	sb.WriteString("─── " + "New Snapshot " + strings.Repeat("─", width-15) + "\n\n")
-
-	if snap.Title != "" {
-		sb.WriteString(Blue("  title: ") + snap.Title + "\n")
-	}
-	if snap.Test != "" {
-		sb.WriteString(Blue("  test: ") + snap.Test + "\n")
-	}
-	if snap.FileName != "" {
-		sb.WriteString(Blue("  file: ") + snap.FileName + "\n")
-	}
-	sb.WriteString("\n")
+	sb.WriteString("─── " + "New Snapshot " + strings.Repeat("─", max(width-15, 0)) + "\n\n")
+	writeNewSnapshotHeader(&sb, snap)
 
 	lines := strings.Split(snap.Content, "\n")
 	numLines := len(lines)
 	lineNumWidth := calculateLineNumWidth(numLines)
 
-	topBar := strings.Repeat("─", lineNumWidth+3) + "┬" +
-		strings.Repeat("─", width-lineNumWidth-2) + "\n"
-	sb.WriteString(topBar)
+	// The gutter holds 2 spaces padding and the line number column.
+	gutterWidth := lineNumWidth + 3
+	numbered := width >= minNumberedWidth
+	if !numbered {
+		gutterWidth = 2
+	}
+	row := func(lineNum, prefix, text string) {
+		if numbered {
+			writeRow(&sb, lineNum, prefix, text)
+		} else {
+			writeRow(&sb, prefix, text)
+		}
+	}
 
-	for i, line := range lines {
-		lineNum := fmt.Sprintf("%*d", lineNumWidth, i+1)
-		prefix := fmt.Sprintf("%s %s", Green(lineNum), Green("+"))
+	writeBar(&sb, gutterWidth, "┬", width-gutterWidth+1)
 
-		maxContentWidth := width - lineNumWidth - 6
-		if maxContentWidth < 20 {
-			maxContentWidth = 20
+	maxContentWidth := width - gutterWidth - 3
+	if maxContentWidth < 20 {
+		maxContentWidth = 20
+	}
+
+	p := newPainter()
+	g := boxStyle.gutter()
+	plus := p.paint(g.added, colorGreen)
+	blank := strings.Repeat(" ", lineNumWidth)
+	// ANSI content is shown in its own colors rather than in green.
+	paint := func(s string) string { return p.paint(s, colorGreen) }
+	if snap.ContentType == files.ContentANSI {
+		paint = func(s string) string {
+			if !p {
+				return s
+			}
+			return s + colorReset
 		}
+	}
+	for i, line := range lines {
+		lineNum := p.paint(padNumber(i+1, lineNumWidth), colorGreen)
 
-		if len(line) > maxContentWidth {
-			remaining := line
-			first := true
-			for len(remaining) > 0 {
-				chunk := remaining
-				if len(chunk) > maxContentWidth {
-					chunk = remaining[:maxContentWidth]
-					remaining = remaining[maxContentWidth:]
+		chunks := wrapDisplay(line, maxContentWidth)
+		if len(chunks) > 1 {
+			for i, chunk := range chunks {
+				if i == 0 {
+					row(lineNum, plus, paint(chunk))
 				} else {
-					remaining = ""
-				}
-				if first {
-					display := fmt.Sprintf("%s %s", prefix, Green(chunk))
-					sb.WriteString(fmt.Sprintf("  %s\n", display))
-					first = false
-				} else {
-					pad := strings.Repeat(" ", lineNumWidth)
-					display := fmt.Sprintf("%s %s %s", pad, "│", Green(chunk))
-					sb.WriteString(fmt.Sprintf("  %s\n", display))
+					row(blank, g.context, paint(chunk))
 				}
 			}
 		} else {
-			display := fmt.Sprintf("%s %s", prefix, Green(line))
-			sb.WriteString(fmt.Sprintf("  %s\n", display))
+			row(lineNum, plus, paint(line))
 		}
 	}
 
-	bottomBar := strings.Repeat("─", lineNumWidth+3) + "┴" +
-		strings.Repeat("─", width-lineNumWidth-2) + "\n"
-	sb.WriteString(bottomBar)
+	writeBar(&sb, gutterWidth, "┴", width-gutterWidth+1)
 
 	return sb.String()
 }
+
+// writeNewSnapshotHeader writes the metadata shown above a new snapshot.
+func writeNewSnapshotHeader(sb *strings.Builder, snap *files.Snapshot) {
+	if boxStyle.HideMetadata {
+		return
+	}
+	if snap.Title != "" {
+		sb.WriteString(Blue("  title: ") + snap.Title + "\n")
+	}
+	if snap.Test != "" {
+		sb.WriteString(Blue("  test: ") + snap.Test + "\n")
+	}
+	if snap.Variant != "" {
+		sb.WriteString(Blue("  variant: ") + snap.Variant + "\n")
+	}
+	writeOptions(sb, snap)
+	if snap.FileName != "" {
+		sb.WriteString(Blue("  file: ") + snap.FileName + "\n")
+	}
+	if snap.Path != "" {
+		sb.WriteString(Blue("  snapshot: ") + files.DisplayPath(snap.Path) + "\n")
+	}
+	sb.WriteString("\n")
+}
@@ -4,8 +4,11 @@ import (
 	"fmt"
 	"math/rand"
 	"os"
+	"path/filepath"
 	"strings"
 	"testing"
+	"time"
+	"unicode/utf8"
 
 	"github.com/ptdewey/shutter"
 	"github.com/ptdewey/shutter/internal/diff"
@@ -39,7 +42,7 @@ func ValidateDiffBox(t *testing.T, output string, validation BoxValidation) {
 	t.Helper()
 
 	// Remove ANSI codes for easier content checking
-	stripped := stripANSI(output)
+	stripped := pretty.StripANSI(output)
 
 	// Check title/test/filename presence
 	if validation.HasTitle {
@@ -107,7 +110,7 @@ func ValidateDiffBox(t *testing.T, output string, validation BoxValidation) {
 // containsDiffLine checks if a line with the given prefix and content exists
 func containsDiffLine(output, prefix, content string) bool {
 	lines := strings.Split(output, "\n")
-	stripped := stripANSI(output)
+	stripped := pretty.StripANSI(output)
 	strippedLines := strings.Split(stripped, "\n")
 
 	for i, line := range strippedLines {
@@ -139,7 +142,7 @@ func containsDiffLine(output, prefix, content string) bool {
 func countContentLines(lines []string) int {
 	count := 0
 	for _, line := range lines {
-		stripped := stripANSI(line)
+		stripped := pretty.StripANSI(line)
 		// Content lines have line numbers followed by +, -, or │
 		if strings.Contains(stripped, "+") ||
 			strings.Contains(stripped, "-") ||
@@ -150,26 +153,6 @@ func countContentLines(lines []string) int {
 	return count
 }
 
-// stripANSI removes ANSI escape codes from a string
-func stripANSI(s string) string {
-	var result strings.Builder
-	inEscape := false
-	for _, r := range s {
-		if r == '\033' {
-			inEscape = true
-			continue
-		}
-		if inEscape {
-			if r == 'm' {
-				inEscape = false
-			}
-			continue
-		}
-		result.WriteRune(r)
-	}
-	return result.String()
-}
-
 // TestDiffSnapshotBox_SimpleModification tests a basic modification scenario
 func TestDiffSnapshotBox_SimpleModification(t *testing.T) {
 	os.Unsetenv("NO_COLOR")
@@ -197,7 +180,7 @@ func TestDiffSnapshotBox_SimpleModification(t *testing.T) {
 	validation := BoxValidation{
 		Title:           "Simple Modification",
 		TestName:        "TestSimple",
-		FileName:        "simple_modification.snap",
+		FileName:        filepath.Join("__snapshots__", "TestSimple", "simple_modification.snap"),
 		HasTitle:        true,
 		HasTestName:     true,
 		HasFileName:     true,
@@ -239,7 +222,7 @@ func TestDiffSnapshotBox_PureAddition(t *testing.T) {
 	validation := BoxValidation{
 		Title:           "Pure Addition",
 		TestName:        "TestAddition",
-		FileName:        "pure_addition.snap",
+		FileName:        filepath.Join("__snapshots__", "TestAddition", "pure_addition.snap"),
 		HasTitle:        true,
 		HasTestName:     true,
 		HasFileName:     true,
@@ -281,7 +264,7 @@ func TestDiffSnapshotBox_PureDeletion(t *testing.T) {
 	validation := BoxValidation{
 		Title:           "Pure Deletion",
 		TestName:        "TestDeletion",
-		FileName:        "pure_deletion.snap",
+		FileName:        filepath.Join("__snapshots__", "TestDeletion", "pure_deletion.snap"),
 		HasTitle:        true,
 		HasTestName:     true,
 		HasFileName:     true,
@@ -334,7 +317,7 @@ add2`
 	validation := BoxValidation{
 		Title:           "Complex Mixed",
 		TestName:        "TestComplexMixed",
-		FileName:        "complex_mixed.snap",
+		FileName:        filepath.Join("__snapshots__", "TestComplexMixed", "complex_mixed.snap"),
 		HasTitle:        true,
 		HasTestName:     true,
 		HasFileName:     true,
@@ -376,7 +359,7 @@ func TestDiffSnapshotBox_EmptyOld(t *testing.T) {
 	validation := BoxValidation{
 		Title:           "Empty to Content",
 		TestName:        "TestEmptyOld",
-		FileName:        "empty_to_content.snap",
+		FileName:        filepath.Join("__snapshots__", "TestEmptyOld", "empty_to_content.snap"),
 		HasTitle:        true,
 		HasTestName:     true,
 		HasFileName:     true,
@@ -418,7 +401,7 @@ func TestDiffSnapshotBox_EmptyNew(t *testing.T) {
 	validation := BoxValidation{
 		Title:           "Content to Empty",
 		TestName:        "TestEmptyNew",
-		FileName:        "content_to_empty.snap",
+		FileName:        filepath.Join("__snapshots__", "TestEmptyNew", "content_to_empty.snap"),
 		HasTitle:        true,
 		HasTestName:     true,
 		HasFileName:     true,
@@ -457,7 +440,7 @@ func TestDiffSnapshotBox_NoTitle(t *testing.T) {
 	diffLines := diff.Histogram(oldContent, newContent)
 	result := pretty.DiffSnapshotBox(oldSnap, newSnap, diffLines)
 
-	stripped := stripANSI(result)
+	stripped := pretty.StripANSI(result)
 
 	// Should NOT contain "title:" line
 	if strings.Contains(stripped, "title:") {
@@ -469,11 +452,83 @@ func TestDiffSnapshotBox_NoTitle(t *testing.T) {
 		t.Error("Expected test name to be present")
 	}
 	// When title is empty, filename should be based on test name
-	if !strings.Contains(stripped, "file: .snap") {
+	if !strings.Contains(stripped, "file: "+filepath.Join("__snapshots__", "TestNoTitle.snap")) {
 		t.Error("Expected file name to be present")
 	}
 }
 
+// TestDiffSnapshotBox_RealPath tests that the file shown is the path the
+// pending snapshot will be accepted to
+func TestDiffSnapshotBox_RealPath(t *testing.T) {
+	os.Setenv("NO_COLOR", "1")
+	defer os.Unsetenv("NO_COLOR")
+
+	pending := filepath.Join("pkg", "__snapshots__", "TestUsers", "admin_case.snap.new")
+	oldSnap := &files.Snapshot{Title: "Admin Case", Test: "TestUsers", Content: "old"}
+	newSnap := &files.Snapshot{Title: "Admin Case", Test: "TestUsers", Content: "new", Path: pending}
+
+	result := pretty.DiffSnapshotBox(oldSnap, newSnap, diff.Histogram("old", "new"), 80)
+
+	want := "file: " + filepath.Join("pkg", "__snapshots__", "TestUsers", "admin_case.snap")
+	if !strings.Contains(result, want+"\n") {
+		t.Errorf("expected %q in output:\n%s", want, result)
+	}
+}
+
+// TestDiffSnapshotBox_Commit tests that the commit of the accepted snapshot
+// is shown when known
+func TestDiffSnapshotBox_Commit(t *testing.T) {
+	os.Setenv("NO_COLOR", "1")
+	defer os.Unsetenv("NO_COLOR")
+
+	oldSnap := &files.Snapshot{
+		Title:   "Admin Case",
+		Test:    "TestUsers",
+		Content: "old",
+		Commit: &files.Commit{
+			Hash:    "1a2b3c4",
+			Author:  "Jane Doe",
+			Date:    time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC),
+			Subject: "Update golden output",
+		},
+	}
+	newSnap := &files.Snapshot{Title: "Admin Case", Test: "TestUsers", Content: "new"}
+
+	result := pretty.DiffSnapshotBox(oldSnap, newSnap, diff.Histogram("old", "new"), 80)
+
+	want := "accepted: Jane Doe, 2024-05-01 — Update golden output (1a2b3c4)\n"
+	if !strings.Contains(result, want) {
+		t.Errorf("expected %q in output:\n%s", want, result)
+	}
+}
+
+// TestDiffSnapshotBox_Options tests that the applied options are shown along
+// with how they changed since the snapshot was accepted
+func TestDiffSnapshotBox_Options(t *testing.T) {
+	os.Setenv("NO_COLOR", "1")
+	defer os.Unsetenv("NO_COLOR")
+
+	oldSnap := &files.Snapshot{Title: "Admin Case", Test: "TestUsers", Content: "old", Options: []string{"ScrubUUID()", `IgnoreKey("id")`}}
+	newSnap := &files.Snapshot{Title: "Admin Case", Test: "TestUsers", Content: "new", Options: []string{"ScrubUUID()", "ScrubEmail()"}}
+
+	result := pretty.DiffSnapshotBox(oldSnap, newSnap, diff.Histogram("old", "new"), 80)
+
+	for _, want := range []string{
+		"options: ScrubUUID(), ScrubEmail()\n",
+		`options changed: added ScrubEmail(), removed IgnoreKey("id")` + "\n",
+	} {
+		if !strings.Contains(result, want) {
+			t.Errorf("expected %q in output:\n%s", want, result)
+		}
+	}
+
+	oldSnap.Options = newSnap.Options
+	result = pretty.DiffSnapshotBox(oldSnap, newSnap, diff.Histogram("old", "new"), 80)
+	if strings.Contains(result, "options changed") {
+		t.Errorf("expected no option changes when options are unchanged:\n%s", result)
+	}
+}
+
 // TestDiffSnapshotBox_LargeLineNumbers tests proper padding for multi-digit line numbers
 func TestDiffSnapshotBox_LargeLineNumbers(t *testing.T) {
 	os.Unsetenv("NO_COLOR")
@@ -513,7 +568,7 @@ func TestDiffSnapshotBox_LargeLineNumbers(t *testing.T) {
 	diffLines := diff.Histogram(oldContent, newContent)
 	result := pretty.DiffSnapshotBox(oldSnap, newSnap, diffLines)
 
-	stripped := stripANSI(result)
+	stripped := pretty.StripANSI(result)
 
 	// Check that 3-digit line numbers appear
 	if !strings.Contains(stripped, "100") {
@@ -575,7 +630,7 @@ func TestDiffSnapshotBox_UnicodeContent(t *testing.T) {
 	validation := BoxValidation{
 		Title:           "Unicode Test",
 		TestName:        "TestUnicode",
-		FileName:        "unicode_test.snap",
+		FileName:        filepath.Join("__snapshots__", "TestUnicode", "unicode_test.snap"),
 		HasTitle:        true,
 		HasTestName:     true,
 		HasFileName:     true,
@@ -590,6 +645,80 @@ func TestDiffSnapshotBox_UnicodeContent(t *testing.T) {
 	ValidateDiffBox(t, result, validation)
 }
 
+// TestDiffSnapshotBox_WideRunesWrap tests that wrapping respects display width
+// and never splits multi-byte runes
+func TestDiffSnapshotBox_WideRunesWrap(t *testing.T) {
+	os.Setenv("NO_COLOR", "1")
+	defer os.Unsetenv("NO_COLOR")
+
+	width := 40
+	oldContent := "short"
+	newContent := strings.Repeat("世界", 30) + "\n" + strings.Repeat("🎉", 30)
+
+	oldSnap := &files.Snapshot{Title: "Wide Runes", Test: "TestWide", Content: oldContent}
+	newSnap := &files.Snapshot{Title: "Wide Runes", Test: "TestWide", Content: newContent}
+
+	diffLines := diff.Histogram(oldContent, newContent)
+	result := pretty.DiffSnapshotBox(oldSnap, newSnap, diffLines, width)
+
+	if strings.ContainsRune(result, utf8.RuneError) {
+		t.Error("wrapping split a multi-byte rune")
+	}
+
+	wrapped := 0
+	for _, line := range strings.Split(result, "\n") {
+		if !strings.Contains(line, "世") && !strings.Contains(line, "🎉") {
+			continue
+		}
+		wrapped++
+		if w := pretty.DisplayWidth(line); w > width {
+			t.Errorf("line is %d columns wide, exceeds box width %d: %q", w, width, line)
+		}
+	}
+	if wrapped < 4 {
+		t.Errorf("expected wide lines to wrap onto several rows, got %d rows", wrapped)
+	}
+}
+
+// TestNewSnapshotBox_ANSIContentNotWrapped tests that escape sequences in
+// content do not count towards line width
+func TestNewSnapshotBox_ANSIContentNotWrapped(t *testing.T) {
+	os.Setenv("NO_COLOR", "1")
+	defer os.Unsetenv("NO_COLOR")
+
+	// 30 visible columns, but well over 40 bytes once escapes are included
+	line := strings.Repeat("\033[92mok\033[0m ", 10)
+	snap := &files.Snapshot{Title: "ANSI Content", Test: "TestANSI", Content: line}
+
+	result := pretty.NewSnapshotBox(snap, 40)
+
+	rows := 0
+	for _, l := range strings.Split(pretty.StripANSI(result), "\n") {
+		if strings.Contains(l, "ok") {
+			rows++
+		}
+	}
+	if rows != 1 {
+		t.Errorf("expected content to fit on 1 row, got %d rows:\n%s", rows, result)
+	}
+}
+
+func TestDiffSnapshotBox_ANSIEscapesShown(t *testing.T) {
+	os.Setenv("NO_COLOR", "1")
+	defer os.Unsetenv("NO_COLOR")
+
+	old := &files.Snapshot{Title: "Status", Test: "TestStatus", Content: "\033[92mok\033[0m"}
+	newSnap := &files.Snapshot{Title: "Status", Test: "TestStatus", Content: "\033[91mok\033[0m", ContentType: files.ContentANSI}
+
+	result := pretty.DiffSnapshotBox(old, newSnap, diff.Snapshots(old, newSnap), 80)
+
+	for _, want := range []string{"␛[92mok␛[0m", "␛[91mok␛[0m"} {
+		if !strings.Contains(result, want) {
+			t.Errorf("expected changed line %q in diff, got:\n%s", want, result)
+		}
+	}
+}
+
 // TestNewSnapshotBox_Basic tests the new snapshot box rendering
 func TestNewSnapshotBox_Basic(t *testing.T) {
 	os.Unsetenv("NO_COLOR")
@@ -607,7 +736,7 @@ func TestNewSnapshotBox_Basic(t *testing.T) {
 
 	result := pretty.NewSnapshotBox(snap)
 
-	stripped := stripANSI(result)
+	stripped := pretty.StripANSI(result)
 
 	// Check header
 	if !strings.Contains(stripped, "New Snapshot") {
@@ -661,7 +790,7 @@ func TestNewSnapshotBox_EmptyContent(t *testing.T) {
 	result := pretty.NewSnapshotBox(snap)
 
 	// Should still render box with metadata, just no content lines
-	stripped := stripANSI(result)
+	stripped := pretty.StripANSI(result)
 
 	if !strings.Contains(stripped, "title: Empty Snapshot") {
 		t.Error("Expected title in output")
@@ -843,7 +972,7 @@ func TestDiffSnapshotBox_Random_Additions(t *testing.T) {
 			result := pretty.DiffSnapshotBox(oldSnap, newSnap, diffLines)
 
 			// Validate structure
-			stripped := stripANSI(result)
+			stripped := pretty.StripANSI(result)
 
 			// Should have box structure
 			if !strings.Contains(stripped, "┬") {
@@ -910,7 +1039,7 @@ func TestDiffSnapshotBox_Random_Deletions(t *testing.T) {
 			result := pretty.DiffSnapshotBox(oldSnap, newSnap, diffLines)
 
 			// Validate structure
-			stripped := stripANSI(result)
+			stripped := pretty.StripANSI(result)
 
 			if !strings.Contains(stripped, "┬") {
 				t.Error("Missing top bar")
@@ -978,7 +1107,7 @@ func TestDiffSnapshotBox_Random_Mixed(t *testing.T) {
 			result := pretty.DiffSnapshotBox(oldSnap, newSnap, diffLines)
 
 			// Validate basic structure
-			stripped := stripANSI(result)
+			stripped := pretty.StripANSI(result)
 
 			if !strings.Contains(stripped, "┬") {
 				t.Error("Missing top bar")
@@ -1007,3 +1136,185 @@ func randomWord(rng *rand.Rand) string {
 	words := []string{"apple", "banana", "cherry", "date", "elderberry", "fig", "grape", "honeydew"}
 	return words[rng.Intn(len(words))]
 }
+
+func TestDiffSnapshotBox_Style(t *testing.T) {
+	os.Setenv("NO_COLOR", "1")
+	defer os.Unsetenv("NO_COLOR")
+	pretty.SetBoxStyle(pretty.BoxStyle{Legend: true, Removed: "<", Added: ">", Context: ":", HideMetadata: true})
+	defer pretty.SetBoxStyle(pretty.BoxStyle{})
+
+	oldContent := "line1\nline2\nline3"
+	newContent := "line1\nmodified\nline3"
+
+	oldSnap := &files.Snapshot{Title: "Style Test", Test: "TestStyle", Content: oldContent}
+	newSnap := &files.Snapshot{Title: "Style Test", Test: "TestStyle", Content: newContent}
+
+	result := pretty.DiffSnapshotBox(oldSnap, newSnap, diff.Histogram(oldContent, newContent), 60)
+	if strings.Contains(result, "title:") || strings.Contains(result, "│") {
+		t.Errorf("expected no metadata and no default gutter:\n%s", result)
+	}
+
+	shutter.SnapString(t, "diff_box_style", result)
+}
+
+func TestDiffSnapshotBox_Narrow(t *testing.T) {
+	os.Setenv("NO_COLOR", "1")
+	defer os.Unsetenv("NO_COLOR")
+
+	oldContent := "line1\nline2\nline3"
+	newContent := "line1\nmodified\nline3"
+
+	oldSnap := &files.Snapshot{Title: "Narrow Test", Test: "TestNarrow", Content: oldContent}
+	newSnap := &files.Snapshot{Title: "Narrow Test", Test: "TestNarrow", Content: newContent}
+
+	// Too narrow for line numbers, and for the title bar.
+	for _, width := range []int{40, 10} {
+		result := pretty.DiffSnapshotBox(oldSnap, newSnap, diff.Histogram(oldContent, newContent), width)
+		if !strings.Contains(result, "\n──┬") || !strings.Contains(result, "  - line2\n") {
+			t.Errorf("expected a box without line numbers at width %d:\n%s", width, result)
+		}
+	}
+
+	shutter.SnapString(t, "diff_box_narrow", pretty.DiffSnapshotBox(oldSnap, newSnap, diff.Histogram(oldContent, newContent), 40))
+}
+
+func TestDiffSnapshotBox_Accessible(t *testing.T) {
+	os.Unsetenv("NO_COLOR")
+	pretty.SetAccessible(true)
+	defer pretty.SetAccessible(false)
+
+	oldContent := "line1\nline2\nline3"
+	newContent := "line1\nmodified\nline3\nline4"
+
+	oldSnap := &files.Snapshot{Title: "Accessible Test", Test: "TestAccessible", Content: oldContent}
+	newSnap := &files.Snapshot{Title: "Accessible Test", Test: "TestAccessible", Content: newContent}
+
+	result := pretty.DiffSnapshotBox(oldSnap, newSnap, diff.Histogram(oldContent, newContent), 80)
+	if strings.ContainsAny(result, "─│┬┴\x1b") {
+		t.Errorf("expected no box drawing or color in accessible output:\n%s", result)
+	}
+
+	shutter.SnapString(t, "accessible_diff", result)
+}
+
+func TestNewSnapshotBox_Accessible(t *testing.T) {
+	os.Unsetenv("NO_COLOR")
+	pretty.SetAccessible(true)
+	defer pretty.SetAccessible(false)
+
+	snap := &files.Snapshot{Title: "Accessible Test", Test: "TestAccessible", Content: "first\nsecond"}
+	result := pretty.NewSnapshotBox(snap, 80)
+
+	for _, want := range []string{"ADDED: line 1: first\n", "ADDED: line 2: second\n"} {
+		if !strings.Contains(result, want) {
+			t.Errorf("expected %q in output:\n%s", want, result)
+		}
+	}
+	if got := pretty.Success("✓ Snapshot accepted"); got != "Snapshot accepted" {
+		t.Errorf("expected status glyph and color to be dropped, got %q", got)
+	}
+}
+
+// largeSnapshot returns about size bytes of numbered lines.
+func largeSnapshot(size int) string {
+	var sb strings.Builder
+	sb.Grow(size + 64)
+	for i := 0; sb.Len() < size; i++ {
+		fmt.Fprintf(&sb, "line %08d: the quick brown fox jumps over the lazy dog\n", i)
+	}
+	return sb.String()
+}
+
+var benchmarkSizes = []struct {
+	name string
+	size int
+}{
+	{"1MB", 1 << 20},
+	{"10MB", 10 << 20},
+	{"50MB", 50 << 20},
+}
+
+func BenchmarkDiffSnapshotBox(b *testing.B) {
+	for _, bs := range benchmarkSizes {
+		b.Run(bs.name, func(b *testing.B) {
+			if testing.Short() && bs.size > 1<<20 {
+				b.Skip("large input skipped with -short")
+			}
+			old := largeSnapshot(bs.size)
+			// Every line changes, so the box shows removed and added lines.
+			new := strings.ReplaceAll(old, "lazy", "sleepy")
+			diffLines := diff.Histogram(old, new)
+			oldSnap := &files.Snapshot{Title: "large", Test: "TestLarge", Content: old}
+			newSnap := &files.Snapshot{Title: "large", Test: "TestLarge", Content: new}
+
+			b.SetBytes(int64(len(old) + len(new)))
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				pretty.DiffSnapshotBox(oldSnap, newSnap, diffLines, 100)
+			}
+		})
+	}
+}
+
+func BenchmarkNewSnapshotBox(b *testing.B) {
+	for _, bs := range benchmarkSizes {
+		b.Run(bs.name, func(b *testing.B) {
+			if testing.Short() && bs.size > 1<<20 {
+				b.Skip("large input skipped with -short")
+			}
+			snap := &files.Snapshot{Title: "large", Test: "TestLarge", Content: largeSnapshot(bs.size)}
+
+			b.SetBytes(int64(len(snap.Content)))
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				pretty.NewSnapshotBox(snap, 100)
+			}
+		})
+	}
+}
+
+func TestRenderUnified(t *testing.T) {
+	if got := pretty.RenderUnified("same\n", "same\n", pretty.UnifiedOptions{}); got != "" {
+		t.Errorf("expected no diff for equal strings, got:\n%s", got)
+	}
+
+	var oldLines, newLines []string
+	for i := 1; i <= 20; i++ {
+		oldLines = append(oldLines, fmt.Sprintf("line %d", i))
+		newLines = append(newLines, fmt.Sprintf("line %d", i))
+	}
+	newLines[9] = "line 10 changed"
+
+	result := pretty.RenderUnified(strings.Join(oldLines, "\n"), strings.Join(newLines, "\n"), pretty.UnifiedOptions{
+		OldLabel: "want",
+		NewLabel: "got",
+		Context:  2,
+		Width:    60,
+		NoColor:  true,
+	})
+	if strings.Contains(result, "\x1b[") {
+		t.Errorf("expected no color with NoColor:\n%s", result)
+	}
+	for _, want := range []string{"- want", "+ got", "line 8", "line 10 changed", "line 12", "… 7 unchanged line(s)", "… 8 unchanged line(s)"} {
+		if !strings.Contains(result, want) {
+			t.Errorf("expected %q in diff:\n%s", want, result)
+		}
+	}
+	if strings.Contains(result, "│ line 7\n") || strings.Contains(result, "│ line 13\n") {
+		t.Errorf("expected lines outside the context to be collapsed:\n%s", result)
+	}
+}
+
+func TestRenderUnifiedAccessible(t *testing.T) {
+	pretty.SetAccessible(true)
+	defer pretty.SetAccessible(false)
+
+	result := pretty.RenderUnified("a\nb\n", "a\nc\n", pretty.UnifiedOptions{OldLabel: "want", NewLabel: "got"})
+	for _, want := range []string{"REMOVED: want", "ADDED: got", "REMOVED: line 2: b", "ADDED: line 2: c"} {
+		if !strings.Contains(result, want) {
+			t.Errorf("expected %q in accessible diff:\n%s", want, result)
+		}
+	}
+}
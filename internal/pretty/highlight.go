@@ -0,0 +1,42 @@
+package pretty
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/ptdewey/shutter/internal/diff"
+	"github.com/ptdewey/shutter/internal/files"
+)
+
+// jsonKeyPattern matches the key of a member on a line of indented JSON.
+var jsonKeyPattern = regexp.MustCompile(`^(\s*)("(?:[^"\\]|\\.)*")(:)`)
+
+// diffText returns the text shown for a diff line of content of the given
+// type. Escape sequences in changed lines of ANSI content are shown as text,
+// so a change of color alone is visible; shared lines keep their colors.
+func diffText(line string, kind diff.DiffKind, contentType string) string {
+	if contentType == files.ContentANSI && kind != diff.DiffShared {
+		return visibleEscapes(line)
+	}
+	return line
+}
+
+// highlight colors a shared line of content of the given type. Member keys
+// of JSON content are shown in blue; other content is left as is.
+func highlight(p painter, line, contentType string) string {
+	if contentType != files.ContentJSON {
+		return line
+	}
+	m := jsonKeyPattern.FindStringSubmatchIndex(line)
+	if m == nil {
+		return line
+	}
+	return line[:m[4]] + p.paint(line[m[4]:m[5]], colorBlue) + line[m[5]:]
+}
+
+// visibleEscapes replaces the escape character starting each ANSI escape
+// sequence in s with the ␛ symbol, so the sequence is shown rather than
+// interpreted by the terminal.
+func visibleEscapes(s string) string {
+	return strings.ReplaceAll(s, "\x1b", "␛")
+}
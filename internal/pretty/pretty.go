@@ -2,6 +2,7 @@ package pretty
 
 import (
 	"os"
+	"regexp"
 	"strconv"
 )
 
@@ -15,6 +16,16 @@ const (
 	colorBold   = "\033[1m"
 )
 
+// ansiPattern matches ANSI escape sequences: CSI sequences (colors, cursor
+// movement), OSC sequences (titles, hyperlinks) terminated by BEL or ST, and
+// two-character escapes.
+var ansiPattern = regexp.MustCompile(`\x1b\[[0-9:;<=>?]*[ -/]*[@-~]|\x1b\][^\x07\x1b]*(?:\x07|\x1b\\)|\x1b[@-Z\\-_]`)
+
+// StripANSI removes ANSI escape sequences from s.
+func StripANSI(s string) string {
+	return ansiPattern.ReplaceAllString(s, "")
+}
+
 func TerminalWidth() int {
 	width := os.Getenv("COLUMNS")
 	if w, err := strconv.Atoi(width); err == nil && w > 0 {
@@ -24,12 +35,25 @@ func TerminalWidth() int {
 }
 
 func hasColor() bool {
-	return os.Getenv("NO_COLOR") == ""
+	return os.Getenv("NO_COLOR") == "" && !accessible
 }
 
 // colorize wraps text with the given color code
 func colorize(s, code string) string {
-	if !hasColor() {
+	return newPainter().paint(s, code)
+}
+
+// painter colors text like colorize, but checks whether color is enabled
+// only once, when it is created. Boxes use one per render rather than
+// looking up NO_COLOR for every line.
+type painter bool
+
+func newPainter() painter {
+	return painter(hasColor())
+}
+
+func (p painter) paint(s, code string) string {
+	if !p {
 		return s
 	}
 	return code + s + colorReset
@@ -52,6 +76,9 @@ func Blue(s string) string {
 }
 
 func Gray(s string) string {
+	if accessible {
+		return plainStatus(s)
+	}
 	return colorize(s, colorGray)
 }
 
@@ -64,13 +91,22 @@ func Header(text string) string {
 }
 
 func Success(text string) string {
+	if accessible {
+		return plainStatus(text)
+	}
 	return Green(text)
 }
 
 func Error(text string) string {
+	if accessible {
+		return plainStatus(text)
+	}
 	return Red(text)
 }
 
 func Warning(text string) string {
+	if accessible {
+		return plainStatus(text)
+	}
 	return Yellow(text)
 }
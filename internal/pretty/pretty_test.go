@@ -121,6 +121,48 @@ func TestWarning(t *testing.T) {
 	}
 }
 
+func TestStripANSI(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{"plain", "no escapes", "no escapes"},
+		{"color", "\033[91mred\033[0m text", "red text"},
+		{"bold and color", "\033[1m\033[94mheader\033[0m", "header"},
+		{"256 color", "\033[38;5;208morange\033[0m", "orange"},
+		{"cursor movement", "\033[2Kline\033[1A", "line"},
+		{"hyperlink", "\033]8;;https://example.com\033\\link\033]8;;\033\\", "link"},
+		{"title with bell", "\033]0;title\007text", "text"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := pretty.StripANSI(tt.input); got != tt.expected {
+				t.Errorf("StripANSI(%q) = %q, want %q", tt.input, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestDisplayWidth(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int
+	}{
+		{"hello", 5},
+		{"世界", 4},
+		{"\033[92mgreen\033[0m", 5},
+		{"", 0},
+	}
+
+	for _, tt := range tests {
+		if got := pretty.DisplayWidth(tt.input); got != tt.expected {
+			t.Errorf("DisplayWidth(%q) = %d, want %d", tt.input, got, tt.expected)
+		}
+	}
+}
+
 func TestTerminalWidth(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -0,0 +1,110 @@
+package pretty
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ptdewey/shutter/internal/diff"
+	"github.com/ptdewey/shutter/internal/files"
+)
+
+// UnifiedOptions configures RenderUnified.
+type UnifiedOptions struct {
+	// OldLabel and NewLabel name the two sides above the diff, such as
+	// "want" and "got". No header is written if both are empty.
+	OldLabel string
+	NewLabel string
+
+	// Context, if positive, is the number of unchanged lines shown around
+	// each change. Other unchanged lines are collapsed. By default every
+	// line is shown.
+	Context int
+
+	// JSON diffs a and b as indented JSON documents, ignoring the commas
+	// that separate members, and highlights them as JSON.
+	JSON bool
+
+	// Width is the width of the rendering. By default it is the terminal
+	// width.
+	Width int
+
+	// NoColor renders without ANSI colors, as NO_COLOR does.
+	NoColor bool
+}
+
+// RenderUnified renders the line diff from a to b in the style of snapshot
+// diffs, or returns "" if a and b are equal.
+func RenderUnified(a, b string, opts UnifiedOptions) string {
+	if a == b {
+		return ""
+	}
+
+	contentType := files.ContentText
+	var diffLines []diff.DiffLine
+	if opts.JSON {
+		contentType = files.ContentJSON
+		diffLines = diff.JSON(a, b)
+	} else {
+		diffLines = diff.Histogram(a, b)
+	}
+	var shown []bool
+	if opts.Context > 0 {
+		shown = contextLines(diffLines, opts.Context)
+	}
+
+	p := newPainter()
+	if opts.NoColor {
+		p = false
+	}
+
+	var sb strings.Builder
+	if accessible {
+		writeUnifiedLabels(&sb, p, opts)
+		for i, dl := range diffLines {
+			if shown != nil && !shown[i] {
+				continue
+			}
+			number := dl.NewNumber
+			if dl.Kind == diff.DiffOld {
+				number = dl.OldNumber
+			}
+			sb.WriteString(fmt.Sprintf("%s: line %d: %s\n", diffLabels[dl.Kind], number, dl.Line))
+		}
+		return sb.String()
+	}
+
+	width := TerminalWidth()
+	if opts.Width > 0 {
+		width = opts.Width
+	}
+	writeUnifiedLabels(&sb, p, opts)
+	writeDiffRows(&sb, p, defaultGutter, diffLines, width, contentType, shown)
+	return sb.String()
+}
+
+// writeUnifiedLabels writes the names of the two sides of a diff, if set.
+func writeUnifiedLabels(sb *strings.Builder, p painter, opts UnifiedOptions) {
+	if opts.OldLabel == "" && opts.NewLabel == "" {
+		return
+	}
+	if accessible {
+		sb.WriteString(fmt.Sprintf("REMOVED: %s\nADDED: %s\n\n", opts.OldLabel, opts.NewLabel))
+		return
+	}
+	sb.WriteString(p.paint("  - "+opts.OldLabel, colorRed) + "\n")
+	sb.WriteString(p.paint("  + "+opts.NewLabel, colorGreen) + "\n\n")
+}
+
+// contextLines marks the diff lines within context lines of a change.
+func contextLines(diffLines []diff.DiffLine, context int) []bool {
+	shown := make([]bool, len(diffLines))
+	for i, dl := range diffLines {
+		if dl.Kind == diff.DiffShared {
+			continue
+		}
+		for j := max(0, i-context); j <= min(len(diffLines)-1, i+context); j++ {
+			shown[j] = true
+		}
+	}
+	return shown
+}
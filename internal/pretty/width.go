@@ -0,0 +1,55 @@
+package pretty
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"github.com/mattn/go-runewidth"
+)
+
+// DisplayWidth returns the number of terminal columns s occupies. ANSI
+// escape sequences take up no space, and wide runes such as CJK characters
+// and most emoji take up two columns.
+func DisplayWidth(s string) int {
+	return runewidth.StringWidth(StripANSI(s))
+}
+
+// wrapDisplay splits s into chunks that each fit within width terminal
+// columns. Runes are never split, and ANSI escape sequences are kept intact
+// without counting towards the width. The result always has at least one
+// element, so an empty line still produces a row.
+func wrapDisplay(s string, width int) []string {
+	// No rune is wider than its UTF-8 encoding and escapes have no width,
+	// so a line with at most width bytes always fits.
+	if len(s) <= width {
+		return []string{s}
+	}
+
+	escapes := ansiPattern.FindAllStringIndex(s, -1)
+
+	var chunks []string
+	var current strings.Builder
+	currentWidth := 0
+
+	for i := 0; i < len(s); {
+		if len(escapes) > 0 && escapes[0][0] == i {
+			current.WriteString(s[i:escapes[0][1]])
+			i = escapes[0][1]
+			escapes = escapes[1:]
+			continue
+		}
+
+		r, size := utf8.DecodeRuneInString(s[i:])
+		rw := runewidth.RuneWidth(r)
+		if currentWidth+rw > width && currentWidth > 0 {
+			chunks = append(chunks, current.String())
+			current.Reset()
+			currentWidth = 0
+		}
+		current.WriteString(s[i : i+size])
+		currentWidth += rw
+		i += size
+	}
+
+	return append(chunks, current.String())
+}
@@ -0,0 +1,77 @@
+package review
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ptdewey/shutter/internal/files"
+	"github.com/ptdewey/shutter/internal/pretty"
+)
+
+// AuditLog lists the entries of the project's audit log, oldest first.
+// Entries are filtered to titles containing name, to those recorded within
+// since (an age such as "30d"), and to action ("accept" or "reject"); empty
+// filters match every entry.
+func AuditLog(name, since, action string) error {
+	if action != "" && action != files.AuditAccept && action != files.AuditReject {
+		return fmt.Errorf("invalid action %q, expected %s or %s", action, files.AuditAccept, files.AuditReject)
+	}
+	var cutoff time.Time
+	if since != "" {
+		age, err := ParseAge(since)
+		if err != nil {
+			return err
+		}
+		cutoff = time.Now().Add(-age)
+	}
+
+	entries, err := files.ReadAuditLog()
+	if err != nil {
+		return err
+	}
+
+	var matched []files.AuditEntry
+	for _, entry := range entries {
+		if strings.Contains(entry.Title, name) &&
+			(action == "" || entry.Action == action) &&
+			!entry.Time.Before(cutoff) {
+			matched = append(matched, entry)
+		}
+	}
+
+	if len(matched) == 0 {
+		if len(entries) == 0 {
+			fmt.Fprintf(out, "No audit log entries; enable the log with audit_log = true in %s\n", files.ConfigFile)
+		} else {
+			fmt.Fprintln(out, "No matching audit log entries")
+		}
+		return nil
+	}
+
+	fmt.Fprintln(out, pretty.Header("Audit Log"))
+	for _, entry := range matched {
+		action := pretty.Green(fmt.Sprintf("%-6s", entry.Action))
+		if entry.Action == files.AuditReject {
+			action = pretty.Red(fmt.Sprintf("%-6s", entry.Action))
+		}
+		fmt.Printf("  %s  %s  %s  %s  %s\n",
+			pretty.Gray(entry.Time.Local().Format("2006-01-02 15:04:05")),
+			action,
+			entry.Title,
+			pretty.Gray(shortDigest(entry.Before)+" → "+shortDigest(entry.After)),
+			pretty.Gray(entry.User),
+		)
+	}
+	return nil
+}
+
+// shortDigest abbreviates a content digest for display, showing "new" for
+// a snapshot that had no accepted content.
+func shortDigest(digest string) string {
+	if digest == "" {
+		return "new"
+	}
+	digest = digest[strings.Index(digest, ":")+1:]
+	return digest[:min(len(digest), 12)]
+}
@@ -0,0 +1,275 @@
+package review
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/ptdewey/shutter/internal/files"
+	"github.com/ptdewey/shutter/internal/pretty"
+)
+
+// Import paths of the packages whose functions take snapshots.
+const (
+	shutterPath = "github.com/ptdewey/shutter"
+	freezePath  = "github.com/ptdewey/shutter/freeze"
+	grpcPath    = "github.com/ptdewey/shutter/shuttergrpc"
+)
+
+// testFuncPattern matches the names go test runs: Test, Benchmark or Fuzz
+// not followed by a lower-case letter.
+var testFuncPattern = regexp.MustCompile(`^(Test|Benchmark|Fuzz)([^\p{Ll}].*)?$`)
+
+// PackageCoverage is the snapshot usage of the tests of one package.
+type PackageCoverage struct {
+	Dir              string
+	WithSnapshots    []string // Tests that call a Snap function
+	WithoutSnapshots []string // Tests that do not
+	Drifted          []DriftedTest
+}
+
+// DriftedTest is a test that has accepted snapshots but no longer takes
+// any, or no longer exists.
+type DriftedTest struct {
+	Test      string
+	Snapshots int
+	Missing   bool // The test function no longer exists
+}
+
+// Coverage lists, per package, the tests that take snapshots and those that
+// do not, followed by the tests whose accepted snapshots no Snap call takes
+// anymore. Calls are found by scanning the test files; helpers are followed
+// within a package but not into other packages.
+func Coverage() error {
+	testFiles, err := files.ListTestFiles()
+	if err != nil {
+		return err
+	}
+	accepted, err := files.ListAccepted()
+	if err != nil {
+		return err
+	}
+	report, err := coverage(testFiles, accepted)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintln(out, pretty.Header("Snapshot Coverage"))
+	var with, total int
+	var drifted []PackageCoverage
+	for _, pkg := range report {
+		if len(pkg.Drifted) > 0 {
+			drifted = append(drifted, pkg)
+		}
+		tests := len(pkg.WithSnapshots) + len(pkg.WithoutSnapshots)
+		if tests == 0 {
+			continue
+		}
+		with += len(pkg.WithSnapshots)
+		total += tests
+		fmt.Fprintf(out, "%s: %d/%d tests take snapshots\n", files.DisplayPath(pkg.Dir), len(pkg.WithSnapshots), tests)
+		if len(pkg.WithoutSnapshots) > 0 {
+			fmt.Fprintf(out, "  without snapshots: %s\n", strings.Join(pkg.WithoutSnapshots, ", "))
+		}
+	}
+
+	if total == 0 {
+		fmt.Fprintln(out, pretty.Warning("No tests found"))
+	} else {
+		fmt.Fprintf(out, pretty.Success("✓ %d of %d tests (%d%%) take snapshots\n"), with, total, with*100/total)
+	}
+
+	if len(drifted) > 0 {
+		fmt.Fprintln(out)
+		fmt.Fprintln(out, pretty.Header("Snapshots Without a Snap Call"))
+		for _, pkg := range drifted {
+			for _, d := range pkg.Drifted {
+				reason := "no longer takes snapshots"
+				if d.Missing {
+					reason = "no longer exists"
+				}
+				fmt.Fprintf(out, "%s: %s has %d accepted snapshot(s) but %s\n", files.DisplayPath(pkg.Dir), d.Test, d.Snapshots, reason)
+			}
+		}
+		fmt.Fprintln(out, pretty.Warning("Remove them with shutter prune --orphaned or shutter clean --from-manifest"))
+	}
+	return nil
+}
+
+// coverage scans the test files of each package, keyed by directory, and
+// matches the accepted snapshots against the tests found. Packages are
+// sorted by directory.
+func coverage(testFiles map[string][]string, accepted []files.AcceptedSnapshot) ([]PackageCoverage, error) {
+	// Accepted snapshots per package and top-level test. Snapshots that do
+	// not record their test are left out.
+	snapshots := map[string]map[string]int{}
+	for _, a := range accepted {
+		if a.Snapshot.Test == "" {
+			continue
+		}
+		dir := filepath.Dir(a.Info.Dir)
+		if snapshots[dir] == nil {
+			snapshots[dir] = map[string]int{}
+		}
+		test, _, _ := strings.Cut(a.Snapshot.Test, "/")
+		snapshots[dir][test]++
+	}
+
+	dirs := make([]string, 0, len(testFiles))
+	for dir := range testFiles {
+		dirs = append(dirs, dir)
+	}
+	for dir := range snapshots {
+		if _, ok := testFiles[dir]; !ok {
+			dirs = append(dirs, dir)
+		}
+	}
+	sort.Strings(dirs)
+
+	report := make([]PackageCoverage, 0, len(dirs))
+	for _, dir := range dirs {
+		tests, err := scanTests(testFiles[dir])
+		if err != nil {
+			return nil, err
+		}
+
+		pkg := PackageCoverage{Dir: dir}
+		for name, snaps := range tests {
+			if snaps {
+				pkg.WithSnapshots = append(pkg.WithSnapshots, name)
+			} else {
+				pkg.WithoutSnapshots = append(pkg.WithoutSnapshots, name)
+			}
+		}
+		sort.Strings(pkg.WithSnapshots)
+		sort.Strings(pkg.WithoutSnapshots)
+
+		for test, count := range snapshots[dir] {
+			snaps, ok := tests[test]
+			if !snaps {
+				pkg.Drifted = append(pkg.Drifted, DriftedTest{Test: test, Snapshots: count, Missing: !ok})
+			}
+		}
+		sort.Slice(pkg.Drifted, func(i, j int) bool { return pkg.Drifted[i].Test < pkg.Drifted[j].Test })
+
+		report = append(report, pkg)
+	}
+	return report, nil
+}
+
+// scanTests parses the test files of a package and reports, for each test
+// function, whether it takes snapshots: whether it calls a Snap function,
+// directly, in a closure, or through functions of the package that do.
+func scanTests(paths []string) (map[string]bool, error) {
+	fset := token.NewFileSet()
+	snaps := map[string]bool{}     // Functions that call a Snap function
+	calls := map[string][]string{} // Package functions each function calls
+	var tests []string
+
+	for _, path := range paths {
+		file, err := parser.ParseFile(fset, path, nil, parser.SkipObjectResolution)
+		if err != nil {
+			return nil, err
+		}
+		imports, dotImports := shutterImports(file)
+		if file.Name.Name == "shutter" {
+			// Shutter's own tests call its functions unqualified.
+			dotImports = append(dotImports, shutterPath)
+		}
+
+		for _, decl := range file.Decls {
+			fn, ok := decl.(*ast.FuncDecl)
+			if !ok || fn.Recv != nil || fn.Body == nil {
+				continue
+			}
+			name := fn.Name.Name
+			if name != "TestMain" && testFuncPattern.MatchString(name) {
+				tests = append(tests, name)
+			}
+
+			ast.Inspect(fn.Body, func(n ast.Node) bool {
+				call, ok := n.(*ast.CallExpr)
+				if !ok {
+					return true
+				}
+				switch f := ast.Unparen(call.Fun).(type) {
+				case *ast.Ident:
+					for _, path := range dotImports {
+						if takesSnapshots(path, f.Name) {
+							snaps[name] = true
+						}
+					}
+					calls[name] = append(calls[name], f.Name)
+				case *ast.SelectorExpr:
+					if x, ok := f.X.(*ast.Ident); ok && takesSnapshots(imports[x.Name], f.Sel.Name) {
+						snaps[name] = true
+					}
+				}
+				return true
+			})
+		}
+	}
+
+	// Follow calls to package functions until nothing changes.
+	for changed := true; changed; {
+		changed = false
+		for name, callees := range calls {
+			if snaps[name] {
+				continue
+			}
+			for _, callee := range callees {
+				if snaps[callee] {
+					snaps[name] = true
+					changed = true
+					break
+				}
+			}
+		}
+	}
+
+	result := make(map[string]bool, len(tests))
+	for _, test := range tests {
+		result[test] = snaps[test]
+	}
+	return result, nil
+}
+
+// shutterImports returns the names under which file imports shutter's
+// packages, mapped to their import paths, and the paths it dot-imports.
+func shutterImports(file *ast.File) (names map[string]string, dotImports []string) {
+	names = map[string]string{}
+	for _, imp := range file.Imports {
+		path, err := strconv.Unquote(imp.Path.Value)
+		if err != nil || (path != shutterPath && path != freezePath && path != grpcPath) {
+			continue
+		}
+		name := filepath.Base(path)
+		if imp.Name != nil {
+			name = imp.Name.Name
+		}
+		if name == "." {
+			dotImports = append(dotImports, path)
+		} else {
+			names[name] = path
+		}
+	}
+	return names, dotImports
+}
+
+// takesSnapshots reports whether the function name of the shutter package
+// at path records snapshots.
+func takesSnapshots(path, name string) bool {
+	switch path {
+	case shutterPath, freezePath:
+		return strings.HasPrefix(name, "Snap")
+	case grpcPath:
+		return name == "SnapCall" || strings.HasSuffix(name, "Interceptor")
+	}
+	return false
+}
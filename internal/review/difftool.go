@@ -0,0 +1,240 @@
+package review
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"text/template"
+
+	"github.com/ptdewey/shutter/internal/files"
+	"github.com/ptdewey/shutter/internal/pretty"
+)
+
+// DiffToolEnv names the environment variable holding the command that opens
+// a snapshot in an external diff tool, such as "code --diff --wait" or
+// "meld". It takes precedence over diff_tool in the config file.
+const DiffToolEnv = "SHUTTER_DIFFTOOL"
+
+// defaultDiffTool hands the files to git, which opens whatever diff.tool
+// the reviewer configured, without asking first.
+const defaultDiffTool = "git difftool --no-index --no-prompt"
+
+// defaultMergeTool hands a conflicted snapshot to git, which opens whatever
+// merge.tool the reviewer configured.
+const defaultMergeTool = "git mergetool --no-prompt {{.Merged}}"
+
+// diffFiles are the files passed to a diff tool.
+type diffFiles struct {
+	Old, New string
+}
+
+// mergeFiles are the files passed to a merge tool. The tool writes its
+// result to Merged.
+type mergeFiles struct {
+	Base, Ours, Theirs, Merged string
+}
+
+// toolCommand returns the command for a tool configured by setting as
+// command. Each argument is a text/template executed with paths, so
+// "{{.New}}" is replaced by the path of the new file; when no argument
+// refers to the files, the fields of paths are appended in order.
+func toolCommand(setting, command string, paths any) (*exec.Cmd, error) {
+	var args []string
+	templated := false
+	for _, field := range strings.Fields(command) {
+		if !strings.Contains(field, "{{") {
+			args = append(args, field)
+			continue
+		}
+		templated = true
+		tmpl, err := template.New(setting).Option("missingkey=error").Parse(field)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", setting, err)
+		}
+		var sb strings.Builder
+		if err := tmpl.Execute(&sb, paths); err != nil {
+			return nil, fmt.Errorf("%s: %w", setting, err)
+		}
+		args = append(args, sb.String())
+	}
+	if len(args) == 0 {
+		return nil, fmt.Errorf("%s is empty", setting)
+	}
+	if !templated {
+		args = append(args, toolArgs(paths)...)
+	}
+
+	cmd := exec.Command(args[0], args[1:]...)
+	if cmd.Err != nil {
+		return nil, fmt.Errorf("%s %q not found: %w", setting, args[0], cmd.Err)
+	}
+	return cmd, nil
+}
+
+// toolArgs returns paths in the order they are appended to a tool that does
+// not refer to them.
+func toolArgs(paths any) []string {
+	switch f := paths.(type) {
+	case diffFiles:
+		return []string{f.Old, f.New}
+	case mergeFiles:
+		return []string{f.Base, f.Ours, f.Theirs, f.Merged}
+	}
+	return nil
+}
+
+// diffToolCommand returns the command that opens old and new side by side:
+// $SHUTTER_DIFFTOOL, else diff_tool from the config file, else git
+// difftool.
+func diffToolCommand(old, new string) (*exec.Cmd, error) {
+	if tool := strings.TrimSpace(os.Getenv(DiffToolEnv)); tool != "" {
+		return toolCommand(DiffToolEnv, tool, diffFiles{Old: old, New: new})
+	}
+	cfg, err := files.LoadConfig()
+	if err != nil {
+		return nil, err
+	}
+	if cfg.DiffTool != "" {
+		return toolCommand("diff_tool", cfg.DiffTool, diffFiles{Old: old, New: new})
+	}
+	return toolCommand("diff tool", defaultDiffTool, diffFiles{Old: old, New: new})
+}
+
+// DiffToolCommand returns the command that opens the accepted and pending
+// files of a snapshot side by side in the configured diff tool. A new
+// snapshot is compared against an empty file.
+func DiffToolCommand(info files.SnapshotInfo) (*exec.Cmd, error) {
+	accepted := info.AcceptedPath()
+	if _, err := os.Stat(accepted); os.IsNotExist(err) {
+		accepted = os.DevNull
+	}
+	return diffToolCommand(accepted, info.Path)
+}
+
+// OpenDiffTool opens a snapshot in the diff tool and waits for it to exit.
+func OpenDiffTool(info files.SnapshotInfo) error {
+	cmd, err := DiffToolCommand(info)
+	if err != nil {
+		return err
+	}
+	return runTool(cmd)
+}
+
+// runTool runs an external tool attached to the terminal. git difftool
+// exits non-zero when the files differ, so only a failure to start the tool
+// is an error.
+func runTool(cmd *exec.Cmd) error {
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	if err := cmd.Run(); err != nil {
+		var exitErr *exec.ExitError
+		if !errors.As(err, &exitErr) {
+			return err
+		}
+	}
+	return nil
+}
+
+// DiffExternal opens an earlier accepted version of a snapshot and the
+// current one in the configured diff tool, like Diff. The earlier version
+// is written to a temporary file for the tool.
+func DiffExternal(name, against string) error {
+	old, current, version, err := diffVersions(name, against)
+	if err != nil {
+		return err
+	}
+
+	dir, err := os.MkdirTemp("", "shutter-diff-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+
+	oldPath := filepath.Join(dir, fmt.Sprintf("%s.v%d", filepath.Base(current.Path), version))
+	if err := os.WriteFile(oldPath, []byte(old.EncodeFile(current.Path)), 0644); err != nil {
+		return err
+	}
+
+	cmd, err := diffToolCommand(oldPath, current.Path)
+	if err != nil {
+		return err
+	}
+	return runTool(cmd)
+}
+
+// Resolve resolves git merge conflicts in an accepted snapshot with the
+// merge tool from the config file, or git mergetool. The ancestor, our and
+// their versions of the conflicting hunks are written to temporary files
+// for the tool; once it leaves no conflicts behind, the snapshot's digest
+// is updated to match the merged content.
+func Resolve(name string) error {
+	path, err := files.FindAccepted(name)
+	if err != nil {
+		return err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	if !files.HasConflicts(string(data)) {
+		return fmt.Errorf("%s has no merge conflicts", files.DisplayPath(path))
+	}
+
+	dir, err := os.MkdirTemp("", "shutter-merge-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+
+	base, ours, theirs := files.SplitConflicts(string(data))
+	merge := mergeFiles{Merged: path}
+	for _, version := range []struct {
+		path    *string
+		suffix  string
+		content string
+	}{
+		{&merge.Base, "BASE", base},
+		{&merge.Ours, "OURS", ours},
+		{&merge.Theirs, "THEIRS", theirs},
+	} {
+		*version.path = filepath.Join(dir, filepath.Base(path)+"."+version.suffix)
+		if err := os.WriteFile(*version.path, []byte(version.content), 0644); err != nil {
+			return err
+		}
+	}
+
+	cfg, err := files.LoadConfig()
+	if err != nil {
+		return err
+	}
+	setting, tool := "merge_tool", cfg.MergeTool
+	if tool == "" {
+		setting, tool = "merge tool", defaultMergeTool
+	}
+	cmd, err := toolCommand(setting, tool, merge)
+	if err != nil {
+		return err
+	}
+	if err := runTool(cmd); err != nil {
+		return err
+	}
+
+	data, err = os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	if files.HasConflicts(string(data)) {
+		return fmt.Errorf("%s still has merge conflicts", files.DisplayPath(path))
+	}
+	snap, err := files.DecodeFile(path, string(data))
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, []byte(snap.EncodeFile(path)), 0644); err != nil {
+		return err
+	}
+	fmt.Fprintln(out, pretty.Success("✓ Resolved "+files.DisplayPath(path)))
+	return nil
+}
@@ -0,0 +1,32 @@
+package review
+
+import (
+	"fmt"
+
+	"github.com/ptdewey/shutter/internal/files"
+	"github.com/ptdewey/shutter/internal/pretty"
+)
+
+// Flakes reports the snapshots that produced more than one distinct content
+// across the runs recorded with SHUTTER_DETECT_FLAKES, showing how the first
+// two contents differ.
+func Flakes() error {
+	flakes, err := files.ListFlakySnapshots()
+	if err != nil {
+		return err
+	}
+
+	if len(flakes) == 0 {
+		fmt.Fprintln(out, pretty.Success("✓ No flaky snapshots found"))
+		return nil
+	}
+
+	fmt.Fprintln(out, pretty.Header("Flaky Snapshots"))
+	for _, flake := range flakes {
+		fmt.Printf("%s: %d distinct contents in %d runs\n", flake.Info.Title, len(flake.Contents), flake.Runs)
+		first := &files.Snapshot{Title: flake.Info.Title, Content: flake.Contents[0], Path: flake.Info.Path}
+		second := &files.Snapshot{Title: flake.Info.Title, Content: flake.Contents[1], Path: flake.Info.Path}
+		printDiff(first, second)
+	}
+	return fmt.Errorf("%d snapshot(s) changed between runs; make their tests deterministic or scrub the changing values", len(flakes))
+}
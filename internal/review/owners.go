@@ -0,0 +1,74 @@
+package review
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ptdewey/shutter/internal/files"
+	"github.com/ptdewey/shutter/internal/pretty"
+)
+
+// mine limits review to the snapshots owned by the current user's teams.
+var mine bool
+
+// SetMine limits the commands that act on pending snapshots to those owned,
+// per CODEOWNERS or the owners setting of .shutterconfig, by one of the
+// current user's teams (see files.MyTeams).
+func SetMine(on bool) {
+	mine = on
+}
+
+// inMine returns the snapshots owned by the current user's teams if SetMine
+// is on, or all of snapshots otherwise.
+func inMine(snapshots []files.SnapshotInfo) ([]files.SnapshotInfo, error) {
+	if !mine {
+		return snapshots, nil
+	}
+
+	teams, err := files.MyTeams()
+	if err != nil {
+		return nil, err
+	}
+	if len(teams) == 0 {
+		return nil, fmt.Errorf("--mine needs to know your teams: set teams = @you @org/team in ~/.config/shutter/%s, or git user.email", files.ConfigFile)
+	}
+	owners, err := files.LoadOwners()
+	if err != nil {
+		return nil, err
+	}
+
+	var owned []files.SnapshotInfo
+	for _, info := range snapshots {
+		if owners.OwnedBy(info.Path, teams) {
+			owned = append(owned, info)
+		}
+	}
+	return owned, nil
+}
+
+// OwnerLabel describes who owns the pending snapshot info, e.g.
+// " (owned by @acme/billing)", or returns "" if no one does. The owner rules
+// are read on every call.
+func OwnerLabel(info files.SnapshotInfo) string {
+	owners, err := files.LoadOwners()
+	if err != nil {
+		return ""
+	}
+	return ownerLabel(owners, info)
+}
+
+// ownerNote is the OwnerLabel of info in gray, for printed reviews.
+func ownerNote(owners *files.Owners, info files.SnapshotInfo) string {
+	if label := ownerLabel(owners, info); label != "" {
+		return pretty.Gray(label)
+	}
+	return ""
+}
+
+func ownerLabel(owners *files.Owners, info files.SnapshotInfo) string {
+	names := owners.Of(info.Path)
+	if len(names) == 0 {
+		return ""
+	}
+	return " (owned by " + strings.Join(names, ", ") + ")"
+}
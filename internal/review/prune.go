@@ -0,0 +1,159 @@
+package review
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ptdewey/shutter/internal/files"
+	"github.com/ptdewey/shutter/internal/pretty"
+)
+
+// Prune lists the accepted snapshots that meet criteria and deletes them,
+// after confirmation unless yes is set. With dryRun nothing is deleted.
+func Prune(criteria files.PruneCriteria, dryRun, yes bool) error {
+	candidates, err := files.FindPruneCandidates(criteria)
+	if err != nil {
+		return err
+	}
+
+	if len(candidates) == 0 {
+		fmt.Fprintln(out, pretty.Success("✓ No snapshots to prune"))
+		return nil
+	}
+
+	fmt.Fprintln(out, pretty.Header("Prune Snapshots"))
+	var total int64
+	for _, c := range candidates {
+		total += c.Size
+		details := []string{FormatSize(c.Size), "accepted " + c.AcceptedAt.Local().Format("2006-01-02")}
+		if c.Orphaned {
+			details = append(details, "orphaned: "+c.Test+" not found")
+		}
+		if c.Untouched {
+			details = append(details, "not in the last run's manifest")
+		}
+		fmt.Printf("%s  %s\n", files.DisplayPath(c.Path), pretty.Gray(strings.Join(details, ", ")))
+	}
+
+	if dryRun {
+		fmt.Fprintf(out, "Would delete %d snapshot(s), %s\n", len(candidates), FormatSize(total))
+		return nil
+	}
+
+	if !yes {
+		question := fmt.Sprintf("Delete %d snapshot(s), %s?", len(candidates), FormatSize(total))
+		if ok, err := confirmPrompt(os.Stdin, os.Stdout, question); err != nil || !ok {
+			return err
+		}
+	}
+
+	for _, c := range candidates {
+		if err := files.PruneSnapshot(c.Path); err != nil {
+			return err
+		}
+	}
+
+	fmt.Fprintf(out, pretty.Success("✓ Pruned %d snapshot(s), %s\n"), len(candidates), FormatSize(total))
+	return nil
+}
+
+// PruneFlags runs Prune with criteria given as command line flag values.
+// Empty ages and sizes are ignored.
+func PruneFlags(olderThan, largerThan string, orphaned, dryRun, yes bool) error {
+	criteria := files.PruneCriteria{Orphaned: orphaned}
+
+	if olderThan != "" {
+		age, err := ParseAge(olderThan)
+		if err != nil {
+			return err
+		}
+		criteria.OlderThan = age
+	}
+	if largerThan != "" {
+		size, err := ParseSize(largerThan)
+		if err != nil {
+			return err
+		}
+		criteria.LargerThan = size
+	}
+
+	if criteria == (files.PruneCriteria{}) {
+		return fmt.Errorf("prune needs at least one of --older-than, --larger-than or --orphaned")
+	}
+	return Prune(criteria, dryRun, yes)
+}
+
+// Clean deletes the accepted snapshots that the last test run recorded with
+// SHUTTER_MANIFEST=1 did not compare. Packages without a manifest are left
+// alone.
+func Clean(fromManifest, dryRun, yes bool) error {
+	if !fromManifest {
+		return fmt.Errorf("clean needs --from-manifest")
+	}
+	return Prune(files.PruneCriteria{Untouched: true}, dryRun, yes)
+}
+
+// ParseAge parses an age such as "180d", "2w", or any time.ParseDuration
+// value ("36h").
+func ParseAge(s string) (time.Duration, error) {
+	units := map[string]time.Duration{"d": 24 * time.Hour, "w": 7 * 24 * time.Hour}
+	for suffix, unit := range units {
+		if n, ok := strings.CutSuffix(s, suffix); ok {
+			count, err := strconv.ParseFloat(n, 64)
+			if err != nil || count < 0 {
+				return 0, fmt.Errorf("invalid age %q", s)
+			}
+			return time.Duration(count * float64(unit)), nil
+		}
+	}
+
+	d, err := time.ParseDuration(s)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("invalid age %q, expected e.g. 180d, 2w or 36h", s)
+	}
+	return d, nil
+}
+
+// sizeUnits are the suffixes accepted by ParseSize, longest first so that
+// "MB" is not mistaken for "B".
+var sizeUnits = []struct {
+	suffix string
+	bytes  int64
+}{
+	{"GB", 1 << 30},
+	{"MB", 1 << 20},
+	{"KB", 1 << 10},
+	{"B", 1},
+}
+
+// ParseSize parses a size such as "1MB", "512KB" or "2048" (bytes). Units
+// are powers of 1024.
+func ParseSize(s string) (int64, error) {
+	upper := strings.ToUpper(strings.TrimSpace(s))
+	unit := int64(1)
+	for _, u := range sizeUnits {
+		if n, ok := strings.CutSuffix(upper, u.suffix); ok {
+			upper, unit = n, u.bytes
+			break
+		}
+	}
+
+	n, err := strconv.ParseFloat(strings.TrimSpace(upper), 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid size %q, expected e.g. 1MB, 512KB or 2048", s)
+	}
+	return int64(n * float64(unit)), nil
+}
+
+// FormatSize formats a byte count using the units accepted by ParseSize.
+func FormatSize(n int64) string {
+	for _, u := range sizeUnits {
+		if u.bytes > 1 && n >= u.bytes {
+			return strconv.FormatFloat(float64(n)/float64(u.bytes), 'f', 1, 64) + u.suffix
+		}
+	}
+	return strconv.FormatInt(n, 10) + "B"
+}
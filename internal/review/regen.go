@@ -0,0 +1,174 @@
+package review
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/ptdewey/shutter/internal/files"
+	"github.com/ptdewey/shutter/internal/pretty"
+)
+
+// Regen re-runs the tests that produced the accepted snapshots whose title
+// matches the regular expression pattern (all of them when it is empty)
+// with SHUTTER_UPDATE=1, so the snapshots they take replace the accepted
+// ones, then reports which accepted snapshots changed. It is meant for
+// intentional changes that touch many snapshots at once, such as a
+// formatter upgrade. The prompt is skipped when yes is true.
+func Regen(pattern string, yes bool) error {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return fmt.Errorf("invalid pattern: %w", err)
+	}
+
+	accepted, err := files.ListAccepted()
+	if err != nil {
+		return err
+	}
+	var selected []files.AcceptedSnapshot
+	for _, a := range accepted {
+		if re.MatchString(a.Info.Title) {
+			selected = append(selected, a)
+		}
+	}
+	if len(selected) == 0 {
+		fmt.Fprintln(out, pretty.Success("✓ No accepted snapshots to regenerate"))
+		return nil
+	}
+
+	runs, unknown := regenRuns(selected)
+	for _, info := range unknown {
+		fmt.Fprintln(out, pretty.Warning(fmt.Sprintf("Skipping %s: it does not record the test that took it", info.Title)))
+	}
+	if len(runs) == 0 {
+		return nil
+	}
+
+	if !yes {
+		infos := make([]files.SnapshotInfo, len(selected))
+		for i, a := range selected {
+			infos[i] = a.Info
+		}
+		ok, err := Confirm(os.Stdin, os.Stdout, "Regenerate", infos)
+		if err != nil || !ok {
+			return err
+		}
+	}
+
+	before := acceptedDigests(accepted)
+
+	var failed []string
+	for _, run := range runs {
+		fmt.Fprintf(out, "Running %s in %s\n", strings.Join(run.Tests, ", "), files.DisplayPath(run.Dir))
+		cmd := exec.Command("go", "test", "-count=1", "-run", run.Pattern(), ".")
+		cmd.Dir = run.Dir
+		cmd.Env = append(os.Environ(), "SHUTTER_UPDATE=1")
+		if output, err := cmd.CombinedOutput(); err != nil {
+			fmt.Fprint(out, string(output))
+			failed = append(failed, files.DisplayPath(run.Dir))
+		}
+	}
+
+	after, err := files.ListAccepted()
+	if err != nil {
+		return err
+	}
+	changed := changedSnapshots(before, acceptedDigests(after))
+
+	fmt.Fprintf(out, pretty.Success("✓ Regenerated snapshots of %d test(s), %d snapshot(s) changed\n"), countTests(runs), len(changed))
+	for _, path := range changed {
+		fmt.Fprintf(out, "  %s\n", files.DisplayPath(path))
+	}
+
+	if len(failed) > 0 {
+		return fmt.Errorf("tests failed in %s; snapshots they could not update are left pending", strings.Join(failed, ", "))
+	}
+	return nil
+}
+
+// regenRun is one go test invocation of Regen: the tests to run in the
+// package at Dir.
+type regenRun struct {
+	Dir   string
+	Tests []string
+}
+
+// Pattern returns the -run pattern matching exactly the tests of the run.
+func (r regenRun) Pattern() string {
+	quoted := make([]string, len(r.Tests))
+	for i, test := range r.Tests {
+		quoted[i] = regexp.QuoteMeta(test)
+	}
+	return "^(" + strings.Join(quoted, "|") + ")$"
+}
+
+// regenRuns groups snapshots by package and the top-level test that took
+// them, sorted by package directory and test name. Snapshots that do not
+// record their test, such as ones written by older versions, are returned
+// as unknown.
+func regenRuns(snapshots []files.AcceptedSnapshot) (runs []regenRun, unknown []files.SnapshotInfo) {
+	tests := map[string]map[string]bool{}
+	for _, a := range snapshots {
+		if a.Snapshot.Test == "" {
+			unknown = append(unknown, a.Info)
+			continue
+		}
+		dir := filepath.Dir(a.Info.Dir)
+		if tests[dir] == nil {
+			tests[dir] = map[string]bool{}
+		}
+		test, _, _ := strings.Cut(a.Snapshot.Test, "/")
+		tests[dir][test] = true
+	}
+
+	for dir, names := range tests {
+		run := regenRun{Dir: dir}
+		for name := range names {
+			run.Tests = append(run.Tests, name)
+		}
+		sort.Strings(run.Tests)
+		runs = append(runs, run)
+	}
+	sort.Slice(runs, func(i, j int) bool { return runs[i].Dir < runs[j].Dir })
+	return runs, unknown
+}
+
+func countTests(runs []regenRun) int {
+	count := 0
+	for _, run := range runs {
+		count += len(run.Tests)
+	}
+	return count
+}
+
+// acceptedDigests maps the path of each accepted snapshot to the digest of
+// its content.
+func acceptedDigests(accepted []files.AcceptedSnapshot) map[string]string {
+	digests := make(map[string]string, len(accepted))
+	for _, a := range accepted {
+		digests[a.Info.Path] = files.ContentDigest(a.Snapshot.Content)
+	}
+	return digests
+}
+
+// changedSnapshots returns the sorted paths of the snapshots whose digest
+// differs between before and after, including ones added or removed.
+func changedSnapshots(before, after map[string]string) []string {
+	var changed []string
+	for path, digest := range after {
+		if before[path] != digest {
+			changed = append(changed, path)
+		}
+	}
+	for path := range before {
+		if _, ok := after[path]; !ok {
+			changed = append(changed, path)
+		}
+	}
+	sort.Strings(changed)
+	return changed
+}
@@ -0,0 +1,200 @@
+package review
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/ptdewey/shutter/internal/diff"
+	"github.com/ptdewey/shutter/internal/files"
+)
+
+// ReportSchemaVersion is the version of the JSON written by Report. It is
+// raised when a field is removed or changes meaning; new fields may be
+// added without raising it.
+const ReportSchemaVersion = 1
+
+// Limits that keep the diff of each snapshot in a report short enough for
+// a chat message or a pull request comment.
+const (
+	reportDiffLines = 10
+	reportLineWidth = 120
+)
+
+// PendingReport summarizes the snapshots pending review for bots.
+type PendingReport struct {
+	SchemaVersion int              `json:"schema_version"`
+	Totals        ReportCounts     `json:"totals"`
+	Packages      []PackageReport  `json:"packages"`
+	Snapshots     []SnapshotReport `json:"snapshots"`
+}
+
+// ReportCounts counts pending snapshots by whether an accepted version
+// exists.
+type ReportCounts struct {
+	Pending int `json:"pending"`
+	New     int `json:"new"`
+	Changed int `json:"changed"`
+}
+
+func (c *ReportCounts) add(status string) {
+	c.Pending++
+	if status == "new" {
+		c.New++
+	} else {
+		c.Changed++
+	}
+}
+
+// PackageReport counts the pending snapshots of one package, and of each
+// test in it.
+type PackageReport struct {
+	Package string       `json:"package"`
+	Tests   []TestReport `json:"tests"`
+	ReportCounts
+}
+
+// TestReport counts the pending snapshots taken by one test function.
+type TestReport struct {
+	Test string `json:"test"`
+	ReportCounts
+}
+
+// SnapshotReport describes one pending snapshot. Diff holds at most a few
+// lines, each prefixed with "+ " or "- "; Truncated tells whether lines
+// were left out.
+type SnapshotReport struct {
+	Title     string `json:"title"`
+	Package   string `json:"package"`
+	Test      string `json:"test"`
+	Path      string `json:"path"`
+	Status    string `json:"status"` // "new" or "changed"
+	Added     int    `json:"added"`
+	Removed   int    `json:"removed"`
+	Diff      string `json:"diff"`
+	Truncated bool   `json:"truncated"`
+}
+
+// Report prints the snapshots pending review: as JSON following
+// ReportSchemaVersion with summaryJSON, and as one line per package, followed
+// by one line per test in it, otherwise. The report is printed even in quiet
+// mode.
+func Report(summaryJSON bool) error {
+	snapshots, err := PendingSnapshots()
+	if err != nil {
+		return err
+	}
+	report, err := buildReport(snapshots)
+	if err != nil {
+		return err
+	}
+
+	if summaryJSON {
+		data, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+
+	for _, pkg := range report.Packages {
+		fmt.Printf("%d snapshot change(s) pending in %s (%d new, %d changed)\n", pkg.Pending, pkg.Package, pkg.New, pkg.Changed)
+		for _, test := range pkg.Tests {
+			fmt.Printf("  %d in %s (%d new, %d changed)\n", test.Pending, test.Test, test.New, test.Changed)
+		}
+	}
+	fmt.Printf("%d snapshot change(s) pending in total\n", report.Totals.Pending)
+	return nil
+}
+
+// buildReport reads the pending snapshots and their accepted versions.
+func buildReport(snapshots []files.SnapshotInfo) (*PendingReport, error) {
+	report := &PendingReport{
+		SchemaVersion: ReportSchemaVersion,
+		Packages:      []PackageReport{},
+		Snapshots:     []SnapshotReport{},
+	}
+	packages := map[string]int{}
+
+	for _, info := range snapshots {
+		pending, err := files.CurrentStorage().Read(info, files.StateNew)
+		if err != nil {
+			return nil, err
+		}
+		accepted, err := files.CurrentStorage().Read(info, files.StateAccepted)
+		if err != nil {
+			accepted = nil
+		}
+
+		snap := SnapshotReport{
+			Title:   info.Title,
+			Package: PackageOf(info),
+			Test:    TestOf(info),
+			Path:    files.DisplayPath(info.Path),
+			Status:  "changed",
+		}
+		if accepted == nil {
+			snap.Status = "new"
+			accepted = &files.Snapshot{}
+		}
+		snap.Added, snap.Removed, snap.Diff, snap.Truncated = shortDiff(diff.Snapshots(accepted, pending))
+		report.Snapshots = append(report.Snapshots, snap)
+
+		i, ok := packages[snap.Package]
+		if !ok {
+			i = len(report.Packages)
+			packages[snap.Package] = i
+			report.Packages = append(report.Packages, PackageReport{Package: snap.Package, Tests: []TestReport{}})
+		}
+		pkg := &report.Packages[i]
+		j := slices.IndexFunc(pkg.Tests, func(t TestReport) bool { return t.Test == snap.Test })
+		if j < 0 {
+			j = len(pkg.Tests)
+			pkg.Tests = append(pkg.Tests, TestReport{Test: snap.Test})
+		}
+		pkg.Tests[j].add(snap.Status)
+		pkg.add(snap.Status)
+		report.Totals.add(snap.Status)
+	}
+	return report, nil
+}
+
+// shortDiff counts the added and removed lines of a diff and renders the
+// first reportDiffLines of them, each cut to reportLineWidth characters.
+func shortDiff(lines []diff.DiffLine) (added, removed int, text string, truncated bool) {
+	var sb strings.Builder
+	shown := 0
+	for _, line := range lines {
+		var prefix string
+		switch line.Kind {
+		case diff.DiffNew:
+			added++
+			prefix = "+ "
+		case diff.DiffOld:
+			removed++
+			prefix = "- "
+		default:
+			continue
+		}
+		if shown == reportDiffLines {
+			truncated = true
+			continue
+		}
+		shown++
+		sb.WriteString(prefix)
+		sb.WriteString(truncateLine(line.Line, reportLineWidth))
+		sb.WriteString("\n")
+	}
+	return added, removed, sb.String(), truncated
+}
+
+// truncateLine cuts s to at most width runes, marking the cut with "…".
+func truncateLine(s string, width int) string {
+	runes := []rune(s)
+	if len(runes) <= width {
+		return s
+	}
+	return string(runes[:width-1]) + "…"
+}
@@ -2,8 +2,12 @@ package review
 
 import (
 	"bufio"
+	"errors"
 	"fmt"
+	"io"
 	"os"
+	"regexp"
+	"strconv"
 	"strings"
 
 	"github.com/ptdewey/shutter/internal/diff"
@@ -21,10 +25,95 @@ const (
 	RejectAllChoice
 	SkipAllChoice
 	Quit
+	DiffToolChoice
 )
 
+// Exit codes shared by the shutter command line tools.
+const (
+	ExitClean   = 0 // No snapshots are pending review
+	ExitPending = 1 // Snapshots are still pending review
+	ExitError   = 2 // The command failed
+)
+
+// out receives decorative output: headers, confirmations, and summaries.
+// Snapshot boxes, prompts, and requested listings are always printed.
+var out io.Writer = os.Stdout
+
+// SetQuiet suppresses decorative output when quiet is true.
+func SetQuiet(quiet bool) {
+	if quiet {
+		out = io.Discard
+	} else {
+		out = os.Stdout
+	}
+}
+
+// ExitCode returns the exit code for a command that finished with err:
+// ExitError if it failed, otherwise ExitPending if any snapshots are still
+// waiting for review and ExitClean if none are.
+func ExitCode(err error) int {
+	if err != nil {
+		return ExitError
+	}
+
+	pending, err := PendingSnapshots()
+	if err != nil {
+		return ExitError
+	}
+	if len(pending) > 0 {
+		return ExitPending
+	}
+	return ExitClean
+}
+
+// CorruptedNotice is shown above the diff of a pending snapshot whose
+// accepted version no longer matches its stored digest.
+const CorruptedNotice = "✗ Accepted snapshot is corrupted: its content does not match its stored digest"
+
+// Status lists the snapshots pending review, followed by any accepted
+// snapshots whose content no longer matches their stored digest. Corrupted
+// snapshots make Status fail, so CI catches them.
+func Status() error {
+	snapshots, err := PendingSnapshots()
+	if err != nil {
+		return err
+	}
+	corrupted, err := files.ListCorruptedSnapshots()
+	if err != nil {
+		return err
+	}
+
+	if len(snapshots) == 0 {
+		fmt.Fprintln(out, pretty.Success("✓ No new snapshots to review"))
+	} else {
+		fmt.Fprintln(out, pretty.Header("Pending Snapshots"))
+		for _, info := range snapshots {
+			fmt.Println(files.DisplayPath(info.Path))
+		}
+		fmt.Fprintf(out, "%d snapshot(s) pending review\n", len(snapshots))
+	}
+
+	if len(corrupted) == 0 {
+		return nil
+	}
+	fmt.Fprintln(out, pretty.Header("Corrupted Snapshots"))
+	for _, info := range corrupted {
+		fmt.Println(files.DisplayPath(info.Path))
+	}
+	return fmt.Errorf("%d accepted snapshot(s) do not match their stored digest; re-run their tests and accept the new snapshots", len(corrupted))
+}
+
 func computeDiffLines(old, new *files.Snapshot) []diff.DiffLine {
-	return diff.Histogram(old.Content, new.Content)
+	return diff.Snapshots(old, new)
+}
+
+// printDiff prints the diff between a pending snapshot and its accepted
+// version, flagging the accepted version if it is corrupted.
+func printDiff(accepted, newSnap *files.Snapshot) {
+	if accepted.Corrupted() {
+		fmt.Println(pretty.Error(CorruptedNotice))
+	}
+	fmt.Println(pretty.DiffSnapshotBox(accepted, newSnap, computeDiffLines(accepted, newSnap)))
 }
 
 // applyToSnapshots applies an operation to all snapshots and returns the count of successful operations
@@ -39,43 +128,153 @@ func applyToSnapshots(snapshots []files.SnapshotInfo, operation func(files.Snaps
 	return successCount, nil
 }
 
+// Options selects the snapshots a review covers and how they are decided.
+type Options struct {
+	Dir            string // Only review snapshots under this directory; default: the whole project
+	Filter         string // Only review snapshots whose title matches this regular expression
+	NonInteractive bool   // Print the pending snapshots instead of prompting
+	AutoAccept     bool   // Accept the selected snapshots without prompting
+	DiffTool       bool   // Open each snapshot in the external diff tool before prompting
+}
+
+// Review interactively reviews every pending snapshot in the project.
 func Review() error {
-	snapshots, err := files.ListNewSnapshots()
+	return ReviewWithOptions(Options{})
+}
+
+// ReviewWithOptions reviews the pending snapshots selected by opts. With
+// AutoAccept, they are all accepted; otherwise with NonInteractive, they are
+// printed and left pending. Neither mode reads from stdin. Unless
+// NonInteractive, the review lock is held while snapshots are decided.
+func ReviewWithOptions(opts Options) error {
+	if !opts.NonInteractive {
+		unlock, err := files.LockReview()
+		if err != nil {
+			return err
+		}
+		defer unlock()
+	}
+
+	snapshots, err := selectSnapshots(opts.Dir, opts.Filter)
 	if err != nil {
 		return err
 	}
 
 	if len(snapshots) == 0 {
-		fmt.Println(pretty.Success("✓ No new snapshots to review"))
+		fmt.Fprintln(out, pretty.Success("✓ No new snapshots to review"))
 		return nil
 	}
 
-	fmt.Println(pretty.Header("Review Snapshots"))
-	fmt.Printf("Found %d new snapshot(s) to review\n\n", len(snapshots))
+	fmt.Fprintln(out, pretty.Header("Review Snapshots"))
+	fmt.Fprintf(out, "Found %d new snapshot(s) to review\n\n", len(snapshots))
+
+	switch {
+	case opts.AutoAccept:
+		return acceptSelected(snapshots)
+	case opts.NonInteractive:
+		return printSelected(snapshots)
+	default:
+		return reviewLoop(snapshots, opts.DiffTool)
+	}
+}
+
+// selectSnapshots lists the pending snapshots under dir (or the whole
+// project if dir is empty) whose title matches filter, within the limits set
+// with SetSuite and SetMine.
+func selectSnapshots(dir, filter string) ([]files.SnapshotInfo, error) {
+	var re *regexp.Regexp
+	if filter != "" {
+		var err error
+		if re, err = regexp.Compile(filter); err != nil {
+			return nil, fmt.Errorf("invalid filter %q: %w", filter, err)
+		}
+	}
+
+	var snapshots []files.SnapshotInfo
+	var err error
+	if dir == "" {
+		snapshots, err = PendingSnapshots()
+	} else if snapshots, err = files.ListNewSnapshotsIn(dir); err == nil {
+		snapshots, err = selectPending(snapshots)
+	}
+	if err != nil || re == nil {
+		return snapshots, err
+	}
 
-	return reviewLoop(snapshots)
+	var selected []files.SnapshotInfo
+	for _, info := range snapshots {
+		if re.MatchString(info.Title) {
+			selected = append(selected, info)
+		}
+	}
+	return selected, nil
 }
 
-func reviewLoop(snapshots []files.SnapshotInfo) error {
+// acceptSelected accepts snapshots without prompting and prints a summary.
+func acceptSelected(snapshots []files.SnapshotInfo) error {
+	summary := NewSummary(snapshots)
+	defer func() { fmt.Fprint(out, "\n"+summary.String()) }()
+
+	_, err := AcceptEach(snapshots, func(info files.SnapshotInfo, changed int) {
+		summary.Record(info, Accepted, changed)
+		fmt.Fprintln(out, pretty.Success("✓ Accepted "+files.DisplayPath(info.Path)))
+	}, printSecrets)
+	return err
+}
+
+// printSelected prints each snapshot with its diff and leaves it pending.
+func printSelected(snapshots []files.SnapshotInfo) error {
+	// Best effort: without owner rules there is simply no owner to show.
+	owners, _ := files.LoadOwners()
+	for i, snapshotInfo := range snapshots {
+		fmt.Printf("\n[%d/%d] %s%s%s\n", i+1, len(snapshots), pretty.Header(snapshotInfo.Title), VariantLabel(snapshots, i), ownerNote(owners, snapshotInfo))
+
+		newSnap, err := files.CurrentStorage().Read(snapshotInfo, files.StateNew)
+		if err != nil {
+			fmt.Println(pretty.Error("✗ Failed to read new snapshot: " + err.Error()))
+			continue
+		}
+		if accepted, err := files.CurrentStorage().Read(snapshotInfo, files.StateAccepted); err == nil {
+			printDiff(accepted, newSnap)
+		} else {
+			fmt.Println(pretty.NewSnapshotBox(newSnap))
+		}
+	}
+
+	fmt.Fprintf(out, "\n%d snapshot(s) pending review\n", len(snapshots))
+	return nil
+}
+
+// reviewLoop prompts for a decision on each snapshot in turn. With
+// diffTool, each snapshot is also opened in the external diff tool first.
+func reviewLoop(snapshots []files.SnapshotInfo, diffTool bool) error {
 	reader := bufio.NewReader(os.Stdin)
+	summary := NewSummary(snapshots)
+	defer func() { fmt.Fprint(out, "\n"+summary.String()) }()
 
+	// Best effort: without owner rules there is simply no owner to show.
+	owners, _ := files.LoadOwners()
 	for i, snapshotInfo := range snapshots {
-		fmt.Printf("\n[%d/%d] %s\n", i+1, len(snapshots), pretty.Header(snapshotInfo.Title))
+		fmt.Printf("\n[%d/%d] %s%s\n", i+1, len(snapshots), pretty.Header(snapshotInfo.Title), ownerNote(owners, snapshotInfo))
 
-		newSnap, err := files.ReadSnapshotFromPath(snapshotInfo.Path)
+		newSnap, err := files.CurrentStorage().Read(snapshotInfo, files.StateNew)
 		if err != nil {
 			fmt.Println(pretty.Error("✗ Failed to read new snapshot: " + err.Error()))
 			continue
 		}
 
-		accepted, acceptErr := files.ReadSnapshotWithDir(snapshotInfo.Dir, snapshotInfo.Title, "accepted")
+		accepted, acceptErr := files.CurrentStorage().Read(snapshotInfo, files.StateAccepted)
 
 		if acceptErr == nil {
-			diffLines := computeDiffLines(accepted, newSnap)
-			fmt.Println(pretty.DiffSnapshotBox(accepted, newSnap, diffLines))
+			// Best effort: without git there is simply no commit to show.
+			accepted.Commit, _ = files.LastCommit(accepted.Path)
+			printDiff(accepted, newSnap)
 		} else {
 			fmt.Println(pretty.NewSnapshotBox(newSnap))
 		}
+		if diffTool {
+			openDiffTool(snapshotInfo)
+		}
 
 		for {
 			choice, err := askChoice(reader, i+1, len(snapshots))
@@ -84,53 +283,107 @@ func reviewLoop(snapshots []files.SnapshotInfo) error {
 			}
 
 			switch choice {
+			case DiffToolChoice:
+				openDiffTool(snapshotInfo)
+				continue
 			case Accept:
-				if err := files.AcceptSnapshotInfo(snapshotInfo); err != nil {
+				changed := ChangedBytesFor(snapshotInfo)
+				err := AcceptSnapshot(snapshotInfo)
+				var secretsErr *files.SecretsError
+				if errors.As(err, &secretsErr) {
+					printSecrets(secretsErr)
+					ok, promptErr := confirmPrompt(reader, os.Stdout, "Accept anyway?")
+					if promptErr != nil {
+						return promptErr
+					}
+					if !ok {
+						summary.Record(snapshotInfo, Skipped, 0)
+						fmt.Fprintln(out, pretty.Warning("⊘ Snapshot skipped"))
+						break
+					}
+					err = files.CurrentStorage().Accept(snapshotInfo, true)
+				}
+				if err != nil {
 					fmt.Println(pretty.Error("✗ Failed to accept snapshot: " + err.Error()))
 				} else {
-					fmt.Println(pretty.Success("✓ Snapshot accepted"))
+					summary.Record(snapshotInfo, Accepted, changed)
+					fmt.Fprintln(out, pretty.Success("✓ Snapshot accepted"))
 				}
 			case Reject:
-				if err := files.RejectSnapshotInfo(snapshotInfo); err != nil {
+				if err := files.CurrentStorage().Reject(snapshotInfo); err != nil {
 					fmt.Println(pretty.Error("✗ Failed to reject snapshot: " + err.Error()))
 				} else {
-					fmt.Println(pretty.Warning("⊘ Snapshot rejected"))
+					summary.Record(snapshotInfo, Rejected, 0)
+					fmt.Fprintln(out, pretty.Warning("⊘ Snapshot rejected"))
 				}
 			case Skip:
-				fmt.Println(pretty.Warning("⊘ Snapshot skipped"))
+				summary.Record(snapshotInfo, Skipped, 0)
+				fmt.Fprintln(out, pretty.Warning("⊘ Snapshot skipped"))
 			case AcceptAllChoice:
-				remaining := snapshots[i:]
-				if _, err := applyToSnapshots(remaining, files.AcceptSnapshotInfo); err != nil {
-					fmt.Println(pretty.Error("✗ Failed to accept snapshot: " + err.Error()))
-					return err
+				count, err := AcceptEach(snapshots[i:], func(info files.SnapshotInfo, changed int) {
+					summary.Record(info, Accepted, changed)
+				}, printSecrets)
+				fmt.Fprintf(out, pretty.Success("✓ Accepted %d snapshot(s)\n"), count)
+				if err != nil {
+					fmt.Println(pretty.Error("✗ " + err.Error()))
 				}
-				fmt.Printf(pretty.Success("✓ Accepted %d snapshot(s)\n"), len(remaining))
-				return nil
+				return err
 			case RejectAllChoice:
 				remaining := snapshots[i:]
-				if _, err := applyToSnapshots(remaining, files.RejectSnapshotInfo); err != nil {
+				if _, err := applyToSnapshots(remaining, func(info files.SnapshotInfo) error {
+					if err := files.CurrentStorage().Reject(info); err != nil {
+						return err
+					}
+					summary.Record(info, Rejected, 0)
+					return nil
+				}); err != nil {
 					fmt.Println(pretty.Error("✗ Failed to reject snapshot: " + err.Error()))
 					return err
 				}
-				fmt.Printf(pretty.Warning("⊘ Rejected %d snapshot(s)\n"), len(remaining))
+				fmt.Fprintf(out, pretty.Warning("⊘ Rejected %d snapshot(s)\n"), len(remaining))
 				return nil
 			case SkipAllChoice:
-				fmt.Printf(pretty.Warning("⊘ Skipped %d snapshot(s)\n"), len(snapshots)-i)
+				for _, info := range snapshots[i:] {
+					summary.Record(info, Skipped, 0)
+				}
+				fmt.Fprintf(out, pretty.Warning("⊘ Skipped %d snapshot(s)\n"), len(snapshots)-i)
 				return nil
 			case Quit:
-				fmt.Println("\nReview interrupted")
+				fmt.Fprintln(out, "\nReview interrupted")
 				return nil
 			}
 			break
 		}
 	}
 
-	fmt.Println("\n" + pretty.Success("✓ Review complete"))
+	fmt.Fprintln(out, "\n"+pretty.Success("✓ Review complete"))
 	return nil
 }
 
+// openDiffTool opens a snapshot in the external diff tool, reporting a
+// failure without ending the review.
+func openDiffTool(info files.SnapshotInfo) {
+	if err := OpenDiffTool(info); err != nil {
+		fmt.Println(pretty.Error("✗ Failed to open diff tool: " + err.Error()))
+	}
+}
+
+// VariantLabel describes which of its title's variants snapshots[i] is, or
+// returns "" when it is the only one pending.
+func VariantLabel(snapshots []files.SnapshotInfo, i int) string {
+	n, total := files.VariantPosition(snapshots, i)
+	if total == 1 {
+		return ""
+	}
+	variant := snapshots[i].Variant
+	if variant == "" {
+		variant = "default"
+	}
+	return fmt.Sprintf(" (variant %s, %d of %d)", variant, n, total)
+}
+
 func askChoice(reader *bufio.Reader, current, total int) (ReviewChoice, error) {
-	fmt.Printf("\nOptions: [a]ccept [r]eject [s]kip [A]ccept All [R]eject All [S]kip All [q]uit: ")
+	fmt.Printf("\nOptions: [a]ccept [r]eject [s]kip [A]ccept All [R]eject All [S]kip All [d]ifftool [q]uit: ")
 
 	input, err := reader.ReadString('\n')
 	if err != nil {
@@ -152,6 +405,8 @@ func askChoice(reader *bufio.Reader, current, total int) (ReviewChoice, error) {
 		return RejectAllChoice, nil
 	case "S", "Skip All":
 		return SkipAllChoice, nil
+	case "d", "difftool":
+		return DiffToolChoice, nil
 	case "q", "quit":
 		return Quit, nil
 	default:
@@ -160,32 +415,309 @@ func askChoice(reader *bufio.Reader, current, total int) (ReviewChoice, error) {
 	}
 }
 
+// Confirm asks whether action should be applied to snapshots, stating how
+// many snapshots and packages are affected. Only "y" or "yes" confirms; an
+// empty answer or end of input declines.
+func Confirm(r io.Reader, w io.Writer, action string, snapshots []files.SnapshotInfo) (bool, error) {
+	packages := map[string]bool{}
+	for _, info := range snapshots {
+		packages[PackageOf(info)] = true
+	}
+
+	return confirmPrompt(r, w, fmt.Sprintf("%s %d snapshot(s) across %d package(s)?", action, len(snapshots), len(packages)))
+}
+
+// confirmPrompt asks a yes/no question. Only "y" or "yes" confirms.
+func confirmPrompt(r io.Reader, w io.Writer, question string) (bool, error) {
+	fmt.Fprintf(w, "%s [y/N]: ", question)
+
+	input, err := bufio.NewReader(r).ReadString('\n')
+	if err != nil && err != io.EOF {
+		return false, err
+	}
+
+	switch strings.ToLower(strings.TrimSpace(input)) {
+	case "y", "yes":
+		return true, nil
+	default:
+		if err == io.EOF {
+			fmt.Fprintln(w)
+		}
+		fmt.Fprintln(w, pretty.Warning("Aborted (use --yes to skip confirmation)"))
+		return false, nil
+	}
+}
+
+// ConfirmAcceptAll accepts all new snapshots after confirmation on stdin.
+// The prompt is skipped when yes is true.
+func ConfirmAcceptAll(yes bool) error {
+	return confirmAll("Accept", yes, AcceptAll)
+}
+
+// ConfirmRejectAll rejects all new snapshots after confirmation on stdin.
+// The prompt is skipped when yes is true.
+func ConfirmRejectAll(yes bool) error {
+	return confirmAll("Reject", yes, RejectAll)
+}
+
+func confirmAll(action string, yes bool, apply func() error) error {
+	unlock, err := files.LockReview()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	if !yes {
+		snapshots, err := PendingSnapshots()
+		if err != nil {
+			return err
+		}
+		if len(snapshots) == 0 {
+			fmt.Fprintln(out, pretty.Success("✓ No new snapshots to review"))
+			return nil
+		}
+
+		ok, err := Confirm(os.Stdin, os.Stdout, action, snapshots)
+		if err != nil || !ok {
+			return err
+		}
+	}
+	return apply()
+}
+
+// AcceptNamed accepts the pending snapshots named name (see files.FindPending),
+// as printed by failing tests, holding the review lock meanwhile.
+func AcceptNamed(name string) error {
+	unlock, err := files.LockReview()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	snapshots, err := files.FindPending(name)
+	if err != nil {
+		return err
+	}
+
+	count, err := AcceptEach(snapshots, nil, printSecrets)
+	fmt.Fprintf(out, pretty.Success("✓ Accepted %d snapshot(s)\n"), count)
+	return err
+}
+
 func AcceptAll() error {
-	snapshots, err := files.ListNewSnapshots()
+	snapshots, err := PendingSnapshots()
 	if err != nil {
 		return err
 	}
 
-	count, err := applyToSnapshots(snapshots, files.AcceptSnapshotInfo)
+	count, err := AcceptEach(snapshots, nil, printSecrets)
+	fmt.Fprintf(out, pretty.Success("✓ Accepted %d snapshot(s)\n"), count)
+	return err
+}
+
+func RejectAll() error {
+	snapshots, err := PendingSnapshots()
+	if err != nil {
+		return err
+	}
+
+	count, err := applyToSnapshots(snapshots, files.CurrentStorage().Reject)
 	if err != nil {
 		return err
 	}
 
-	fmt.Printf(pretty.Success("✓ Accepted %d snapshot(s)\n"), count)
+	fmt.Fprintf(out, pretty.Warning("⊘ Rejected %d snapshot(s)\n"), count)
 	return nil
 }
 
-func RejectAll() error {
-	snapshots, err := files.ListNewSnapshots()
+// Migrate moves accepted snapshots from the legacy flat layout into per-test
+// directories, and rewrites them in the extension and header style
+// configured for the project.
+func Migrate() error {
+	migrated, err := files.MigrateLegacySnapshots()
+	if err != nil {
+		return err
+	}
+	rewritten, err := files.MigrateSnapshotFormat()
+	if err != nil {
+		return err
+	}
+	migrated = append(migrated, rewritten...)
+
+	fmt.Fprintf(out, pretty.Success("✓ Migrated %d snapshot(s)\n"), len(migrated))
+	return nil
+}
+
+// CollectBazel copies the pending snapshots that Bazel tests wrote to their
+// undeclared outputs below testlogs (default: the workspace's
+// bazel-testlogs) back into the workspace, so they can be reviewed.
+func CollectBazel(testlogs string) error {
+	collected, err := files.CollectBazelOutputs(testlogs)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(out, pretty.Success("✓ Collected %d file(s) from Bazel test outputs\n"), len(collected))
+	return nil
+}
+
+// Restore moves the most recently rejected snapshot named name out of the
+// trash so it can be reviewed again. With an empty name it lists the trash.
+func Restore(name string) error {
+	if name == "" {
+		return listTrash()
+	}
+
+	restored, err := files.RestoreSnapshot(name)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(out, pretty.Success("✓ Restored %s\n"), files.DisplayPath(restored.Original))
+	return nil
+}
+
+// PurgeTrash deletes every rejected snapshot from the trash, after
+// confirmation unless yes is set.
+func PurgeTrash(yes bool) error {
+	trashed, err := files.ListTrash()
+	if err != nil {
+		return err
+	}
+
+	if len(trashed) == 0 {
+		fmt.Fprintln(out, pretty.Success("✓ The trash is empty"))
+		return nil
+	}
+
+	if !yes {
+		question := fmt.Sprintf("Permanently delete %d rejected snapshot(s)?", len(trashed))
+		if ok, err := confirmPrompt(os.Stdin, os.Stdout, question); err != nil || !ok {
+			return err
+		}
+	}
+
+	purged, err := files.PurgeTrash(0)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(out, pretty.Success("✓ Purged %d rejected snapshot(s)\n"), purged)
+	return nil
+}
+
+func listTrash() error {
+	trashed, err := files.ListTrash()
+	if err != nil {
+		return err
+	}
+
+	if len(trashed) == 0 {
+		fmt.Fprintln(out, pretty.Success("✓ No rejected snapshots to restore"))
+		return nil
+	}
+
+	fmt.Println(pretty.Header("Rejected Snapshots"))
+	for _, t := range trashed {
+		fmt.Printf("  %s  %s  %s\n",
+			pretty.Gray(t.RejectedAt.Local().Format("2006-01-02 15:04:05")),
+			t.Title,
+			pretty.Gray(files.DisplayPath(t.Original)),
+		)
+	}
+	return nil
+}
+
+// History lists the recorded accepted versions of the snapshot named name,
+// newest first.
+func History(name string) error {
+	acceptedPath, err := files.FindAccepted(name)
 	if err != nil {
 		return err
 	}
 
-	count, err := applyToSnapshots(snapshots, files.RejectSnapshotInfo)
+	entries, err := files.ReadHistory(acceptedPath)
 	if err != nil {
 		return err
 	}
+	if len(entries) == 0 {
+		return fmt.Errorf("no history recorded for %q", name)
+	}
 
-	fmt.Printf(pretty.Warning("⊘ Rejected %d snapshot(s)\n"), count)
+	fmt.Println(pretty.Header("History: "+name) + " " + pretty.Gray(files.DisplayPath(acceptedPath)))
+	for i := len(entries) - 1; i >= 0; i-- {
+		entry := entries[i]
+		acceptedAt := "unknown date"
+		if !entry.AcceptedAt.IsZero() {
+			acceptedAt = entry.AcceptedAt.Local().Format("2006-01-02 15:04:05")
+		}
+		line := fmt.Sprintf("  v%-3d %-19s  %d line(s)", entry.Version, acceptedAt, strings.Count(entry.Content, "\n")+1)
+		if i == len(entries)-1 {
+			line += pretty.Gray("  (current)")
+		}
+		fmt.Println(line)
+	}
 	return nil
 }
+
+// Diff compares a recorded version of the snapshot named name against the
+// currently accepted snapshot. against names the version, as "v2" or "2";
+// when empty, the version before the current one is used.
+func Diff(name, against string) error {
+	old, current, version, err := diffVersions(name, against)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println(pretty.Header(fmt.Sprintf("v%d → current", version)))
+	fmt.Println(pretty.DiffSnapshotBox(old, current, computeDiffLines(old, current)))
+	return nil
+}
+
+// diffVersions reads the accepted snapshot name and the version of it
+// selected by against, returning that version's number.
+func diffVersions(name, against string) (old, current *files.Snapshot, version int, err error) {
+	acceptedPath, err := files.FindAccepted(name)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+
+	current, err = files.ReadSnapshotFromPath(acceptedPath)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+
+	entries, err := files.ReadHistory(acceptedPath)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+
+	entry, err := findVersion(entries, against)
+	if err != nil {
+		return nil, nil, 0, fmt.Errorf("%q: %w", name, err)
+	}
+
+	previous := *current
+	previous.Content = entry.Content
+	return &previous, current, entry.Version, nil
+}
+
+func findVersion(entries []files.HistoryEntry, against string) (files.HistoryEntry, error) {
+	if against == "" {
+		if len(entries) < 2 {
+			return files.HistoryEntry{}, fmt.Errorf("no earlier version recorded")
+		}
+		return entries[len(entries)-2], nil
+	}
+
+	version, err := strconv.Atoi(strings.TrimPrefix(against, "v"))
+	if err != nil {
+		return files.HistoryEntry{}, fmt.Errorf("invalid version %q, expected e.g. v2", against)
+	}
+	for _, entry := range entries {
+		if entry.Version == version {
+			return entry, nil
+		}
+	}
+	return files.HistoryEntry{}, fmt.Errorf("version v%d not found in history", version)
+}
@@ -0,0 +1,87 @@
+package review
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/ptdewey/shutter/internal/files"
+	"github.com/ptdewey/shutter/internal/pretty"
+)
+
+// allowSecrets skips the secret scan when accepting snapshots.
+var allowSecrets bool
+
+// SetAllowSecrets accepts snapshots that look like they contain secrets
+// when allow is true, instead of leaving them pending.
+func SetAllowSecrets(allow bool) {
+	allowSecrets = allow
+}
+
+// AcceptSnapshot accepts a pending snapshot. Unless SetAllowSecrets(true) was
+// called, a snapshot that looks like it contains secrets is left pending and
+// a *files.SecretsError is returned.
+func AcceptSnapshot(info files.SnapshotInfo) error {
+	return files.CurrentStorage().Accept(info, allowSecrets)
+}
+
+// AcceptEach accepts snapshots in order, calling accepted with the bytes
+// changed by each one. Snapshots that look like they contain secrets are
+// passed to held and left pending; the returned error then says how many.
+func AcceptEach(snapshots []files.SnapshotInfo, accepted func(files.SnapshotInfo, int), held func(*files.SecretsError)) (int, error) {
+	count, heldCount := 0, 0
+	for _, info := range snapshots {
+		changed := ChangedBytesFor(info)
+		err := AcceptSnapshot(info)
+		var secretsErr *files.SecretsError
+		if errors.As(err, &secretsErr) {
+			heldCount++
+			if held != nil {
+				held(secretsErr)
+			}
+			continue
+		}
+		if err != nil {
+			return count, err
+		}
+		count++
+		if accepted != nil {
+			accepted(info, changed)
+		}
+	}
+
+	if heldCount > 0 {
+		return count, fmt.Errorf("%d snapshot(s) left pending because they may contain secrets; review them or pass --allow-secrets", heldCount)
+	}
+	return count, nil
+}
+
+// printSecrets lists what the secret scan found in a snapshot.
+func printSecrets(err *files.SecretsError) {
+	fmt.Println(pretty.Error("✗ " + files.DisplayPath(err.Path) + " may contain secrets:"))
+	for _, f := range err.Findings {
+		fmt.Println("    " + f.String())
+	}
+}
+
+// Audit scans the accepted snapshots in the project for suspected secrets,
+// such as API keys or email addresses that scrubbers did not remove.
+func Audit() error {
+	results, err := files.AuditSnapshots()
+	if err != nil {
+		return err
+	}
+
+	if len(results) == 0 {
+		fmt.Fprintln(out, pretty.Success("✓ No secrets found in accepted snapshots"))
+		return nil
+	}
+
+	fmt.Fprintln(out, pretty.Header("Suspected Secrets"))
+	for _, result := range results {
+		fmt.Println(files.DisplayPath(result.Info.Path))
+		for _, f := range result.Findings {
+			fmt.Println("    " + f.String())
+		}
+	}
+	return fmt.Errorf("%d accepted snapshot(s) may contain secrets; scrub them with options such as ScrubEmail or IgnoreSensitive and re-record", len(results))
+}
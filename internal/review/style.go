@@ -0,0 +1,27 @@
+package review
+
+import (
+	"github.com/ptdewey/shutter/internal/files"
+	"github.com/ptdewey/shutter/internal/pretty"
+)
+
+// SetDiffStyle sets how snapshot boxes are drawn from the diff_legend and
+// diff_gutter settings of the config file. legend adds the legend even if
+// the config does not; hideMetadata leaves out the metadata lines above
+// each snapshot.
+func SetDiffStyle(legend, hideMetadata bool) error {
+	cfg, err := files.LoadConfig()
+	if err != nil {
+		return err
+	}
+
+	style := pretty.BoxStyle{
+		Legend:       legend || cfg.DiffLegend,
+		HideMetadata: hideMetadata,
+	}
+	if len(cfg.DiffGutter) == 3 {
+		style.Removed, style.Added, style.Context = cfg.DiffGutter[0], cfg.DiffGutter[1], cfg.DiffGutter[2]
+	}
+	pretty.SetBoxStyle(style)
+	return nil
+}
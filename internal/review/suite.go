@@ -0,0 +1,44 @@
+package review
+
+import "github.com/ptdewey/shutter/internal/files"
+
+// suite limits review to the snapshots of the named suite when set.
+var suite string
+
+// SetSuite limits the commands that act on pending snapshots to those taken
+// in the named suite. An empty name selects every snapshot.
+func SetSuite(name string) {
+	suite = name
+}
+
+// PendingSnapshots lists the snapshots pending review, limited to the suite
+// set with SetSuite and, with SetMine, to the current user's. The snapshots
+// of each test are listed together.
+func PendingSnapshots() ([]files.SnapshotInfo, error) {
+	snapshots, err := files.CurrentStorage().List()
+	if err != nil {
+		return nil, err
+	}
+	snapshots, err = selectPending(snapshots)
+	if err != nil {
+		return nil, err
+	}
+	return groupByTest(snapshots), nil
+}
+
+// selectPending applies the SetSuite and SetMine limits to snapshots.
+func selectPending(snapshots []files.SnapshotInfo) ([]files.SnapshotInfo, error) {
+	snapshots, err := inSuite(snapshots)
+	if err != nil {
+		return nil, err
+	}
+	return inMine(snapshots)
+}
+
+// inSuite returns the snapshots taken in the suite set with SetSuite.
+func inSuite(snapshots []files.SnapshotInfo) ([]files.SnapshotInfo, error) {
+	if suite == "" {
+		return snapshots, nil
+	}
+	return files.FilterSuite(snapshots, suite)
+}
@@ -0,0 +1,278 @@
+package review
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/ptdewey/shutter/internal/diff"
+	"github.com/ptdewey/shutter/internal/files"
+	"github.com/ptdewey/shutter/internal/pretty"
+)
+
+// Outcome is what happened to a snapshot during review.
+type Outcome int
+
+const (
+	Remaining Outcome = iota
+	Accepted
+	Rejected
+	Skipped
+)
+
+// Counts holds the review outcomes for a group of snapshots.
+type Counts struct {
+	Accepted  int
+	Rejected  int
+	Skipped   int
+	Remaining int
+}
+
+func (c *Counts) add(outcome Outcome, delta int) {
+	switch outcome {
+	case Accepted:
+		c.Accepted += delta
+	case Rejected:
+		c.Rejected += delta
+	case Skipped:
+		c.Skipped += delta
+	case Remaining:
+		c.Remaining += delta
+	}
+}
+
+// Reviewed returns the number of snapshots decided on, including skipped
+// ones.
+func (c Counts) Reviewed() int {
+	return c.Accepted + c.Rejected + c.Skipped
+}
+
+// Total returns the number of snapshots counted.
+func (c Counts) Total() int {
+	return c.Reviewed() + c.Remaining
+}
+
+// Summary tracks review outcomes per package directory, and per test within
+// each package.
+type Summary struct {
+	packages     []string
+	counts       map[string]*Counts
+	tests        map[string][]string
+	testCounts   map[string]*Counts
+	outcomes     map[string]Outcome
+	bytesChanged int
+}
+
+// NewSummary creates a summary in which every snapshot is still remaining.
+func NewSummary(snapshots []files.SnapshotInfo) *Summary {
+	s := &Summary{
+		counts:     map[string]*Counts{},
+		tests:      map[string][]string{},
+		testCounts: map[string]*Counts{},
+		outcomes:   map[string]Outcome{},
+	}
+	for _, info := range snapshots {
+		pkg := PackageOf(info)
+		if _, ok := s.counts[pkg]; !ok {
+			s.packages = append(s.packages, pkg)
+			s.counts[pkg] = &Counts{}
+		}
+		s.counts[pkg].Remaining++
+
+		key := testKey(pkg, TestOf(info))
+		if _, ok := s.testCounts[key]; !ok {
+			s.tests[pkg] = append(s.tests[pkg], TestOf(info))
+			s.testCounts[key] = &Counts{}
+		}
+		s.testCounts[key].Remaining++
+		s.outcomes[info.Path] = Remaining
+	}
+	return s
+}
+
+// PackageOf returns the package directory a snapshot belongs to, i.e. the
+// directory containing its __snapshots__ directory, for display.
+func PackageOf(info files.SnapshotInfo) string {
+	return files.DisplayPath(filepath.Dir(info.Dir))
+}
+
+// TestOf returns the test function that took a snapshot, such as TestUsers
+// for a snapshot of its subtest TestUsers/admin. It is read from the
+// snapshot's header, or else from the directory the snapshot is in.
+func TestOf(info files.SnapshotInfo) string {
+	name := info.Test
+	if name == "" {
+		name = info.Title
+	}
+	test, _, _ := strings.Cut(name, "/")
+	return test
+}
+
+func testKey(pkg, test string) string {
+	return pkg + "\x00" + test
+}
+
+// groupByTest orders snapshots by package and, within each package, by the
+// test that took them, so the snapshots of one test are reviewed together.
+// Packages and tests keep the order they are first seen in.
+func groupByTest(snapshots []files.SnapshotInfo) []files.SnapshotInfo {
+	var order []string
+	groups := map[string][]files.SnapshotInfo{}
+	for _, info := range snapshots {
+		pkg := PackageOf(info)
+		if _, ok := groups[pkg]; !ok {
+			order = append(order, pkg)
+		}
+		groups[pkg] = append(groups[pkg], info)
+	}
+
+	grouped := make([]files.SnapshotInfo, 0, len(snapshots))
+	for _, pkg := range order {
+		var tests []string
+		byTest := map[string][]files.SnapshotInfo{}
+		for _, info := range groups[pkg] {
+			test := TestOf(info)
+			if _, ok := byTest[test]; !ok {
+				tests = append(tests, test)
+			}
+			byTest[test] = append(byTest[test], info)
+		}
+		for _, test := range tests {
+			grouped = append(grouped, byTest[test]...)
+		}
+	}
+	return grouped
+}
+
+// Record sets the outcome of a snapshot. bytesChanged is the number of bytes
+// the decision changes in accepted snapshots; see ChangedBytes.
+func (s *Summary) Record(info files.SnapshotInfo, outcome Outcome, bytesChanged int) {
+	counts, ok := s.counts[PackageOf(info)]
+	if !ok {
+		return
+	}
+	counts.add(s.outcomes[info.Path], -1)
+	counts.add(outcome, 1)
+	if counts, ok := s.testCounts[testKey(PackageOf(info), TestOf(info))]; ok {
+		counts.add(s.outcomes[info.Path], -1)
+		counts.add(outcome, 1)
+	}
+	s.outcomes[info.Path] = outcome
+	s.bytesChanged += bytesChanged
+}
+
+// Packages returns the package directories of the snapshots, in the order
+// they were first seen.
+func (s *Summary) Packages() []string {
+	return s.packages
+}
+
+// PackageCounts returns the counts for the package directory pkg.
+func (s *Summary) PackageCounts(pkg string) Counts {
+	if c, ok := s.counts[pkg]; ok {
+		return *c
+	}
+	return Counts{}
+}
+
+// Tests returns the tests that took the snapshots of the package directory
+// pkg, in the order they were first seen.
+func (s *Summary) Tests(pkg string) []string {
+	return s.tests[pkg]
+}
+
+// TestCounts returns the counts for the test named test in the package
+// directory pkg.
+func (s *Summary) TestCounts(pkg, test string) Counts {
+	if c, ok := s.testCounts[testKey(pkg, test)]; ok {
+		return *c
+	}
+	return Counts{}
+}
+
+// Outcome returns the outcome recorded for a snapshot, Remaining if there
+// is none.
+func (s *Summary) Outcome(info files.SnapshotInfo) Outcome {
+	return s.outcomes[info.Path]
+}
+
+// Total returns the counts summed over all packages.
+func (s *Summary) Total() Counts {
+	var total Counts
+	for _, c := range s.counts {
+		total.Accepted += c.Accepted
+		total.Rejected += c.Rejected
+		total.Skipped += c.Skipped
+		total.Remaining += c.Remaining
+	}
+	return total
+}
+
+// BytesChanged returns the number of bytes changed in accepted snapshots.
+func (s *Summary) BytesChanged() int {
+	return s.bytesChanged
+}
+
+// String renders the summary as a table with one row per package.
+func (s *Summary) String() string {
+	nameWidth := len("total")
+	for _, pkg := range s.packages {
+		nameWidth = max(nameWidth, pretty.DisplayWidth(pkg))
+	}
+
+	var sb strings.Builder
+	sb.WriteString(pretty.Header("Review Summary") + "\n")
+	for _, pkg := range s.packages {
+		sb.WriteString(formatCountsRow(pkg, nameWidth, *s.counts[pkg]))
+	}
+	if len(s.packages) > 1 {
+		sb.WriteString(formatCountsRow("total", nameWidth, s.Total()))
+	}
+	sb.WriteString(fmt.Sprintf("  %d byte(s) changed\n", s.bytesChanged))
+	return sb.String()
+}
+
+func formatCountsRow(name string, nameWidth int, c Counts) string {
+	padding := strings.Repeat(" ", nameWidth-pretty.DisplayWidth(name))
+	return fmt.Sprintf("  %s%s  %s  %s  %s  %s\n",
+		name, padding,
+		pretty.Success(fmt.Sprintf("✓ %d accepted", c.Accepted)),
+		pretty.Error(fmt.Sprintf("✗ %d rejected", c.Rejected)),
+		pretty.Warning(fmt.Sprintf("⊘ %d skipped", c.Skipped)),
+		pretty.Gray(fmt.Sprintf("… %d remaining", c.Remaining)),
+	)
+}
+
+// ChangedBytes returns the number of bytes in lines that differ between an
+// accepted snapshot and its replacement. A nil accepted snapshot means the
+// whole new snapshot is counted.
+func ChangedBytes(accepted, newSnap *files.Snapshot) int {
+	if accepted == nil {
+		return len(newSnap.Content)
+	}
+	if !accepted.Corrupted() && files.SameContent(accepted, newSnap) {
+		return 0
+	}
+
+	changed := 0
+	for _, dl := range diff.Snapshots(accepted, newSnap) {
+		if dl.Kind != diff.DiffShared {
+			changed += len(dl.Line) + 1
+		}
+	}
+	return changed
+}
+
+// ChangedBytesFor reads a new snapshot and its accepted counterpart and
+// returns ChangedBytes for them. Unreadable new snapshots count as zero.
+func ChangedBytesFor(info files.SnapshotInfo) int {
+	newSnap, err := files.CurrentStorage().Read(info, files.StateNew)
+	if err != nil {
+		return 0
+	}
+	accepted, err := files.CurrentStorage().Read(info, files.StateAccepted)
+	if err != nil {
+		accepted = nil
+	}
+	return ChangedBytes(accepted, newSnap)
+}
@@ -0,0 +1,120 @@
+package review
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ptdewey/shutter/internal/files"
+)
+
+func TestSummaryGroupsByPackage(t *testing.T) {
+	t.Setenv("NO_COLOR", "1")
+
+	root := t.TempDir()
+	origCwd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(root); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = os.Chdir(origCwd) })
+
+	info := func(pkg, title string) files.SnapshotInfo {
+		dir := filepath.Join(root, pkg, "__snapshots__")
+		return files.SnapshotInfo{
+			Title: title,
+			Path:  filepath.Join(dir, title+".snap.new"),
+			Dir:   dir,
+		}
+	}
+	a1 := info("pkg/a", "TestA/one")
+	a2 := info("pkg/a", "TestA/two")
+	b1 := info("pkg/b", "TestB/one")
+	b2 := info("pkg/b", "TestB/two")
+
+	summary := NewSummary([]files.SnapshotInfo{a1, a2, b1, b2})
+	summary.Record(a1, Accepted, 12)
+	summary.Record(a2, Rejected, 0)
+	summary.Record(b1, Skipped, 0)
+
+	total := summary.Total()
+	if total != (Counts{Accepted: 1, Rejected: 1, Skipped: 1, Remaining: 1}) {
+		t.Errorf("unexpected totals: %+v", total)
+	}
+	if got := summary.Packages(); len(got) != 2 || got[0] != "pkg/a" || got[1] != "pkg/b" {
+		t.Errorf("expected packages in order of appearance, got %q", got)
+	}
+	if c := summary.PackageCounts("pkg/b"); c.Reviewed() != 1 || c.Total() != 2 {
+		t.Errorf("expected 1/2 reviewed in pkg/b, got %d/%d", c.Reviewed(), c.Total())
+	}
+	if summary.Outcome(a2) != Rejected || summary.Outcome(b2) != Remaining {
+		t.Errorf("unexpected outcomes: %v, %v", summary.Outcome(a2), summary.Outcome(b2))
+	}
+	if summary.BytesChanged() != 12 {
+		t.Errorf("expected 12 bytes changed, got %d", summary.BytesChanged())
+	}
+
+	out := summary.String()
+	for _, want := range []string{
+		"pkg/a  ✓ 1 accepted  ✗ 1 rejected  ⊘ 0 skipped  … 0 remaining",
+		"pkg/b  ✓ 0 accepted  ✗ 0 rejected  ⊘ 1 skipped  … 1 remaining",
+		"total  ✓ 1 accepted  ✗ 1 rejected  ⊘ 1 skipped  … 1 remaining",
+		"12 byte(s) changed",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("summary missing %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestSummaryGroupsByTest(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "pkg", "__snapshots__")
+	info := func(title, test string) files.SnapshotInfo {
+		return files.SnapshotInfo{
+			Title: title,
+			Path:  filepath.Join(dir, title+".snap.new"),
+			Dir:   dir,
+			Test:  test,
+		}
+	}
+	users := info("TestUsers/list", "TestUsers")
+	orders := info("TestOrders/total", "TestOrders")
+	admin := info("TestUsers/admin/role", "TestUsers/admin")
+	legacy := info("TestLegacy/output", "")
+
+	grouped := groupByTest([]files.SnapshotInfo{users, orders, admin, legacy})
+	var titles []string
+	for _, info := range grouped {
+		titles = append(titles, info.Title)
+	}
+	if want := "TestUsers/list TestUsers/admin/role TestOrders/total TestLegacy/output"; strings.Join(titles, " ") != want {
+		t.Errorf("expected snapshots grouped by test, got %q", titles)
+	}
+
+	summary := NewSummary(grouped)
+	summary.Record(admin, Accepted, 0)
+	pkg := PackageOf(users)
+	if got := summary.Tests(pkg); strings.Join(got, " ") != "TestUsers TestOrders TestLegacy" {
+		t.Errorf("expected tests in order of appearance, got %q", got)
+	}
+	if c := summary.TestCounts(pkg, "TestUsers"); c.Reviewed() != 1 || c.Total() != 2 {
+		t.Errorf("expected 1/2 reviewed in TestUsers, got %d/%d", c.Reviewed(), c.Total())
+	}
+}
+
+func TestChangedBytes(t *testing.T) {
+	newSnap := &files.Snapshot{Content: "a\nb\nc"}
+
+	if got := ChangedBytes(nil, newSnap); got != len(newSnap.Content) {
+		t.Errorf("new snapshot: expected %d, got %d", len(newSnap.Content), got)
+	}
+
+	accepted := &files.Snapshot{Content: "a\nx\nc"}
+	// "x" removed and "b" added, each counted with its newline.
+	if got := ChangedBytes(accepted, newSnap); got != 4 {
+		t.Errorf("changed snapshot: expected 4, got %d", got)
+	}
+}
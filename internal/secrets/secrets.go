@@ -0,0 +1,145 @@
+// Package secrets looks for content that resembles credentials or personal
+// data, so snapshots are checked before they are accepted. Scrubbers only
+// remove the patterns a test asks for; this catches what they missed.
+package secrets
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strings"
+)
+
+// Finding is a suspected secret in scanned content.
+type Finding struct {
+	Line  int    // 1-based line number
+	Kind  string // What the match looks like, e.g. "AWS access key"
+	Match string // The matched text
+}
+
+// String describes the finding with the match partially masked, so that
+// reporting a secret does not print it in full.
+func (f Finding) String() string {
+	return fmt.Sprintf("line %d: %s %s", f.Line, f.Kind, mask(f.Match))
+}
+
+// mask keeps the first four and last two characters of long matches.
+func mask(s string) string {
+	if len(s) <= 8 {
+		return s
+	}
+	return s[:4] + strings.Repeat("*", len(s)-6) + s[len(s)-2:]
+}
+
+type detector struct {
+	kind    string
+	pattern *regexp.Regexp
+	// ignore reports whether a match is known to be harmless.
+	ignore func(match string) bool
+}
+
+var detectors = []detector{
+	{kind: "AWS access key", pattern: regexp.MustCompile(`\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`)},
+	{kind: "Stripe live key", pattern: regexp.MustCompile(`\b[rs]k_live_[0-9A-Za-z]{16,}\b`)},
+	{kind: "GitHub token", pattern: regexp.MustCompile(`\b(?:gh[pousr]_[0-9A-Za-z]{36,}|github_pat_[0-9A-Za-z_]{22,})\b`)},
+	{kind: "email address", pattern: regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`), ignore: reservedEmail},
+	{kind: "high-entropy string", pattern: regexp.MustCompile(`[0-9A-Za-z+_-]{20,}={0,2}`), ignore: lowEntropy},
+}
+
+// reservedDomains are set aside for documentation and testing (RFC 2606 and
+// RFC 6761), so addresses in them are placeholders rather than personal
+// data.
+var reservedDomains = []string{"example.com", "example.org", "example.net", "example", "test", "invalid", "localhost"}
+
+func reservedEmail(match string) bool {
+	domain := strings.ToLower(match[strings.LastIndex(match, "@")+1:])
+	for _, reserved := range reservedDomains {
+		if domain == reserved || strings.HasSuffix(domain, "."+reserved) {
+			return true
+		}
+	}
+	return false
+}
+
+// Minimum Shannon entropy, in bits per character, for a token to be
+// reported. Hex strings draw from 16 symbols and so top out lower than
+// base64.
+const (
+	minEntropy    = 4.5
+	minHexEntropy = 3.3
+	minHexLength  = 32
+)
+
+// lowEntropy reports whether token is unlikely to be a generated secret:
+// it lacks either letters or digits, as words, identifiers and paths do, or
+// its characters repeat too much.
+func lowEntropy(token string) bool {
+	token = strings.TrimRight(token, "=")
+	if !strings.ContainsAny(token, "0123456789") || strings.IndexFunc(token, isLetter) < 0 {
+		return true
+	}
+	if isHex(token) {
+		return len(token) < minHexLength || entropy(token) < minHexEntropy
+	}
+	return entropy(token) < minEntropy
+}
+
+func isLetter(r rune) bool {
+	return r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z'
+}
+
+func isHex(s string) bool {
+	for _, r := range s {
+		if !(r >= '0' && r <= '9' || r >= 'a' && r <= 'f' || r >= 'A' && r <= 'F') {
+			return false
+		}
+	}
+	return true
+}
+
+// entropy returns the Shannon entropy of s in bits per character.
+func entropy(s string) float64 {
+	var counts [256]int
+	for i := 0; i < len(s); i++ {
+		counts[s[i]]++
+	}
+	var h float64
+	for _, c := range counts {
+		if c == 0 {
+			continue
+		}
+		p := float64(c) / float64(len(s))
+		h -= p * math.Log2(p)
+	}
+	return h
+}
+
+// Scan returns the suspected secrets in content, in line order. Text
+// matched by a specific detector, such as an AWS key, is not reported again
+// as a high-entropy string.
+func Scan(content string) []Finding {
+	var findings []Finding
+	for i, line := range strings.Split(content, "\n") {
+		var claimed [][]int
+		for _, d := range detectors {
+			for _, loc := range d.pattern.FindAllStringIndex(line, -1) {
+				match := line[loc[0]:loc[1]]
+				if overlaps(claimed, loc) || (d.ignore != nil && d.ignore(match)) {
+					continue
+				}
+				claimed = append(claimed, loc)
+				findings = append(findings, Finding{Line: i + 1, Kind: d.kind, Match: match})
+			}
+		}
+	}
+	return findings
+}
+
+func overlaps(claimed [][]int, loc []int) bool {
+	for _, c := range claimed {
+		if loc[0] < c[1] && c[0] < loc[1] {
+			return true
+		}
+	}
+	return false
+}
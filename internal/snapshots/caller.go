@@ -0,0 +1,97 @@
+package snapshots
+
+import (
+	"fmt"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+)
+
+// modulePath is the import path of the shutter module. It is derived from
+// this package's own path so that forks and vendored copies work too.
+var modulePath = func() string {
+	pc, _, _, _ := runtime.Caller(0)
+	name := runtime.FuncForPC(pc).Name()
+	if i := strings.Index(name, "/internal/snapshots."); i >= 0 {
+		return name[:i]
+	}
+	return "github.com/ptdewey/shutter"
+}()
+
+var (
+	helpersMu sync.RWMutex
+	helpers   = map[string]bool{}
+)
+
+// MarkHelper marks the function skip frames above its caller as a snapshot
+// helper. Frames of helper functions are skipped when detecting the file
+// that took a snapshot. MarkHelper(0) marks the function calling MarkHelper.
+func MarkHelper(skip int) {
+	pc, _, _, ok := runtime.Caller(skip + 1)
+	if !ok {
+		return
+	}
+	fn := runtime.FuncForPC(pc)
+	if fn == nil {
+		return
+	}
+
+	helpersMu.Lock()
+	helpers[fn.Name()] = true
+	helpersMu.Unlock()
+}
+
+func isHelper(function string) bool {
+	helpersMu.RLock()
+	defer helpersMu.RUnlock()
+	return helpers[function]
+}
+
+// callerFileName returns the base name of the file that took the snapshot:
+// the first frame on the call stack that is neither shutter library code nor
+// a function marked with MarkHelper.
+func callerFileName() string {
+	if frame, ok := callerFrame(); ok {
+		return filepath.Base(frame.File)
+	}
+	return "unknown"
+}
+
+// CallSite returns the file and line that took the snapshot, such as
+// render_test.go:42, found the same way as by callerFileName.
+func CallSite() string {
+	if frame, ok := callerFrame(); ok {
+		return fmt.Sprintf("%s:%d", filepath.Base(frame.File), frame.Line)
+	}
+	return "unknown"
+}
+
+func callerFrame() (runtime.Frame, bool) {
+	pcs := make([]uintptr, 64)
+	n := runtime.Callers(3, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+	for {
+		frame, more := frames.Next()
+		if frame.File != "" && !skipFrame(frame) {
+			return frame, true
+		}
+		if !more {
+			return runtime.Frame{}, false
+		}
+	}
+}
+
+// skipFrame reports whether frame belongs to shutter itself or to a marked
+// helper. Test files inside the shutter module are not skipped, so the
+// module's own tests are attributed correctly.
+func skipFrame(frame runtime.Frame) bool {
+	if isHelper(frame.Function) {
+		return true
+	}
+	if strings.HasSuffix(frame.File, "_test.go") {
+		return false
+	}
+	return strings.HasPrefix(frame.Function, modulePath+".") ||
+		strings.HasPrefix(frame.Function, modulePath+"/")
+}
@@ -0,0 +1,18 @@
+package snapshots
+
+// These helpers live in their own file so tests can tell whether the helper's
+// file or its caller's file was detected.
+
+func unmarkedHelper() string {
+	return callerFileName()
+}
+
+func markedHelper() string {
+	MarkHelper(0)
+	return callerFileName()
+}
+
+func nestedMarkedHelper() string {
+	MarkHelper(0)
+	return markedHelper()
+}
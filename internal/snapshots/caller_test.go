@@ -0,0 +1,45 @@
+package snapshots
+
+import (
+	"runtime"
+	"testing"
+)
+
+func TestCallerFileName(t *testing.T) {
+	tests := []struct {
+		name   string
+		detect func() string
+		want   string
+	}{
+		{"unmarked helper", unmarkedHelper, "caller_helper_test.go"},
+		{"marked helper", markedHelper, "caller_test.go"},
+		{"nested marked helpers", nestedMarkedHelper, "caller_test.go"},
+	}
+
+	for _, tt := range tests {
+		if got := tt.detect(); got != tt.want {
+			t.Errorf("%s: expected %q, got %q", tt.name, tt.want, got)
+		}
+	}
+}
+
+func TestSkipFrame(t *testing.T) {
+	tests := []struct {
+		frame runtime.Frame
+		want  bool
+	}{
+		{runtime.Frame{Function: modulePath + ".Snap", File: "/src/shutter/shutter.go"}, true},
+		{runtime.Frame{Function: modulePath + "/internal/snapshots.Snap", File: "/src/shutter/internal/snapshots/snapshot.go"}, true},
+		{runtime.Frame{Function: modulePath + "/freeze.init", File: "/src/shutter/freeze/freeze.go"}, true},
+		{runtime.Frame{Function: modulePath + "_test.TestSnap", File: "/src/shutter/shutter_test.go"}, false},
+		{runtime.Frame{Function: modulePath + ".TestInternal", File: "/src/shutter/internal_test.go"}, false},
+		{runtime.Frame{Function: modulePath + "extra.Snap", File: "/src/shutterextra/snap.go"}, false},
+		{runtime.Frame{Function: "example.com/app.TestUsers", File: "/src/app/users_test.go"}, false},
+	}
+
+	for _, tt := range tests {
+		if got := skipFrame(tt.frame); got != tt.want {
+			t.Errorf("skipFrame(%s in %s): expected %v, got %v", tt.frame.Function, tt.frame.File, tt.want, got)
+		}
+	}
+}
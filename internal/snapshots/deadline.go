@@ -0,0 +1,58 @@
+package snapshots
+
+import (
+	"fmt"
+	"time"
+)
+
+// deadliner is implemented by a T that knows when its test times out, like
+// *testing.T.
+type deadliner interface {
+	Deadline() (time.Time, bool)
+}
+
+// maxDeadlineMargin bounds the time kept back before a test's deadline to
+// report that a snapshot could not be processed in time.
+const maxDeadlineMargin = 5 * time.Second
+
+// WithinDeadline runs fn, the formatting or diffing of a snapshot named by
+// step, and returns its result. If t has a deadline, fn is given until
+// shortly before it: a value too large to process by then fails the
+// snapshot with an error, instead of the test timing out with no hint of
+// which snapshot it was stuck on. fn keeps running in the background after
+// that, so it must not panic.
+func WithinDeadline[V any](t T, step string, fn func() (V, error)) (V, error) {
+	d, ok := t.(deadliner)
+	if !ok {
+		return fn()
+	}
+	deadline, ok := d.Deadline()
+	if !ok {
+		return fn()
+	}
+	remaining := time.Until(deadline)
+	if remaining <= 0 {
+		return fn()
+	}
+	budget := remaining - min(remaining/10, maxDeadlineMargin)
+
+	type result struct {
+		v   V
+		err error
+	}
+	done := make(chan result, 1)
+	go func() {
+		v, err := fn()
+		done <- result{v, err}
+	}()
+
+	timer := time.NewTimer(budget)
+	defer timer.Stop()
+	select {
+	case r := <-done:
+		return r.v, r.err
+	case <-timer.C:
+		var zero V
+		return zero, fmt.Errorf("snapshot too large to process before deadline: %s did not finish in the %s left before the test times out", step, remaining.Round(time.Millisecond))
+	}
+}
@@ -0,0 +1,45 @@
+package snapshots
+
+import (
+	"fmt"
+
+	"github.com/ptdewey/shutter/internal/files"
+)
+
+// takenSnapshot is a snapshot already taken by a test, with the call site
+// that took it.
+type takenSnapshot struct {
+	digest string
+	site   string
+}
+
+// checkDuplicate reports an error if the test of t has already taken the
+// snapshot of key with different content: its pending snapshot would
+// otherwise be overwritten, and review would show only the second one.
+// Taking the same content twice is allowed. Like the summary of a test, this
+// needs a T with a Cleanup method, so that a T reused by a later run of the
+// test starts afresh.
+func checkDuplicate(t T, key, content string) error {
+	c, ok := t.(cleanuper)
+	if !ok {
+		return nil
+	}
+	site := CallSite()
+	digest := files.ContentDigest(content)
+
+	testReports.Lock()
+	defer testReports.Unlock()
+	r := reportFor(t, c)
+	first, ok := r.taken[key]
+	if !ok {
+		if r.taken == nil {
+			r.taken = make(map[string]takenSnapshot)
+		}
+		r.taken[key] = takenSnapshot{digest: digest, site: site}
+		return nil
+	}
+	if first.digest == digest {
+		return nil
+	}
+	return fmt.Errorf("taken twice in this test with different content, first at %s and again at %s; give each snapshot its own title", first.site, site)
+}
@@ -0,0 +1,74 @@
+package snapshots
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+
+	"github.com/ptdewey/shutter/internal/files"
+	"github.com/ptdewey/shutter/internal/transform"
+)
+
+// normalizers returns the normalization steps enabled in o, in the order
+// they are applied. Content of a structured type is first brought into its
+// canonical form, so an accepted snapshot whose formatting was edited by
+// hand still matches.
+func (o Options) normalizers() []func(string) string {
+	var steps []func(string) string
+	switch o.ContentType {
+	case files.ContentJSON:
+		steps = append(steps, normalizeJSON)
+	case files.ContentXML:
+		steps = append(steps, normalizeXML)
+	case files.ContentYAML:
+		steps = append(steps, transform.NormalizeYAML)
+	}
+	if o.NormalizeLineEndings {
+		steps = append(steps, normalizeLineEndings)
+	}
+	if o.TrimTrailingWhitespace {
+		steps = append(steps, trimTrailingWhitespace)
+	}
+	return steps
+}
+
+// normalize applies the normalization enabled in o to content.
+func (o Options) normalize(content string) string {
+	for _, step := range o.normalizers() {
+		content = step(content)
+	}
+	return content
+}
+
+func normalizeLineEndings(content string) string {
+	return strings.ReplaceAll(content, "\r\n", "\n")
+}
+
+func trimTrailingWhitespace(content string) string {
+	lines := strings.Split(content, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(line, " \t")
+	}
+	return strings.Join(lines, "\n")
+}
+
+// normalizeJSON re-indents JSON content the way SnapJSON formats it, keeping
+// the order of keys. Content that is not valid JSON, such as JSON with
+// unquoted placeholders left by scrubbers, is returned as is.
+func normalizeJSON(content string) string {
+	var buf bytes.Buffer
+	if err := json.Indent(&buf, []byte(content), "", "  "); err != nil {
+		return content
+	}
+	return buf.String()
+}
+
+// normalizeXML re-indents XML content the way SnapAuto formats it. Content
+// that is not well-formed is returned as is.
+func normalizeXML(content string) string {
+	indented, err := transform.IndentXML(content)
+	if err != nil {
+		return content
+	}
+	return indented
+}
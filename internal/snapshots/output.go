@@ -0,0 +1,35 @@
+package snapshots
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sync"
+)
+
+// output is where boxes and diagnostics are written. Writes are serialized,
+// so a writer such as a bytes.Buffer may be shared by parallel tests.
+var output struct {
+	sync.Mutex
+	w io.Writer
+}
+
+// SetOutput sets where boxes and diagnostics are written. A nil writer
+// restores the default, os.Stdout.
+func SetOutput(w io.Writer) {
+	output.Lock()
+	defer output.Unlock()
+	output.w = w
+}
+
+// Println writes its operands to the output set with SetOutput, like
+// fmt.Println.
+func Println(a ...any) {
+	output.Lock()
+	defer output.Unlock()
+	w := output.w
+	if w == nil {
+		w = os.Stdout
+	}
+	fmt.Fprintln(w, a...)
+}
@@ -0,0 +1,48 @@
+package snapshots
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/ptdewey/shutter/internal/files"
+)
+
+// pendingWrites records the pending snapshot files written in this process,
+// by the name of the test that took them.
+var pendingWrites struct {
+	sync.Mutex
+	byTest map[string][]string
+}
+
+func recordPendingWrite(testName, path string) {
+	pendingWrites.Lock()
+	defer pendingWrites.Unlock()
+	if pendingWrites.byTest == nil {
+		pendingWrites.byTest = make(map[string][]string)
+	}
+	pendingWrites.byTest[testName] = append(pendingWrites.byTest[testName], path)
+}
+
+// AssertNoPendingWrites reports an error if t or any of its subtests wrote a
+// pending snapshot file in this process.
+func AssertNoPendingWrites(t T) {
+	t.Helper()
+
+	pendingWrites.Lock()
+	var paths []string
+	for testName, written := range pendingWrites.byTest {
+		if testName == t.Name() || strings.HasPrefix(testName, t.Name()+"/") {
+			for _, path := range written {
+				paths = append(paths, files.DisplayPath(path))
+			}
+		}
+	}
+	pendingWrites.Unlock()
+	slices.Sort(paths)
+
+	if len(paths) > 0 {
+		t.Error(fmt.Sprintf("expected no snapshot changes, but %d snapshot(s) were written for review: %s", len(paths), strings.Join(paths, ", ")))
+	}
+}
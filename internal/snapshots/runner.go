@@ -0,0 +1,48 @@
+package snapshots
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"path"
+	"strings"
+	"sync"
+
+	"github.com/ptdewey/shutter/internal/files"
+)
+
+// benchmarkSnaps records the snapshots already taken by benchmarks in this
+// process. Benchmark functions run several times while b.N is calibrated,
+// and each snapshot only needs to be compared once.
+var benchmarkSnaps sync.Map
+
+// Benchmark is the part of *testing.B that tells a benchmark apart from
+// other tests. It is matched by method so that package testing is only
+// linked into test binaries.
+type Benchmark interface {
+	T
+	ResetTimer()
+}
+
+// firstBenchmarkSnap reports whether this is the first time the benchmark
+// takes the snapshot with the given title and variant.
+func firstBenchmarkSnap(b Benchmark, title, variant string) bool {
+	key := b.Name() + "\x00" + title + "\x00" + variant
+	_, taken := benchmarkSnaps.LoadOrStore(key, true)
+	return !taken
+}
+
+// generatedFuzzInput reports whether testName is that of a fuzz target
+// running an input generated by the fuzzing engine. Seed corpus entries and
+// stored corpus files run as subtests named after them (FuzzParse/seed#0),
+// generated inputs under the name of the target itself.
+func generatedFuzzInput(testName string) bool {
+	return strings.HasPrefix(testName, "Fuzz") && !strings.Contains(testName, "/")
+}
+
+// fuzzInputKey returns the test name and title under which a snapshot of a
+// fuzz-generated input is stored, so that its file is
+// <test>/fuzz/<title>/<first 12 hex digits of the content hash>.
+func fuzzInputKey(testName, title, content string) (string, string) {
+	sum := sha256.Sum256([]byte(content))
+	return path.Join(testName, "fuzz", files.SnapshotFileName(title)), hex.EncodeToString(sum[:])[:12]
+}
@@ -1,47 +1,260 @@
 package snapshots
 
 import (
+	"errors"
 	"fmt"
-	"path/filepath"
-	"runtime"
+	"io/fs"
+	"path"
+	"strings"
+	"time"
 
 	"github.com/ptdewey/shutter/internal/diff"
 	"github.com/ptdewey/shutter/internal/files"
 	"github.com/ptdewey/shutter/internal/pretty"
+	"github.com/ptdewey/shutter/internal/transform"
 )
 
+// T is the subset of testing.TB needed to take a snapshot. It is kept small
+// so that benchmarks, fuzz targets, and other test runners can satisfy it.
 type T interface {
 	Helper()
-	Skip(...any)
-	Skipf(string, ...any)
-	SkipNow()
 	Name() string
 	Error(...any)
 	Log(...any)
-	Cleanup(func())
+}
+
+// Options controls how a snapshot is stored.
+type Options struct {
+	// Variant distinguishes snapshots of the same test and title whose
+	// content legitimately differs between environments, such as the
+	// operating system. Each variant is accepted separately.
+	Variant string
+
+	// Applied names the scrubbers and ignore patterns that produced the
+	// content. It is recorded in the snapshot header for reviewers.
+	Applied []string
+
+	// ReadOnly compares against the accepted snapshot without writing a
+	// pending .snap.new file. A missing accepted snapshot is logged rather
+	// than reported as an error.
+	ReadOnly bool
+
+	// Hermetic is ReadOnly for source trees that cannot be written, such as
+	// in sandboxed build systems: no file is created or modified, not even
+	// manifests or flake records, and a missing accepted snapshot fails the
+	// test like a mismatching one, saying nothing was written.
+	Hermetic bool
+
+	// Group stores the snapshot in a directory named after the group within
+	// the test's directory, as if it were taken by a subtest of that name.
+	Group string
+
+	// Suite names the suite the snapshot was taken in. It is recorded in
+	// the header so the suite's snapshots can be reviewed as a group.
+	Suite string
+
+	// NormalizeLineEndings converts CRLF line endings to LF in both the new
+	// and the accepted content before they are compared.
+	NormalizeLineEndings bool
+
+	// TrimTrailingWhitespace removes spaces and tabs at the end of each line
+	// of both the new and the accepted content before they are compared.
+	TrimTrailingWhitespace bool
+
+	// DetectFlakes, when positive, records the content of this run next to
+	// the snapshot, keeping the last DetectFlakes runs, so that snapshots
+	// whose content changes between runs can be reported.
+	DetectFlakes int
+
+	// RecordManifest adds the snapshot to the manifest of its __snapshots__
+	// directory, which lists the snapshots compared by the current test run.
+	RecordManifest bool
+
+	// ExternalAbove, when positive, stores the content of snapshots larger
+	// than this many bytes in a sibling file, leaving only the header and
+	// its digest in the snapshot file.
+	ExternalAbove int
+
+	// ContentType is the format of the content, one of the files.Content
+	// constants. It is recorded in the header and decides how the content
+	// is normalized and displayed.
+	ContentType string
+
+	// Update accepts new and changed snapshots as they are taken instead of
+	// leaving them pending, as SHUTTER_UPDATE=1 and shutter regen do. A
+	// snapshot that may contain secrets is still left pending.
+	Update bool
+
+	// StaleAfter and StaleVersions enable warnings about stale baselines:
+	// accepted snapshots older than StaleAfter, or whose format version is
+	// StaleVersions or more minor versions behind. A matching snapshot is
+	// logged; a mismatching one is marked stale for review.
+	StaleAfter    time.Duration
+	StaleVersions int
+
+	// Tolerance, when positive, lets content whose numbers differ from the
+	// accepted snapshot by at most Tolerance match it. With TolerancePaths,
+	// only the numbers at those dotted paths of JSON content may differ.
+	Tolerance      float64
+	TolerancePaths []string
+
+	// AnyKeyOrder lets JSON content match the accepted snapshot when they
+	// hold the same values with object keys in a different order. With
+	// RewriteKeyOrder, the accepted snapshot is then rewritten in the new
+	// order, so the snapshot file follows the output again.
+	AnyKeyOrder     bool
+	RewriteKeyOrder bool
+
+	// Raw is the content before scrubbers and ignore patterns were
+	// applied. If set and different from the content, it is stored with a
+	// pending snapshot for review.
+	Raw string
+
+	// Equal, if set, decides whether content that is not equal to the
+	// accepted content as a string still matches it.
+	Equal func(accepted, content string) bool
+
+	// FuzzInput marks a snapshot of a fuzz-generated input. It is stored as
+	// fuzz/<title>/<content hash> within the target's directory, so that each
+	// distinct output is recorded once, apart from the target's regular
+	// snapshots.
+	FuzzInput bool
+
+	// SkipGeneratedInputs skips the snapshots of inputs generated by the
+	// fuzzing engine instead of comparing them against the accepted
+	// snapshot of a seed corpus entry.
+	SkipGeneratedInputs bool
 }
 
 func Snap(t T, title, version, content string) {
 	t.Helper()
+	SnapWithOptions(t, title, version, content, Options{})
+}
+
+// SnapWithOptions is like Snap but stores the snapshot according to opts.
+func SnapWithOptions(t T, title, version, content string, opts Options) {
+	t.Helper()
+
+	if b, ok := t.(Benchmark); ok && !firstBenchmarkSnap(b, path.Join(opts.Group, title), opts.Variant) {
+		return
+	}
+	if opts.SkipGeneratedInputs && generatedFuzzInput(t.Name()) {
+		return
+	}
 	testName := t.Name()
+	if opts.Group != "" {
+		testName = path.Join(testName, files.SnapshotFileName(opts.Group))
+	}
+	content = opts.normalize(content)
+	if opts.FuzzInput {
+		testName, title = fuzzInputKey(testName, title, content)
+	}
+	if err := checkDuplicate(t, files.SnapshotKey(testName, title)+"\x00"+opts.Variant, content); err != nil {
+		t.Error(fmt.Sprintf("snapshot %q: %v", title, err))
+		return
+	}
 
-	// Capture the caller's filename by walking up the call stack
-	// to find the first file that's not shutter.go
-	fileName := "unknown"
-	for i := 1; i < 10; i++ {
-		_, file, _, ok := runtime.Caller(i)
-		if !ok {
-			break
-		}
-		baseName := filepath.Base(file)
-		// Skip frames within shutter.go to get to the actual test file
-		if baseName != "shutter.go" {
-			fileName = baseName
-			break
+	snapshot := &files.Snapshot{
+		Title:       title,
+		Test:        testName,
+		FileName:    callerFileName(),
+		Content:     content,
+		Version:     version,
+		Variant:     opts.Variant,
+		Suite:       opts.Suite,
+		ContentType: opts.ContentType,
+		Options:     opts.Applied,
+		External:    opts.ExternalAbove > 0 && len(content) > opts.ExternalAbove,
+	}
+	if opts.Raw != "" && opts.Raw != content {
+		snapshot.Raw = opts.Raw
+		snapshot.RawFingerprint = files.OptionsFingerprint(opts.Applied)
+	}
+
+	if opts.DetectFlakes > 0 && !opts.FuzzInput && !opts.Hermetic {
+		if err := files.RecordRun(snapshot, opts.DetectFlakes); err != nil {
+			t.Error("failed to record run:", err)
 		}
 	}
 
-	SnapWithTitle(t, title, testName, fileName, version, content)
+	compare(t, snapshot, opts)
+}
+
+// matchesLoosely reports whether content matches the accepted snapshot
+// without being equal to it: by the Equal function of o, apart from the
+// lines it ignores, through its <<regex>> regions if it has Regions, or by
+// numbers within the tolerance of o. A corrupted snapshot never matches.
+func (o Options) matchesLoosely(t T, accepted *files.Snapshot, content string) bool {
+	t.Helper()
+	if accepted.Corrupted() {
+		return false
+	}
+	same, err := accepted.SameIgnoringLines(content)
+	if err != nil {
+		t.Error(fmt.Sprintf("snapshot %q: %v", accepted.Title, err))
+	}
+	if o.Equal != nil && o.Equal(accepted.Content, content) {
+		return true
+	}
+	return same || (accepted.Regions && transform.MatchRegions(accepted.Content, content)) || o.withinTolerance(accepted.Content, content)
+}
+
+// reorderedKeys reports whether content holds the same JSON values as the
+// accepted snapshot, with object keys in a different order, when o allows
+// any key order. A corrupted snapshot never matches.
+func (o Options) reorderedKeys(accepted *files.Snapshot, content string) bool {
+	return o.AnyKeyOrder && !accepted.Corrupted() && transform.SameJSON(accepted.Content, content)
+}
+
+// rewriteAccepted replaces the accepted snapshot with snapshot, whose
+// content only orders the accepted content's keys differently.
+func rewriteAccepted(t T, snapshot *files.Snapshot) {
+	t.Helper()
+
+	// Raw content is only kept for review.
+	rewritten := *snapshot
+	rewritten.Raw, rewritten.RawFingerprint = "", ""
+	if err := files.CurrentStorage().Write(&rewritten, files.StateAccepted); err != nil {
+		t.Error(fmt.Sprintf("snapshot %q: failed to rewrite key order: %v", snapshot.Title, err))
+		return
+	}
+	t.Log(fmt.Sprintf("snapshot %q rewritten in the new key order", snapshot.Title))
+}
+
+// withinTolerance reports whether the accepted and new content differ only
+// by numbers within the tolerance of o.
+func (o Options) withinTolerance(accepted, content string) bool {
+	if o.Tolerance <= 0 {
+		return false
+	}
+	if len(o.TolerancePaths) > 0 {
+		return o.ContentType == files.ContentJSON && transform.JSONNumbersWithin(accepted, content, o.Tolerance, o.TolerancePaths)
+	}
+	return transform.NumbersWithin(accepted, content, o.Tolerance)
+}
+
+// update accepts a snapshot just saved as pending, in update mode. A
+// snapshot that cannot be accepted, such as one that may contain secrets,
+// is left pending and reported. created tells whether no snapshot was
+// accepted before.
+func update(t T, snapshot *files.Snapshot, created bool) {
+	t.Helper()
+
+	dir, err := files.SnapshotDir()
+	if err == nil {
+		err = files.CurrentStorage().Accept(files.SnapshotInfo{Title: snapshot.Key(), Path: snapshot.Path, Dir: dir}, false)
+	}
+	if err != nil {
+		if created {
+			countRun(&runCounts.New)
+		} else {
+			countRun(&runCounts.Mismatched)
+		}
+		t.Error(fmt.Sprintf("snapshot %q left pending: %v - run '%s'", snapshot.Title, err, ReviewCommand()))
+		return
+	}
+	countRun(&runCounts.Updated)
+	t.Log(fmt.Sprintf("snapshot %q updated", snapshot.Title))
 }
 
 func SnapWithTitle(t T, title, testName, fileName, version, content string) {
@@ -55,28 +268,170 @@ func SnapWithTitle(t T, title, testName, fileName, version, content string) {
 		Version:  version,
 	}
 
-	accepted, err := files.ReadAccepted(title)
+	compare(t, snapshot, Options{})
+}
+
+// compare checks snapshot against its accepted counterpart, saving it as a
+// new snapshot and reporting an error if they differ or none was accepted.
+// In read-only mode nothing is saved and a missing snapshot is only logged.
+//
+// Snapshots are compared by digest when the accepted one stores it, so an
+// unchanged snapshot passes without comparing its content. The accepted
+// snapshot is checked for corruption first, so that a hand-edited or
+// merge-damaged file is reported as such rather than matching on a digest
+// its content no longer has, or failing as an ordinary mismatch.
+//
+// The accepted content is normalized like the new content, so enabling
+// normalization does not turn every existing snapshot into a mismatch.
+// Content that differs from it may still match it loosely (see
+// matchesLoosely).
+func compare(t T, snapshot *files.Snapshot, opts Options) {
+	t.Helper()
+
+	readOnly := opts.ReadOnly || opts.Hermetic
+	accepted, err := files.CurrentStorage().Read(files.SnapshotInfoFor(snapshot), files.StateAccepted)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		// Only a missing baseline makes this a new snapshot. Accepting one
+		// taken because the baseline could not be read, say for a remote
+		// without credentials, would overwrite the baseline.
+		t.Error(fmt.Sprintf("snapshot %q: failed to read the accepted snapshot: %v", snapshot.Title, err))
+		return
+	}
+	if opts.RecordManifest && !opts.Hermetic {
+		if err := files.RecordInManifest(snapshot, accepted); err != nil {
+			t.Error("failed to record snapshot in manifest:", err)
+		}
+	}
+	if err := files.RecordCompared(snapshot, accepted); err != nil {
+		t.Error("failed to record compared snapshot:", err)
+	}
 	if err == nil {
-		if accepted.Content == content {
+		// Step by step, so a digest matching any intermediate form is kept
+		// valid, e.g. for a file whose line endings git converted.
+		for _, step := range opts.normalizers() {
+			accepted.Normalize(step)
+		}
+		snapshot.Digest = files.ContentDigest(snapshot.Content)
+		stale := staleReason(accepted, snapshot, opts)
+		// Lines ignored in the accepted snapshot stay ignored once a new
+		// version is accepted.
+		snapshot.IgnoreLines = accepted.IgnoreLines
+		corrupted := accepted.Corrupted()
+		matched := !corrupted && files.SameContent(accepted, snapshot)
+		if !matched && opts.reorderedKeys(accepted, snapshot.Content) {
+			matched = true
+			if opts.RewriteKeyOrder && !readOnly {
+				rewriteAccepted(t, snapshot)
+			}
+		}
+		if matched || opts.matchesLoosely(t, accepted, snapshot.Content) {
+			countRun(&runCounts.Matched)
+			if stale != "" {
+				t.Log(fmt.Sprintf("snapshot %q has a stale baseline (%s); re-validate it", snapshot.Title, stale))
+			}
+			return
+		}
+		snapshot.Stale = stale
+
+		mismatch := "snapshot mismatch"
+		if corrupted {
+			mismatch = "accepted snapshot is corrupted: its content does not match its digest"
+		}
+
+		if readOnly {
+			countRun(&runCounts.Mismatched)
+			if diffLines, ok := diffWithinDeadline(t, accepted, snapshot); ok {
+				Println(pretty.DiffSnapshotBox(accepted, snapshot, diffLines))
+			}
+			if opts.Hermetic {
+				mismatch += " - snapshots are read-only, so no pending snapshot was written; update it where the source tree is writable"
+			}
+			t.Error(mismatch)
 			return
 		}
 
-		if err := files.SaveSnapshot(snapshot, "new"); err != nil {
+		if err := files.CurrentStorage().Write(snapshot, files.StateNew); err != nil {
 			t.Error("failed to save snapshot:", err)
 			return
 		}
+		recordPendingWrite(t.Name(), snapshot.Path)
+		if opts.Update {
+			update(t, snapshot, false)
+			return
+		}
+		countRun(&runCounts.Mismatched)
 
-		diffLines := diff.Histogram(accepted.Content, snapshot.Content)
-		fmt.Println(pretty.DiffSnapshotBox(accepted, snapshot, diffLines))
-		t.Error("snapshot mismatch - run 'shutter review' to update")
+		if diffLines, ok := diffWithinDeadline(t, accepted, snapshot); ok {
+			report(t, snapshot.Title, pretty.DiffSnapshotBox(accepted, snapshot, diffLines), false)
+		}
+		t.Error(mismatch + " - run '" + ReviewCommand() + "' to update, or: " + acceptCommand(snapshot))
 		return
 	}
 
-	if err := files.SaveSnapshot(snapshot, "new"); err != nil {
+	if opts.Hermetic {
+		countRun(&runCounts.New)
+		t.Error(fmt.Sprintf("snapshot %q has not been accepted - snapshots are read-only, so it was not recorded; take it where the source tree is writable", snapshot.Title))
+		return
+	}
+	if readOnly {
+		t.Log(fmt.Sprintf("snapshot %q not recorded: snapshots are read-only while fuzzing", snapshot.Title))
+		return
+	}
+
+	if err := files.CurrentStorage().Write(snapshot, files.StateNew); err != nil {
 		t.Error("failed to save snapshot:", err)
 		return
 	}
+	recordPendingWrite(t.Name(), snapshot.Path)
+	if opts.Update {
+		update(t, snapshot, true)
+		return
+	}
+	countRun(&runCounts.New)
 
-	fmt.Println(pretty.NewSnapshotBox(snapshot))
-	t.Error("new snapshot created - run 'shutter review' to accept")
+	report(t, snapshot.Title, pretty.NewSnapshotBox(snapshot), true)
+	t.Error("new snapshot created - run '" + ReviewCommand() + "' to accept, or: " + acceptCommand(snapshot))
+}
+
+// diffWithinDeadline diffs a mismatching snapshot against the accepted one.
+// If that cannot finish before the test's deadline, the snapshot fails with
+// the reason and no diff is shown.
+func diffWithinDeadline(t T, accepted, snapshot *files.Snapshot) ([]diff.DiffLine, bool) {
+	t.Helper()
+	diffLines, err := WithinDeadline(t, "diffing", func() ([]diff.DiffLine, error) {
+		return diff.Snapshots(accepted, snapshot), nil
+	})
+	if err != nil {
+		t.Error(fmt.Sprintf("snapshot %q: %v", snapshot.Title, err))
+		return nil, false
+	}
+	return diffLines, true
+}
+
+// ReviewCommand returns the command line that reviews the pending snapshots
+// of a run. Under Bazel they are collected from the test outputs first.
+func ReviewCommand() string {
+	if files.UnderBazel() {
+		return "shutter collect-bazel && shutter review"
+	}
+	return "shutter review"
+}
+
+// acceptCommand returns the command line that accepts snapshot alone, by
+// its test name and title.
+func acceptCommand(snapshot *files.Snapshot) string {
+	name := snapshot.Title
+	if snapshot.Test != "" {
+		name = snapshot.Test + "/" + name
+	}
+	return "shutter accept " + shellQuote(name)
+}
+
+// shellQuote quotes s as a single shell word: in double quotes, or in
+// single quotes if it holds characters double quotes don't protect.
+func shellQuote(s string) string {
+	if !strings.ContainsAny(s, "\"$`\\!") {
+		return `"` + s + `"`
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
 }
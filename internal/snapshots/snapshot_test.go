@@ -1,11 +1,14 @@
 package snapshots
 
 import (
+	"bytes"
 	"fmt"
+	"io/fs"
 	"os"
 	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/ptdewey/shutter/internal/files"
 )
@@ -122,7 +125,7 @@ func TestSnap_NewSnapshot(t *testing.T) {
 	}
 
 	// Verify snapshot file was created
-	snapPath := filepath.Join("__snapshots__", "test_snap.snap.new")
+	snapPath := filepath.Join("__snapshots__", "TestExample", "test_snap.snap.new")
 	if _, err := os.Stat(snapPath); os.IsNotExist(err) {
 		t.Error("expected snapshot file to be created")
 	}
@@ -139,7 +142,7 @@ func TestSnap_MatchingSnapshot(t *testing.T) {
 		Content:  "expected content",
 		Version:  "v1",
 	}
-	if err := files.SaveSnapshot(accepted, "accepted"); err != nil {
+	if err := files.SaveSnapshot(accepted, files.StateAccepted); err != nil {
 		t.Fatalf("failed to save accepted snapshot: %v", err)
 	}
 
@@ -163,7 +166,7 @@ func TestSnap_MismatchedSnapshot(t *testing.T) {
 		Content:  "old content",
 		Version:  "v1",
 	}
-	if err := files.SaveSnapshot(accepted, "accepted"); err != nil {
+	if err := files.SaveSnapshot(accepted, files.StateAccepted); err != nil {
 		t.Fatalf("failed to save accepted snapshot: %v", err)
 	}
 
@@ -180,12 +183,49 @@ func TestSnap_MismatchedSnapshot(t *testing.T) {
 	}
 
 	// Verify new snapshot file was created
-	snapPath := filepath.Join("__snapshots__", "mismatched_test.snap.new")
+	snapPath := filepath.Join("__snapshots__", "TestExample", "mismatched_test.snap.new")
 	if _, err := os.Stat(snapPath); os.IsNotExist(err) {
 		t.Error("expected new snapshot file to be created")
 	}
 }
 
+func TestSnapWithOptions_Normalize(t *testing.T) {
+	setupTestDir(t)
+
+	// An accepted snapshot with trailing whitespace, checked out with CRLF
+	// line endings.
+	accepted := &files.Snapshot{
+		Title:    "normalized",
+		Test:     "TestExample",
+		FileName: "test.go",
+		Content:  "name  \nvalue\t\n",
+		Version:  "v1",
+	}
+	raw := strings.ReplaceAll(accepted.Serialize(), "\n", "\r\n")
+	path := filepath.Join("__snapshots__", "TestExample", "normalized.snap")
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(raw), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	mt := &mockT{name: "TestExample"}
+	SnapWithOptions(mt, "normalized", "v1", "name\r\nvalue\r\n", Options{})
+	if len(mt.errors) != 1 {
+		t.Errorf("expected a mismatch without normalization, got %v", mt.errors)
+	}
+
+	mt = &mockT{name: "TestExample"}
+	SnapWithOptions(mt, "normalized", "v1", "name\r\nvalue\r\n", Options{
+		NormalizeLineEndings:   true,
+		TrimTrailingWhitespace: true,
+	})
+	if len(mt.errors) != 0 {
+		t.Errorf("expected normalized snapshots to match, got %v", mt.errors)
+	}
+}
+
 func TestSnap_CallerDetection(t *testing.T) {
 	setupTestDir(t)
 
@@ -193,7 +233,7 @@ func TestSnap_CallerDetection(t *testing.T) {
 	Snap(mt, "caller_test", "v1", "test content")
 
 	// Read the created snapshot
-	snap, err := files.ReadSnapshot("caller_test", "new")
+	snap, err := files.ReadSnapshot("TestCallerDetection", "caller_test", files.StateNew)
 	if err != nil {
 		t.Fatalf("failed to read snapshot: %v", err)
 	}
@@ -216,7 +256,7 @@ func TestSnapWithTitle_CreatesCorrectSnapshot(t *testing.T) {
 	SnapWithTitle(mt, "custom_title", "TestExample", "test.go", "v1", "custom content")
 
 	// Read the snapshot
-	snap, err := files.ReadSnapshot("custom_title", "new")
+	snap, err := files.ReadSnapshot("TestExample", "custom_title", files.StateNew)
 	if err != nil {
 		t.Fatalf("failed to read snapshot: %v", err)
 	}
@@ -249,7 +289,7 @@ func TestSnapWithTitle_MatchingContent(t *testing.T) {
 		Content:  "same content",
 		Version:  "v1",
 	}
-	if err := files.SaveSnapshot(accepted, "accepted"); err != nil {
+	if err := files.SaveSnapshot(accepted, files.StateAccepted); err != nil {
 		t.Fatalf("failed to save accepted snapshot: %v", err)
 	}
 
@@ -273,7 +313,7 @@ func TestSnapWithTitle_MismatchedContent(t *testing.T) {
 		Content:  "old content",
 		Version:  "v1",
 	}
-	if err := files.SaveSnapshot(accepted, "accepted"); err != nil {
+	if err := files.SaveSnapshot(accepted, files.StateAccepted); err != nil {
 		t.Fatalf("failed to save accepted snapshot: %v", err)
 	}
 
@@ -289,7 +329,7 @@ func TestSnapWithTitle_MismatchedContent(t *testing.T) {
 	}
 
 	// Should create new snapshot file
-	newSnap, err := files.ReadSnapshot("mismatch_title", "new")
+	newSnap, err := files.ReadSnapshot("TestMismatch", "mismatch_title", files.StateNew)
 	if err != nil {
 		t.Fatalf("failed to read new snapshot: %v", err)
 	}
@@ -337,7 +377,7 @@ func TestSnap_MultipleDifferentSnapshots(t *testing.T) {
 
 	// Verify all files exist
 	for _, title := range []string{"snap_one", "snap_two", "snap_three"} {
-		snapPath := filepath.Join("__snapshots__", title+".snap.new")
+		snapPath := filepath.Join("__snapshots__", "TestMultiple", title+".snap.new")
 		if _, err := os.Stat(snapPath); os.IsNotExist(err) {
 			t.Errorf("expected snapshot %s to exist", title)
 		}
@@ -351,7 +391,7 @@ func TestSnap_EmptyContent(t *testing.T) {
 	Snap(mt, "empty_test", "v1", "")
 
 	// Should create snapshot with empty content
-	snap, err := files.ReadSnapshot("empty_test", "new")
+	snap, err := files.ReadSnapshot("TestEmpty", "empty_test", files.StateNew)
 	if err != nil {
 		t.Fatalf("failed to read snapshot: %v", err)
 	}
@@ -370,7 +410,7 @@ line three`
 	mt := &mockT{name: "TestMultiline"}
 	Snap(mt, "multiline_test", "v1", content)
 
-	snap, err := files.ReadSnapshot("multiline_test", "new")
+	snap, err := files.ReadSnapshot("TestMultiline", "multiline_test", files.StateNew)
 	if err != nil {
 		t.Fatalf("failed to read snapshot: %v", err)
 	}
@@ -387,7 +427,7 @@ func TestSnap_SpecialCharacters(t *testing.T) {
 	mt := &mockT{name: "TestSpecial"}
 	Snap(mt, "special_test", "v1", content)
 
-	snap, err := files.ReadSnapshot("special_test", "new")
+	snap, err := files.ReadSnapshot("TestSpecial", "special_test", files.StateNew)
 	if err != nil {
 		t.Fatalf("failed to read snapshot: %v", err)
 	}
@@ -402,7 +442,7 @@ func TestSnap_VersionTracking(t *testing.T) {
 	mt := &mockT{name: "TestVersion"}
 	Snap(mt, "version_test", "v2", "content")
 
-	snap, err := files.ReadSnapshot("version_test", "new")
+	snap, err := files.ReadSnapshot("TestVersion", "version_test", files.StateNew)
 	if err != nil {
 		t.Fatalf("failed to read snapshot: %v", err)
 	}
@@ -422,7 +462,7 @@ func TestSnap_UpdateAcceptedSnapshot(t *testing.T) {
 		Content:  "old version",
 		Version:  "v1",
 	}
-	if err := files.SaveSnapshot(accepted, "accepted"); err != nil {
+	if err := files.SaveSnapshot(accepted, files.StateAccepted); err != nil {
 		t.Fatalf("failed to save accepted snapshot: %v", err)
 	}
 
@@ -431,7 +471,7 @@ func TestSnap_UpdateAcceptedSnapshot(t *testing.T) {
 	Snap(mt, "update_test", "v2", "new version")
 
 	// Verify new snapshot was created
-	newSnap, err := files.ReadSnapshot("update_test", "new")
+	newSnap, err := files.ReadSnapshot("TestUpdate", "update_test", files.StateNew)
 	if err != nil {
 		t.Fatalf("failed to read new snapshot: %v", err)
 	}
@@ -444,7 +484,7 @@ func TestSnap_UpdateAcceptedSnapshot(t *testing.T) {
 	}
 
 	// Accepted snapshot should remain unchanged
-	acceptedSnap, err := files.ReadSnapshot("update_test", "snap")
+	acceptedSnap, err := files.ReadSnapshot("TestUpdate", "update_test", files.StateAccepted)
 	if err != nil {
 		t.Fatalf("failed to read accepted snapshot: %v", err)
 	}
@@ -460,7 +500,7 @@ func TestSnap_WithSpacesInTitle(t *testing.T) {
 	Snap(mt, "test with spaces", "v1", "content")
 
 	// Should normalize title to filename
-	snap, err := files.ReadSnapshot("test with spaces", "new")
+	snap, err := files.ReadSnapshot("TestSpaces", "test with spaces", files.StateNew)
 	if err != nil {
 		t.Fatalf("failed to read snapshot: %v", err)
 	}
@@ -469,3 +509,663 @@ func TestSnap_WithSpacesInTitle(t *testing.T) {
 		t.Errorf("expected title to preserve spaces, got %q", snap.Title)
 	}
 }
+
+func TestSnap_SameTitleDifferentTests(t *testing.T) {
+	setupTestDir(t)
+
+	first := &mockT{name: "TestFirst"}
+	Snap(first, "shared title", "v1", "first content")
+	second := &mockT{name: "TestSecond"}
+	Snap(second, "shared title", "v1", "second content")
+
+	for _, want := range []struct{ test, content string }{
+		{"TestFirst", "first content"},
+		{"TestSecond", "second content"},
+	} {
+		snap, err := files.ReadSnapshot(want.test, "shared title", files.StateNew)
+		if err != nil {
+			t.Fatalf("failed to read snapshot for %s: %v", want.test, err)
+		}
+		if snap.Content != want.content {
+			t.Errorf("%s: expected content %q, got %q", want.test, want.content, snap.Content)
+		}
+	}
+}
+
+func TestSnap_CorruptedSnapshot(t *testing.T) {
+	setupTestDir(t)
+
+	accepted := &files.Snapshot{
+		Title:   "corrupted_test",
+		Test:    "TestExample",
+		Content: "recorded content",
+		Version: "v1",
+	}
+	if err := files.SaveSnapshot(accepted, files.StateAccepted); err != nil {
+		t.Fatalf("failed to save accepted snapshot: %v", err)
+	}
+
+	// Edit the content by hand, leaving the stored digest behind.
+	data, err := os.ReadFile(accepted.Path)
+	if err != nil {
+		t.Fatalf("failed to read accepted snapshot: %v", err)
+	}
+	edited := strings.Replace(string(data), "recorded content", "edited content", 1)
+	if err := os.WriteFile(accepted.Path, []byte(edited), 0644); err != nil {
+		t.Fatalf("failed to edit accepted snapshot: %v", err)
+	}
+
+	// Output matching the recorded digest does not hide the corruption.
+	for _, content := range []string{"recorded content", "new content"} {
+		mt := &mockT{name: "TestExample"}
+		Snap(mt, "corrupted_test", "v1", content)
+		if len(mt.errors) != 1 || !strings.Contains(mt.errors[0], "accepted snapshot is corrupted") {
+			t.Errorf("%q: expected a corrupted snapshot error, got: %v", content, mt.errors)
+		}
+	}
+}
+
+func TestSnap_Regions(t *testing.T) {
+	setupTestDir(t)
+
+	for _, regions := range []bool{false, true} {
+		accepted := &files.Snapshot{Title: "regions", Test: "TestExample", Content: "cat <<.*>>\n", Version: "v1", Regions: regions}
+		if err := files.SaveSnapshot(accepted, files.StateAccepted); err != nil {
+			t.Fatalf("failed to save accepted snapshot: %v", err)
+		}
+
+		// Without the header field, <<...>> is plain text, as in a heredoc.
+		mt := &mockT{name: "TestExample"}
+		Snap(mt, "regions", "v1", "cat EOF\n")
+		if matched := len(mt.errors) == 0; matched != regions {
+			t.Errorf("regions %v: expected a match %v, got errors %v", regions, regions, mt.errors)
+		}
+	}
+}
+
+func TestSnap_LegacyFlatLayout(t *testing.T) {
+	setupTestDir(t)
+
+	// Accepted snapshots written before per-test directories live directly
+	// in __snapshots__ and should still be compared against.
+	legacy := &files.Snapshot{
+		Title:   "legacy_test",
+		Test:    "TestLegacy",
+		Content: "legacy content",
+		Version: "v1",
+	}
+	if err := os.MkdirAll("__snapshots__", 0755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join("__snapshots__", "legacy_test.snap"), []byte(legacy.Serialize()), 0644); err != nil {
+		t.Fatalf("write legacy snapshot: %v", err)
+	}
+
+	mt := &mockT{name: "TestLegacy"}
+	Snap(mt, "legacy_test", "v1", "legacy content")
+	if len(mt.errors) != 0 {
+		t.Errorf("expected legacy snapshot to match, got: %v", mt.errors)
+	}
+
+	// A legacy file recorded by another test must not be used.
+	other := &mockT{name: "TestOther"}
+	Snap(other, "legacy_test", "v1", "legacy content")
+	if len(other.errors) != 1 || !strings.Contains(other.errors[0], "new snapshot created") {
+		t.Errorf("expected new snapshot for other test, got: %v", other.errors)
+	}
+}
+
+func TestSnapWithOptions_Variants(t *testing.T) {
+	setupTestDir(t)
+
+	accepted := &files.Snapshot{
+		Title:   "paths",
+		Test:    "TestVariants",
+		Content: "C:\\Users",
+		Variant: "windows",
+	}
+	if err := files.SaveSnapshot(accepted, files.StateAccepted); err != nil {
+		t.Fatalf("failed to save accepted snapshot: %v", err)
+	}
+
+	windows := &mockT{name: "TestVariants"}
+	SnapWithOptions(windows, "paths", "v1", "C:\\Users", Options{Variant: "windows"})
+	if len(windows.errors) != 0 {
+		t.Errorf("expected windows variant to match, got: %v", windows.errors)
+	}
+
+	// Other variants are accepted independently.
+	linux := &mockT{name: "TestVariants"}
+	SnapWithOptions(linux, "paths", "v1", "/home", Options{Variant: "linux"})
+	if len(linux.errors) != 1 || !strings.Contains(linux.errors[0], "new snapshot created") {
+		t.Errorf("expected new snapshot for linux variant, got: %v", linux.errors)
+	}
+
+	snapPath := filepath.Join("__snapshots__", "TestVariants", "paths~linux.snap.new")
+	if _, err := os.Stat(snapPath); err != nil {
+		t.Errorf("expected variant snapshot at %s: %v", snapPath, err)
+	}
+}
+
+func TestSnapWithOptions_ReadOnly(t *testing.T) {
+	setupTestDir(t)
+
+	// A missing snapshot is logged, not written or reported.
+	mt := &mockT{name: "TestReadOnly"}
+	SnapWithOptions(mt, "output", "v1", "content", Options{ReadOnly: true})
+
+	if len(mt.errors) != 0 {
+		t.Errorf("expected no errors, got %v", mt.errors)
+	}
+	if len(mt.logs) != 1 || !strings.Contains(mt.logs[0], "not recorded") {
+		t.Errorf("expected a log about the unrecorded snapshot, got %v", mt.logs)
+	}
+	if _, err := files.ReadNew("TestReadOnly", "output"); err == nil {
+		t.Error("expected no .snap.new file in read-only mode")
+	}
+
+	// A mismatch is still an error, but no .snap.new is written.
+	accepted := &files.Snapshot{Title: "output", Test: "TestReadOnly", Content: "accepted"}
+	if err := files.SaveSnapshot(accepted, files.StateAccepted); err != nil {
+		t.Fatalf("SaveSnapshot failed: %v", err)
+	}
+
+	mt = &mockT{name: "TestReadOnly"}
+	SnapWithOptions(mt, "output", "v1", "content", Options{ReadOnly: true})
+
+	if len(mt.errors) != 1 || !strings.Contains(mt.errors[0], "snapshot mismatch") {
+		t.Errorf("expected a mismatch error, got %v", mt.errors)
+	}
+	if _, err := files.ReadNew("TestReadOnly", "output"); err == nil {
+		t.Error("expected no .snap.new file in read-only mode")
+	}
+}
+
+func TestSnapWithOptions_Hermetic(t *testing.T) {
+	dir := setupTestDir(t)
+	opts := Options{Hermetic: true, Update: true, DetectFlakes: 3, RecordManifest: true}
+
+	// A missing snapshot fails the test, and nothing is written.
+	mt := &mockT{name: "TestHermetic"}
+	SnapWithOptions(mt, "output", "v1", "content", opts)
+
+	if len(mt.errors) != 1 || !strings.Contains(mt.errors[0], "has not been accepted - snapshots are read-only") {
+		t.Errorf("expected an error about the missing snapshot, got %v", mt.errors)
+	}
+	if entries, err := os.ReadDir(dir); err != nil || len(entries) != 0 {
+		t.Fatalf("expected no files to be written, got %v (err %v)", entries, err)
+	}
+
+	// A mismatch fails without a pending snapshot, even in update mode.
+	accepted := &files.Snapshot{Title: "output", Test: "TestHermetic", Content: "accepted"}
+	if err := files.SaveSnapshot(accepted, files.StateAccepted); err != nil {
+		t.Fatalf("SaveSnapshot failed: %v", err)
+	}
+	before, err := os.ReadDir(filepath.Join(dir, "__snapshots__", "TestHermetic"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mt = &mockT{name: "TestHermetic"}
+	SnapWithOptions(mt, "output", "v1", "content", opts)
+
+	if len(mt.errors) != 1 || !strings.Contains(mt.errors[0], "snapshot mismatch - snapshots are read-only") {
+		t.Errorf("expected a read-only mismatch error, got %v", mt.errors)
+	}
+	after, err := os.ReadDir(filepath.Join(dir, "__snapshots__", "TestHermetic"))
+	if err != nil || len(after) != len(before) {
+		t.Errorf("expected no new files, got %v (err %v)", after, err)
+	}
+	if snap, err := files.ReadAccepted("TestHermetic", "output"); err != nil || snap.Content != "accepted" {
+		t.Errorf("expected the accepted snapshot to be unchanged, got %+v (err %v)", snap, err)
+	}
+}
+
+func TestSnapWithOptions_FuzzInput(t *testing.T) {
+	setupTestDir(t)
+
+	for _, content := range []string{"first", "second", "first"} {
+		mt := &mockT{name: "FuzzParse"}
+		SnapWithOptions(mt, "parsed", "v1", content, Options{FuzzInput: true})
+	}
+
+	entries, err := os.ReadDir(filepath.Join("__snapshots__", "FuzzParse", "fuzz", "parsed"))
+	if err != nil {
+		t.Fatalf("ReadDir failed: %v", err)
+	}
+	if len(entries) != 2 {
+		t.Errorf("expected one snapshot per distinct output, got %d", len(entries))
+	}
+
+	_, first := fuzzInputKey("FuzzParse", "parsed", "first")
+	_, again := fuzzInputKey("FuzzParse", "parsed", "first")
+	if first != again {
+		t.Error("expected fuzz input titles to be stable")
+	}
+}
+
+func TestSnapWithOptions_SkipGeneratedInputs(t *testing.T) {
+	dir := setupTestDir(t)
+	opts := Options{ReadOnly: true, SkipGeneratedInputs: true}
+
+	// A generated input runs under the fuzz target's own name.
+	mt := &mockT{name: "FuzzParse"}
+	SnapWithOptions(mt, "parsed", "v1", "generated", opts)
+	if len(mt.errors) != 0 || len(mt.logs) != 0 {
+		t.Errorf("expected the generated input to be skipped, got errors %v, logs %v", mt.errors, mt.logs)
+	}
+	if entries, err := os.ReadDir(dir); err != nil || len(entries) != 0 {
+		t.Errorf("expected no files to be written, got %v (err %v)", entries, err)
+	}
+
+	// Seed corpus entries are still compared.
+	accepted := &files.Snapshot{Title: "parsed", Test: "FuzzParse/seed#0", Content: "seed"}
+	if err := files.SaveSnapshot(accepted, files.StateAccepted); err != nil {
+		t.Fatalf("SaveSnapshot failed: %v", err)
+	}
+	mt = &mockT{name: "FuzzParse/seed#0"}
+	SnapWithOptions(mt, "parsed", "v1", "changed", opts)
+	if len(mt.errors) != 1 || !strings.Contains(mt.errors[0], "snapshot mismatch") {
+		t.Errorf("expected the seed to be compared, got %v", mt.errors)
+	}
+}
+
+func TestFirstBenchmarkSnap(t *testing.T) {
+	// Let the test run again with -count or -shuffle.
+	t.Cleanup(benchmarkSnaps.Clear)
+
+	// testing.Benchmark runs the function several times while calibrating
+	// b.N; the snapshot must only be taken on the first run.
+	runs, taken := 0, 0
+	testing.Benchmark(func(b *testing.B) {
+		runs++
+		if firstBenchmarkSnap(b, "output", "") {
+			taken++
+		}
+	})
+
+	if taken != 1 {
+		t.Errorf("expected the snapshot to be taken once over %d runs, got %d", runs, taken)
+	}
+}
+
+func TestSnapWithOptions_StaleBaseline(t *testing.T) {
+	setupTestDir(t)
+
+	accepted := &files.Snapshot{Title: "stale_test", Test: "TestExample", Content: "content", Version: "0.1.0"}
+	if err := files.SaveSnapshot(accepted, files.StateAccepted); err != nil {
+		t.Fatalf("failed to save accepted snapshot: %v", err)
+	}
+	old := time.Now().Add(-400 * 24 * time.Hour)
+	if err := os.Chtimes(accepted.Path, old, old); err != nil {
+		t.Fatal(err)
+	}
+
+	// Checks are off by default.
+	mt := &mockT{name: "TestExample"}
+	SnapWithOptions(mt, "stale_test", "0.3.0", "content", Options{})
+	if len(mt.logs) != 0 || len(mt.errors) != 0 {
+		t.Errorf("expected no output without stale checks, got logs %v, errors %v", mt.logs, mt.errors)
+	}
+
+	mt = &mockT{name: "TestExample"}
+	SnapWithOptions(mt, "stale_test", "0.3.0", "content", Options{StaleAfter: 365 * 24 * time.Hour, StaleVersions: 2})
+	if len(mt.errors) != 0 {
+		t.Errorf("expected a matching snapshot to pass, got %v", mt.errors)
+	}
+	if len(mt.logs) != 1 || !strings.Contains(mt.logs[0], "accepted 400 days ago") || !strings.Contains(mt.logs[0], "format version 0.1.0") {
+		t.Errorf("expected a stale baseline warning, got %v", mt.logs)
+	}
+
+	// A mismatch marks the pending snapshot for review; accepting it
+	// renews the baseline.
+	mt = &mockT{name: "TestExample"}
+	SnapWithOptions(mt, "stale_test", "0.1.0", "changed", Options{StaleAfter: 365 * 24 * time.Hour})
+	pending, err := files.ReadSnapshot("TestExample", "stale_test", files.StateNew)
+	if err != nil {
+		t.Fatalf("failed to read pending snapshot: %v", err)
+	}
+	if pending.Stale != "accepted 400 days ago" {
+		t.Errorf("expected the pending snapshot to be marked stale, got %q", pending.Stale)
+	}
+	if err := files.AcceptSnapshot("TestExample", "stale_test"); err != nil {
+		t.Fatal(err)
+	}
+	renewed, err := files.ReadSnapshot("TestExample", "stale_test", files.StateAccepted)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if renewed.Stale != "" || renewed.Content != "changed" || renewed.Corrupted() {
+		t.Errorf("expected the accepted snapshot without a stale mark, got %+v", renewed)
+	}
+}
+
+func TestSnapWithOptions_Update(t *testing.T) {
+	setupTestDir(t)
+
+	// A new snapshot is accepted as it is taken.
+	mt := &mockT{name: "TestExample"}
+	SnapWithOptions(mt, "update_test", "0.1.0", "first", Options{Update: true})
+	if len(mt.errors) != 0 {
+		t.Errorf("expected no errors in update mode, got %v", mt.errors)
+	}
+	if len(mt.logs) != 1 || !strings.Contains(mt.logs[0], `snapshot "update_test" updated`) {
+		t.Errorf("expected an update log, got %v", mt.logs)
+	}
+
+	// A changed snapshot replaces the accepted one.
+	mt = &mockT{name: "TestExample"}
+	SnapWithOptions(mt, "update_test", "0.1.0", "second", Options{Update: true})
+	if len(mt.errors) != 0 {
+		t.Errorf("expected no errors in update mode, got %v", mt.errors)
+	}
+	accepted, err := files.ReadSnapshot("TestExample", "update_test", files.StateAccepted)
+	if err != nil {
+		t.Fatalf("failed to read accepted snapshot: %v", err)
+	}
+	if accepted.Content != "second" {
+		t.Errorf("expected the accepted snapshot to be updated, got %q", accepted.Content)
+	}
+	if _, err := files.ReadSnapshot("TestExample", "update_test", files.StateNew); err == nil {
+		t.Error("expected no pending snapshot in update mode")
+	}
+}
+
+func TestSnap_CleanupSummary(t *testing.T) {
+	setupTestDir(t)
+
+	accepted := &files.Snapshot{Title: "changed", Test: "TestExample", Content: "old", Version: "0.1.0"}
+	if err := files.SaveSnapshot(accepted, files.StateAccepted); err != nil {
+		t.Fatalf("failed to save accepted snapshot: %v", err)
+	}
+
+	mt := &mockT{name: "TestExample"}
+	SnapWithOptions(mt, "first", "0.1.0", "one", Options{})
+	SnapWithOptions(mt, "changed", "0.1.0", "new", Options{})
+	SnapWithOptions(mt, "second", "0.1.0", "two", Options{})
+
+	if len(mt.errors) != 3 {
+		t.Errorf("expected each snapshot to fail the test right away, got %v", mt.errors)
+	}
+	if len(mt.cleanupFuncs) != 1 {
+		t.Fatalf("expected one cleanup per test, got %d", len(mt.cleanupFuncs))
+	}
+	if len(mt.logs) != 0 {
+		t.Errorf("expected the summary to wait for the end of the test, got %v", mt.logs)
+	}
+
+	mt.runCleanups()
+	want := `3 snapshot(s) need review: 2 new ("first", "second"), 1 mismatched ("changed") - run 'shutter review'`
+	if len(mt.logs) != 1 || mt.logs[0] != want {
+		t.Errorf("expected summary %q, got %v", want, mt.logs)
+	}
+}
+
+// unreadableStorage fails to read accepted snapshots with err, like a remote
+// whose credentials are rejected.
+type unreadableStorage struct {
+	files.FileStorage
+	err error
+}
+
+func (s unreadableStorage) Read(info files.SnapshotInfo, state files.State) (*files.Snapshot, error) {
+	if state == files.StateAccepted {
+		return nil, s.err
+	}
+	return files.FileStorage{}.Read(info, state)
+}
+
+func TestSnap_UnreadableBaseline(t *testing.T) {
+	setupTestDir(t)
+	prev := files.SetStorage(unreadableStorage{err: fmt.Errorf("403 Forbidden")})
+	t.Cleanup(func() { files.SetStorage(prev) })
+
+	mt := &mockT{name: "TestExample"}
+	SnapWithOptions(mt, "output", "0.1.0", "content", Options{})
+
+	if len(mt.errors) != 1 || !strings.Contains(mt.errors[0], "failed to read the accepted snapshot: 403 Forbidden") {
+		t.Errorf("expected the read error to be reported, got %v", mt.errors)
+	}
+	if _, err := files.ReadSnapshot("TestExample", "output", files.StateNew); err == nil {
+		t.Error("expected no pending snapshot to be written")
+	}
+}
+
+func TestSnap_WrappedMissingBaseline(t *testing.T) {
+	setupTestDir(t)
+	// A backend may wrap its not-found error.
+	prev := files.SetStorage(unreadableStorage{err: fmt.Errorf("get output: %w", fs.ErrNotExist)})
+	t.Cleanup(func() { files.SetStorage(prev) })
+
+	mt := &mockT{name: "TestExample"}
+	SnapWithOptions(mt, "output", "0.1.0", "content", Options{})
+
+	if len(mt.errors) != 1 || !strings.Contains(mt.errors[0], "new snapshot") {
+		t.Errorf("expected a new snapshot, got %v", mt.errors)
+	}
+	if _, err := files.ReadSnapshot("TestExample", "output", files.StateNew); err != nil {
+		t.Errorf("expected a pending snapshot to be written: %v", err)
+	}
+}
+
+func TestSnap_DuplicateTitle(t *testing.T) {
+	setupTestDir(t)
+
+	duplicates := func(m *mockT) []string {
+		var errs []string
+		for _, e := range m.errors {
+			if strings.Contains(e, "taken twice in this test with different content") {
+				errs = append(errs, e)
+			}
+		}
+		return errs
+	}
+
+	mt := &mockT{name: "TestExample"}
+	SnapWithOptions(mt, "output", "0.1.0", "first", Options{})
+	SnapWithOptions(mt, "output", "0.1.0", "first", Options{})
+	if errs := duplicates(mt); len(errs) != 0 {
+		t.Fatalf("expected taking the same content twice to be allowed, got %v", errs)
+	}
+
+	SnapWithOptions(mt, "output", "0.1.0", "second", Options{})
+	errs := duplicates(mt)
+	if len(errs) != 1 {
+		t.Fatalf("expected the second snapshot to fail, got %v", mt.errors)
+	}
+	if strings.Count(errs[0], "snapshot_test.go:") != 2 {
+		t.Errorf("expected an error naming both call sites, got %q", errs[0])
+	}
+
+	pending, err := files.ReadSnapshot("TestExample", "output", files.StateNew)
+	if err != nil {
+		t.Fatalf("failed to read pending snapshot: %v", err)
+	}
+	if pending.Content != "first" {
+		t.Errorf("expected the first pending snapshot to be kept, got %q", pending.Content)
+	}
+
+	mt.runCleanups()
+	rerun := &mockT{name: "TestExample"}
+	SnapWithOptions(rerun, "output", "0.1.0", "second", Options{})
+	if errs := duplicates(rerun); len(errs) != 0 {
+		t.Errorf("expected another run of the test to start afresh, got %v", errs)
+	}
+}
+
+func TestSetOutput(t *testing.T) {
+	setupTestDir(t)
+
+	var buf bytes.Buffer
+	SetOutput(&buf)
+	t.Cleanup(func() { SetOutput(nil) })
+
+	mt := &mockT{name: "TestExample"}
+	SnapWithOptions(mt, "captured", "0.1.0", "captured content", Options{})
+	if buf.Len() != 0 {
+		t.Errorf("expected the box to wait for the end of the test, got %q", buf.String())
+	}
+	mt.runCleanups()
+	if !strings.Contains(buf.String(), "captured content") {
+		t.Errorf("expected the box to be written to the output, got %q", buf.String())
+	}
+}
+
+func TestSnap_IgnoreLines(t *testing.T) {
+	setupTestDir(t)
+
+	accepted := &files.Snapshot{
+		Title:       "artifact",
+		Test:        "TestExample",
+		Content:     "name: app\nbuilt: 2024-03-01T09:30:00Z\nsize: 12\n",
+		Version:     "v1",
+		IgnoreLines: "2",
+	}
+	if err := files.SaveSnapshot(accepted, files.StateAccepted); err != nil {
+		t.Fatalf("failed to save accepted snapshot: %v", err)
+	}
+
+	mt := &mockT{name: "TestExample"}
+	Snap(mt, "artifact", "v1", "name: app\nbuilt: 2024-05-07T11:00:00Z\nsize: 12\n")
+	if len(mt.errors) != 0 {
+		t.Errorf("expected the ignored line not to be compared, got: %v", mt.errors)
+	}
+
+	mt = &mockT{name: "TestExample"}
+	Snap(mt, "artifact", "v1", "name: app\nbuilt: 2024-05-07T11:00:00Z\nsize: 13\n")
+	if len(mt.errors) != 1 || !strings.Contains(mt.errors[0], "snapshot mismatch") {
+		t.Fatalf("expected a mismatch, got: %v", mt.errors)
+	}
+	pending, err := files.ReadSnapshot("TestExample", "artifact", files.StateNew)
+	if err != nil {
+		t.Fatalf("failed to read pending snapshot: %v", err)
+	}
+	if pending.IgnoreLines != "2" {
+		t.Errorf("expected ignore_lines to carry over to the pending snapshot, got %q", pending.IgnoreLines)
+	}
+}
+
+func TestSnap_IgnoreLinesInvalid(t *testing.T) {
+	setupTestDir(t)
+
+	accepted := &files.Snapshot{
+		Title:       "artifact",
+		Test:        "TestExample",
+		Content:     "a\nb\n",
+		Version:     "v1",
+		IgnoreLines: "3-1",
+	}
+	if err := files.SaveSnapshot(accepted, files.StateAccepted); err != nil {
+		t.Fatalf("failed to save accepted snapshot: %v", err)
+	}
+
+	mt := &mockT{name: "TestExample"}
+	Snap(mt, "artifact", "v1", "a\nc\n")
+	if len(mt.errors) != 2 || !strings.Contains(mt.errors[0], `invalid ignore_lines "3-1"`) {
+		t.Errorf("expected an invalid ignore_lines error and a mismatch, got: %v", mt.errors)
+	}
+}
+
+func TestSnap_Raw(t *testing.T) {
+	setupTestDir(t)
+
+	opts := Options{Applied: []string{"ScrubUUID()"}, Raw: "id: 3f2a9c1e-8b4d-4e6f-a1c2-7d9e0b5f3a48\n"}
+	mt := &mockT{name: "TestExample"}
+	SnapWithOptions(mt, "session", "v1", "id: <UUID>\n", opts)
+	pending, err := files.ReadSnapshot("TestExample", "session", files.StateNew)
+	if err != nil {
+		t.Fatalf("failed to read pending snapshot: %v", err)
+	}
+	if _, err := os.Stat(files.RawPath(pending.Path)); err != nil {
+		t.Fatalf("expected a raw file next to the pending snapshot: %v", err)
+	}
+	if pending.RawFingerprint != files.OptionsFingerprint(opts.Applied) {
+		t.Errorf("RawFingerprint = %q, want %q", pending.RawFingerprint, files.OptionsFingerprint(opts.Applied))
+	}
+
+	// Content that scrubbing left unchanged has no raw content to keep.
+	mt = &mockT{name: "TestExample"}
+	SnapWithOptions(mt, "session", "v1", "id: 1\n", Options{Raw: "id: 1\n"})
+	if _, err := os.Stat(files.RawPath(pending.Path)); !os.IsNotExist(err) {
+		t.Errorf("expected the raw file to be removed, got err %v", err)
+	}
+}
+
+func TestSnap_AcceptCommand(t *testing.T) {
+	setupTestDir(t)
+
+	mt := &mockT{name: "TestExample"}
+	Snap(mt, "user api response", "v1", "content here")
+	if len(mt.errors) != 1 || !strings.HasSuffix(mt.errors[0], `shutter accept "TestExample/user api response"`) {
+		t.Errorf("expected the command accepting the snapshot, got: %v", mt.errors)
+	}
+
+	for s, want := range map[string]string{
+		"TestA/plain":     `"TestA/plain"`,
+		`TestA/costs $5`:  `'TestA/costs $5'`,
+		`TestA/it's "ok"`: `'TestA/it'\''s "ok"'`,
+	} {
+		if got := shellQuote(s); got != want {
+			t.Errorf("shellQuote(%q) = %s, want %s", s, got, want)
+		}
+	}
+}
+
+func TestSnapWithOptions_AnyKeyOrder(t *testing.T) {
+	setupTestDir(t)
+
+	accepted := &files.Snapshot{
+		Title:       "reordered",
+		Test:        "TestExample",
+		Content:     "{\n  \"id\": 1,\n  \"name\": \"a\"\n}",
+		Version:     "v1",
+		ContentType: files.ContentJSON,
+	}
+	if err := files.SaveSnapshot(accepted, files.StateAccepted); err != nil {
+		t.Fatal(err)
+	}
+	reordered := "{\n  \"name\": \"a\",\n  \"id\": 1\n}"
+	opts := Options{ContentType: files.ContentJSON}
+
+	mt := &mockT{name: "TestExample"}
+	SnapWithOptions(mt, "reordered", "v1", reordered, opts)
+	if len(mt.errors) != 1 {
+		t.Errorf("expected a mismatch by default, got %v", mt.errors)
+	}
+	if err := os.Remove(accepted.Path + ".new"); err != nil {
+		t.Fatal(err)
+	}
+
+	opts.AnyKeyOrder = true
+	mt = &mockT{name: "TestExample"}
+	SnapWithOptions(mt, "reordered", "v1", "{\n  \"name\": \"b\",\n  \"id\": 1\n}", opts)
+	if len(mt.errors) != 1 {
+		t.Errorf("expected different values to mismatch, got %v", mt.errors)
+	}
+	if err := os.Remove(accepted.Path + ".new"); err != nil {
+		t.Fatal(err)
+	}
+
+	mt = &mockT{name: "TestExample"}
+	SnapWithOptions(mt, "reordered", "v1", reordered, opts)
+	if len(mt.errors) != 0 {
+		t.Errorf("expected reordered keys to match, got %v", mt.errors)
+	}
+	if read, err := files.ReadSnapshotFromPath(accepted.Path); err != nil || read.Content != accepted.Content {
+		t.Errorf("expected the accepted snapshot to be kept, got %v (err %v)", read, err)
+	}
+
+	opts.RewriteKeyOrder = true
+	mt = &mockT{name: "TestExample"}
+	SnapWithOptions(mt, "reordered", "v1", reordered, opts)
+	if len(mt.errors) != 0 || len(mt.logs) != 1 || !strings.Contains(mt.logs[0], "rewritten in the new key order") {
+		t.Errorf("expected the snapshot to match and be rewritten, got errors %v, logs %v", mt.errors, mt.logs)
+	}
+	read, err := files.ReadSnapshotFromPath(accepted.Path)
+	if err != nil || read.Content != reordered || read.Corrupted() {
+		t.Errorf("expected the accepted snapshot in the new order, got %v (err %v)", read, err)
+	}
+	if _, err := os.Stat(accepted.Path + ".new"); !os.IsNotExist(err) {
+		t.Errorf("expected no pending snapshot, got err %v", err)
+	}
+}
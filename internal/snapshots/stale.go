@@ -0,0 +1,82 @@
+package snapshots
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ptdewey/shutter/internal/files"
+)
+
+// staleReason explains why accepted is a stale baseline for snapshot under
+// opts, or returns "" if it is not or no staleness check is enabled. A
+// baseline is stale when it was accepted longer ago than opts.StaleAfter,
+// when its format version is opts.StaleVersions or more minor versions
+// behind the current one, or when it was taken with other options than
+// the snapshot, even if the content still matches.
+func staleReason(accepted, snapshot *files.Snapshot, opts Options) string {
+	if opts.StaleAfter <= 0 && opts.StaleVersions <= 0 {
+		return ""
+	}
+
+	var reasons []string
+	if opts.StaleAfter > 0 {
+		if at, err := files.AcceptedAt(accepted.Path); err == nil {
+			if age := time.Since(at); age > opts.StaleAfter {
+				reasons = append(reasons, fmt.Sprintf("accepted %d days ago", int(age.Hours()/24)))
+			}
+		}
+	}
+	if opts.StaleVersions > 0 {
+		if behind := versionsBehind(accepted.Version, snapshot.Version); behind >= opts.StaleVersions {
+			reasons = append(reasons, fmt.Sprintf("recorded with format version %s, current is %s", accepted.Version, snapshot.Version))
+		}
+	}
+	if !slices.Equal(accepted.Options, snapshot.Options) {
+		reasons = append(reasons, "accepted with different options")
+	}
+	return strings.Join(reasons, "; ")
+}
+
+// versionsBehind returns how many minor versions the format version old is
+// behind current. A different major version counts as far behind; versions
+// that cannot be parsed, such as the empty version of very old snapshots,
+// count as not behind.
+func versionsBehind(old, current string) int {
+	oldMajor, oldMinor, ok := parseVersion(old)
+	if !ok {
+		return 0
+	}
+	major, minor, ok := parseVersion(current)
+	if !ok {
+		return 0
+	}
+	if oldMajor != major {
+		if oldMajor < major {
+			return math.MaxInt
+		}
+		return 0
+	}
+	return max(minor-oldMinor, 0)
+}
+
+// parseVersion returns the major and minor numbers of a version such as
+// "0.1.0".
+func parseVersion(v string) (major, minor int, ok bool) {
+	parts := strings.SplitN(strings.TrimPrefix(v, "v"), ".", 3)
+	if len(parts) < 2 {
+		return 0, 0, false
+	}
+	major, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return 0, 0, false
+	}
+	minor, err = strconv.Atoi(parts[1])
+	if err != nil {
+		return 0, 0, false
+	}
+	return major, minor, true
+}
@@ -0,0 +1,138 @@
+package snapshots
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// cleanuper is implemented by the T of package testing, whose cleanup
+// functions run when the test and its subtests have finished.
+type cleanuper interface {
+	Cleanup(func())
+}
+
+// testReports collects the snapshots each running test left for review.
+// Tests are keyed by their T rather than their name, as a name may be
+// reused by another T.
+var testReports struct {
+	sync.Mutex
+	byTest map[cleanuper]*testReport
+}
+
+// testReport holds the snapshots of one test that need review, with the
+// boxes showing them, and all the snapshots it has taken.
+type testReport struct {
+	created    []string
+	mismatched []string
+	boxes      []string
+	taken      map[string]takenSnapshot
+}
+
+// report shows box, the box of a snapshot left for review. For a T with a
+// Cleanup method, the boxes of a test are collected and printed together
+// when it ends, followed by a one-line summary, so tests running in
+// parallel do not interleave their boxes. Other Ts print it right away.
+func report(t T, title, box string, created bool) {
+	c, ok := t.(cleanuper)
+	if !ok {
+		Println(box)
+		return
+	}
+
+	testReports.Lock()
+	defer testReports.Unlock()
+	r := reportFor(t, c)
+	if created {
+		r.created = append(r.created, title)
+	} else {
+		r.mismatched = append(r.mismatched, title)
+	}
+	r.boxes = append(r.boxes, box)
+}
+
+// reportFor returns the report of the test of c, creating it to be flushed
+// when the test ends. testReports must be locked.
+func reportFor(t T, c cleanuper) *testReport {
+	if testReports.byTest == nil {
+		testReports.byTest = make(map[cleanuper]*testReport)
+	}
+	r, ok := testReports.byTest[c]
+	if !ok {
+		r = &testReport{}
+		testReports.byTest[c] = r
+		c.Cleanup(func() { flushReport(t, c) })
+	}
+	return r
+}
+
+// flushReport prints the boxes collected for the test of c and logs its
+// summary.
+func flushReport(t T, c cleanuper) {
+	testReports.Lock()
+	r := testReports.byTest[c]
+	delete(testReports.byTest, c)
+	testReports.Unlock()
+	if r == nil || len(r.boxes) == 0 {
+		return
+	}
+
+	Println(strings.Join(r.boxes, "\n"))
+	t.Log(r.summary())
+}
+
+// summary describes the snapshots of the report on one line, e.g.
+//
+//	2 snapshot(s) need review: 1 new ("users"), 1 mismatched ("admin") - run 'shutter review'
+func (r *testReport) summary() string {
+	var parts []string
+	if len(r.created) > 0 {
+		parts = append(parts, fmt.Sprintf("%d new (%s)", len(r.created), quoteTitles(r.created)))
+	}
+	if len(r.mismatched) > 0 {
+		parts = append(parts, fmt.Sprintf("%d mismatched (%s)", len(r.mismatched), quoteTitles(r.mismatched)))
+	}
+	total := len(r.created) + len(r.mismatched)
+	return fmt.Sprintf("%d snapshot(s) need review: %s - run '%s'", total, strings.Join(parts, ", "), ReviewCommand())
+}
+
+func quoteTitles(titles []string) string {
+	quoted := make([]string, len(titles))
+	for i, title := range titles {
+		quoted[i] = strconv.Quote(title)
+	}
+	return strings.Join(quoted, ", ")
+}
+
+// RunCounts counts the snapshots compared by this process, by outcome.
+type RunCounts struct {
+	Matched    int // Equal to the accepted snapshot
+	New        int // Left pending with no accepted snapshot
+	Mismatched int // Different from the accepted snapshot
+	Updated    int // Accepted in place in update mode
+}
+
+// Pending returns the number of snapshots that need review.
+func (c RunCounts) Pending() int {
+	return c.New + c.Mismatched
+}
+
+// runCounts is guarded by runCountsMu.
+var (
+	runCountsMu sync.Mutex
+	runCounts   RunCounts
+)
+
+func countRun(n *int) {
+	runCountsMu.Lock()
+	defer runCountsMu.Unlock()
+	*n++
+}
+
+// Counts returns how many snapshots this process compared so far.
+func Counts() RunCounts {
+	runCountsMu.Lock()
+	defer runCountsMu.Unlock()
+	return runCounts
+}
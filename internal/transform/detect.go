@@ -0,0 +1,71 @@
+package transform
+
+import (
+	"encoding/json"
+	"regexp"
+	"strings"
+)
+
+// Format is the kind of content Detect recognizes.
+type Format string
+
+const (
+	JSON Format = "json"
+	XML  Format = "xml"
+	YAML Format = "yaml"
+	Text Format = "text"
+)
+
+// Detect sniffs the format of content. JSON objects and arrays and
+// well-formed XML documents are recognized by parsing them; YAML by every
+// line being a mapping key, a sequence item, a comment or an indented
+// continuation. Anything else is Text.
+func Detect(content string) Format {
+	trimmed := strings.TrimSpace(content)
+	switch {
+	case trimmed == "":
+		return Text
+	case (trimmed[0] == '{' || trimmed[0] == '[') && json.Valid([]byte(trimmed)):
+		return JSON
+	case trimmed[0] == '<' && checkXML(trimmed) == nil:
+		return XML
+	case looksLikeYAML(trimmed):
+		return YAML
+	}
+	return Text
+}
+
+var (
+	yamlKey  = regexp.MustCompile(`^[^\s#:\-{[<][^#]*?:(\s|$)`)
+	yamlItem = regexp.MustCompile(`^-(\s|$)`)
+)
+
+func looksLikeYAML(content string) bool {
+	structured := false
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimRight(line, " \t\r")
+		switch {
+		case line == "", strings.HasPrefix(line, "#"):
+			continue
+		case line == "---":
+			structured = true
+		case line[0] == ' ' || line[0] == '\t':
+			// Continuation of the previous key or item
+		case yamlKey.MatchString(line), yamlItem.MatchString(line):
+			structured = true
+		default:
+			return false
+		}
+	}
+	return structured
+}
+
+// NormalizeYAML converts line endings to \n and removes trailing whitespace,
+// leaving the document otherwise as written.
+func NormalizeYAML(content string) string {
+	lines := strings.Split(strings.ReplaceAll(content, "\r\n", "\n"), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(line, " \t")
+	}
+	return strings.TrimRight(strings.Join(lines, "\n"), "\n")
+}
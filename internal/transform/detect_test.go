@@ -0,0 +1,80 @@
+package transform
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    Format
+	}{
+		{"json object", `{"a": 1}`, JSON},
+		{"json array with whitespace", "\n  [1, 2]\n", JSON},
+		{"json scalar is text", `42`, Text},
+		{"invalid json", `{"a": 1`, Text},
+		{"xml", `<?xml version="1.0"?><user id="1"><name>Ada</name></user>`, XML},
+		{"unclosed xml", `<user><name>Ada</user>`, Text},
+		{"html fragment", `<p>one<br>two</p>`, Text},
+		{"yaml mapping", "name: Ada\nroles:\n  - admin\n  - dev\n", YAML},
+		{"yaml sequence", "- one\n- two", YAML},
+		{"yaml document with comment", "# config\n---\nport: 8080", YAML},
+		{"plain text", "Hello, world.\nSecond line.", Text},
+		{"text with a colon", "Note: this is prose\nthat keeps going.", Text},
+		{"url", "https://example.com/path", Text},
+		{"empty", "  ", Text},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Detect(tt.content); got != tt.want {
+				t.Errorf("Detect(%q) = %s, want %s", tt.content, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIndentXML(t *testing.T) {
+	input := `<?xml version="1.0" encoding="UTF-8"?>
+<ns:users xmlns:ns="urn:users"><!-- all users --><ns:user id="1" role="a&amp;b">
+	<name>  Ada &lt;Lovelace&gt; </name><tags/><bio></bio>
+	</ns:user>note</ns:users>`
+
+	result, err := IndentXML(input)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := `<?xml version="1.0" encoding="UTF-8"?>
+<ns:users xmlns:ns="urn:users">
+  <!-- all users -->
+  <ns:user id="1" role="a&amp;b">
+    <name>Ada &lt;Lovelace&gt;</name>
+    <tags/>
+    <bio/>
+  </ns:user>
+  note
+</ns:users>`
+	if result != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, result)
+	}
+}
+
+func TestIndentXML_Invalid(t *testing.T) {
+	for _, input := range []string{`<a><b></a>`, `just text`} {
+		_, err := IndentXML(input)
+		if err == nil || !strings.Contains(err.Error(), "failed to parse XML") {
+			t.Errorf("IndentXML(%q): expected a parse error, got %v", input, err)
+		}
+	}
+}
+
+func TestNormalizeYAML(t *testing.T) {
+	input := "name: Ada  \r\nroles:\r\n  - admin\t\r\n\r\n"
+	expected := "name: Ada\nroles:\n  - admin"
+	if got := NormalizeYAML(input); got != expected {
+		t.Errorf("NormalizeYAML(%q) = %q, want %q", input, got, expected)
+	}
+}
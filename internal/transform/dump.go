@@ -0,0 +1,242 @@
+package transform
+
+import (
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// SortMapEntries puts the map entries of a value dump in a canonical order.
+// The dump is in the Go syntax printed by utter, indented with indent.
+//
+// utter sorts map keys, but keys that format alike (1 and "1" in a
+// map[any]) tie and keep the random iteration order, and struct and pointer
+// keys are ordered by their fmt form, which holds addresses. Maps with
+// composite keys are sorted by formatted key, and ties among scalar keys
+// are sorted by formatted key, so maps keyed by numbers or strings keep
+// utter's order. Struct fields and slice elements are not reordered.
+func SortMapEntries(dump, indent string) string {
+	if indent == "" || !strings.Contains(dump, ": ") {
+		return dump
+	}
+	lines := strings.Split(dump, "\n")
+	sortBlocks(lines, 0, len(lines), indent)
+	return strings.Join(lines, "\n")
+}
+
+// sortBlocks sorts the entries of the blocks opened in lines[start:end],
+// innermost blocks first so entries compare by their canonical text.
+func sortBlocks(lines []string, start, end int, indent string) {
+	for i := start; i < end; {
+		if !strings.HasSuffix(lines[i], "{") {
+			i++
+			continue
+		}
+		depth := indentDepth(lines[i], indent)
+		j := i + 1
+		for j < end && indentDepth(lines[j], indent) > depth {
+			j++
+		}
+		sortBlocks(lines, i+1, j, indent)
+		sortEntries(lines[i+1:j], depth+1, indent)
+		// The closing line may open the value of a map entry, as in "}: {".
+		i = j
+	}
+}
+
+// mapEntry is a run of lines holding one "key: value," entry of a map.
+type mapEntry struct {
+	key  string
+	text string
+	sort string // the key in the form utter sorts by
+	kind keyKind
+}
+
+type keyKind int
+
+const (
+	scalarKey keyKind = iota
+	compositeKey
+	fieldKey
+)
+
+// sortEntries reorders the entries of body, the lines of a block whose
+// entries start at depth, if the block is a map.
+func sortEntries(body []string, depth int, indent string) {
+	var starts []int
+	for i, line := range body {
+		if indentDepth(line, indent) == depth && !closesBlock(line[len(indent)*depth:]) {
+			starts = append(starts, i)
+		}
+	}
+	if len(starts) < 2 {
+		return
+	}
+
+	entries := make([]mapEntry, len(starts))
+	composite := false
+	for n, start := range starts {
+		end := len(body)
+		if n+1 < len(starts) {
+			end = starts[n+1]
+		}
+		key, ok := entryKey(body[start:end], depth, indent)
+		if !ok {
+			return
+		}
+		e := mapEntry{key: key, text: strings.Join(body[start:end], "\n"), kind: classifyKey(key)}
+		switch e.kind {
+		case fieldKey:
+			return
+		case compositeKey:
+			composite = true
+		default:
+			e.sort = scalarSortForm(key)
+		}
+		entries[n] = e
+	}
+
+	sorted := make([]mapEntry, len(entries))
+	copy(sorted, entries)
+	byText := func(a, b mapEntry) bool {
+		if a.key != b.key {
+			return a.key < b.key
+		}
+		return a.text < b.text
+	}
+	if composite {
+		sort.SliceStable(sorted, func(i, j int) bool { return byText(sorted[i], sorted[j]) })
+	} else {
+		for i := 0; i < len(sorted); {
+			j := i + 1
+			for j < len(sorted) && sorted[j].sort == sorted[i].sort {
+				j++
+			}
+			run := sorted[i:j]
+			sort.SliceStable(run, func(a, b int) bool { return byText(run[a], run[b]) })
+			i = j
+		}
+	}
+
+	var out []string
+	for _, e := range sorted {
+		out = append(out, strings.Split(e.text, "\n")...)
+	}
+	copy(body, out)
+}
+
+// closesBlock reports whether a line starts by closing a bracket, so it
+// continues an entry rather than starting one.
+func closesBlock(line string) bool {
+	return line == "" || strings.ContainsAny(line[:1], "})]")
+}
+
+// entryKey returns the formatted key of an entry, or false if the lines are
+// not a "key: value," entry.
+func entryKey(lines []string, depth int, indent string) (string, bool) {
+	prefix := len(indent) * depth
+	first := lines[0][prefix:]
+	if key, ok := splitKey(first); ok {
+		return key, true
+	}
+	if !strings.HasSuffix(first, "{") {
+		return "", false
+	}
+	// A multi-line key ends on the line that closes it at the entry's depth.
+	for i := 1; i < len(lines); i++ {
+		if indentDepth(lines[i], indent) != depth {
+			continue
+		}
+		rest, ok := splitKey(lines[i][prefix:])
+		if !ok {
+			return "", false
+		}
+		keyLines := append([]string{first}, lines[1:i]...)
+		return strings.Join(append(keyLines, strings.Repeat(indent, depth)+rest), "\n"), true
+	}
+	return "", false
+}
+
+// splitKey returns the text of line before its first ": " outside of
+// strings and brackets.
+func splitKey(line string) (string, bool) {
+	nesting := 0
+	inString := false
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case inString:
+			if c == '\\' {
+				i++
+			} else if c == '"' {
+				inString = false
+			}
+		case c == '"':
+			inString = true
+		case c == '(' || c == '[' || c == '{':
+			nesting++
+		case c == ')' || c == ']' || c == '}':
+			nesting--
+		case c == ':' && nesting <= 0 && strings.HasPrefix(line[i:], ": "):
+			return line[:i], true
+		}
+	}
+	return "", false
+}
+
+var (
+	identPattern       = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+	numberPattern      = regexp.MustCompile(`^[-+]?[0-9][0-9a-fA-FxXoObB._+\-pPi]*$`)
+	typedScalarPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_.]*\((.*)\)$`)
+)
+
+// classifyKey tells a struct field name from a map key, and a scalar key
+// from a composite one.
+func classifyKey(key string) keyKind {
+	switch {
+	case key == "true" || key == "false" || key == "nil" || key == "NaN" || key == "+Inf" || key == "-Inf":
+		return scalarKey
+	case identPattern.MatchString(key):
+		return fieldKey
+	case isScalar(key):
+		return scalarKey
+	case typedScalarPattern.MatchString(key) && isScalar(typedScalarPattern.FindStringSubmatch(key)[1]):
+		return scalarKey
+	}
+	return compositeKey
+}
+
+func isScalar(s string) bool {
+	if numberPattern.MatchString(s) || s == "true" || s == "false" {
+		return true
+	}
+	if strings.HasPrefix(s, `"`) {
+		_, err := strconv.Unquote(s)
+		return err == nil
+	}
+	// Complex numbers, as in (1+2i).
+	return strings.HasPrefix(s, "(") && strings.HasSuffix(s, "i)") && !strings.Contains(s, "*")
+}
+
+// scalarSortForm returns a scalar key the way fmt prints it, which utter
+// compares keys of interface type by: without quotes or a type.
+func scalarSortForm(key string) string {
+	if m := typedScalarPattern.FindStringSubmatch(key); m != nil {
+		key = m[1]
+	}
+	if s, err := strconv.Unquote(key); err == nil {
+		return s
+	}
+	return key
+}
+
+// indentDepth returns how many indents line starts with.
+func indentDepth(line, indent string) int {
+	depth := 0
+	for strings.HasPrefix(line, indent) {
+		line = line[len(indent):]
+		depth++
+	}
+	return depth
+}
@@ -0,0 +1,72 @@
+package transform
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/kortschak/utter"
+)
+
+var dumpConfig = &utter.ConfigState{Indent: "  ", ElideType: true, SortKeys: true}
+
+type point struct {
+	X, Y int
+	Tag  *string
+}
+
+// dumpStable formats v repeatedly and fails unless every result is the
+// same.
+func dumpStable(t *testing.T, v any) string {
+	t.Helper()
+	want := SortMapEntries(dumpConfig.Sdump(v), "  ")
+	for range 50 {
+		if got := SortMapEntries(dumpConfig.Sdump(v), "  "); got != want {
+			t.Fatalf("formatting is not deterministic:\n%s\nvs\n%s", want, got)
+		}
+	}
+	return want
+}
+
+func TestSortMapEntries_IntKeys(t *testing.T) {
+	m := map[int]string{10: "ten", 2: "two", -1: "minus one", 33: "thirty-three"}
+	got := dumpStable(t, m)
+	if want := dumpConfig.Sdump(m); got != want {
+		t.Errorf("expected utter's numeric order to be kept, got:\n%s\nwant:\n%s", got, want)
+	}
+	if !(strings.Index(got, "-1:") < strings.Index(got, "2:") && strings.Index(got, "2:") < strings.Index(got, "10:")) {
+		t.Errorf("expected numeric order, got:\n%s", got)
+	}
+}
+
+func TestSortMapEntries_StructKeys(t *testing.T) {
+	a, b := "a", "b"
+	m := map[point]int{
+		{X: 1, Y: 2, Tag: &b}: 1,
+		{X: 1, Y: 2, Tag: &a}: 2,
+		{X: 0, Y: 5}:          3,
+	}
+	got := dumpStable(t, m)
+	if !(strings.Index(got, "Y: 5") < strings.Index(got, `"a"`) && strings.Index(got, `"a"`) < strings.Index(got, `"b"`)) {
+		t.Errorf("expected struct keys sorted by their formatted form, got:\n%s", got)
+	}
+}
+
+func TestSortMapEntries_InterfaceKeys(t *testing.T) {
+	m := map[any]string{1: "int", "1": "string", int8(1): "int8", 2.5: "float", true: "bool"}
+	got := dumpStable(t, m)
+	if !(strings.Index(got, `"1": "string"`) < strings.Index(got, `1: "int"`) && strings.Index(got, `1: "int"`) < strings.Index(got, `int8(1): "int8"`)) {
+		t.Errorf("expected keys that tie to be sorted by their formatted form, got:\n%s", got)
+	}
+}
+
+func TestSortMapEntries_KeepsStructsAndSlices(t *testing.T) {
+	v := struct {
+		Zeta  int
+		Alpha []string
+		Maps  []map[string]int
+	}{1, []string{"b: x", "a"}, []map[string]int{{"b": 1, "a": 2}}}
+	dump := dumpConfig.Sdump(v)
+	if got := SortMapEntries(dump, "  "); got != dump {
+		t.Errorf("expected the dump to be unchanged, got:\n%s\nwant:\n%s", got, dump)
+	}
+}
@@ -0,0 +1,92 @@
+package transform
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// stripJSONC turns JSONC (JSON with comments) into JSON in place by
+// removing line (//) and block (/* */) comments and trailing commas before a
+// closing bracket or brace. Removed characters become spaces, newlines are
+// kept, so syntax errors in the result still point at the right line and
+// column.
+func stripJSONC(out []byte) error {
+	if err := stripComments(out); err != nil {
+		return err
+	}
+	stripTrailingCommas(out)
+	return nil
+}
+
+func stripComments(out []byte) error {
+	inString := false
+	for i := 0; i < len(out); i++ {
+		c := out[i]
+		if inString {
+			switch c {
+			case '\\':
+				i++
+			case '"':
+				inString = false
+			}
+			continue
+		}
+
+		switch {
+		case c == '"':
+			inString = true
+		case c == '/' && i+1 < len(out) && out[i+1] == '/':
+			for ; i < len(out) && out[i] != '\n'; i++ {
+				out[i] = ' '
+			}
+		case c == '/' && i+1 < len(out) && out[i+1] == '*':
+			end := bytes.Index(out[i+2:], []byte("*/"))
+			if end < 0 {
+				return fmt.Errorf("unterminated block comment at offset %d", i)
+			}
+			end += i + 4
+			for ; i < end; i++ {
+				if out[i] != '\n' {
+					out[i] = ' '
+				}
+			}
+			i--
+		}
+	}
+	return nil
+}
+
+// stripTrailingCommas removes commas followed only by whitespace and a
+// closing bracket or brace. Comments must already be stripped.
+func stripTrailingCommas(out []byte) {
+	inString := false
+	for i := 0; i < len(out); i++ {
+		c := out[i]
+		if inString {
+			switch c {
+			case '\\':
+				i++
+			case '"':
+				inString = false
+			}
+			continue
+		}
+
+		switch c {
+		case '"':
+			inString = true
+		case ',':
+			j := i + 1
+			for j < len(out) && isJSONSpace(out[j]) {
+				j++
+			}
+			if j < len(out) && (out[j] == '}' || out[j] == ']') {
+				out[i] = ' '
+			}
+		}
+	}
+}
+
+func isJSONSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r'
+}
@@ -0,0 +1,62 @@
+package transform
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestStripJSONC(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{"line comment", "{\"a\": 1 // one\n}", "{\"a\": 1       \n}"},
+		{"block comment", `{/* note */"a": 1}`, `{          "a": 1}`},
+		{"multi-line block comment keeps newlines", "{/* a\nb */\"a\": 1}", "{    \n    \"a\": 1}"},
+		{"trailing commas", `{"a": [1, 2,], "b": 3,}`, `{"a": [1, 2 ], "b": 3 }`},
+		{"trailing comma before comment", "[1, // last\n]", "[1         \n]"},
+		{"comment markers in strings", `{"url": "http://x/*y*/", "s": "a,]"}`, `{"url": "http://x/*y*/", "s": "a,]"}`},
+		{"escaped quote in string", `{"q": "say \"// hi\"",}`, `{"q": "say \"// hi\"" }`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := []byte(tt.input)
+			if err := stripJSONC(data); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := string(data); got != tt.want {
+				t.Errorf("stripJSONC(%q) = %q, want %q", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStripJSONC_UnterminatedComment(t *testing.T) {
+	err := stripJSONC([]byte(`{"a": 1 /* never closed`))
+	if err == nil || !strings.Contains(err.Error(), "unterminated block comment") {
+		t.Errorf("expected an unterminated comment error, got %v", err)
+	}
+}
+
+func TestTransformJSON_AllowComments(t *testing.T) {
+	input := `{
+		// Editor settings
+		"tabSize": 2,
+		"rulers": [80, 120,], /* columns */
+	}`
+
+	if _, err := TransformJSON(input, &Config{}); err == nil {
+		t.Error("expected JSONC to be rejected without AllowComments")
+	}
+
+	result, err := TransformJSON(input, &Config{AllowComments: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := "{\n  \"rulers\": [\n    80,\n    120\n  ],\n  \"tabSize\": 2\n}"
+	if result != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, result)
+	}
+}
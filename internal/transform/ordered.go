@@ -0,0 +1,103 @@
+package transform
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+// object is a JSON object that keeps its members in their original order.
+// Duplicate keys are kept as they appear.
+type object []member
+
+type member struct {
+	Key   string
+	Value any
+}
+
+// MarshalJSON writes the members in order. json.MarshalIndent re-indents the
+// result, so ordered and sorted output are formatted alike.
+func (o object) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, m := range o {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		key, err := json.Marshal(m.Key)
+		if err != nil {
+			return nil, err
+		}
+		value, err := json.Marshal(m.Value)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// decodeValue decodes the next JSON value from dec, keeping object members
+// in their original order. Objects decode to object and arrays to []any.
+func decodeValue(dec *json.Decoder) (any, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	delim, ok := tok.(json.Delim)
+	if !ok {
+		return tok, nil
+	}
+
+	switch delim {
+	case '{':
+		obj := object{}
+		for dec.More() {
+			keyTok, err := dec.Token()
+			if err != nil {
+				return nil, err
+			}
+			value, err := decodeValue(dec)
+			if err != nil {
+				return nil, err
+			}
+			obj = append(obj, member{Key: keyTok.(string), Value: value})
+		}
+		if _, err := dec.Token(); err != nil {
+			return nil, err
+		}
+		return obj, nil
+	case '[':
+		arr := []any{}
+		for dec.More() {
+			value, err := decodeValue(dec)
+			if err != nil {
+				return nil, err
+			}
+			arr = append(arr, value)
+		}
+		if _, err := dec.Token(); err != nil {
+			return nil, err
+		}
+		return arr, nil
+	default:
+		return nil, fmt.Errorf("unexpected delimiter %q", delim)
+	}
+}
+
+// filterObject filters an ordered object, removing members that match ignore
+// patterns.
+func filterObject(o object, ignorePatterns []IgnorePattern, path string) object {
+	result := object{}
+	for _, m := range o {
+		field := Field{Path: fieldPath(path, m.Key), Key: m.Key, Value: m.Value}
+		if !shouldIgnore(field, ignorePatterns) {
+			result = append(result, member{Key: m.Key, Value: walkAndFilterAt(m.Value, ignorePatterns, field.Path)})
+		}
+	}
+	return result
+}
@@ -0,0 +1,135 @@
+package transform
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+)
+
+// ReferencePlaceholders returns the placeholders for the func, chan and
+// unsafe.Pointer values reachable from v, keyed by the address a value dump
+// prints for them: <func>, the channel type as in <chan int>, and
+// <unsafe.Pointer>. Nil values have no placeholder, as they print as nil.
+func ReferencePlaceholders(v any) map[string]string {
+	refs := make(map[string]string)
+	collectReferences(reflect.ValueOf(v), refs, make(map[visit]bool))
+	return refs
+}
+
+// visit identifies a pointer, slice or map already walked, so cyclic values
+// are walked once.
+type visit struct {
+	ptr uintptr
+	typ reflect.Type
+	len int
+}
+
+// walked reports whether v was walked before and records it.
+func walked(v reflect.Value, seen map[visit]bool) bool {
+	key := visit{v.Pointer(), v.Type(), 0}
+	if v.Kind() == reflect.Slice {
+		key.len = v.Len()
+	}
+	if seen[key] {
+		return true
+	}
+	seen[key] = true
+	return false
+}
+
+func collectReferences(v reflect.Value, refs map[string]string, seen map[visit]bool) {
+	switch v.Kind() {
+	case reflect.Func, reflect.Chan, reflect.UnsafePointer:
+		if v.IsNil() {
+			return
+		}
+		placeholder := "<func>"
+		switch v.Kind() {
+		case reflect.Chan:
+			placeholder = "<" + v.Type().String() + ">"
+		case reflect.UnsafePointer:
+			placeholder = "<unsafe.Pointer>"
+		}
+		refs[fmt.Sprintf("%#x", v.Pointer())] = placeholder
+	case reflect.Pointer:
+		if v.IsNil() || walked(v, seen) {
+			return
+		}
+		collectReferences(v.Elem(), refs, seen)
+	case reflect.Interface:
+		collectReferences(v.Elem(), refs, seen)
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			collectReferences(v.Field(i), refs, seen)
+		}
+	case reflect.Slice:
+		if v.IsNil() || walked(v, seen) {
+			return
+		}
+		fallthrough
+	case reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			collectReferences(v.Index(i), refs, seen)
+		}
+	case reflect.Map:
+		if v.IsNil() || walked(v, seen) {
+			return
+		}
+		iter := v.MapRange()
+		for iter.Next() {
+			collectReferences(iter.Key(), refs, seen)
+			collectReferences(iter.Value(), refs, seen)
+		}
+	}
+}
+
+var addressPattern = regexp.MustCompile(`\b0x[0-9a-f]+\b`)
+
+// ReplaceReferences replaces the addresses of refs, as returned by
+// ReferencePlaceholders, in a value dump printed by utter. A value shown
+// with its type, as in func()(0xc000012345), is replaced whole.
+func ReplaceReferences(dump string, refs map[string]string) string {
+	if len(refs) == 0 {
+		return dump
+	}
+	lines := strings.Split(dump, "\n")
+	for i, line := range lines {
+		if !strings.Contains(line, "0x") {
+			continue
+		}
+		lines[i] = replaceTypedReferences(line, refs)
+		lines[i] = addressPattern.ReplaceAllStringFunc(lines[i], func(addr string) string {
+			if placeholder, ok := refs[addr]; ok {
+				return placeholder
+			}
+			return addr
+		})
+	}
+	return strings.Join(lines, "\n")
+}
+
+// replaceTypedReferences replaces the references of line that are printed
+// as a type followed by the address in parentheses. The type starts after
+// the indentation or the last ": " before it.
+func replaceTypedReferences(line string, refs map[string]string) string {
+	for addr, placeholder := range refs {
+		wrapped := "(" + addr + ")"
+		for {
+			end := strings.Index(line, wrapped)
+			if end < 0 {
+				break
+			}
+			start := len(line[:end]) - len(strings.TrimLeft(line[:end], " \t"))
+			if colon := strings.LastIndex(line[:end], ": "); colon >= 0 {
+				start = colon + 2
+			}
+			if start == end {
+				// The address is not preceded by a type.
+				break
+			}
+			line = line[:start] + placeholder + line[end+len(wrapped):]
+		}
+	}
+	return line
+}
@@ -0,0 +1,76 @@
+package transform
+
+import (
+	"strings"
+	"testing"
+	"unsafe"
+)
+
+type handlers struct {
+	OnEvent  func(string) error
+	Events   chan int
+	Done     <-chan struct{}
+	Raw      unsafe.Pointer
+	Missing  func()
+	Callback any
+	Hooks    []func()
+	ByChan   map[chan bool]string
+}
+
+func TestReplaceReferences(t *testing.T) {
+	n := 1
+	done := make(chan struct{})
+	v := &handlers{
+		OnEvent:  func(string) error { return nil },
+		Events:   make(chan int, 2),
+		Done:     done,
+		Raw:      unsafe.Pointer(&n),
+		Callback: func() {},
+		Hooks:    []func(){func() {}, nil},
+		ByChan:   map[chan bool]string{make(chan bool): "first"},
+	}
+	got := ReplaceReferences(dumpConfig.Sdump(v), ReferencePlaceholders(v))
+
+	want := `&transform.handlers{
+  OnEvent: <func>,
+  Events: <chan int>,
+  Done: <<-chan struct {}>,
+  Raw: <unsafe.Pointer>,
+  Missing: nil,
+  Callback: <func>,
+  Hooks: []func(){
+    <func>,
+    nil,
+  },
+  ByChan: map[chan bool]string{
+    <chan bool>: "first",
+  },
+}
+`
+	if got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestReplaceReferences_KeepsOtherHex(t *testing.T) {
+	v := struct {
+		Flags uintptr
+		F     func()
+	}{Flags: 0xff, F: func() {}}
+	got := ReplaceReferences(dumpConfig.Sdump(v), ReferencePlaceholders(v))
+	if !strings.Contains(got, "Flags: 0xff,") || !strings.Contains(got, "F: <func>,") {
+		t.Errorf("unexpected dump:\n%s", got)
+	}
+}
+
+func TestReferencePlaceholders_Cycle(t *testing.T) {
+	type node struct {
+		Next *node
+		F    func()
+	}
+	n := &node{F: func() {}}
+	n.Next = n
+	if refs := ReferencePlaceholders(n); len(refs) != 1 {
+		t.Errorf("expected one reference, got %v", refs)
+	}
+}
@@ -0,0 +1,39 @@
+package transform
+
+import (
+	"regexp"
+	"strings"
+)
+
+// regionPattern matches the <<regex>> regions of an accepted snapshot.
+var regionPattern = regexp.MustCompile(`<<(.+?)>>`)
+
+// MatchRegions reports whether content matches accepted, an accepted
+// snapshot holding regions such as <<\d{4}-\d{2}-\d{2}>> that match any
+// text matching their regular expression. The rest of accepted must match
+// literally. It reports false if accepted has no regions or one of them is
+// not a valid regular expression.
+func MatchRegions(accepted, content string) bool {
+	regions := regionPattern.FindAllStringSubmatchIndex(accepted, -1)
+	if len(regions) == 0 {
+		return false
+	}
+	var sb strings.Builder
+	sb.WriteString("^")
+	last := 0
+	for _, loc := range regions {
+		sb.WriteString(regexp.QuoteMeta(accepted[last:loc[0]]))
+		sb.WriteString("(?:")
+		sb.WriteString(accepted[loc[2]:loc[3]])
+		sb.WriteString(")")
+		last = loc[1]
+	}
+	sb.WriteString(regexp.QuoteMeta(accepted[last:]))
+	sb.WriteString("$")
+
+	re, err := regexp.Compile(sb.String())
+	if err != nil {
+		return false
+	}
+	return re.MatchString(content)
+}
@@ -0,0 +1,27 @@
+package transform
+
+import "testing"
+
+func TestMatchRegions(t *testing.T) {
+	tests := []struct {
+		name, accepted, content string
+		want                    bool
+	}{
+		{"date", "built: <<\\d{4}-\\d{2}-\\d{2}>>\nok\n", "built: 2024-03-01\nok\n", true},
+		{"several", "id=<<[a-f0-9]+>> took <<\\d+>>ms", "id=3fa9 took 12ms", true},
+		{"literal part differs", "built: <<\\d{4}>>\nok\n", "built: 2024\nfailed\n", false},
+		{"region does not match", "built: <<\\d{4}>>", "built: soon", false},
+		{"whole region only", "n=<<\\d>>", "n=12", false},
+		{"metacharacters quoted", "a.b <<x>>", "aXb x", false},
+		{"no regions", "plain", "plain", false},
+		{"invalid regex", "v=<<(>>", "v=(", false},
+		{"alternation scoped", "<<a|b>>c", "ac", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := MatchRegions(tt.accepted, tt.content); got != tt.want {
+				t.Errorf("MatchRegions(%q, %q) = %v, want %v", tt.accepted, tt.content, got, tt.want)
+			}
+		})
+	}
+}
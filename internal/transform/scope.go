@@ -0,0 +1,104 @@
+package transform
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Scope applies scrubbers to the values at one path of a JSON document
+// only. Path is the dotted path of a field, as in Field.Path; the empty path
+// is the whole document.
+type Scope struct {
+	Path      string
+	Scrubbers []Scrubber
+}
+
+// scoped is a value found at the path of a scope, cut out of the document
+// while it is marshaled.
+type scoped struct {
+	placeholder string
+	path        string
+	value       any
+}
+
+// marshalScoped marshals data, found at path, indented with indent, and
+// applies the scrubbers of each scope to the values at its path alone. Each
+// such value is replaced by a placeholder string while the rest of the
+// document is marshaled, then marshaled on its own at the indentation of
+// the placeholder, scrubbed, and put in its place, so the output matches
+// marshaling the document in one go.
+func marshalScoped(data any, path, indent string, scopes []Scope) (string, error) {
+	var cut []scoped
+	data = cutScoped(data, path, scopes, &cut)
+
+	out, err := json.MarshalIndent(data, indent, "  ")
+	if err != nil {
+		return "", err
+	}
+	result := string(out)
+
+	for _, s := range cut {
+		quoted := `"` + s.placeholder + `"`
+		i := strings.Index(result, quoted)
+		if i < 0 {
+			return "", fmt.Errorf("scoped value at %s was lost while marshaling", s.path)
+		}
+		line := result[strings.LastIndex(result[:i], "\n")+1 : i]
+		lineIndent := line[:len(line)-len(strings.TrimLeft(line, " "))]
+
+		value, err := marshalScoped(s.value, s.path, lineIndent, scopes)
+		if err != nil {
+			return "", err
+		}
+		result = result[:i] + value + result[i+len(quoted):]
+	}
+
+	for _, scope := range scopes {
+		if scope.Path == path {
+			result = ApplyScrubbers(result, scope.Scrubbers)
+		}
+	}
+	return result, nil
+}
+
+// cutScoped returns a copy of data, found at path, in which the values of
+// fields at the path of a scope are replaced by placeholders, and appends
+// the replaced values to cut. Values below a replaced one are left for the
+// recursive marshalScoped of that value.
+func cutScoped(data any, path string, scopes []Scope, cut *[]scoped) any {
+	replace := func(key string, value any) any {
+		p := fieldPath(path, key)
+		for _, scope := range scopes {
+			if scope.Path == p {
+				placeholder := fmt.Sprintf("\uE000scope-%d\uE000", len(*cut))
+				*cut = append(*cut, scoped{placeholder: placeholder, path: p, value: value})
+				return placeholder
+			}
+		}
+		return cutScoped(value, p, scopes, cut)
+	}
+
+	switch v := data.(type) {
+	case map[string]any:
+		result := make(map[string]any, len(v))
+		for key, value := range v {
+			result[key] = replace(key, value)
+		}
+		return result
+	case object:
+		result := make(object, len(v))
+		for i, m := range v {
+			result[i] = member{Key: m.Key, Value: replace(m.Key, m.Value)}
+		}
+		return result
+	case []any:
+		result := make([]any, len(v))
+		for i, item := range v {
+			result[i] = cutScoped(item, path, scopes, cut)
+		}
+		return result
+	default:
+		return data
+	}
+}
@@ -0,0 +1,110 @@
+package transform
+
+import (
+	"fmt"
+	"strings"
+)
+
+// selectPaths keeps only the parts of data at paths, dotted paths from the
+// document root as in Field.Path, along with the objects and arrays that
+// lead to them. A path that selects nothing is an error, so a mistyped path
+// does not leave an empty snapshot, unless it leads into an empty array.
+func selectPaths(data any, paths []string) (any, error) {
+	s := &selector{paths: paths, matched: make([]bool, len(paths))}
+	result, _ := s.at(data, "")
+
+	var missing []string
+	for i, ok := range s.matched {
+		if !ok {
+			missing = append(missing, fmt.Sprintf("%q", paths[i]))
+		}
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("nothing to select at %s", strings.Join(missing, ", "))
+	}
+	return result, nil
+}
+
+// selector records which of its paths matched while selecting.
+type selector struct {
+	paths   []string
+	matched []bool
+}
+
+// at selects from data found at path, reporting whether anything was kept.
+func (s *selector) at(data any, path string) (any, bool) {
+	if path != "" && s.selected(path) {
+		return data, true
+	}
+
+	switch v := data.(type) {
+	case map[string]any:
+		result := make(map[string]any)
+		for key, value := range v {
+			if child := fieldPath(path, key); s.leadsTo(child) {
+				if kept, ok := s.at(value, child); ok {
+					result[key] = kept
+				}
+			}
+		}
+		return result, path == "" || len(result) > 0
+	case object:
+		result := object{}
+		for _, m := range v {
+			if child := fieldPath(path, m.Key); s.leadsTo(child) {
+				if kept, ok := s.at(m.Value, child); ok {
+					result = append(result, member{Key: m.Key, Value: kept})
+				}
+			}
+		}
+		return result, path == "" || len(result) > 0
+	case []any:
+		// Elements share the path of their array. Objects without the
+		// selected members are kept empty, so the length stays visible.
+		if len(v) == 0 {
+			// An empty array has nothing to select, but no path is mistyped.
+			s.reach(path)
+		}
+		result := []any{}
+		for _, item := range v {
+			if kept, ok := s.at(item, path); ok || kept != nil {
+				result = append(result, kept)
+			}
+		}
+		return result, path == "" || len(result) > 0 || len(v) == 0
+	default:
+		return nil, false
+	}
+}
+
+// selected reports whether path is one of the selected paths or inside one,
+// and marks the paths it matches.
+func (s *selector) selected(path string) bool {
+	found := false
+	for i, p := range s.paths {
+		if path == p || strings.HasPrefix(path, p+".") {
+			s.matched[i] = true
+			found = true
+		}
+	}
+	return found
+}
+
+// reach marks the selected paths inside path as matched.
+func (s *selector) reach(path string) {
+	for i, p := range s.paths {
+		if strings.HasPrefix(p, path+".") {
+			s.matched[i] = true
+		}
+	}
+}
+
+// leadsTo reports whether path is, or is on the way to, a selected path.
+func (s *selector) leadsTo(path string) bool {
+	for _, p := range s.paths {
+		if p == path || strings.HasPrefix(p, path+".") || strings.HasPrefix(path, p+".") {
+			return true
+		}
+	}
+	return false
+}
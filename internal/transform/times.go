@@ -0,0 +1,124 @@
+package transform
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// timestampPattern matches RFC 3339 timestamps with a zone, the only ones
+// whose instant is known.
+var timestampPattern = regexp.MustCompile(`\b\d{4}-\d{2}-\d{2}T\d{2}:\d{2}:\d{2}(\.\d+)?(Z|[+-]\d{2}:\d{2})`)
+
+// NormalizeTimestamps rewrites the RFC 3339 timestamps in content into loc,
+// keeping their precision. 2024-03-01T09:30:00+01:00 becomes
+// 2024-03-01T08:30:00Z in UTC. Timestamps without a zone are left as is.
+func NormalizeTimestamps(content string, loc *time.Location) string {
+	return timestampPattern.ReplaceAllStringFunc(content, func(s string) string {
+		t, err := time.Parse(time.RFC3339Nano, s)
+		if err != nil {
+			return s
+		}
+		return formatTime(t.In(loc), len(timestampPattern.FindStringSubmatch(s)[1]))
+	})
+}
+
+// formatTime formats t as RFC 3339 with a fraction of fraction characters,
+// including the dot, or with the shortest fraction if fraction is -1.
+func formatTime(t time.Time, fraction int) string {
+	if fraction < 0 {
+		return t.Format(time.RFC3339Nano)
+	}
+	layout := "2006-01-02T15:04:05"
+	if fraction > 1 {
+		layout += "." + strings.Repeat("0", fraction-1)
+	}
+	return t.Format(layout + "Z07:00")
+}
+
+// Constants of the time.Time representation, which the wall and ext fields
+// of a dump hold: see the comment on time.Time in the standard library.
+const (
+	hasMonotonic   = 1 << 63
+	nsecMask       = 1<<30 - 1
+	nsecShift      = 30
+	secondsPerDay  = 24 * 60 * 60
+	wallToInternal = (1884*365 + 1884/4 - 1884/100 + 1884/400) * secondsPerDay
+	unixToInternal = (1969*365 + 1969/4 - 1969/100 + 1969/400) * secondsPerDay
+)
+
+// NormalizeTimeValues replaces the time.Time values of a dump printed by
+// utter, shown as their wall, ext and loc fields, with the instant they
+// hold as an RFC 3339 timestamp in loc. The dump is indented with indent.
+func NormalizeTimeValues(dump, indent string, loc *time.Location) string {
+	if !strings.Contains(dump, "wall: ") {
+		return dump
+	}
+	lines := strings.Split(dump, "\n")
+	for i := 0; i < len(lines); i++ {
+		if !strings.HasSuffix(lines[i], "{") {
+			continue
+		}
+		depth := indentDepth(lines[i], indent)
+		end := i + 1
+		for end < len(lines) && indentDepth(lines[end], indent) > depth {
+			end++
+		}
+		if end == len(lines) {
+			continue
+		}
+		t, ok := parseTimeFields(lines[i+1:end], depth+1, indent)
+		if !ok {
+			continue
+		}
+
+		// Keep what precedes the type and what follows the closing brace,
+		// such as the field name and the comma.
+		opener := lines[i]
+		start := len(opener) - len(strings.TrimLeft(opener, " \t"))
+		if colon := strings.LastIndex(opener, ": "); colon >= 0 {
+			start = colon + 2
+		}
+		closing := strings.TrimPrefix(strings.TrimLeft(lines[end], " \t"), "}")
+		lines[i] = opener[:start] + formatTime(t.In(loc), -1) + closing
+		lines = append(lines[:i+1], lines[end+1:]...)
+		// The replaced line may open the value of a map entry keyed by the
+		// time, so it is checked again.
+		i--
+	}
+	return strings.Join(lines, "\n")
+}
+
+// parseTimeFields returns the instant held by the body of a dumped
+// time.Time: a wall and an ext field, then a loc field that may span
+// several lines.
+func parseTimeFields(body []string, depth int, indent string) (time.Time, bool) {
+	prefix := strings.Repeat(indent, depth)
+	if len(body) < 3 ||
+		!strings.HasPrefix(body[0], prefix+"wall: ") ||
+		!strings.HasPrefix(body[1], prefix+"ext: ") ||
+		!strings.HasPrefix(body[2], prefix+"loc: ") {
+		return time.Time{}, false
+	}
+	for _, line := range body[3:] {
+		if indentDepth(line, indent) == depth && !closesBlock(line[len(prefix):]) {
+			// Another field at the depth of wall and ext.
+			return time.Time{}, false
+		}
+	}
+	wall, err := strconv.ParseUint(strings.TrimSuffix(body[0][len(prefix)+len("wall: "):], ","), 0, 64)
+	if err != nil {
+		return time.Time{}, false
+	}
+	ext, err := strconv.ParseInt(strings.TrimSuffix(body[1][len(prefix)+len("ext: "):], ","), 10, 64)
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	sec := ext
+	if wall&hasMonotonic != 0 {
+		sec = wallToInternal + int64(wall<<1>>(nsecShift+1))
+	}
+	return time.Unix(sec-unixToInternal, int64(wall&nsecMask)), true
+}
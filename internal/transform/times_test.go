@@ -0,0 +1,69 @@
+package transform
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNormalizeTimestamps(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"at 2024-03-01T09:30:00+01:00", "at 2024-03-01T08:30:00Z"},
+		{"2024-03-01T09:30:00.120-05:00", "2024-03-01T14:30:00.120Z"},
+		{"2024-03-01T09:30:00Z", "2024-03-01T09:30:00Z"},
+		{"no zone 2024-03-01T09:30:00", "no zone 2024-03-01T09:30:00"},
+		{"invalid 2024-13-01T09:30:00Z", "invalid 2024-13-01T09:30:00Z"},
+	}
+	for _, tt := range tests {
+		if got := NormalizeTimestamps(tt.in, time.UTC); got != tt.want {
+			t.Errorf("NormalizeTimestamps(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestNormalizeTimeValues(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skip("time zone database not available")
+	}
+	type event struct {
+		Name  string
+		At    time.Time
+		Times []time.Time
+		ByAt  map[time.Time]int
+	}
+	at := time.Date(2024, 3, 1, 9, 30, 0, 500, berlin)
+	v := event{
+		Name:  "deploy",
+		At:    at,
+		Times: []time.Time{at.UTC(), {}},
+		ByAt:  map[time.Time]int{at: 1},
+	}
+	got := NormalizeTimeValues(dumpConfig.Sdump(v), "  ", time.UTC)
+
+	want := `transform.event{
+  Name: "deploy",
+  At: 2024-03-01T08:30:00.0000005Z,
+  Times: []time.Time{
+    2024-03-01T08:30:00.0000005Z,
+    0001-01-01T00:00:00Z,
+  },
+  ByAt: map[time.Time]int{
+    2024-03-01T08:30:00.0000005Z: 1,
+  },
+}
+`
+	if got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestNormalizeTimeValues_Monotonic(t *testing.T) {
+	now := time.Now()
+	got := NormalizeTimeValues(dumpConfig.Sdump(now), "  ", time.UTC)
+	if want := now.UTC().Format(time.RFC3339Nano); strings.TrimSpace(got) != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
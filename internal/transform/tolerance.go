@@ -0,0 +1,119 @@
+package transform
+
+import (
+	"encoding/json"
+	"math"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// decimalPattern matches decimal numbers, with an optional fraction and
+// exponent.
+var decimalPattern = regexp.MustCompile(`[-+]?(\d+\.?\d*|\.\d+)([eE][-+]?\d+)?`)
+
+// NumbersWithin reports whether a and b are the same text apart from
+// numbers that differ by at most delta.
+func NumbersWithin(a, b string, delta float64) bool {
+	if a == b {
+		return true
+	}
+	aNumbers := decimalPattern.FindAllStringIndex(a, -1)
+	bNumbers := decimalPattern.FindAllStringIndex(b, -1)
+	if len(aNumbers) != len(bNumbers) {
+		return false
+	}
+	aStart, bStart := 0, 0
+	for i := range aNumbers {
+		aLoc, bLoc := aNumbers[i], bNumbers[i]
+		if a[aStart:aLoc[0]] != b[bStart:bLoc[0]] {
+			return false
+		}
+		if !numberWithin(a[aLoc[0]:aLoc[1]], b[bLoc[0]:bLoc[1]], delta) {
+			return false
+		}
+		aStart, bStart = aLoc[1], bLoc[1]
+	}
+	return a[aStart:] == b[bStart:]
+}
+
+// JSONNumbersWithin reports whether the JSON documents a and b hold the
+// same values apart from numbers that differ by at most delta, at the given
+// dotted paths only (see Field.Path). Numbers elsewhere must be equal.
+func JSONNumbersWithin(a, b string, delta float64, paths []string) bool {
+	var aValue, bValue any
+	if decodeNumbers(a, &aValue) != nil || decodeNumbers(b, &bValue) != nil {
+		return false
+	}
+	return jsonWithin(aValue, bValue, "", delta, paths)
+}
+
+// SameJSON reports whether a and b are JSON documents holding the same
+// values, whatever the order of their object keys and their formatting.
+// Arrays must hold their elements in the same order.
+func SameJSON(a, b string) bool {
+	return JSONNumbersWithin(a, b, 0, nil)
+}
+
+func decodeNumbers(s string, v *any) error {
+	dec := json.NewDecoder(strings.NewReader(s))
+	dec.UseNumber()
+	return dec.Decode(v)
+}
+
+func jsonWithin(a, b any, path string, delta float64, paths []string) bool {
+	switch a := a.(type) {
+	case map[string]any:
+		b, ok := b.(map[string]any)
+		if !ok || len(a) != len(b) {
+			return false
+		}
+		for key, value := range a {
+			other, ok := b[key]
+			if !ok || !jsonWithin(value, other, fieldPath(path, key), delta, paths) {
+				return false
+			}
+		}
+		return true
+	case []any:
+		b, ok := b.([]any)
+		if !ok || len(a) != len(b) {
+			return false
+		}
+		for i := range a {
+			if !jsonWithin(a[i], b[i], path, delta, paths) {
+				return false
+			}
+		}
+		return true
+	case json.Number:
+		b, ok := b.(json.Number)
+		if !ok {
+			return false
+		}
+		if a == b {
+			return true
+		}
+		return slices.Contains(paths, path) && numberWithin(string(a), string(b), delta)
+	default:
+		return reflect.DeepEqual(a, b)
+	}
+}
+
+// numberWithin reports whether the numbers a and b differ by at most delta.
+func numberWithin(a, b string, delta float64) bool {
+	if a == b {
+		return true
+	}
+	x, err := strconv.ParseFloat(a, 64)
+	if err != nil {
+		return false
+	}
+	y, err := strconv.ParseFloat(b, 64)
+	if err != nil {
+		return false
+	}
+	return math.Abs(x-y) <= delta
+}
@@ -0,0 +1,60 @@
+package transform
+
+import "testing"
+
+func TestNumbersWithin(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want bool
+	}{
+		{"[]float64{0.3, 1.5}", "[]float64{0.30000000000000004, 1.5}", true},
+		{"x: 1e-10\n", "x: 0\n", true},
+		{"x: 0.3\n", "x: 0.31\n", false},
+		{"x: 0.3\n", "y: 0.3\n", false},
+		{"[1, 2]", "[1, 2, 3]", false},
+	}
+	for _, tt := range tests {
+		if got := NumbersWithin(tt.a, tt.b, 1e-9); got != tt.want {
+			t.Errorf("NumbersWithin(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestJSONNumbersWithin(t *testing.T) {
+	accepted := `{"metrics": {"score": 0.3, "count": 2}, "points": [{"x": 1.0}]}`
+	tests := []struct {
+		content string
+		want    bool
+	}{
+		{`{"metrics": {"score": 0.3000001, "count": 2}, "points": [{"x": 1.0000001}]}`, true},
+		{`{"metrics": {"score": 0.3, "count": 3}, "points": [{"x": 1.0}]}`, false},
+		{`{"metrics": {"score": 0.4, "count": 2}, "points": [{"x": 1.0}]}`, false},
+		{`{"metrics": {"score": 0.3, "count": 2}, "points": []}`, false},
+		{`{"metrics": {"score": "0.3", "count": 2}, "points": [{"x": 1.0}]}`, false},
+	}
+	paths := []string{"metrics.score", "points.x"}
+	for _, tt := range tests {
+		if got := JSONNumbersWithin(accepted, tt.content, 1e-6, paths); got != tt.want {
+			t.Errorf("JSONNumbersWithin(%s) = %v, want %v", tt.content, got, tt.want)
+		}
+	}
+}
+
+func TestSameJSON(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want bool
+	}{
+		{`{"a": 1, "b": [1, 2]}`, `{"b": [1, 2], "a": 1}`, true},
+		{`{"a": {"x": 1, "y": 2}}`, "{\n  \"a\": {\n    \"y\": 2,\n    \"x\": 1\n  }\n}", true},
+		{`{"b": [1, 2]}`, `{"b": [2, 1]}`, false},
+		{`{"a": 1}`, `{"a": 1.0}`, false},
+		{`{"a": 1}`, `{"a": 1, "b": null}`, false},
+		{`{"a": 1}`, `not json`, false},
+	}
+	for _, tt := range tests {
+		if got := SameJSON(tt.a, tt.b); got != tt.want {
+			t.Errorf("SameJSON(%s, %s) = %v, expected %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
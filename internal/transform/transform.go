@@ -1,8 +1,11 @@
 package transform
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
+	"io"
+	"strings"
 )
 
 // Scrubber transforms content before snapshotting.
@@ -15,10 +18,71 @@ type IgnorePattern interface {
 	ShouldIgnore(key, value string) bool
 }
 
+// FieldIgnorePattern is an IgnorePattern that needs more than the key and
+// the string form of the value, such as the value's JSON type or where the
+// field is in the document. ShouldIgnoreField is used instead of
+// ShouldIgnore.
+type FieldIgnorePattern interface {
+	IgnorePattern
+	ShouldIgnoreField(f Field) bool
+}
+
+// Field is a key-value pair of a JSON object.
+type Field struct {
+	// Path is the dotted path of the field from the document root, such as
+	// data.items.id. Array indexes are not part of it, so the fields of
+	// every element of an array share a path.
+	Path  string
+	Key   string
+	Value any
+}
+
+// ValueString returns the value in the form passed to
+// IgnorePattern.ShouldIgnore.
+func (f Field) ValueString() string {
+	return valueToString(f.Value)
+}
+
+// Type returns the JSON type of the field's value: "object", "array",
+// "string", "number", "boolean" or "null".
+func (f Field) Type() string {
+	switch f.Value.(type) {
+	case map[string]any, object:
+		return "object"
+	case []any:
+		return "array"
+	case string:
+		return "string"
+	case json.Number, float64, int, int64:
+		return "number"
+	case bool:
+		return "boolean"
+	default:
+		return "null"
+	}
+}
+
 // Config holds the transformation configuration.
 type Config struct {
 	Scrubbers []Scrubber
 	Ignore    []IgnorePattern
+
+	// Scopes apply scrubbers to parts of the document only. They run before
+	// Scrubbers.
+	Scopes []Scope
+
+	// PreserveOrder keeps object keys in the order they appear in the input
+	// instead of sorting them.
+	PreserveOrder bool
+
+	// AllowComments accepts JSONC input: comments and trailing commas are
+	// stripped before the JSON is parsed.
+	AllowComments bool
+
+	// Select, if set, keeps only the values at these dotted paths, such as
+	// data.items.id, and the objects and arrays that lead to them. It is
+	// applied before ignore patterns.
+	Select []string
 }
 
 // ApplyScrubbers applies all scrubbers to the content in order.
@@ -32,71 +96,146 @@ func ApplyScrubbers(content string, scrubbers []Scrubber) string {
 
 // TransformJSON applies scrubbers and ignore patterns to JSON data.
 func TransformJSON(jsonStr string, config *Config) (string, error) {
-	var data any
-	if err := json.Unmarshal([]byte(jsonStr), &data); err != nil {
+	return TransformJSONReader(strings.NewReader(jsonStr), config)
+}
+
+// TransformJSONReader is like TransformJSON but reads the JSON from r, so
+// large inputs such as response bodies need not be copied into a string
+// first. JSONC input is read in full to strip its comments.
+func TransformJSONReader(r io.Reader, config *Config) (string, error) {
+	if config.AllowComments {
+		input, err := io.ReadAll(r)
+		if err != nil {
+			return "", fmt.Errorf("failed to read JSON: %w", err)
+		}
+		if err := stripJSONC(input); err != nil {
+			return "", fmt.Errorf("failed to unmarshal JSON: %w", err)
+		}
+		r = bytes.NewReader(input)
+	}
+
+	data, err := decode(r, config.PreserveOrder)
+	if err != nil {
 		return "", fmt.Errorf("failed to unmarshal JSON: %w", err)
 	}
 
+	if len(config.Select) > 0 {
+		if data, err = selectPaths(data, config.Select); err != nil {
+			return "", err
+		}
+	}
+
 	// Apply ignore patterns first (removes fields)
 	if len(config.Ignore) > 0 {
 		data = walkAndFilter(data, config.Ignore)
 	}
 
 	// Marshal back to JSON
-	prettyJSON, err := json.MarshalIndent(data, "", "  ")
+	var result string
+	if len(config.Scopes) > 0 {
+		result, err = marshalScoped(data, "", "", config.Scopes)
+	} else {
+		var prettyJSON []byte
+		prettyJSON, err = json.MarshalIndent(data, "", "  ")
+		result = string(prettyJSON)
+	}
 	if err != nil {
 		return "", fmt.Errorf("failed to marshal JSON: %w", err)
 	}
 
-	result := string(prettyJSON)
-
 	// Apply scrubbers to the final string
 	result = ApplyScrubbers(result, config.Scrubbers)
 
 	return result, nil
 }
 
+// decode parses the JSON in r, in its original order if preserveOrder is set.
+// Numbers decode to json.Number and are written back exactly as they
+// appear, so 64-bit IDs and high-precision decimals do not pass through
+// float64 and come out rounded or in scientific notation.
+func decode(r io.Reader, preserveOrder bool) (any, error) {
+	dec := json.NewDecoder(r)
+	dec.UseNumber()
+
+	var data any
+	var err error
+	if preserveOrder {
+		data, err = decodeValue(dec)
+	} else {
+		err = dec.Decode(&data)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return nil, fmt.Errorf("unexpected data after top-level value")
+	}
+	return data, nil
+}
+
 // walkAndFilter recursively walks the data structure and filters out ignored fields.
 func walkAndFilter(data any, ignorePatterns []IgnorePattern) any {
+	return walkAndFilterAt(data, ignorePatterns, "")
+}
+
+// walkAndFilterAt is walkAndFilter for data found at path.
+func walkAndFilterAt(data any, ignorePatterns []IgnorePattern, path string) any {
 	switch v := data.(type) {
 	case map[string]any:
-		return filterMap(v, ignorePatterns)
+		return filterMap(v, ignorePatterns, path)
+	case object:
+		return filterObject(v, ignorePatterns, path)
 	case []any:
-		return filterSlice(v, ignorePatterns)
+		return filterSlice(v, ignorePatterns, path)
 	default:
 		return data
 	}
 }
 
+// fieldPath returns the path of the field key of the object at path.
+func fieldPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
 // filterMap filters a map, removing entries that match ignore patterns.
-func filterMap(m map[string]any, ignorePatterns []IgnorePattern) map[string]any {
+func filterMap(m map[string]any, ignorePatterns []IgnorePattern, path string) map[string]any {
 	result := make(map[string]any)
 	for key, value := range m {
-		// Convert value to string for comparison
-		valueStr := valueToString(value)
-
-		// Check if this key-value pair should be ignored
-		shouldIgnore := false
-		for _, pattern := range ignorePatterns {
-			if pattern.ShouldIgnore(key, valueStr) {
-				shouldIgnore = true
-				break
-			}
-		}
-
-		if !shouldIgnore {
+		field := Field{Path: fieldPath(path, key), Key: key, Value: value}
+		if !shouldIgnore(field, ignorePatterns) {
 			// Recursively filter nested structures
-			result[key] = walkAndFilter(value, ignorePatterns)
+			result[key] = walkAndFilterAt(value, ignorePatterns, field.Path)
 		}
 	}
 	return result
 }
 
+// shouldIgnore reports whether any ignore pattern matches the field.
+func shouldIgnore(field Field, ignorePatterns []IgnorePattern) bool {
+	// Convert value to string for comparison
+	valueStr := valueToString(field.Value)
+	for _, pattern := range ignorePatterns {
+		if fp, ok := pattern.(FieldIgnorePattern); ok {
+			if fp.ShouldIgnoreField(field) {
+				return true
+			}
+			continue
+		}
+		if pattern.ShouldIgnore(field.Key, valueStr) {
+			return true
+		}
+	}
+	return false
+}
+
 // filterSlice filters a slice, recursively processing each element.
-func filterSlice(s []any, ignorePatterns []IgnorePattern) []any {
+func filterSlice(s []any, ignorePatterns []IgnorePattern, path string) []any {
 	result := make([]any, len(s))
 	for i, item := range s {
-		result[i] = walkAndFilter(item, ignorePatterns)
+		result[i] = walkAndFilterAt(item, ignorePatterns, path)
 	}
 	return result
 }
@@ -115,6 +254,8 @@ func valueToString(value any) string {
 		return "false"
 	case float64:
 		return fmt.Sprintf("%v", v)
+	case json.Number:
+		return v.String()
 	case int, int64:
 		return fmt.Sprintf("%d", v)
 	default:
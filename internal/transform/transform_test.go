@@ -1,6 +1,7 @@
 package transform
 
 import (
+	"fmt"
 	"regexp"
 	"strings"
 	"testing"
@@ -346,7 +347,7 @@ func TestFilterMap_RemovesMatchingKeys(t *testing.T) {
 		"also_keep": "value3",
 	}
 
-	result := filterMap(input, []IgnorePattern{ignorePattern})
+	result := filterMap(input, []IgnorePattern{ignorePattern}, "")
 
 	if _, exists := result["remove_me"]; exists {
 		t.Error("expected 'remove_me' to be filtered out")
@@ -374,7 +375,7 @@ func TestFilterMap_NestedStructures(t *testing.T) {
 		},
 	}
 
-	result := filterMap(input, []IgnorePattern{ignorePattern})
+	result := filterMap(input, []IgnorePattern{ignorePattern}, "")
 
 	nested, ok := result["nested"].(map[string]any)
 	if !ok {
@@ -401,7 +402,7 @@ func TestFilterSlice_ProcessesAllElements(t *testing.T) {
 		map[string]any{"id": "2", "name": "Bob"},
 	}
 
-	result := filterSlice(input, []IgnorePattern{ignorePattern})
+	result := filterSlice(input, []IgnorePattern{ignorePattern}, "")
 
 	if len(result) != 2 {
 		t.Fatalf("expected 2 elements, got %d", len(result))
@@ -519,3 +520,283 @@ func TestTransformJSON_IgnoreByValue(t *testing.T) {
 		t.Errorf("expected other fields to remain, got: %s", result)
 	}
 }
+
+type mockFieldIgnorePattern struct {
+	mockIgnorePattern
+	fields []Field
+}
+
+func (m *mockFieldIgnorePattern) ShouldIgnoreField(f Field) bool {
+	m.fields = append(m.fields, f)
+	return f.Type() == "number"
+}
+
+func TestTransformJSON_FieldIgnorePattern(t *testing.T) {
+	for _, preserveOrder := range []bool{false, true} {
+		ignorePattern := &mockFieldIgnorePattern{}
+		config := &Config{Ignore: []IgnorePattern{ignorePattern}, PreserveOrder: preserveOrder}
+
+		input := `{"count":1,"data":{"items":[{"id":"a","size":2}],"ok":true}}`
+		result, err := TransformJSON(input, config)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if strings.Contains(result, "count") || strings.Contains(result, "size") {
+			t.Errorf("expected numeric fields to be removed, got: %s", result)
+		}
+
+		types := map[string]string{}
+		for _, f := range ignorePattern.fields {
+			types[f.Path] = f.Type()
+		}
+		want := map[string]string{
+			"count":           "number",
+			"data":            "object",
+			"data.items":      "array",
+			"data.items.id":   "string",
+			"data.items.size": "number",
+			"data.ok":         "boolean",
+		}
+		if fmt.Sprint(types) != fmt.Sprint(want) {
+			t.Errorf("preserveOrder=%v: expected fields %v, got %v", preserveOrder, want, types)
+		}
+	}
+}
+
+func TestTransformJSON_Scopes(t *testing.T) {
+	input := `{"a":{"b":[{"c":{"x":"keep"},"d":1}],"x":"keep"},"x":"keep"}`
+	upper := &mockScrubber{fn: strings.ToUpper}
+	mark := &mockScrubber{fn: func(s string) string { return strings.ReplaceAll(s, "keep", "kept") }}
+
+	for _, preserveOrder := range []bool{false, true} {
+		plain, err := TransformJSON(input, &Config{PreserveOrder: preserveOrder})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		identity := &mockScrubber{fn: func(s string) string { return s }}
+		same, err := TransformJSON(input, &Config{PreserveOrder: preserveOrder, Scopes: []Scope{{Path: "a.b", Scrubbers: []Scrubber{identity}}}})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if same != plain {
+			t.Errorf("expected scoping alone not to change the output, got:\n%s\nwant:\n%s", same, plain)
+		}
+
+		result, err := TransformJSON(input, &Config{
+			PreserveOrder: preserveOrder,
+			Scopes: []Scope{
+				{Path: "a.b.c", Scrubbers: []Scrubber{mark}},
+				{Path: "a.b", Scrubbers: []Scrubber{upper}},
+			},
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		want := strings.Replace(plain, `"c": {
+          "x": "keep"
+        },
+        "d": 1`, `"C": {
+          "X": "KEPT"
+        },
+        "D": 1`, 1)
+		if result != want {
+			t.Errorf("preserveOrder=%v: unexpected output:\n%s\nwant:\n%s", preserveOrder, result, want)
+		}
+	}
+}
+
+func TestTransformJSON_NumberPrecision(t *testing.T) {
+	input := `{"id":9007199254740993,"big":18446744073709551615,"amount":0.1000000000000000055511151231257827,"price":9.90,"exp":1e21,"neg":-0.0}`
+
+	for _, preserveOrder := range []bool{false, true} {
+		result, err := TransformJSON(input, &Config{PreserveOrder: preserveOrder})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		for _, want := range []string{
+			`"id": 9007199254740993`,
+			`"big": 18446744073709551615`,
+			`"amount": 0.1000000000000000055511151231257827`,
+			`"price": 9.90`,
+			`"exp": 1e21`,
+			`"neg": -0.0`,
+		} {
+			if !strings.Contains(result, want) {
+				t.Errorf("PreserveOrder=%v: expected %s in output:\n%s", preserveOrder, want, result)
+			}
+		}
+	}
+}
+
+func TestTransformJSON_IgnoreLargeIntegerValue(t *testing.T) {
+	ignorePattern := &mockIgnorePattern{
+		fn: func(key, value string) bool {
+			return value == "9007199254740993"
+		},
+	}
+
+	result, err := TransformJSON(`{"id":9007199254740993,"other":9007199254740992}`, &Config{Ignore: []IgnorePattern{ignorePattern}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := "{\n  \"other\": 9007199254740992\n}"
+	if result != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, result)
+	}
+}
+
+func TestTransformJSON_TrailingData(t *testing.T) {
+	_, err := TransformJSON(`{"a":1} {"b":2}`, &Config{})
+	if err == nil || !strings.Contains(err.Error(), "failed to unmarshal JSON") {
+		t.Errorf("expected an unmarshal error, got %v", err)
+	}
+}
+
+func TestTransformJSON_PreserveOrder(t *testing.T) {
+	config := &Config{PreserveOrder: true}
+	input := `{"zeta":1,"alpha":{"y":1.50,"x":[3,{"b":true,"a":null}]},"mid":12345678901234567890,"empty":{},"list":[]}`
+
+	result, err := TransformJSON(input, config)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := `{
+  "zeta": 1,
+  "alpha": {
+    "y": 1.50,
+    "x": [
+      3,
+      {
+        "b": true,
+        "a": null
+      }
+    ]
+  },
+  "mid": 12345678901234567890,
+  "empty": {},
+  "list": []
+}`
+	if result != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, result)
+	}
+}
+
+func TestTransformJSON_PreserveOrderIgnore(t *testing.T) {
+	ignorePattern := &mockIgnorePattern{
+		fn: func(key, value string) bool {
+			return key == "password" || value == "2"
+		},
+	}
+	config := &Config{Ignore: []IgnorePattern{ignorePattern}, PreserveOrder: true}
+	input := `{"user":{"name":"John","password":"secret","id":7},"count":2,"items":[{"password":"x","sku":"a"}]}`
+
+	result, err := TransformJSON(input, config)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := "{\n  \"user\": {\n    \"name\": \"John\",\n    \"id\": 7\n  },\n  \"items\": [\n    {\n      \"sku\": \"a\"\n    }\n  ]\n}"
+	if result != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, result)
+	}
+}
+
+func TestTransformJSON_PreserveOrderInvalid(t *testing.T) {
+	for _, input := range []string{"not valid json", `{"a":1`, `{"a":1} {"b":2}`, `[1,]`} {
+		_, err := TransformJSON(input, &Config{PreserveOrder: true})
+		if err == nil || !strings.Contains(err.Error(), "failed to unmarshal JSON") {
+			t.Errorf("TransformJSON(%q): expected an unmarshal error, got %v", input, err)
+		}
+	}
+}
+
+// Benchmarks
+
+func TestTransformJSON_Select(t *testing.T) {
+	input := `{
+		"data": {
+			"items": [
+				{"id": 1, "name": "a", "tags": ["x"]},
+				{"id": 2, "name": "b"},
+				{"name": "c"}
+			],
+			"total": 3,
+			"cursor": "abc"
+		},
+		"meta": {"took_ms": 12}
+	}`
+
+	for _, preserveOrder := range []bool{false, true} {
+		result, err := TransformJSON(input, &Config{Select: []string{"data.items.id", "data.total"}, PreserveOrder: preserveOrder})
+		if err != nil {
+			t.Fatalf("preserveOrder %v: unexpected error: %v", preserveOrder, err)
+		}
+		compact := strings.Join(strings.Fields(result), "")
+		want := `{"data":{"items":[{"id":1},{"id":2},{}],"total":3}}`
+		if compact != want {
+			t.Errorf("preserveOrder %v: expected %s, got %s", preserveOrder, want, compact)
+		}
+	}
+
+	result, err := TransformJSON(input, &Config{Select: []string{"data.items"}, Ignore: []IgnorePattern{
+		&mockIgnorePattern{fn: func(key, _ string) bool { return key == "name" }},
+	}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if compact := strings.Join(strings.Fields(result), ""); compact != `{"data":{"items":[{"id":1,"tags":["x"]},{"id":2},{}]}}` {
+		t.Errorf("expected the whole items array less ignored keys, got %s", compact)
+	}
+
+	result, err = TransformJSON(`{"data": {"items": []}}`, &Config{Select: []string{"data.items.id"}})
+	if err != nil || strings.Join(strings.Fields(result), "") != `{"data":{"items":[]}}` {
+		t.Errorf("expected an empty array to be selected, got %s (err %v)", result, err)
+	}
+
+	_, err = TransformJSON(input, &Config{Select: []string{"data.items.id", "data.totl"}})
+	if err == nil || !strings.Contains(err.Error(), `nothing to select at "data.totl"`) {
+		t.Errorf("expected an error for a path that selects nothing, got %v", err)
+	}
+}
+
+func BenchmarkTransformJSON(b *testing.B) {
+	sizes := []struct {
+		name string
+		size int
+	}{
+		{"1MB", 1 << 20},
+		{"10MB", 10 << 20},
+		{"50MB", 50 << 20},
+	}
+	ignoreID := &mockIgnorePattern{fn: func(key, _ string) bool { return key == "id" }}
+
+	for _, bs := range sizes {
+		b.Run(bs.name, func(b *testing.B) {
+			if testing.Short() && bs.size > 1<<20 {
+				b.Skip("large input skipped with -short")
+			}
+			var sb strings.Builder
+			sb.WriteString("[")
+			for i := 0; sb.Len() < bs.size; i++ {
+				if i > 0 {
+					sb.WriteString(",")
+				}
+				fmt.Fprintf(&sb, `{"id":%d,"name":"user %d","tags":["a","b"]}`, i, i)
+			}
+			sb.WriteString("]")
+			input := sb.String()
+			config := &Config{Ignore: []IgnorePattern{ignoreID}}
+
+			b.SetBytes(int64(len(input)))
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err := TransformJSON(input, config); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,142 @@
+package transform
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// IndentXML re-indents an XML document with two spaces per level, so that
+// documents differing only in whitespace snapshot alike. Whitespace-only
+// text is dropped and other text is trimmed; an element holding only text
+// stays on one line and an empty element is written self-closing. Names
+// keep their prefixes and attributes keep their order.
+func IndentXML(content string) (string, error) {
+	tokens, err := xmlTokens(content)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse XML: %w", err)
+	}
+
+	var lines []string
+	depth := 0
+	indent := func() string { return strings.Repeat("  ", depth) }
+	for i := 0; i < len(tokens); i++ {
+		switch tok := tokens[i].(type) {
+		case xml.StartElement:
+			start := "<" + xmlName(tok.Name) + xmlAttrs(tok.Attr)
+			if next, ok := tokenAt(tokens, i+1).(xml.EndElement); ok && next.Name == tok.Name {
+				lines = append(lines, indent()+start+"/>")
+				i++
+				continue
+			}
+			if text, ok := tokenAt(tokens, i+1).(xml.CharData); ok {
+				if _, ok := tokenAt(tokens, i+2).(xml.EndElement); ok {
+					lines = append(lines, indent()+start+">"+escapeXML(text)+"</"+xmlName(tok.Name)+">")
+					i += 2
+					continue
+				}
+			}
+			lines = append(lines, indent()+start+">")
+			depth++
+		case xml.EndElement:
+			depth--
+			lines = append(lines, indent()+"</"+xmlName(tok.Name)+">")
+		case xml.CharData:
+			lines = append(lines, indent()+escapeXML(tok))
+		case xml.Comment:
+			lines = append(lines, indent()+"<!--"+string(tok)+"-->")
+		case xml.ProcInst:
+			lines = append(lines, indent()+"<?"+tok.Target+" "+string(tok.Inst)+"?>")
+		case xml.Directive:
+			lines = append(lines, indent()+"<!"+string(tok)+">")
+		}
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+// xmlTokens reads the tokens of a well-formed document without resolving
+// namespaces, trimming text and dropping whitespace-only text.
+func xmlTokens(content string) ([]xml.Token, error) {
+	// RawToken keeps prefixes as written but does not check that end tags
+	// match, so check the document first.
+	if err := checkXML(content); err != nil {
+		return nil, err
+	}
+
+	dec := xml.NewDecoder(strings.NewReader(content))
+	var tokens []xml.Token
+	for {
+		tok, err := dec.RawToken()
+		if err == io.EOF {
+			return tokens, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if text, ok := tok.(xml.CharData); ok {
+			text = bytes.TrimSpace(text)
+			if len(text) == 0 {
+				continue
+			}
+			tok = text
+		}
+		tokens = append(tokens, xml.CopyToken(tok))
+	}
+}
+
+// checkXML returns an error unless content is a well-formed document with
+// at least one element.
+func checkXML(content string) error {
+	dec := xml.NewDecoder(strings.NewReader(content))
+	hasElement := false
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			if !hasElement {
+				return fmt.Errorf("no root element")
+			}
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if _, ok := tok.(xml.StartElement); ok {
+			hasElement = true
+		}
+	}
+}
+
+func tokenAt(tokens []xml.Token, i int) xml.Token {
+	if i < len(tokens) {
+		return tokens[i]
+	}
+	return nil
+}
+
+func xmlName(name xml.Name) string {
+	if name.Space == "" {
+		return name.Local
+	}
+	return name.Space + ":" + name.Local
+}
+
+// Escapers for text and attribute values. Unlike xml.EscapeText they leave
+// newlines and tabs as written.
+var (
+	textEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+	attrEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;", `"`, "&quot;")
+)
+
+func xmlAttrs(attrs []xml.Attr) string {
+	var sb strings.Builder
+	for _, attr := range attrs {
+		sb.WriteString(" " + xmlName(attr.Name) + `="` + attrEscaper.Replace(attr.Value) + `"`)
+	}
+	return sb.String()
+}
+
+func escapeXML(text []byte) string {
+	return textEscaper.Replace(string(text))
+}
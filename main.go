@@ -0,0 +1,193 @@
+package shutter
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/ptdewey/shutter/internal/files"
+	"github.com/ptdewey/shutter/internal/review"
+	"github.com/ptdewey/shutter/internal/snapshots"
+)
+
+// MainOption configures Main.
+type MainOption interface {
+	applyMain(cfg *mainConfig)
+}
+
+type mainConfig struct {
+	defaults []Option
+	strict   bool
+	review   bool
+}
+
+// defaults holds the options set with Defaults. Main sets it before any
+// test runs.
+var defaults []Option
+
+// withDefaults returns opts preceded by the package defaults. Default
+// IgnorePattern options only apply to JSON snapshots, the only ones that
+// support them.
+func withDefaults(opts []Option, json bool) []Option {
+	if len(defaults) == 0 {
+		return opts
+	}
+	all := make([]Option, 0, len(defaults)+len(opts))
+	for _, opt := range defaults {
+		if _, ok := opt.(IgnorePattern); ok && !json {
+			continue
+		}
+		all = append(all, opt)
+	}
+	return append(all, opts...)
+}
+
+type defaultsOption struct {
+	opts []Option
+}
+
+func (o defaultsOption) applyMain(cfg *mainConfig) {
+	cfg.defaults = append(cfg.defaults, o.opts...)
+}
+
+// Defaults applies opts to every snapshot the package's tests take, before
+// the options passed to each call, so shared scrubbers and settings are
+// given once.
+//
+// Example:
+//
+//	os.Exit(shutter.Main(m, shutter.Defaults(shutter.ScrubUUID(), shutter.ScrubTimestamp())))
+func Defaults(opts ...Option) MainOption {
+	return defaultsOption{opts}
+}
+
+type strictOption struct{}
+
+func (strictOption) applyMain(cfg *mainConfig) {
+	cfg.strict = true
+}
+
+// Strict fails the run if the tests pass but the package has accepted
+// snapshots that no test compared, so a baseline left behind by a renamed
+// title or a removed test is caught right away rather than by a later
+// cleanup. The check is skipped when tests are selected with -run or -skip,
+// with -short, where tests may skip their snapshots, and while fuzzing.
+// Benchmark snapshots and stored fuzz inputs, which an ordinary test run
+// does not take, and the other variants of a compared title, such as the
+// baselines of other platforms, are never reported.
+func Strict() MainOption {
+	return strictOption{}
+}
+
+type reviewOption struct{}
+
+func (reviewOption) applyMain(cfg *mainConfig) {
+	cfg.review = true
+}
+
+// ReviewPending starts an interactive review after the tests when they
+// left snapshots pending, as long as the tests run in a terminal and not
+// in CI (the CI environment variable is unset).
+func ReviewPending() MainOption {
+	return reviewOption{}
+}
+
+// Main runs the tests of a package and returns the exit code for the test
+// binary. After the tests, it prints how many snapshots matched, were new
+// or mismatched. Call it from TestMain with the *testing.M:
+//
+//	func TestMain(m *testing.M) {
+//	    os.Exit(shutter.Main(m, shutter.Strict()))
+//	}
+func Main(m interface{ Run() int }, opts ...MainOption) int {
+	cfg := &mainConfig{}
+	for _, opt := range opts {
+		opt.applyMain(cfg)
+	}
+	defaults = cfg.defaults
+	if cfg.strict {
+		files.TrackCompared()
+	}
+
+	code := m.Run()
+	if cfg.strict && code == 0 && !testsFiltered() && !shortRun() {
+		code = checkUncompared()
+	}
+
+	counts := snapshots.Counts()
+	if summary := runSummary(counts); summary != "" {
+		snapshots.Println(summary)
+	}
+	if cfg.review && counts.Pending() > 0 && interactive() {
+		if err := review.Review(); err != nil {
+			snapshots.Println("shutter: review failed:", err)
+		}
+	}
+	return code
+}
+
+// runSummary describes the snapshot outcomes of a run on one line, or
+// returns "" if no snapshot was compared.
+func runSummary(c snapshots.RunCounts) string {
+	if c.Matched+c.Pending()+c.Updated == 0 {
+		return ""
+	}
+	summary := fmt.Sprintf("shutter: %d matched, %d new, %d mismatched", c.Matched, c.New, c.Mismatched)
+	if c.Updated > 0 {
+		summary += fmt.Sprintf(", %d updated", c.Updated)
+	}
+	if c.Pending() > 0 {
+		summary += " - run '" + snapshots.ReviewCommand() + "'"
+	}
+	return summary
+}
+
+// interactive reports whether a person can answer a review on stdin: stdin
+// is a terminal and the CI environment variable is unset.
+func interactive() bool {
+	if os.Getenv("CI") != "" {
+		return false
+	}
+	info, err := os.Stdin.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// testsFiltered reports whether the test binary runs only some of the
+// tests, so snapshots of the others are not compared.
+func testsFiltered() bool {
+	for _, name := range []string{"test.run", "test.skip", "test.fuzz"} {
+		if f := flag.Lookup(name); f != nil && f.Value.String() != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// shortRun reports whether the tests run with -short, so tests that check
+// testing.Short skip their snapshots.
+func shortRun() bool {
+	f := flag.Lookup("test.short")
+	return f != nil && f.Value.String() == "true"
+}
+
+// checkUncompared reports the accepted snapshots of the package that were
+// not compared and returns the exit code for the run.
+func checkUncompared() int {
+	unused, err := files.UncomparedSnapshots()
+	if err != nil {
+		snapshots.Println("shutter: failed to check for unused snapshots:", err)
+		return 1
+	}
+	if len(unused) == 0 {
+		return 0
+	}
+
+	lines := []string{fmt.Sprintf("shutter: %d accepted snapshot(s) were not compared by any test:", len(unused))}
+	for _, path := range unused {
+		lines = append(lines, "  "+files.DisplayPath(path))
+	}
+	lines = append(lines, "Delete them, or update the tests that should take them.")
+	snapshots.Println(strings.Join(lines, "\n"))
+	return 1
+}
@@ -0,0 +1,103 @@
+package shutter_test
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestMainHelper runs go test on a module whose TestMain calls shutter.Main.
+func TestMainHelper(t *testing.T) {
+	if testing.Short() {
+		t.Skip("runs go test")
+	}
+	repo, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sum, err := os.ReadFile(filepath.Join(repo, "go.sum"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	write := func(name, content string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("go.mod", "module example\n\ngo 1.23\n\nrequire github.com/ptdewey/shutter v0.0.0\n\nreplace github.com/ptdewey/shutter => "+repo+"\n")
+	write("go.sum", string(sum))
+	write("main_test.go", `package example
+
+import (
+	"os"
+	"testing"
+
+	"github.com/ptdewey/shutter"
+)
+
+func TestMain(m *testing.M) {
+	os.Exit(shutter.Main(m, shutter.Strict(), shutter.Defaults(shutter.ScrubUUID())))
+}
+`)
+	write("a_test.go", `package example
+
+import (
+	"testing"
+
+	"github.com/ptdewey/shutter"
+)
+
+func TestA(t *testing.T) {
+	shutter.SnapString(t, "id", "id: 550e8400-e29b-41d4-a716-446655440000")
+}
+`)
+
+	goTestArgs := func(args []string, env ...string) (string, bool) {
+		t.Helper()
+		cmd := exec.Command("go", append([]string{"test", "-count=1", "."}, args...)...)
+		cmd.Dir = dir
+		cmd.Env = append(os.Environ(), append([]string{"GOWORK=off", "GOFLAGS=-mod=mod", "CI=1"}, env...)...)
+		out, err := cmd.CombinedOutput()
+		return string(out), err == nil
+	}
+	goTest := func(env ...string) (string, bool) {
+		t.Helper()
+		return goTestArgs(nil, env...)
+	}
+
+	out, ok := goTest()
+	if ok || !strings.Contains(out, "shutter: 0 matched, 1 new, 0 mismatched - run 'shutter review'") {
+		t.Fatalf("expected a failing run with one new snapshot, got:\n%s", out)
+	}
+
+	if out, ok := goTest("SHUTTER_UPDATE=1"); !ok {
+		t.Fatalf("expected the update run to pass, got:\n%s", out)
+	}
+	accepted, err := os.ReadFile(filepath.Join(dir, "__snapshots__", "TestA", "id.snap"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(accepted), "id: <UUID>") {
+		t.Errorf("expected the default scrubber to apply, got:\n%s", accepted)
+	}
+
+	if out, ok := goTest(); !ok {
+		t.Fatalf("expected a passing run, got:\n%s", out)
+	}
+
+	// Strict mode catches the snapshot of a removed test.
+	write("a_test.go", "package example\n")
+	out, ok = goTest()
+	if ok || !strings.Contains(out, "1 accepted snapshot(s) were not compared by any test") {
+		t.Errorf("expected strict mode to fail the run, got:\n%s", out)
+	}
+	// Under -short, tests may skip their snapshots.
+	if out, ok := goTestArgs([]string{"-short"}); !ok {
+		t.Errorf("expected strict mode to be skipped with -short, got:\n%s", out)
+	}
+}
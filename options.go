@@ -0,0 +1,772 @@
+package shutter
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ptdewey/shutter/internal/review"
+	"github.com/ptdewey/shutter/internal/snapshots"
+	"github.com/ptdewey/shutter/internal/transform"
+)
+
+// setting is an Option that changes how a snapshot is taken rather than
+// transforming its content.
+type setting interface {
+	Option
+	apply(cfg *snapConfig)
+}
+
+// snapConfig holds the settings resolved for a single snapshot call.
+type snapConfig struct {
+	checkDeterminism bool
+	variants         []string
+	fuzzWrites       bool
+	preserveKeyOrder bool
+	allowJSONC       bool
+	normalizeEOL     bool
+	trimWhitespace   bool
+	detectFlakes     int
+	recordManifest   bool
+	externalAbove    int
+	staleAfter       time.Duration
+	staleVersions    int
+	update           bool
+	readOnly         bool
+	tolerance        float64
+	tolerancePaths   []string
+	anyKeyOrder      bool
+	rewriteKeyOrder  bool
+	comparator       Comparator
+	suite            string
+	placeholders     placeholders
+	// selectValue and selectPaths choose the part of the value or JSON
+	// document that is snapshotted.
+	selectValue func(v any) (any, error)
+	selectPaths []string
+	// excludeUnexported leaves unexported struct fields out of formatted
+	// values.
+	excludeUnexported bool
+	// timeZone, if set, is the zone formatted time.Time values are shown in.
+	timeZone *time.Location
+	// keepRaw keeps the content before scrubbing in raw, for review.
+	keepRaw bool
+	raw     string
+	// applied names the scrubbers and ignore patterns, in the order given.
+	applied []string
+}
+
+// newSnapConfig resolves settings from environment defaults and the given
+// options. Options take precedence over the environment.
+func newSnapConfig(opts []Option) *snapConfig {
+	cfg := &snapConfig{
+		checkDeterminism: envBool("SHUTTER_CHECK_DETERMINISM"),
+		fuzzWrites:       envBool("SHUTTER_FUZZ_WRITES"),
+		normalizeEOL:     envBool("SHUTTER_NORMALIZE_LINE_ENDINGS"),
+		trimWhitespace:   envBool("SHUTTER_TRIM_TRAILING_WHITESPACE"),
+		detectFlakes:     envInt("SHUTTER_DETECT_FLAKES"),
+		recordManifest:   envBool("SHUTTER_MANIFEST"),
+		externalAbove:    envInt("SHUTTER_EXTERNAL_CONTENT_ABOVE"),
+		staleAfter:       envAge("SHUTTER_STALE_AFTER"),
+		staleVersions:    envInt("SHUTTER_STALE_VERSIONS"),
+		update:           envBool("SHUTTER_UPDATE"),
+		readOnly:         envBool("SHUTTER_READ_ONLY"),
+		keepRaw:          envBool("SHUTTER_KEEP_RAW"),
+		anyKeyOrder:      envBool("SHUTTER_ANY_KEY_ORDER") || envBool("SHUTTER_REWRITE_KEY_ORDER"),
+		rewriteKeyOrder:  envBool("SHUTTER_REWRITE_KEY_ORDER"),
+		placeholders: placeholders{
+			style:      parsePlaceholderStyle(os.Getenv("SHUTTER_PLACEHOLDER_STYLE")),
+			revealLast: envInt("SHUTTER_REVEAL_LAST"),
+		},
+	}
+	for _, opt := range opts {
+		switch o := opt.(type) {
+		case setting:
+			o.apply(cfg)
+		case Scrubber, IgnorePattern:
+			cfg.applied = append(cfg.applied, optionName(o))
+		}
+	}
+	return cfg
+}
+
+// optionName describes a scrubber or ignore pattern for the snapshot header,
+// e.g. ScrubRegex(`user-\d+`, "<USER_ID>").
+func optionName(opt Option) string {
+	if s, ok := opt.(fmt.Stringer); ok {
+		return s.String()
+	}
+	return fmt.Sprintf("%T", opt)
+}
+
+// quoteArg quotes an option argument for optionName. Arguments with
+// backslashes, typically regular expressions, are backquoted so they read
+// as written.
+func quoteArg(s string) string {
+	if strings.Contains(s, `\`) && strconv.CanBackquote(s) {
+		return "`" + s + "`"
+	}
+	return strconv.Quote(s)
+}
+
+// quoteArgs quotes and comma-separates variadic option arguments.
+func quoteArgs(args []string) string {
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		quoted[i] = quoteArg(arg)
+	}
+	return strings.Join(quoted, ", ")
+}
+
+// envBool reports whether the named environment variable is set to a true
+// value as understood by strconv.ParseBool.
+func envBool(name string) bool {
+	v, err := strconv.ParseBool(os.Getenv(name))
+	return err == nil && v
+}
+
+// envInt returns the named environment variable as a non-negative integer,
+// or 0 if it is unset or invalid.
+func envInt(name string) int {
+	v, err := strconv.Atoi(os.Getenv(name))
+	if err != nil || v < 0 {
+		return 0
+	}
+	return v
+}
+
+// envAge returns the named environment variable as an age such as "180d",
+// "2w" or "36h", or 0 if it is unset or invalid.
+func envAge(name string) time.Duration {
+	age, err := review.ParseAge(os.Getenv(name))
+	if err != nil || age < 0 {
+		return 0
+	}
+	return age
+}
+
+// fuzzing reports whether the test binary is running the fuzzing engine
+// (go test -fuzz), as opposed to only running the seed corpus.
+func fuzzing() bool {
+	f := flag.Lookup("test.fuzz")
+	return f != nil && f.Value.String() != ""
+}
+
+// snapshotOptions returns the storage options for the snapshots package, for
+// content of the given type.
+func (c *snapConfig) snapshotOptions(contentType string) snapshots.Options {
+	opts := snapshots.Options{
+		Variant:                strings.Join(c.variants, "."),
+		Suite:                  c.suite,
+		ContentType:            contentType,
+		Applied:                c.applied,
+		NormalizeLineEndings:   c.normalizeEOL,
+		TrimTrailingWhitespace: c.trimWhitespace,
+		DetectFlakes:           c.detectFlakes,
+		RecordManifest:         c.recordManifest && !testsFiltered() && !shortRun(),
+		ExternalAbove:          c.externalAbove,
+		StaleAfter:             c.staleAfter,
+		StaleVersions:          c.staleVersions,
+		Update:                 c.update,
+		Hermetic:               c.readOnly,
+		Tolerance:              c.tolerance,
+		TolerancePaths:         c.tolerancePaths,
+		AnyKeyOrder:            c.anyKeyOrder,
+		RewriteKeyOrder:        c.rewriteKeyOrder,
+		Raw:                    c.raw,
+	}
+	if c.comparator != nil {
+		opts.Equal = c.comparator.Equal
+	}
+	if fuzzing() {
+		opts.ReadOnly = !c.fuzzWrites
+		opts.FuzzInput = c.fuzzWrites
+		opts.SkipGeneratedInputs = !c.fuzzWrites
+	}
+	return opts
+}
+
+// produce renders snapshot content. When determinism checking is enabled the
+// content is rendered a second time and both results must match. Rendering
+// must finish before the deadline of t, if it has one.
+func (c *snapConfig) produce(t T, render func() (string, error)) (string, error) {
+	site := snapshots.CallSite()
+	guarded := func() (string, error) {
+		return snapshots.WithinDeadline(t, "formatting", func() (string, error) {
+			return recovering(site, render)
+		})
+	}
+	content, err := guarded()
+	if err != nil || !c.checkDeterminism {
+		return content, err
+	}
+
+	again, err := guarded()
+	if err != nil {
+		return "", err
+	}
+	if again != content {
+		return "", nondeterminismError(content, again)
+	}
+	return content, nil
+}
+
+// recovering calls fn, turning a panic while formatting or scrubbing, such as
+// in a ScrubWith function, into an error with the panic value and site, the
+// call site of the snapshot. Nothing has been written at that point, so the
+// test fails without leaving a pending snapshot behind.
+func recovering[V any](site string, fn func() (V, error)) (v V, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic while formatting or scrubbing the snapshot taken at %s: %v", site, r)
+		}
+	}()
+	return fn()
+}
+
+// nondeterminismError describes the first line at which two renders differ.
+func nondeterminismError(first, second string) error {
+	firstLines := strings.Split(first, "\n")
+	secondLines := strings.Split(second, "\n")
+	for i := 0; i < len(firstLines) || i < len(secondLines); i++ {
+		var a, b string
+		if i < len(firstLines) {
+			a = firstLines[i]
+		}
+		if i < len(secondLines) {
+			b = secondLines[i]
+		}
+		if a != b {
+			return fmt.Errorf("snapshot content is nondeterministic: line %d differs between renders: %q != %q", i+1, a, b)
+		}
+	}
+	return fmt.Errorf("snapshot content is nondeterministic")
+}
+
+// determinismSetting enables or disables determinism checking.
+type determinismSetting struct {
+	enabled bool
+}
+
+func (d *determinismSetting) isOption() {}
+
+func (d *determinismSetting) apply(cfg *snapConfig) {
+	cfg.checkDeterminism = d.enabled
+}
+
+// CheckDeterminism renders the snapshot content twice and fails with a
+// "snapshot content is nondeterministic" error if the two renders differ,
+// before any snapshot file is written. This catches unsorted output,
+// timestamps, and randomness that would otherwise produce flaky snapshots.
+//
+// Determinism checking can also be enabled for every snapshot by setting
+// SHUTTER_CHECK_DETERMINISM=1.
+//
+// Example:
+//
+//	shutter.Snap(t, "report", report, shutter.CheckDeterminism())
+func CheckDeterminism() Option {
+	return &determinismSetting{enabled: true}
+}
+
+// variantSetting adds a variant name to the snapshot.
+type variantSetting struct {
+	name string
+}
+
+func (v *variantSetting) isOption() {}
+
+func (v *variantSetting) apply(cfg *snapConfig) {
+	if v.name != "" {
+		cfg.variants = append(cfg.variants, v.name)
+	}
+}
+
+// Variant keeps a separate accepted snapshot per variant name, for output
+// that legitimately differs between environments such as operating systems,
+// architectures, or Go versions. The variant is inserted before the file
+// extension (admin_case~linux.snap) and shown during review.
+//
+// Multiple Variant options are combined in order, so
+// Variant(runtime.GOOS), Variant(runtime.GOARCH) stores
+// admin_case~linux.amd64.snap.
+//
+// Example:
+//
+//	shutter.Snap(t, "paths", paths, shutter.Variant(runtime.GOOS))
+func Variant(name string) Option {
+	return &variantSetting{name: name}
+}
+
+// Key keeps a separate accepted snapshot per key, for a test that runs
+// against several backends or feature flags and legitimately produces
+// different output for each. It stores the snapshot the same way as
+// Variant (admin_case~postgres-15.snap), and review presents the keys of
+// one title one after another.
+//
+// Example:
+//
+//	for _, db := range []string{"postgres-15", "sqlite"} {
+//	    shutter.Snap(t, "schema", dump(db), shutter.Key(db))
+//	}
+func Key(name string) Option {
+	return &variantSetting{name: name}
+}
+
+// fuzzWritesSetting enables writing snapshots while fuzzing.
+type fuzzWritesSetting struct{}
+
+func (f *fuzzWritesSetting) isOption() {}
+
+func (f *fuzzWritesSetting) apply(cfg *snapConfig) {
+	cfg.fuzzWrites = true
+}
+
+// FuzzWrites records snapshots taken while the fuzzing engine is running
+// (go test -fuzz). By default only the seed corpus is compared against
+// accepted snapshots and nothing is written; snapshots of generated inputs
+// are skipped, since comparing them against a seed's snapshot would report
+// every new output as a failing input. With FuzzWrites each distinct output
+// is stored once, as fuzz/<title>/<content hash> under the fuzz target.
+//
+// Snapshots taken while running the seed corpus with plain go test are
+// unaffected. FuzzWrites can also be enabled with SHUTTER_FUZZ_WRITES=1.
+//
+// Example:
+//
+//	f.Fuzz(func(t *testing.T, input string) {
+//	    shutter.SnapString(t, "parsed", parse(input), shutter.FuzzWrites())
+//	})
+func FuzzWrites() Option {
+	return &fuzzWritesSetting{}
+}
+
+// unexportedSetting shows or hides unexported struct fields.
+type unexportedSetting struct {
+	include bool
+}
+
+func (u *unexportedSetting) isOption() {}
+
+func (u *unexportedSetting) apply(cfg *snapConfig) {
+	cfg.excludeUnexported = !u.include
+}
+
+// ExcludeUnexported leaves unexported struct fields out of the values
+// formatted by Snap, SnapMany and SnapEach, so the internals of a type, such
+// as mutexes and caches, do not need scrubbing. Exported fields are shown
+// as usual. Other snapshot functions ignore this option.
+//
+// Pass it to Defaults to hide unexported fields for a whole package.
+//
+// Example:
+//
+//	shutter.Snap(t, "client", client, shutter.ExcludeUnexported())
+func ExcludeUnexported() Option {
+	return &unexportedSetting{include: false}
+}
+
+// IncludeUnexported shows unexported struct fields in formatted values,
+// which is the default. It overrides an ExcludeUnexported given earlier,
+// such as one passed to Defaults.
+//
+// Example:
+//
+//	shutter.Snap(t, "cache internals", cache, shutter.IncludeUnexported())
+func IncludeUnexported() Option {
+	return &unexportedSetting{include: true}
+}
+
+// normalizeTimesOption shows times in a single zone.
+type normalizeTimesOption struct {
+	loc *time.Location
+}
+
+func (n *normalizeTimesOption) isOption() {}
+
+func (n *normalizeTimesOption) apply(cfg *snapConfig) {
+	cfg.timeZone = n.loc
+	cfg.applied = append(cfg.applied, n.String())
+}
+
+func (n *normalizeTimesOption) Scrub(content string) string {
+	return transform.NormalizeTimestamps(content, n.loc)
+}
+
+func (n *normalizeTimesOption) String() string {
+	return fmt.Sprintf("NormalizeTimes(%s)", quoteArg(n.loc.String()))
+}
+
+// NormalizeTimes shows times in the zone loc, for suites run on machines
+// with different local time zones that keep real timestamps rather than
+// scrubbing them. time.Time values formatted by Snap, SnapMany and SnapEach
+// are shown as RFC 3339 timestamps in loc instead of their internal fields,
+// and RFC 3339 timestamps with a zone in any snapshot, such as
+// 2024-03-01T09:30:00+01:00, are rewritten into loc with their precision
+// kept. Timestamps without a zone are left as is.
+//
+// Pass it before scrubbers such as ScrubTimestamp, which would otherwise
+// replace the timestamps first.
+//
+// Example:
+//
+//	shutter.Snap(t, "event", event, shutter.NormalizeTimes(time.UTC))
+func NormalizeTimes(loc *time.Location) Option {
+	if loc == nil {
+		loc = time.UTC
+	}
+	return &normalizeTimesOption{loc: loc}
+}
+
+// toleranceSetting lets numbers differ from the accepted snapshot.
+type toleranceSetting struct {
+	delta float64
+	paths []string
+}
+
+func (s *toleranceSetting) isOption() {}
+
+func (s *toleranceSetting) apply(cfg *snapConfig) {
+	cfg.tolerance = s.delta
+	cfg.tolerancePaths = s.paths
+}
+
+// Tolerance lets a snapshot match its accepted version when they differ
+// only by numbers, each by at most delta, as for results of floating-point
+// computation that vary in the last digits between platforms. Everything
+// other than the numbers must be equal. A snapshot that matches within
+// tolerance is not rewritten, so the accepted numbers are kept.
+//
+// For SnapJSON, paths limit the tolerance to the numbers at the given
+// dotted paths from the document root, such as "metrics.score"; array
+// indexes are not part of a path. Other snapshot functions ignore
+// Tolerance when paths are given.
+//
+// Example:
+//
+//	shutter.Snap(t, "regression", model.Coefficients(), shutter.Tolerance(1e-9))
+//	shutter.SnapJSON(t, "metrics", body, shutter.Tolerance(1e-6, "metrics.score"))
+func Tolerance(delta float64, paths ...string) Option {
+	return &toleranceSetting{delta: delta, paths: paths}
+}
+
+// keyOrderSetting keeps JSON object keys in their original order.
+type keyOrderSetting struct{}
+
+func (k *keyOrderSetting) isOption() {}
+
+func (k *keyOrderSetting) apply(cfg *snapConfig) {
+	cfg.preserveKeyOrder = true
+}
+
+// PreserveKeyOrder makes SnapJSON keep object keys in the order they appear
+// in the input, such as the field order of an API response, instead of
+// sorting them.
+//
+// Sorted keys are the default because they do not change when the producer
+// reorders fields. Other snapshot functions ignore this option.
+//
+// Example:
+//
+//	shutter.SnapJSON(t, "response", body, shutter.PreserveKeyOrder())
+func PreserveKeyOrder() Option {
+	return &keyOrderSetting{}
+}
+
+// anyKeyOrderSetting compares JSON snapshots regardless of key order.
+type anyKeyOrderSetting struct {
+	rewrite bool
+}
+
+func (a *anyKeyOrderSetting) isOption() {}
+
+func (a *anyKeyOrderSetting) apply(cfg *snapConfig) {
+	cfg.anyKeyOrder = true
+	cfg.rewriteKeyOrder = cfg.rewriteKeyOrder || a.rewrite
+}
+
+// AnyKeyOrder lets a snapshot match its accepted version when both are JSON
+// documents holding the same values, even if their object keys are in a
+// different order, such as snapshots accepted before PreserveKeyOrder was
+// added or before the producer reordered its fields. Arrays must still hold
+// their elements in the same order. A snapshot that matches this way is not
+// rewritten, so the accepted order is kept; see RewriteKeyOrder.
+// SHUTTER_ANY_KEY_ORDER=1 enables it for every snapshot.
+//
+// Example:
+//
+//	shutter.SnapJSON(t, "response", body, shutter.PreserveKeyOrder(), shutter.AnyKeyOrder())
+func AnyKeyOrder() Option {
+	return &anyKeyOrderSetting{}
+}
+
+// RewriteKeyOrder is like AnyKeyOrder, but rewrites an accepted snapshot
+// that matches only in a different key order with the new content, so the
+// snapshot file follows the current order from then on. Use it once to
+// migrate snapshots after an ordering change: SHUTTER_REWRITE_KEY_ORDER=1
+// rewrites every snapshot in a test run.
+func RewriteKeyOrder() Option {
+	return &anyKeyOrderSetting{rewrite: true}
+}
+
+// jsoncSetting accepts comments and trailing commas in JSON input.
+type jsoncSetting struct{}
+
+func (j *jsoncSetting) isOption() {}
+
+func (j *jsoncSetting) apply(cfg *snapConfig) {
+	cfg.allowJSONC = true
+}
+
+// AllowJSONC makes SnapJSON accept JSONC input, as used by many config files:
+// line (//) and block (/* */) comments and trailing commas are stripped
+// before the JSON is formatted. Other JSON5 extensions, such as single-quoted
+// strings or unquoted keys, are still rejected. Other snapshot functions
+// ignore this option.
+//
+// Example:
+//
+//	shutter.SnapJSON(t, "settings", string(settingsJSONC), shutter.AllowJSONC())
+func AllowJSONC() Option {
+	return &jsoncSetting{}
+}
+
+// lineEndingSetting converts CRLF line endings to LF.
+type lineEndingSetting struct{}
+
+func (l *lineEndingSetting) isOption() {}
+
+func (l *lineEndingSetting) apply(cfg *snapConfig) {
+	cfg.normalizeEOL = true
+}
+
+// NormalizeLineEndings converts CRLF line endings to LF before the snapshot
+// is compared and saved, so output produced on Windows matches snapshots
+// accepted on Linux. Accepted snapshots are normalized the same way when
+// they are read, so enabling it does not change existing snapshots.
+//
+// Line ending normalization can also be enabled for every snapshot by
+// setting SHUTTER_NORMALIZE_LINE_ENDINGS=1.
+//
+// Example:
+//
+//	shutter.SnapString(t, "output", out, shutter.NormalizeLineEndings())
+func NormalizeLineEndings() Option {
+	return &lineEndingSetting{}
+}
+
+// trailingWhitespaceSetting strips trailing whitespace from each line.
+type trailingWhitespaceSetting struct{}
+
+func (w *trailingWhitespaceSetting) isOption() {}
+
+func (w *trailingWhitespaceSetting) apply(cfg *snapConfig) {
+	cfg.trimWhitespace = true
+}
+
+// TrimTrailingWhitespace removes spaces and tabs from the end of every line
+// before the snapshot is compared and saved. Like NormalizeLineEndings it is
+// also applied to accepted snapshots when they are read.
+//
+// Trimming can also be enabled for every snapshot by setting
+// SHUTTER_TRIM_TRAILING_WHITESPACE=1.
+//
+// Example:
+//
+//	shutter.SnapString(t, "table", rendered, shutter.TrimTrailingWhitespace())
+func TrimTrailingWhitespace() Option {
+	return &trailingWhitespaceSetting{}
+}
+
+// externalContentSetting sets the size above which content is stored
+// externally.
+type externalContentSetting struct {
+	above int
+}
+
+func (e *externalContentSetting) isOption() {}
+
+func (e *externalContentSetting) apply(cfg *snapConfig) {
+	cfg.externalAbove = e.above
+}
+
+// ExternalContentAbove stores the content of snapshots larger than n bytes
+// in a sibling .content file, leaving only the header and a digest of the
+// content in the .snap file. A changed snapshot then shows up in git diff as
+// a one-line digest change rather than a wall of content. Review and
+// comparison load the content transparently.
+//
+// Mark the content files binary, or track them with Git LFS, in
+// .gitattributes:
+//
+//	*.snap.content binary
+//
+// The threshold can also be set for every snapshot with
+// SHUTTER_EXTERNAL_CONTENT_ABOVE=<bytes>.
+//
+// Example:
+//
+//	shutter.SnapString(t, "rendered page", html, shutter.ExternalContentAbove(64<<10))
+func ExternalContentAbove(n int) Option {
+	return &externalContentSetting{above: n}
+}
+
+// placeholderSetting sets the style of built-in placeholders.
+type placeholderSetting struct {
+	style PlaceholderStyle
+}
+
+func (p *placeholderSetting) isOption() {}
+
+func (p *placeholderSetting) apply(cfg *snapConfig) {
+	cfg.placeholders.style = p.style
+}
+
+// Placeholders writes the placeholders of built-in scrubbers in style, for
+// reviewers who require a specific masking format: PlaceholderAngle gives
+// "<EMAIL>", PlaceholderAsterisks gives "***", and PlaceholderKeepLength
+// replaces each character of the value with "*", so column alignment and
+// length-dependent formatting survive scrubbing. A replacement passed to the
+// scrubber itself, as in ScrubEmail("[email]"), is kept as given.
+//
+// The style can also be set for every snapshot with
+// SHUTTER_PLACEHOLDER_STYLE=angle, asterisks or keep-length.
+//
+// Example:
+//
+//	shutter.Snap(t, "user", user,
+//	    shutter.ScrubEmail(),
+//	    shutter.ScrubUUID(),
+//	    shutter.Placeholders(shutter.PlaceholderAsterisks),
+//	)
+func Placeholders(style PlaceholderStyle) Option {
+	return &placeholderSetting{style: style}
+}
+
+// revealSetting sets how many trailing characters masking leaves visible.
+type revealSetting struct {
+	n int
+}
+
+func (r *revealSetting) isOption() {}
+
+func (r *revealSetting) apply(cfg *snapConfig) {
+	cfg.placeholders.revealLast = r.n
+}
+
+// RevealLast leaves the last n characters of each value masked with
+// PlaceholderKeepLength visible, as in sk_live_****************1234, so
+// reviewers can tell values apart. Non-secret prefixes such as the sk_live_
+// of an API key are always kept. At most half of a value is revealed.
+//
+// It can also be set for every snapshot with SHUTTER_REVEAL_LAST=<n>.
+//
+// Example:
+//
+//	shutter.SnapString(t, "config", config,
+//	    shutter.ScrubAPIKey(),
+//	    shutter.Placeholders(shutter.PlaceholderKeepLength),
+//	    shutter.RevealLast(4),
+//	)
+func RevealLast(n int) Option {
+	return &revealSetting{n: n}
+}
+
+// staleAfterSetting sets the age after which a baseline is stale.
+type staleAfterSetting struct {
+	age time.Duration
+}
+
+func (s *staleAfterSetting) isOption() {}
+
+func (s *staleAfterSetting) apply(cfg *snapConfig) {
+	cfg.staleAfter = s.age
+}
+
+// StaleAfter warns about accepted snapshots last accepted longer ago than
+// age, so that ancient baselines get re-validated rather than trusted
+// forever. A snapshot that still matches is reported with t.Log; one that
+// changed is marked as a stale baseline in review. Either way, a baseline
+// taken with other scrubbers or ignore patterns than the test now uses is
+// reported as well.
+//
+// It can also be set for every snapshot with SHUTTER_STALE_AFTER, e.g.
+// SHUTTER_STALE_AFTER=365d.
+//
+// Example:
+//
+//	shutter.SnapJSON(t, "config", config, shutter.StaleAfter(365*24*time.Hour))
+func StaleAfter(age time.Duration) Option {
+	return &staleAfterSetting{age: age}
+}
+
+// staleVersionsSetting sets how many format versions behind a baseline is
+// stale.
+type staleVersionsSetting struct {
+	n int
+}
+
+func (s *staleVersionsSetting) isOption() {}
+
+func (s *staleVersionsSetting) apply(cfg *snapConfig) {
+	cfg.staleVersions = s.n
+}
+
+// StaleVersions warns, like StaleAfter, about accepted snapshots recorded
+// with a snapshot format version n or more minor versions behind the one
+// this version of shutter writes, or with a different major version.
+//
+// It can also be set for every snapshot with SHUTTER_STALE_VERSIONS=<n>.
+func StaleVersions(n int) Option {
+	return &staleVersionsSetting{n: n}
+}
+
+// keepRawSetting keeps the content before scrubbing for review.
+type keepRawSetting struct{}
+
+func (k *keepRawSetting) isOption() {}
+
+func (k *keepRawSetting) apply(cfg *snapConfig) {
+	cfg.keepRaw = true
+}
+
+// KeepRaw stores the content as it was before scrubbers and ignore patterns
+// were applied next to a pending snapshot, so the v key of the review TUI
+// can show it, to check that scrubbing isn't hiding a real regression. The
+// raw content is deleted when the snapshot is accepted or rejected, but it
+// may hold the very data scrubbers remove: keep pending snapshots out of
+// version control.
+//
+// It can also be enabled for every snapshot with SHUTTER_KEEP_RAW=1.
+//
+// Example:
+//
+//	shutter.SnapJSON(t, "session", body, shutter.ScrubJWT(), shutter.KeepRaw())
+func KeepRaw() Option {
+	return &keepRawSetting{}
+}
+
+// readOnlySetting forbids writing snapshot files.
+type readOnlySetting struct{}
+
+func (r *readOnlySetting) isOption() {}
+
+func (r *readOnlySetting) apply(cfg *snapConfig) {
+	cfg.readOnly = true
+}
+
+// ReadOnly compares against accepted snapshots without creating or modifying
+// any file, for running tests where the source tree is read-only, such as in
+// hermetic or sandboxed build systems. A new or mismatching snapshot fails
+// the test with its diff, saying that nothing was written, instead of
+// leaving a pending snapshot for review. It overrides SHUTTER_UPDATE, and
+// manifests and flake records are not written either.
+//
+// It is usually enabled for the whole run with SHUTTER_READ_ONLY=1, set by
+// the build system.
+func ReadOnly() Option {
+	return &readOnlySetting{}
+}
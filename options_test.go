@@ -0,0 +1,332 @@
+package shutter_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ptdewey/shutter"
+)
+
+// recordingT wraps a *testing.T and records errors instead of failing, so
+// tests can assert on the errors shutter reports.
+type recordingT struct {
+	*testing.T
+	errors []string
+}
+
+func (r *recordingT) Error(args ...any) {
+	r.errors = append(r.errors, fmt.Sprint(args...))
+}
+
+func TestCheckDeterminism(t *testing.T) {
+	calls := 0
+	counter := shutter.ScrubWith(func(content string) string {
+		calls++
+		return content + strconv.Itoa(calls)
+	})
+
+	rt := &recordingT{T: t}
+	shutter.SnapString(rt, "Nondeterministic Content", "value", counter, shutter.CheckDeterminism())
+
+	if len(rt.errors) != 1 || !strings.Contains(rt.errors[0], "snapshot content is nondeterministic") {
+		t.Fatalf("expected nondeterminism error, got %v", rt.errors)
+	}
+	if calls != 2 {
+		t.Errorf("expected content to be rendered twice, got %d renders", calls)
+	}
+
+	dir := filepath.Join("__snapshots__", t.Name())
+	if _, err := os.Stat(dir); !os.IsNotExist(err) {
+		t.Errorf("expected no snapshot to be written, found %s", dir)
+	}
+}
+
+func TestCheckDeterminismStable(t *testing.T) {
+	shutter.Snap(t, "Deterministic Content", map[string]int{"b": 2, "a": 1, "c": 3},
+		shutter.CheckDeterminism(),
+	)
+}
+
+func TestPanickingScrubber(t *testing.T) {
+	panicking := shutter.ScrubWith(func(string) string {
+		panic("scrubber exploded")
+	})
+
+	rt := &recordingT{T: t}
+	shutter.SnapString(rt, "Panicking Scrubber", "value", panicking)
+
+	if len(rt.errors) != 1 {
+		t.Fatalf("expected one error, got %v", rt.errors)
+	}
+	for _, want := range []string{`"Panicking Scrubber"`, "scrubber exploded", "options_test.go:"} {
+		if !strings.Contains(rt.errors[0], want) {
+			t.Errorf("expected error to mention %s, got %q", want, rt.errors[0])
+		}
+	}
+
+	dir := filepath.Join("__snapshots__", t.Name())
+	if _, err := os.Stat(dir); !os.IsNotExist(err) {
+		t.Errorf("expected no snapshot to be written, found %s", dir)
+	}
+}
+
+// deadlineT is a recordingT whose test times out at deadline.
+type deadlineT struct {
+	recordingT
+	deadline time.Time
+}
+
+func (d *deadlineT) Deadline() (time.Time, bool) {
+	return d.deadline, true
+}
+
+func TestSnapshotDeadline(t *testing.T) {
+	release := make(chan struct{})
+	t.Cleanup(func() { close(release) })
+	stuck := shutter.ScrubWith(func(content string) string {
+		<-release
+		return content
+	})
+
+	dt := &deadlineT{recordingT: recordingT{T: t}, deadline: time.Now().Add(100 * time.Millisecond)}
+	shutter.SnapString(dt, "Huge Value", "value", stuck)
+
+	if len(dt.errors) != 1 {
+		t.Fatalf("expected one error, got %v", dt.errors)
+	}
+	for _, want := range []string{`"Huge Value"`, "snapshot too large to process before deadline", "formatting"} {
+		if !strings.Contains(dt.errors[0], want) {
+			t.Errorf("expected error to mention %s, got %q", want, dt.errors[0])
+		}
+	}
+
+	dir := filepath.Join("__snapshots__", t.Name())
+	if _, err := os.Stat(dir); !os.IsNotExist(err) {
+		t.Errorf("expected no snapshot to be written, found %s", dir)
+	}
+}
+
+func TestVariant(t *testing.T) {
+	shutter.Snap(t, "Variant Content", "example output", shutter.Variant("example"))
+}
+
+func TestNormalizeWhitespace(t *testing.T) {
+	shutter.SnapString(t, "Windows Output", "name  \r\nvalue\t\r\n",
+		shutter.NormalizeLineEndings(),
+		shutter.TrimTrailingWhitespace(),
+	)
+}
+
+func TestNormalizeWhitespaceFromEnvironment(t *testing.T) {
+	t.Setenv("SHUTTER_NORMALIZE_LINE_ENDINGS", "1")
+	t.Setenv("SHUTTER_TRIM_TRAILING_WHITESPACE", "true")
+	shutter.SnapString(t, "Windows Output", "name  \r\nvalue\t\r\n")
+}
+
+func TestKey(t *testing.T) {
+	for _, backend := range []string{"postgres-15", "sqlite"} {
+		shutter.Snap(t, "Backend Schema", "schema for "+backend, shutter.Key(backend))
+	}
+}
+
+func TestDetectFlakes(t *testing.T) {
+	tempProject(t)
+	t.Setenv("SHUTTER_DETECT_FLAKES", "3")
+
+	for _, content := range []string{"a, b", "b, a"} {
+		rt := &recordingT{T: t}
+		shutter.SnapString(rt, "Unordered", content)
+	}
+
+	data, err := os.ReadFile(filepath.Join("__snapshots__", t.Name(), "unordered.snap.flakes"))
+	if err != nil {
+		t.Fatalf("expected the runs to be recorded: %v", err)
+	}
+	if lines := strings.Count(string(data), "\n"); lines != 2 {
+		t.Errorf("expected 2 recorded runs, got %d:\n%s", lines, data)
+	}
+}
+
+func TestRecordManifest(t *testing.T) {
+	tempProject(t)
+	t.Setenv("SHUTTER_MANIFEST", "1")
+
+	for _, title := range []string{"First", "Second"} {
+		rt := &recordingT{T: t}
+		shutter.SnapString(rt, title, "content")
+	}
+
+	data, err := os.ReadFile(filepath.Join("__snapshots__", ".manifest"))
+	if err != nil {
+		t.Fatalf("expected a manifest: %v", err)
+	}
+	if want := "TestRecordManifest/first\nTestRecordManifest/second\n"; string(data) != want {
+		t.Errorf("manifest = %q, want %q", data, want)
+	}
+}
+
+func TestReadOnly(t *testing.T) {
+	tempProject(t)
+	t.Setenv("SHUTTER_READ_ONLY", "1")
+
+	rt := &recordingT{T: t}
+	shutter.SnapString(rt, "Unrecorded", "content")
+	if len(rt.errors) != 1 || !strings.Contains(rt.errors[0], "snapshots are read-only") {
+		t.Errorf("expected a read-only error, got %v", rt.errors)
+	}
+	if _, err := os.Stat("__snapshots__"); !os.IsNotExist(err) {
+		t.Errorf("expected nothing to be written, got %v", err)
+	}
+}
+
+func TestAppliedOptionsRecorded(t *testing.T) {
+	tempProject(t)
+
+	rt := &recordingT{T: t}
+	shutter.SnapJSON(rt, "Applied Options", `{"id": 1, "email": "jane@example.com"}`,
+		shutter.ScrubEmail(),
+		shutter.ScrubRegex(`user-\d+`, "<USER>"),
+		shutter.ScrubExact("hunter2", "<PASSWORD>"),
+		shutter.IgnoreKey("id"),
+		shutter.Variant("example"),
+	)
+
+	pending := filepath.Join("__snapshots__", t.Name(), "applied_options~example.snap.new")
+	data, err := os.ReadFile(pending)
+	if err != nil {
+		t.Fatalf("expected a pending snapshot: %v", err)
+	}
+	want := "option: ScrubEmail()\n" +
+		"option: ScrubRegex(`user-\\d+`, \"<USER>\")\n" +
+		"option: ScrubExact(…, \"<PASSWORD>\")\n" +
+		"option: IgnoreKey(\"id\")\n"
+	if !strings.Contains(string(data), want) {
+		t.Errorf("expected header to record options %q, got:\n%s", want, data)
+	}
+}
+
+func TestExternalContentAbove(t *testing.T) {
+	shutter.SnapString(t, "Small Report", "fits inline\n", shutter.ExternalContentAbove(64))
+	shutter.SnapString(t, "Large Report", strings.Repeat("row of generated output\n", 8), shutter.ExternalContentAbove(64))
+
+	dir := filepath.Join("__snapshots__", t.Name())
+	if _, err := os.Stat(filepath.Join(dir, "small_report.snap.content")); !os.IsNotExist(err) {
+		t.Errorf("expected small snapshot to be stored inline, got err %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "large_report.snap.content")); err != nil {
+		t.Errorf("expected large snapshot content in a sibling file: %v", err)
+	}
+}
+
+type cachedClient struct {
+	Name     string
+	Endpoint string
+	hits     int
+	cache    map[string]string
+}
+
+func TestExcludeUnexported(t *testing.T) {
+	client := &cachedClient{Name: "billing", Endpoint: "https://billing.internal", hits: 3, cache: map[string]string{"token": "abc"}}
+	shutter.Snap(t, "excluded", client, shutter.ExcludeUnexported())
+	shutter.Snap(t, "included again", client, shutter.ExcludeUnexported(), shutter.IncludeUnexported())
+}
+
+type scheduledJob struct {
+	Name    string
+	NextRun time.Time
+	Log     string
+}
+
+func TestNormalizeTimes(t *testing.T) {
+	tokyo := time.FixedZone("JST", 9*60*60)
+	job := scheduledJob{
+		Name:    "backup",
+		NextRun: time.Date(2024, 3, 1, 9, 30, 0, 0, tokyo),
+		Log:     "last run 2024-02-29T09:30:00.250+09:00",
+	}
+	shutter.Snap(t, "job", job, shutter.NormalizeTimes(time.UTC))
+	shutter.SnapJSON(t, "json", `{"next_run": "2024-03-01T09:30:00+09:00"}`, shutter.NormalizeTimes(time.UTC))
+}
+
+// TestTolerance compares against snapshots accepted with 0.3 as the score.
+func TestTolerance(t *testing.T) {
+	shutter.Snap(t, "sums", []float64{0.1 + 0.2, 1.5}, shutter.Tolerance(1e-9))
+	shutter.SnapJSON(t, "metrics", `{"score": 0.3000001, "count": 2}`, shutter.Tolerance(1e-6, "score"))
+}
+
+// TestRegexRegions compares against a snapshot whose build date was replaced
+// by a <<regex>> region after it was accepted, leaving its digest behind.
+func TestRegexRegions(t *testing.T) {
+	shutter.SnapString(t, "build info", "version: 1.4.2\nbuilt: "+time.Now().Format("2006-01-02")+"\n")
+}
+
+// TestCompareWith compares against a snapshot accepted with the hosts in
+// alphabetical order.
+func TestCompareWith(t *testing.T) {
+	sameLines := shutter.ComparatorFunc(func(accepted, actual string) bool {
+		a, b := strings.Split(accepted, "\n"), strings.Split(actual, "\n")
+		slices.Sort(a)
+		slices.Sort(b)
+		return slices.Equal(a, b)
+	})
+	shutter.SnapString(t, "hosts", "gamma\nalpha\nbeta", shutter.CompareWith(sameLines))
+}
+
+var billing = shutter.Suite("billing-api", shutter.ScrubUUID(), shutter.IgnoreKey("created_at"))
+
+func TestSuite(t *testing.T) {
+	invoice := map[string]any{
+		"id":     "3f2a9c1e-8b4d-4e6f-a1c2-7d9e0b5f3a48",
+		"amount": 1250,
+	}
+	billing.Snap(t, "invoice", invoice)
+	billing.SnapJSON(t, "invoice json", `{
+		"id": "3f2a9c1e-8b4d-4e6f-a1c2-7d9e0b5f3a48",
+		"amount": 1250,
+		"created_at": "2024-03-01T09:30:00Z"
+	}`)
+}
+
+type listResponse struct {
+	Status int
+	Items  []struct {
+		ID   string `json:"id"`
+		Name string `json:"name"`
+	} `json:"items"`
+}
+
+func TestSelect(t *testing.T) {
+	var resp listResponse
+	if err := json.Unmarshal([]byte(`{"items": [{"id": "a1", "name": "Ann"}, {"id": "b2", "name": "Bob"}]}`), &resp); err != nil {
+		t.Fatal(err)
+	}
+	ids := shutter.Select(func(r listResponse) any {
+		var ids []string
+		for _, item := range r.Items {
+			ids = append(ids, item.ID)
+		}
+		return ids
+	})
+	shutter.Snap(t, "item ids", resp, ids)
+	shutter.SnapJSONValue(t, "item ids json", resp, ids)
+	shutter.SnapJSON(t, "item ids by path", `{
+		"items": [{"id": "a1", "name": "Ann"}, {"id": "b2", "name": "Bob"}],
+		"next_page": "cGFnZT0y"
+	}`, shutter.SelectPaths("items.id"))
+
+	rt := &recordingT{T: t}
+	shutter.Snap(rt, "wrong type", &resp, ids)
+	shutter.SnapJSON(rt, "missing path", `{"items": [{"ID": "a1"}]}`, shutter.SelectPaths("items.id"))
+	if len(rt.errors) != 2 ||
+		!strings.Contains(rt.errors[0], "Select: value is *shutter_test.listResponse, not shutter_test.listResponse") ||
+		!strings.Contains(rt.errors[1], `nothing to select at "items.id"`) {
+		t.Errorf("expected type and path errors, got %v", rt.errors)
+	}
+}
@@ -0,0 +1,45 @@
+// Package pretty renders shutter's colored diffs outside of snapshot tests,
+// so that regular assertions can show the same diffs as snapshots while a
+// test suite moves to snapshots incrementally.
+//
+// Example:
+//
+//	if diff := pretty.RenderUnified(want, got, pretty.Options{OldLabel: "want", NewLabel: "got"}); diff != "" {
+//	    t.Errorf("Render() mismatch:\n%s", diff)
+//	}
+package pretty
+
+import internal "github.com/ptdewey/shutter/internal/pretty"
+
+// Options configures RenderUnified.
+type Options struct {
+	// OldLabel and NewLabel name the two sides above the diff, such as
+	// "want" and "got". No header is written if both are empty.
+	OldLabel string
+	NewLabel string
+
+	// Context, if positive, is the number of unchanged lines shown around
+	// each change. Other unchanged lines are collapsed. By default every
+	// line is shown.
+	Context int
+
+	// JSON diffs a and b as indented JSON documents, ignoring the commas
+	// that separate members, and highlights them as JSON.
+	JSON bool
+
+	// Width is the width of the rendering. By default it is $COLUMNS, or
+	// 80.
+	Width int
+
+	// NoColor renders without ANSI colors. Colors are also left out when
+	// NO_COLOR is set, and SHUTTER_ACCESSIBLE=1 labels each line instead.
+	NoColor bool
+}
+
+// RenderUnified renders the line diff from a to b as shutter renders
+// snapshot diffs, or returns "" if a and b are equal. It suits the output
+// of cmp.Diff's inputs, or any two strings: pass values through fmt or
+// json.MarshalIndent first.
+func RenderUnified(a, b string, opts Options) string {
+	return internal.RenderUnified(a, b, internal.UnifiedOptions(opts))
+}
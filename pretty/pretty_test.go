@@ -0,0 +1,26 @@
+package pretty_test
+
+import (
+	"testing"
+
+	"github.com/ptdewey/shutter"
+	"github.com/ptdewey/shutter/pretty"
+)
+
+func TestRenderUnified(t *testing.T) {
+	want := "name: alice\nrole: admin\nteam: core\n"
+	got := "name: alice\nrole: viewer\nteam: core\n"
+
+	shutter.SnapString(t, "Unified Diff", pretty.RenderUnified(want, got, pretty.Options{
+		OldLabel: "want",
+		NewLabel: "got",
+		Width:    60,
+		NoColor:  true,
+	}))
+}
+
+func TestRenderUnifiedEqual(t *testing.T) {
+	if diff := pretty.RenderUnified("same", "same", pretty.Options{}); diff != "" {
+		t.Errorf("expected no diff for equal strings, got:\n%s", diff)
+	}
+}
@@ -9,7 +9,7 @@ if [[ "${1:-}" == "--dry-run" ]]; then
     DRY_RUN=true
 fi
 
-# Get the latest root module tag (ignore cmd/shutter/ prefixed tags)
+# Get the latest root module tag (ignore cmd/shutter/, analyzer/ and shuttergrpc/ prefixed tags)
 LATEST_TAG=$(jj tag list | grep -E '^v[0-9]' | sort -V -t: -k1,1 | tail -1 | awk '{print $1}' | tr -d ':')
 
 if [[ -z "$LATEST_TAG" ]]; then
@@ -66,7 +66,7 @@ NEW_VERSION="v${MAJOR}.${MINOR}.${PATCH}"
 
 echo "Bump type: $BUMP"
 echo "New version: $NEW_VERSION"
-echo "Tags: $NEW_VERSION, cmd/shutter/$NEW_VERSION"
+echo "Tags: $NEW_VERSION, cmd/shutter/$NEW_VERSION, analyzer/$NEW_VERSION, shuttergrpc/$NEW_VERSION"
 
 if $DRY_RUN; then
     echo ""
@@ -79,11 +79,11 @@ read -p "Create tags and push? [y/N] " -n 1 -r
 echo ""
 
 if [[ $REPLY =~ ^[Yy]$ ]]; then
-    jj tag set "$NEW_VERSION" "cmd/shutter/$NEW_VERSION"
+    jj tag set "$NEW_VERSION" "cmd/shutter/$NEW_VERSION" "analyzer/$NEW_VERSION" "shuttergrpc/$NEW_VERSION"
     # jj git push doesn't support tags, so export to git and push via git
     jj git export
     GIT_DIR=$(jj git root)
-    git --git-dir="$GIT_DIR" push origin "$NEW_VERSION" "cmd/shutter/$NEW_VERSION"
+    git --git-dir="$GIT_DIR" push origin "$NEW_VERSION" "cmd/shutter/$NEW_VERSION" "analyzer/$NEW_VERSION" "shuttergrpc/$NEW_VERSION"
     echo "Done. Tagged and pushed $NEW_VERSION"
 else
     echo "Aborted."
@@ -1,22 +1,152 @@
 package shutter
 
 import (
+	"fmt"
 	"regexp"
 	"strings"
+
+	"github.com/ptdewey/shutter/internal/files"
+	"github.com/ptdewey/shutter/internal/idmap"
+	"github.com/ptdewey/shutter/internal/pretty"
 )
 
 // regexScrubber replaces all matches of a regex pattern with a replacement string.
 type regexScrubber struct {
+	name        string
 	pattern     *regexp.Regexp
 	replacement string
+	// label names the built-in placeholder in replacement, e.g. UUID for
+	// "<UUID>", which is rendered in the snapshot's PlaceholderStyle. It is
+	// empty for user-supplied replacements.
+	label string
+	// prefix is the submatch at the start of each match, such as a header
+	// name or the sk_live_ of an API key, that PlaceholderKeepLength leaves
+	// unmasked. Zero masks the whole match.
+	prefix int
 }
 
 func (r *regexScrubber) isOption() {}
 
+func (r *regexScrubber) String() string { return r.name }
+
 func (r *regexScrubber) Scrub(content string) string {
 	return r.pattern.ReplaceAllString(content, r.replacement)
 }
 
+func (r *regexScrubber) scrubStyled(content string, p placeholders) string {
+	switch {
+	case r.label == "" || p.style == PlaceholderAngle:
+		return r.Scrub(content)
+	case p.style == PlaceholderKeepLength:
+		return r.mask(content, p.revealLast)
+	}
+	replacement := strings.Replace(r.replacement, "<"+r.label+">", p.style.placeholder(r.label), 1)
+	return r.pattern.ReplaceAllString(content, replacement)
+}
+
+// mask replaces every match with a run of asterisks of the same length,
+// keeping its prefix submatch and up to revealLast trailing characters.
+func (r *regexScrubber) mask(content string, revealLast int) string {
+	var sb strings.Builder
+	last := 0
+	for _, m := range r.pattern.FindAllStringSubmatchIndex(content, -1) {
+		start := m[0]
+		if r.prefix > 0 && m[2*r.prefix] == m[0] {
+			start = m[2*r.prefix+1]
+		}
+		sb.WriteString(content[last:start])
+		sb.WriteString(maskValue(content[start:m[1]], revealLast))
+		last = m[1]
+	}
+	sb.WriteString(content[last:])
+	return sb.String()
+}
+
+// maskValue replaces the characters of value with asterisks, leaving the
+// last revealLast of them visible. At most half of value is revealed, so
+// short values are never shown in full.
+func maskValue(value string, revealLast int) string {
+	runes := []rune(value)
+	reveal := min(revealLast, len(runes)/2)
+	return strings.Repeat("*", len(runes)-reveal) + string(runes[len(runes)-reveal:])
+}
+
+// placeholders describes how built-in placeholders are written in a
+// snapshot.
+type placeholders struct {
+	style      PlaceholderStyle
+	revealLast int
+}
+
+// styledScrubber is a Scrubber whose built-in placeholders follow the
+// snapshot's PlaceholderStyle.
+type styledScrubber interface {
+	Scrubber
+	scrubStyled(content string, p placeholders) string
+}
+
+// scrubStyled applies s to content, writing its placeholders as described
+// by p if it has built-in ones.
+func scrubStyled(s Scrubber, content string, p placeholders) string {
+	if styled, ok := s.(styledScrubber); ok {
+		return styled.scrubStyled(content, p)
+	}
+	return s.Scrub(content)
+}
+
+// PlaceholderStyle controls how the placeholders of built-in scrubbers,
+// such as ScrubUUID's "<UUID>", are written. See Placeholders.
+type PlaceholderStyle int
+
+const (
+	PlaceholderAngle      PlaceholderStyle = iota // <UUID> (the default)
+	PlaceholderAsterisks                          // ***
+	PlaceholderKeepLength                         // one * per masked character
+)
+
+// placeholder renders the placeholder for label, e.g. UUID, in style s.
+func (s PlaceholderStyle) placeholder(label string) string {
+	switch s {
+	case PlaceholderAsterisks:
+		return "***"
+	default:
+		return "<" + label + ">"
+	}
+}
+
+// parsePlaceholderStyle parses a style name as used in
+// SHUTTER_PLACEHOLDER_STYLE. Unknown names yield PlaceholderAngle.
+func parsePlaceholderStyle(name string) PlaceholderStyle {
+	switch strings.ToLower(name) {
+	case "asterisks":
+		return PlaceholderAsterisks
+	case "keep-length":
+		return PlaceholderKeepLength
+	default:
+		return PlaceholderAngle
+	}
+}
+
+// placeholderScrubber creates the scrubber of the built-in function fn,
+// which replaces matches of pattern with the placeholder <label>, or with
+// the replacement given to fn. A given replacement is used literally and is
+// not affected by the PlaceholderStyle.
+func placeholderScrubber(fn string, pattern *regexp.Regexp, label string, replacement []string) *regexScrubber {
+	if len(replacement) > 0 {
+		return &regexScrubber{
+			name:        fmt.Sprintf("%s(%s)", fn, quoteArg(replacement[0])),
+			pattern:     pattern,
+			replacement: strings.ReplaceAll(replacement[0], "$", "$$"),
+		}
+	}
+	return &regexScrubber{
+		name:        fn + "()",
+		pattern:     pattern,
+		replacement: "<" + label + ">",
+		label:       label,
+	}
+}
+
 // ScrubRegex creates a scrubber that replaces all matches of the given
 // regex pattern with the replacement string.
 //
@@ -26,6 +156,7 @@ func (r *regexScrubber) Scrub(content string) string {
 func ScrubRegex(pattern string, replacement string) Scrubber {
 	re := regexp.MustCompile(pattern)
 	return &regexScrubber{
+		name:        fmt.Sprintf("ScrubRegex(%s, %s)", quoteArg(pattern), quoteArg(replacement)),
 		pattern:     re,
 		replacement: replacement,
 	}
@@ -39,6 +170,12 @@ type exactMatchScrubber struct {
 
 func (e *exactMatchScrubber) isOption() {}
 
+// String leaves out the matched string, which is often the very value the
+// scrubber keeps out of the snapshot.
+func (e *exactMatchScrubber) String() string {
+	return fmt.Sprintf("ScrubExact(…, %s)", quoteArg(e.replacement))
+}
+
 func (e *exactMatchScrubber) Scrub(content string) string {
 	return strings.ReplaceAll(content, e.match, e.replacement)
 }
@@ -71,127 +208,332 @@ var (
 	// Date patterns
 	datePattern = regexp.MustCompile(`\b\d{4}[-/]\d{2}[-/]\d{2}\b|\b\d{2}[-/]\d{2}[-/]\d{4}\b`)
 	// API key pattern - matches patterns like: sk_live_..., pk_test_..., api_key_...
-	apiKeyPattern = regexp.MustCompile(`\b(sk|pk|api[_-]?key)[_-](live|test|prod|dev)[_-][a-zA-Z0-9]+\b`)
+	apiKeyPattern = regexp.MustCompile(`\b((?:sk|pk|api[_-]?key)[_-](?:live|test|prod|dev)[_-])[a-zA-Z0-9]+\b`)
 )
 
-// ScrubUUID replaces all UUIDs with "<UUID>".
+// ScrubUUID replaces all UUIDs with "<UUID>", or with replacement if one is
+// given.
 //
 // Example:
 //
 //	shutter.Snap(t, "user", user, shutter.ScrubUUID())
-func ScrubUUID() Scrubber {
-	return &regexScrubber{
-		pattern:     uuidPattern,
-		replacement: "<UUID>",
-	}
+//	shutter.Snap(t, "user", user, shutter.ScrubUUID("<ID>"))
+func ScrubUUID(replacement ...string) Scrubber {
+	return placeholderScrubber("ScrubUUID", uuidPattern, "UUID", replacement)
 }
 
-// ScrubTimestamp replaces ISO8601 timestamps with "<TIMESTAMP>".
+// ScrubTimestamp replaces ISO8601 timestamps with "<TIMESTAMP>", or with
+// replacement if one is given.
 //
 // Example:
 //
 //	shutter.Snap(t, "event", event, shutter.ScrubTimestamp())
-func ScrubTimestamp() Scrubber {
-	return &regexScrubber{
-		pattern:     iso8601Pattern,
-		replacement: "<TIMESTAMP>",
-	}
+func ScrubTimestamp(replacement ...string) Scrubber {
+	return placeholderScrubber("ScrubTimestamp", iso8601Pattern, "TIMESTAMP", replacement)
 }
 
-// ScrubEmail replaces email addresses with "<EMAIL>".
+// ScrubEmail replaces email addresses with "<EMAIL>", or with replacement if
+// one is given.
 //
 // Example:
 //
 //	shutter.Snap(t, "user", user, shutter.ScrubEmail())
-func ScrubEmail() Scrubber {
-	return &regexScrubber{
-		pattern:     emailPattern,
-		replacement: "<EMAIL>",
-	}
+//	shutter.Snap(t, "user", user, shutter.ScrubEmail("***"))
+func ScrubEmail(replacement ...string) Scrubber {
+	return placeholderScrubber("ScrubEmail", emailPattern, "EMAIL", replacement)
 }
 
-// ScrubUnixTimestamp replaces Unix timestamps (10-13 digits) with "<UNIX_TS>".
+// ScrubUnixTimestamp replaces Unix timestamps (10-13 digits) with "<UNIX_TS>",
+// or with replacement if one is given.
 // Note: This is aggressive and may match other long numbers. For more conservative
 // scrubbing with context keywords, use ScrubRegex with a custom pattern.
 //
 // Example:
 //
 //	shutter.Snap(t, "data", data, shutter.ScrubUnixTimestamp())
-func ScrubUnixTimestamp() Scrubber {
-	return &regexScrubber{
-		pattern:     unixTsPattern,
-		replacement: "<UNIX_TS>",
-	}
+func ScrubUnixTimestamp(replacement ...string) Scrubber {
+	return placeholderScrubber("ScrubUnixTimestamp", unixTsPattern, "UNIX_TS", replacement)
 }
 
-// ScrubIP replaces IPv4 addresses with "<IP>".
+// ScrubIP replaces IPv4 addresses with "<IP>", or with replacement if one is
+// given.
 //
 // Example:
 //
 //	shutter.Snap(t, "request", request, shutter.ScrubIP())
-func ScrubIP() Scrubber {
-	return &regexScrubber{
-		pattern:     ipv4Pattern,
-		replacement: "<IP>",
-	}
+func ScrubIP(replacement ...string) Scrubber {
+	return placeholderScrubber("ScrubIP", ipv4Pattern, "IP", replacement)
 }
 
-// ScrubCreditCard replaces credit card numbers with "<CREDIT_CARD>".
+// ScrubCreditCard replaces credit card numbers with "<CREDIT_CARD>", or with
+// replacement if one is given.
 //
 // Example:
 //
 //	shutter.Snap(t, "payment", payment, shutter.ScrubCreditCard())
-func ScrubCreditCard() Scrubber {
-	return &regexScrubber{
-		pattern:     creditCardPattern,
-		replacement: "<CREDIT_CARD>",
-	}
+func ScrubCreditCard(replacement ...string) Scrubber {
+	return placeholderScrubber("ScrubCreditCard", creditCardPattern, "CREDIT_CARD", replacement)
 }
 
-// ScrubJWT replaces JWT tokens with "<JWT>".
+// ScrubJWT replaces JWT tokens with "<JWT>", or with replacement if one is
+// given.
 //
 // Example:
 //
 //	shutter.Snap(t, "auth", authData, shutter.ScrubJWT())
-func ScrubJWT() Scrubber {
-	return &regexScrubber{
-		pattern:     jwtPattern,
-		replacement: "<JWT>",
-	}
+func ScrubJWT(replacement ...string) Scrubber {
+	return placeholderScrubber("ScrubJWT", jwtPattern, "JWT", replacement)
 }
 
-// ScrubDate replaces various date formats with "<DATE>".
+// ScrubDate replaces various date formats with "<DATE>", or with replacement
+// if one is given.
 //
 // Example:
 //
 //	shutter.Snap(t, "data", data, shutter.ScrubDate())
-func ScrubDate() Scrubber {
-	return &regexScrubber{
-		pattern:     datePattern,
-		replacement: "<DATE>",
-	}
+func ScrubDate(replacement ...string) Scrubber {
+	return placeholderScrubber("ScrubDate", datePattern, "DATE", replacement)
 }
 
-// ScrubAPIKey replaces common API key patterns with "<API_KEY>".
+// ScrubAPIKey replaces common API key patterns with "<API_KEY>", or with
+// replacement if one is given.
 // Matches patterns like: sk_live_..., pk_test_..., api_key_...
 //
 // Example:
 //
 //	shutter.Snap(t, "config", config, shutter.ScrubAPIKey())
-func ScrubAPIKey() Scrubber {
+func ScrubAPIKey(replacement ...string) Scrubber {
+	s := placeholderScrubber("ScrubAPIKey", apiKeyPattern, "API_KEY", replacement)
+	s.prefix = 1 // sk_live_ and the like are kept when masking
+	return s
+}
+
+// mappedScrubber replaces each distinct match with a numbered placeholder
+// whose number is persisted across runs.
+type mappedScrubber struct {
+	name    string
+	pattern *regexp.Regexp
+	label   string
+}
+
+func (m *mappedScrubber) isOption() {}
+
+func (m *mappedScrubber) String() string { return m.name }
+
+func (m *mappedScrubber) Scrub(content string) string {
+	var store *idmap.Store
+	if mem, ok := files.CurrentStorage().(*files.MemoryStorage); ok {
+		store = mem.IDs()
+	} else {
+		dir, err := files.SnapshotDir()
+		if err != nil {
+			return m.pattern.ReplaceAllString(content, "<"+m.label+">")
+		}
+		store = idmap.ForDir(dir)
+	}
+
+	return m.pattern.ReplaceAllStringFunc(content, func(match string) string {
+		// A failed save still yields a number that is valid for this run.
+		// Only a mapping that cannot be loaded leaves the value unnumbered.
+		n, _ := store.Lookup(m.label, match)
+		if n == 0 {
+			return "<" + m.label + ">"
+		}
+		return fmt.Sprintf("<%s_%d>", m.label, n)
+	})
+}
+
+// ScrubMapped creates a scrubber that replaces each distinct match of the
+// pattern with a numbered placeholder such as "<USER_3>". The same value
+// always receives the same number, across snapshots and across test runs,
+// so references between values stay visible while the values themselves
+// are redacted.
+//
+// Assignments are persisted in ids.json inside the __snapshots__ directory.
+// Only SHA-256 digests of the matched values are stored there; commit it
+// alongside the snapshots to keep numbering stable for everyone.
+//
+// Example:
+//
+//	shutter.ScrubMapped(`user-\d+`, "USER")
+func ScrubMapped(pattern string, label string) Scrubber {
+	return &mappedScrubber{
+		name:    fmt.Sprintf("ScrubMapped(%s, %s)", quoteArg(pattern), quoteArg(label)),
+		pattern: regexp.MustCompile(pattern),
+		label:   label,
+	}
+}
+
+// ScrubUUIDMapped replaces UUIDs with numbered placeholders such as
+// "<UUID_1>", keeping the numbering stable across snapshots and runs.
+// See ScrubMapped for details on how the mapping is stored.
+//
+// Example:
+//
+//	shutter.Snap(t, "order", order, shutter.ScrubUUIDMapped())
+func ScrubUUIDMapped() Scrubber {
+	return &mappedScrubber{
+		name:    "ScrubUUIDMapped()",
+		pattern: uuidPattern,
+		label:   "UUID",
+	}
+}
+
+// HTTP header patterns. Header names are matched case-insensitively at the
+// start of a line, as they appear in dumped requests and responses.
+var (
+	httpDateHeaderPattern = regexp.MustCompile(`(?im)^((?:Date|Expires|Last-Modified|If-Modified-Since|If-Unmodified-Since):[ \t]*)[^\r\n]*`)
+	setCookiePattern      = regexp.MustCompile(`(?im)^(Set-Cookie:[ \t]*)([^\r\n]*)`)
+	cookiePattern         = regexp.MustCompile(`(?im)^(Cookie:[ \t]*)([^\r\n]*)`)
+	etagHeaderPattern     = regexp.MustCompile(`(?im)^((?:ETag|If-Match|If-None-Match):[ \t]*)([^\r\n]*)`)
+	etagPattern           = regexp.MustCompile(`(W/)?"[^"]*"`)
+)
+
+// ScrubHTTPDateHeader replaces the values of date-valued HTTP headers
+// (Date, Expires, Last-Modified, If-Modified-Since and If-Unmodified-Since)
+// with "<HTTP_DATE>".
+//
+// Example:
+//
+//	dump, _ := httputil.DumpResponse(resp, true)
+//	shutter.SnapString(t, "response", string(dump), shutter.ScrubHTTPDateHeader())
+func ScrubHTTPDateHeader() Scrubber {
 	return &regexScrubber{
-		pattern:     apiKeyPattern,
-		replacement: "<API_KEY>",
+		name:        "ScrubHTTPDateHeader()",
+		pattern:     httpDateHeaderPattern,
+		replacement: "${1}<HTTP_DATE>",
+		label:       "HTTP_DATE",
+		prefix:      1,
+	}
+}
+
+// ScrubSetCookie masks cookie values in Set-Cookie and Cookie headers while
+// keeping cookie names and attributes, so a snapshot still shows which
+// cookies are set and how. Values become "<COOKIE>", and the Expires and
+// Max-Age attributes become "<HTTP_DATE>" and "<MAX_AGE>".
+//
+// Example:
+//
+//	shutter.SnapString(t, "login response", string(dump), shutter.ScrubSetCookie())
+func ScrubSetCookie() Scrubber {
+	return &customScrubber{
+		name: "ScrubSetCookie()",
+		scrubFunc: func(content string) string {
+			content = replaceHeaderValues(content, setCookiePattern, scrubSetCookieValue)
+			return replaceHeaderValues(content, cookiePattern, scrubCookieValue)
+		},
+	}
+}
+
+// ScrubETag replaces entity tags in ETag, If-Match and If-None-Match headers
+// with "<ETAG>", keeping the weak validator prefix W/.
+//
+// Example:
+//
+//	shutter.SnapString(t, "response", string(dump), shutter.ScrubETag())
+func ScrubETag() Scrubber {
+	return &customScrubber{
+		name: "ScrubETag()",
+		scrubFunc: func(content string) string {
+			return replaceHeaderValues(content, etagHeaderPattern, func(value string) string {
+				return etagPattern.ReplaceAllString(value, `${1}"<ETAG>"`)
+			})
+		},
+	}
+}
+
+// replaceHeaderValues rewrites the value of every header matched by pattern,
+// which must capture the header name and separator as group 1 and the value
+// as group 2.
+func replaceHeaderValues(content string, pattern *regexp.Regexp, scrub func(string) string) string {
+	return pattern.ReplaceAllStringFunc(content, func(line string) string {
+		m := pattern.FindStringSubmatch(line)
+		return m[1] + scrub(m[2])
+	})
+}
+
+// scrubSetCookieValue masks the value of a Set-Cookie header: the cookie
+// value and its Expires and Max-Age attributes.
+func scrubSetCookieValue(value string) string {
+	parts := strings.Split(value, ";")
+	for i, part := range parts {
+		name, _, ok := strings.Cut(part, "=")
+		if !ok {
+			continue
+		}
+		switch {
+		case i == 0:
+			parts[i] = name + "=<COOKIE>"
+		case strings.EqualFold(strings.TrimSpace(name), "Expires"):
+			parts[i] = name + "=<HTTP_DATE>"
+		case strings.EqualFold(strings.TrimSpace(name), "Max-Age"):
+			parts[i] = name + "=<MAX_AGE>"
+		}
+	}
+	return strings.Join(parts, ";")
+}
+
+// scrubCookieValue masks every value in a Cookie header.
+func scrubCookieValue(value string) string {
+	parts := strings.Split(value, ";")
+	for i, part := range parts {
+		if name, _, ok := strings.Cut(part, "="); ok {
+			parts[i] = name + "=<COOKIE>"
+		}
+	}
+	return strings.Join(parts, ";")
+}
+
+// Whitespace patterns used by CollapseWhitespace
+var (
+	inlineSpacePattern = regexp.MustCompile(`[ \t]+`)
+	blankLinesPattern  = regexp.MustCompile(`\n{2,}`)
+)
+
+// CollapseWhitespace trims each line, collapses runs of spaces and tabs into
+// a single space, and removes blank lines. This keeps snapshots of rendered
+// templates readable regardless of how the template itself is indented.
+//
+// Example:
+//
+//	shutter.SnapTemplate(t, "page", tmpl, data, shutter.CollapseWhitespace())
+func CollapseWhitespace() Scrubber {
+	return &customScrubber{
+		name: "CollapseWhitespace()",
+		scrubFunc: func(content string) string {
+			lines := strings.Split(content, "\n")
+			for i, line := range lines {
+				lines[i] = strings.TrimSpace(inlineSpacePattern.ReplaceAllString(line, " "))
+			}
+			collapsed := blankLinesPattern.ReplaceAllString(strings.Join(lines, "\n"), "\n")
+			return strings.Trim(collapsed, "\n")
+		},
+	}
+}
+
+// StripANSI removes ANSI escape sequences (colors, cursor movement,
+// hyperlinks) so snapshots of colored CLI output stay readable.
+//
+// Example:
+//
+//	shutter.SnapString(t, "help output", output, shutter.StripANSI())
+func StripANSI() Scrubber {
+	return &customScrubber{
+		name:      "StripANSI()",
+		scrubFunc: pretty.StripANSI,
 	}
 }
 
 // customScrubber allows users to provide a custom scrubbing function.
 type customScrubber struct {
+	name      string
 	scrubFunc func(string) string
 }
 
 func (c *customScrubber) isOption() {}
 
+func (c *customScrubber) String() string { return c.name }
+
 func (c *customScrubber) Scrub(content string) string {
 	return c.scrubFunc(content)
 }
@@ -208,6 +550,7 @@ func (c *customScrubber) Scrub(content string) string {
 //	)
 func ScrubWith(scrubFunc func(string) string) Scrubber {
 	return &customScrubber{
+		name:      "ScrubWith(func)",
 		scrubFunc: scrubFunc,
 	}
 }
@@ -200,3 +200,97 @@ func TestScrubWithSnapFunction(t *testing.T) {
 		shutter.ScrubTimestamp(),
 	)
 }
+
+func TestScrubUUIDMapped(t *testing.T) {
+	data := map[string]any{
+		"order_id":    "7c9e6679-7425-40de-944b-e07fc1f90ae7",
+		"customer_id": "550e8400-e29b-41d4-a716-446655440000",
+		"parent_id":   "7c9e6679-7425-40de-944b-e07fc1f90ae7",
+	}
+
+	shutter.Snap(t, "Scrub UUID Mapped", data, shutter.ScrubUUIDMapped())
+}
+
+func TestStripANSI(t *testing.T) {
+	output := "\033[1m\033[94mUsage:\033[0m tool [flags]\n  \033[92m--verbose\033[0m  print more\n"
+
+	shutter.SnapString(t, "Strip ANSI", output, shutter.StripANSI())
+}
+
+func TestHTTPScrubbers(t *testing.T) {
+	response := "HTTP/1.1 200 OK\n" +
+		"Cache-Control: max-age=60\n" +
+		"Date: Tue, 14 Oct 2025 09:21:07 GMT\n" +
+		"Etag: W/\"5e15-1a2b3c\"\n" +
+		"Last-Modified: Mon, 13 Oct 2025 17:02:44 GMT\n" +
+		"Set-Cookie: session=f3a9c1d2e4; Path=/; Expires=Wed, 15 Oct 2025 09:21:07 GMT; HttpOnly; Secure\n" +
+		"Set-Cookie: theme=dark; Max-Age=31536000; SameSite=Lax\n" +
+		"\n" +
+		"{\"updated\": \"Date: keep me\"}\n"
+
+	shutter.SnapString(t, "HTTP Response", response,
+		shutter.ScrubHTTPDateHeader(),
+		shutter.ScrubSetCookie(),
+		shutter.ScrubETag(),
+	)
+
+	request := "GET /account HTTP/1.1\r\n" +
+		"Host: example.com\r\n" +
+		"Cookie: session=f3a9c1d2e4; theme=dark\r\n" +
+		"If-None-Match: \"5e15-1a2b3c\", W/\"77-aa\"\r\n" +
+		"If-Modified-Since: Mon, 13 Oct 2025 17:02:44 GMT\r\n\r\n"
+
+	scrubbed := request
+	for _, s := range []shutter.Scrubber{shutter.ScrubHTTPDateHeader(), shutter.ScrubSetCookie(), shutter.ScrubETag()} {
+		scrubbed = s.Scrub(scrubbed)
+	}
+	want := "GET /account HTTP/1.1\r\n" +
+		"Host: example.com\r\n" +
+		"Cookie: session=<COOKIE>; theme=<COOKIE>\r\n" +
+		"If-None-Match: \"<ETAG>\", W/\"<ETAG>\"\r\n" +
+		"If-Modified-Since: <HTTP_DATE>\r\n\r\n"
+	if scrubbed != want {
+		t.Errorf("scrubbed request:\n%q\nwant:\n%q", scrubbed, want)
+	}
+}
+
+func TestPlaceholderText(t *testing.T) {
+	jsonStr := `{
+		"id": "550e8400-e29b-41d4-a716-446655440000",
+		"email": "user@example.com",
+		"ip": "192.168.1.1",
+		"price": "$5"
+	}`
+
+	shutter.SnapJSON(t, "Custom Replacements", jsonStr,
+		shutter.ScrubUUID("<ID>"),
+		shutter.ScrubEmail("$EMAIL"),
+		shutter.ScrubIP(),
+	)
+
+	shutter.SnapJSON(t, "Asterisk Placeholders", jsonStr,
+		shutter.ScrubUUID("<ID>"),
+		shutter.ScrubEmail(),
+		shutter.ScrubIP(),
+		shutter.Placeholders(shutter.PlaceholderAsterisks),
+	)
+}
+
+func TestKeepLengthMasking(t *testing.T) {
+	table := "name   | email                  | key\n" +
+		"alice  | alice@example.com      | sk_live_51HqZ2bKl4FGBMFpLxO0123\n" +
+		"bob    | bob.smith@example.org  | pk_test_9aB8cD7eF6\n"
+
+	shutter.SnapString(t, "Masked Table", table,
+		shutter.ScrubEmail(),
+		shutter.ScrubAPIKey(),
+		shutter.Placeholders(shutter.PlaceholderKeepLength),
+	)
+
+	shutter.SnapString(t, "Partially Revealed Table", table,
+		shutter.ScrubEmail(),
+		shutter.ScrubAPIKey(),
+		shutter.Placeholders(shutter.PlaceholderKeepLength),
+		shutter.RevealLast(4),
+	)
+}
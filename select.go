@@ -0,0 +1,83 @@
+package shutter
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/ptdewey/shutter/internal/snapshots"
+)
+
+// selectSetting snapshots a projection of the value passed to Snap or
+// SnapJSONValue.
+type selectSetting struct {
+	fn func(v any) (any, error)
+}
+
+func (s *selectSetting) isOption() {}
+
+func (s *selectSetting) apply(cfg *snapConfig) {
+	cfg.selectValue = s.fn
+}
+
+// Select snapshots fn(v) in place of the value v, so that a test of a large
+// value records only the part it is about, such as the IDs of a response's
+// items, and other changes to the value do not churn the snapshot. The
+// snapshot fails if v is not a T.
+//
+// This option only works with Snap and SnapJSONValue. For JSON input, use
+// SelectPaths.
+//
+// Example:
+//
+//	shutter.Snap(t, "item ids", resp, shutter.Select(func(r *Response) any {
+//	    ids := make([]string, len(r.Body.Items))
+//	    for i, item := range r.Body.Items {
+//	        ids[i] = item.ID
+//	    }
+//	    return ids
+//	}))
+func Select[T any](fn func(v T) any) Option {
+	return &selectSetting{fn: func(v any) (any, error) {
+		typed, ok := v.(T)
+		if !ok {
+			return nil, fmt.Errorf("Select: value is %T, not %s", v, reflect.TypeFor[T]())
+		}
+		return fn(typed), nil
+	}}
+}
+
+// selectPathsSetting keeps only parts of a JSON document.
+type selectPathsSetting struct {
+	paths []string
+}
+
+func (s *selectPathsSetting) isOption() {}
+
+func (s *selectPathsSetting) apply(cfg *snapConfig) {
+	cfg.selectPaths = append(cfg.selectPaths, s.paths...)
+}
+
+// SelectPaths snapshots only the values at paths within a JSON document,
+// along with the objects and arrays that lead to them. Paths are dotted keys
+// from the document root, as in Within; array elements share the path of
+// their array, so "data.items.id" keeps the id of every item. Ignore
+// patterns and scrubbers then apply to what is left. A path that selects
+// nothing, except within an empty array, fails the snapshot, so that a
+// mistyped path is not mistaken for an empty result.
+//
+// This option only works with SnapJSON and the other JSON functions.
+//
+// Example:
+//
+//	shutter.SnapJSON(t, "item ids", body, shutter.SelectPaths("data.items.id", "data.total"))
+func SelectPaths(paths ...string) Option {
+	return &selectPathsSetting{paths: paths}
+}
+
+// selected returns the projection of v chosen with Select, or v itself.
+func (c *snapConfig) selected(v any) (any, error) {
+	if c.selectValue == nil {
+		return v, nil
+	}
+	return recovering(snapshots.CallSite(), func() (any, error) { return c.selectValue(v) })
+}
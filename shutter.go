@@ -1,9 +1,16 @@
 package shutter
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
+	"io"
+	"strconv"
+	"strings"
 
 	"github.com/kortschak/utter"
+	"github.com/ptdewey/shutter/internal/files"
+	"github.com/ptdewey/shutter/internal/pretty"
 	"github.com/ptdewey/shutter/internal/review"
 	"github.com/ptdewey/shutter/internal/snapshots"
 	"github.com/ptdewey/shutter/internal/transform"
@@ -56,6 +63,50 @@ type IgnorePattern interface {
 	ShouldIgnore(key, value string) bool
 }
 
+// Helper marks the calling function as a snapshot helper, much like
+// t.Helper marks test helpers. Snapshots record the file of the test that
+// took them; frames of marked helpers are skipped so that a shared helper
+// records its caller's file instead of its own.
+//
+// Example:
+//
+//	func snapResponse(t *testing.T, title string, resp *http.Response) {
+//	    t.Helper()
+//	    shutter.Helper()
+//	    shutter.SnapJSON(t, title, readBody(t, resp))
+//	}
+func Helper() {
+	snapshots.MarkHelper(1)
+}
+
+// AssertNoSnapshotChange fails the test if it or any of its subtests wrote
+// a snapshot for review (a .snap.new file), either because content differed
+// from its accepted snapshot or because none was accepted yet. Use it in
+// tests that exercise code paths which must not alter snapshot baselines.
+// Call it after the snapshots are taken, typically in t.Cleanup.
+//
+// Example:
+//
+//	func TestRetryKeepsOutput(t *testing.T) {
+//	    t.Cleanup(func() { shutter.AssertNoSnapshotChange(t) })
+//	    shutter.Snap(t, "report", runWithRetries(3))
+//	}
+func AssertNoSnapshotChange(t T) {
+	t.Helper()
+	snapshots.AssertNoPendingWrites(t)
+}
+
+// SetOutput sets where shutter writes the boxes showing new and
+// mismatched snapshots and its other diagnostics, such as the summary
+// printed by Main. It defaults to os.Stdout; pass io.Discard to silence
+// them, or a buffer to capture them. Writes are serialized, so the writer
+// may be shared by parallel tests. A nil writer restores os.Stdout.
+//
+// Test failures are still reported through T.
+func SetOutput(w io.Writer) {
+	snapshots.SetOutput(w)
+}
+
 // Snap takes a single value, formats it, and creates a snapshot with the given title.
 // Complex types are formatted using a pretty-printer for readability.
 //
@@ -69,20 +120,32 @@ type IgnorePattern interface {
 //	    shutter.ScrubUUID(),
 //	    shutter.ScrubEmail(),
 //	)
-func Snap(t snapshots.T, title string, value any, opts ...Option) {
+func Snap(t T, title string, value any, opts ...Option) {
 	t.Helper()
+	opts = withDefaults(opts, false)
 
 	scrubbers, ignores := separateOptions(opts)
 
 	if len(ignores) > 0 {
-		t.Error(fmt.Sprintf("snapshot %q: IgnorePattern options are not supported with Snap; use SnapJSON instead", title))
+		t.Error(fmt.Sprintf("snapshot %q: IgnorePattern options are not supported with Snap; use SnapJSONValue instead", title))
 		return
 	}
 
-	content := formatValue(value)
-	scrubbedContent := applyScrubbers(content, scrubbers)
+	cfg := newSnapConfig(opts)
+	value, err := cfg.selected(value)
+	if err != nil {
+		t.Error(fmt.Sprintf("snapshot %q: %v", title, err))
+		return
+	}
+	scrubbedContent, err := cfg.produce(t, func() (string, error) {
+		return cfg.scrub(cfg.formatValue(value), scrubbers), nil
+	})
+	if err != nil {
+		t.Error(fmt.Sprintf("snapshot %q: %v", title, err))
+		return
+	}
 
-	snapshots.Snap(t, title, snapshotFormatVersion, scrubbedContent)
+	snapshots.SnapWithOptions(t, title, snapshotFormatVersion, scrubbedContent, cfg.snapshotOptions(files.ContentText))
 }
 
 // SnapMany takes multiple values, formats them, and creates a snapshot with the given title.
@@ -98,8 +161,9 @@ func Snap(t snapshots.T, title string, value any, opts ...Option) {
 //	    shutter.ScrubUUID(),
 //	    shutter.ScrubTimestamp(),
 //	)
-func SnapMany(t snapshots.T, title string, values []any, opts ...Option) {
+func SnapMany(t T, title string, values []any, opts ...Option) {
 	t.Helper()
+	opts = withDefaults(opts, false)
 
 	scrubbers, ignores := separateOptions(opts)
 
@@ -108,10 +172,76 @@ func SnapMany(t snapshots.T, title string, values []any, opts ...Option) {
 		return
 	}
 
-	content := formatValues(values...)
-	scrubbedContent := applyScrubbers(content, scrubbers)
+	cfg := newSnapConfig(opts)
+	scrubbedContent, err := cfg.produce(t, func() (string, error) {
+		return cfg.scrub(cfg.formatValues(values...), scrubbers), nil
+	})
+	if err != nil {
+		t.Error(fmt.Sprintf("snapshot %q: %v", title, err))
+		return
+	}
 
-	snapshots.Snap(t, title, snapshotFormatVersion, scrubbedContent)
+	snapshots.SnapWithOptions(t, title, snapshotFormatVersion, scrubbedContent, cfg.snapshotOptions(files.ContentText))
+}
+
+// Case is a single named input for SnapEach.
+type Case struct {
+	// Name identifies the case and is appended to the snapshot title.
+	Name string
+	// Value is formatted the same way as the value passed to Snap.
+	Value any
+}
+
+// SnapEach snapshots every case separately, titling each snapshot with its
+// case name. Each case becomes its own reviewable snapshot, grouped under a
+// directory named after the shared title as if it were a subtest.
+//
+// Options are applied to every case. Only Scrubber options are supported;
+// IgnorePattern options will cause an error.
+//
+// Example:
+//
+//	shutter.SnapEach(t, "parse", []shutter.Case{
+//	    {Name: "empty", Value: Parse("")},
+//	    {Name: "single", Value: Parse("a")},
+//	})
+func SnapEach(t T, title string, cases []Case, opts ...Option) {
+	t.Helper()
+	opts = withDefaults(opts, false)
+
+	scrubbers, ignores := separateOptions(opts)
+
+	if len(ignores) > 0 {
+		t.Error(fmt.Sprintf("snapshot %q: IgnorePattern options are not supported with SnapEach; use SnapJSON instead", title))
+		return
+	}
+
+	cfg := newSnapConfig(opts)
+	seen := make(map[string]bool, len(cases))
+	for i, c := range cases {
+		name := c.Name
+		if name == "" {
+			name = strconv.Itoa(i)
+		}
+		if seen[name] {
+			t.Error(fmt.Sprintf("snapshot %q: duplicate case name %q", title, name))
+			continue
+		}
+		seen[name] = true
+
+		caseTitle := title + "/" + name
+		scrubbedContent, err := cfg.produce(t, func() (string, error) {
+			return cfg.scrub(cfg.formatValue(c.Value), scrubbers), nil
+		})
+		if err != nil {
+			t.Error(fmt.Sprintf("snapshot %q: %v", caseTitle, err))
+			continue
+		}
+
+		opts := cfg.snapshotOptions(files.ContentText)
+		opts.Group = title
+		snapshots.SnapWithOptions(t, name, snapshotFormatVersion, scrubbedContent, opts)
+	}
 }
 
 // SnapString takes a string value and creates a snapshot with the given title.
@@ -126,8 +256,9 @@ func SnapMany(t snapshots.T, title string, values []any, opts ...Option) {
 //	shutter.SnapString(t, "report output", output,
 //	    shutter.ScrubTimestamp(),
 //	)
-func SnapString(t snapshots.T, title string, content string, opts ...Option) {
+func SnapString(t T, title string, content string, opts ...Option) {
 	t.Helper()
+	opts = withDefaults(opts, false)
 
 	scrubbers, ignores := separateOptions(opts)
 
@@ -136,14 +267,79 @@ func SnapString(t snapshots.T, title string, content string, opts ...Option) {
 		return
 	}
 
-	scrubbedContent := applyScrubbers(content, scrubbers)
+	cfg := newSnapConfig(opts)
+	scrubbedContent, err := cfg.produce(t, func() (string, error) {
+		return cfg.scrub(content, scrubbers), nil
+	})
+	if err != nil {
+		t.Error(fmt.Sprintf("snapshot %q: %v", title, err))
+		return
+	}
+
+	snapshots.SnapWithOptions(t, title, snapshotFormatVersion, scrubbedContent, cfg.snapshotOptions(textContentType(scrubbedContent)))
+}
+
+// textContentType returns the content type of a string snapshot: ANSI when
+// it holds escape sequences, such as colored terminal output, and text
+// otherwise.
+func textContentType(content string) string {
+	if pretty.StripANSI(content) != content {
+		return files.ContentANSI
+	}
+	return files.ContentText
+}
+
+// Template is implemented by both *text/template.Template and
+// *html/template.Template.
+type Template interface {
+	Execute(w io.Writer, data any) error
+}
+
+// SnapTemplate executes tmpl with data and snapshots the rendered output.
+// Both text/template and html/template templates are supported.
+//
+// Options can be provided to scrub sensitive or dynamic data before snapshotting.
+// Only Scrubber options are supported; IgnorePattern options will cause an error.
+// CollapseWhitespace is useful for templates whose actions leave blank lines
+// and indentation behind.
+//
+// Example:
+//
+//	tmpl := template.Must(template.New("page").Parse(page))
+//	shutter.SnapTemplate(t, "user page", tmpl, user,
+//	    shutter.CollapseWhitespace(),
+//	)
+func SnapTemplate(t T, title string, tmpl Template, data any, opts ...Option) {
+	t.Helper()
+	opts = withDefaults(opts, false)
+
+	scrubbers, ignores := separateOptions(opts)
+
+	if len(ignores) > 0 {
+		t.Error(fmt.Sprintf("snapshot %q: IgnorePattern options are not supported with SnapTemplate; use SnapJSON instead", title))
+		return
+	}
+
+	cfg := newSnapConfig(opts)
+	scrubbedContent, err := cfg.produce(t, func() (string, error) {
+		var sb strings.Builder
+		if err := tmpl.Execute(&sb, data); err != nil {
+			return "", fmt.Errorf("failed to execute template: %w", err)
+		}
+		return cfg.scrub(sb.String(), scrubbers), nil
+	})
+	if err != nil {
+		t.Error(fmt.Sprintf("snapshot %q: %v", title, err))
+		return
+	}
 
-	snapshots.Snap(t, title, snapshotFormatVersion, scrubbedContent)
+	snapshots.SnapWithOptions(t, title, snapshotFormatVersion, scrubbedContent, cfg.snapshotOptions(textContentType(scrubbedContent)))
 }
 
 // SnapJSON takes a JSON string, validates it, and pretty-prints it with
 // consistent formatting before snapshotting. This preserves the raw JSON
-// format while ensuring valid JSON structure.
+// format while ensuring valid JSON structure. Object keys are sorted unless
+// PreserveKeyOrder is given.
 //
 // Options can be provided to apply both Scrubbers and IgnorePatterns.
 // IgnorePatterns remove fields from the JSON structure before scrubbing.
@@ -157,24 +353,189 @@ func SnapString(t snapshots.T, title string, content string, opts ...Option) {
 //	    shutter.ScrubUUID(),               // Second: scrub remaining UUIDs
 //	    shutter.ScrubEmail(),              // Third: scrub emails
 //	)
-func SnapJSON(t snapshots.T, title string, jsonStr string, opts ...Option) {
+func SnapJSON(t T, title string, jsonStr string, opts ...Option) {
+	t.Helper()
+	snapJSON(t, title, func() (io.Reader, error) { return strings.NewReader(jsonStr), nil }, opts)
+}
+
+// SnapJSONBytes is like SnapJSON but takes the JSON as a byte slice, such as
+// an httptest.ResponseRecorder body, without copying it into a string.
+//
+// Example:
+//
+//	rec := httptest.NewRecorder()
+//	handler.ServeHTTP(rec, req)
+//	shutter.SnapJSONBytes(t, "response", rec.Body.Bytes(), shutter.ScrubUUID())
+func SnapJSONBytes(t T, title string, jsonBytes []byte, opts ...Option) {
 	t.Helper()
+	snapJSON(t, title, func() (io.Reader, error) { return bytes.NewReader(jsonBytes), nil }, opts)
+}
+
+// SnapJSONValue marshals v with encoding/json and snapshots the result like
+// SnapJSON, so ignore patterns such as IgnoreKey work on structs and other Go
+// values without marshaling them first. Struct fields appear under their
+// json tag names.
+//
+// Example:
+//
+//	user := User{ID: 42, Email: "user@example.com", Password: "secret"}
+//	shutter.SnapJSONValue(t, "user", user,
+//	    shutter.IgnoreKey("password"),
+//	    shutter.ScrubEmail(),
+//	)
+func SnapJSONValue(t T, title string, v any, opts ...Option) {
+	t.Helper()
+	v, err := newSnapConfig(withDefaults(opts, true)).selected(v)
+	if err != nil {
+		t.Error(fmt.Sprintf("snapshot %q: %v", title, err))
+		return
+	}
+	snapJSON(t, title, func() (io.Reader, error) {
+		jsonBytes, err := json.Marshal(v)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal value: %w", err)
+		}
+		return bytes.NewReader(jsonBytes), nil
+	}, opts)
+}
+
+// SnapJSONReader is like SnapJSON but reads the JSON from r, such as an
+// http.Response body, so large payloads are decoded as they are read. The
+// reader is not closed. With CheckDeterminism, which renders the snapshot
+// twice, or KeepRaw, the input is read into memory first.
+//
+// Example:
+//
+//	resp, err := http.Get(server.URL + "/users")
+//	// handle err
+//	defer resp.Body.Close()
+//	shutter.SnapJSONReader(t, "users", resp.Body)
+func SnapJSONReader(t T, title string, r io.Reader, opts ...Option) {
+	t.Helper()
+
+	if cfg := newSnapConfig(withDefaults(opts, true)); !cfg.checkDeterminism && !cfg.keepRaw {
+		snapJSON(t, title, func() (io.Reader, error) { return r, nil }, opts)
+		return
+	}
+
+	jsonBytes, err := io.ReadAll(r)
+	if err != nil {
+		t.Error(fmt.Sprintf("snapshot %q: failed to read JSON: %v", title, err))
+		return
+	}
+	snapJSON(t, title, func() (io.Reader, error) { return bytes.NewReader(jsonBytes), nil }, opts)
+}
+
+// snapJSON snapshots the JSON returned by input, which is called once per
+// render.
+func snapJSON(t T, title string, input func() (io.Reader, error), opts []Option) {
+	t.Helper()
+	opts = withDefaults(opts, true)
 
 	scrubbers, ignores := separateOptions(opts)
 
+	cfg := newSnapConfig(opts)
+
+	var scopes []transform.Scope
+	for _, ignore := range ignores {
+		if w, ok := ignore.(*withinOption); ok {
+			scopes = append(scopes, w.scopes(cfg.placeholders)...)
+		}
+	}
+
 	// Transform the JSON with ignore patterns and scrubbers
 	transformConfig := &transform.Config{
-		Scrubbers: toTransformScrubbers(scrubbers),
-		Ignore:    toTransformIgnorePatterns(ignores),
+		Scrubbers:     toTransformScrubbers(scrubbers, cfg.placeholders),
+		Ignore:        toTransformIgnorePatterns(ignores),
+		Scopes:        scopes,
+		PreserveOrder: cfg.preserveKeyOrder,
+		AllowComments: cfg.allowJSONC,
+		Select:        cfg.selectPaths,
+	}
+
+	transformedJSON, err := cfg.produce(t, func() (string, error) {
+		r, err := input()
+		if err != nil {
+			return "", err
+		}
+		result, err := transform.TransformJSONReader(r, transformConfig)
+		if err != nil {
+			return "", fmt.Errorf("failed to transform JSON: %w", err)
+		}
+		return result, nil
+	})
+	if err != nil {
+		t.Error(fmt.Sprintf("snapshot %q: %v", title, err))
+		return
+	}
+	if cfg.keepRaw {
+		// Best effort: the raw content is only shown during review.
+		if r, err := input(); err == nil {
+			cfg.raw, _ = transform.TransformJSONReader(r, &transform.Config{
+				PreserveOrder: cfg.preserveKeyOrder,
+				AllowComments: cfg.allowJSONC,
+				Select:        cfg.selectPaths,
+			})
+		}
+	}
+
+	snapshots.SnapWithOptions(t, title, snapshotFormatVersion, transformedJSON, cfg.snapshotOptions(files.ContentJSON))
+}
+
+// SnapAuto detects whether content is JSON, XML, YAML or plain text and
+// snapshots it in a canonical form for that format: JSON like SnapJSON, XML
+// re-indented with two spaces per level, YAML with line endings and trailing
+// whitespace normalized, and plain text as is, like SnapString. This suits
+// bodies whose type varies per test case, such as responses passing through
+// a proxy or gateway.
+//
+// Scrubbers apply to every format. IgnorePattern options are only supported
+// when the content is JSON and cause an error otherwise.
+//
+// Example:
+//
+//	for _, tc := range cases {
+//	    body := proxy(t, tc.request)
+//	    shutter.SnapAuto(t, tc.name, body, shutter.ScrubUUID())
+//	}
+func SnapAuto(t T, title string, content string, opts ...Option) {
+	t.Helper()
+
+	format := transform.Detect(content)
+	if format == transform.JSON {
+		snapJSON(t, title, func() (io.Reader, error) { return strings.NewReader(content), nil }, opts)
+		return
 	}
+	opts = withDefaults(opts, false)
 
-	transformedJSON, err := transform.TransformJSON(jsonStr, transformConfig)
+	scrubbers, ignores := separateOptions(opts)
+
+	if len(ignores) > 0 {
+		t.Error(fmt.Sprintf("snapshot %q: IgnorePattern options are only supported when SnapAuto detects JSON, not %s", title, format))
+		return
+	}
+
+	cfg := newSnapConfig(opts)
+	scrubbedContent, err := cfg.produce(t, func() (string, error) {
+		canonical := content
+		switch format {
+		case transform.XML:
+			indented, err := transform.IndentXML(content)
+			if err != nil {
+				return "", err
+			}
+			canonical = indented
+		case transform.YAML:
+			canonical = transform.NormalizeYAML(content)
+		}
+		return cfg.scrub(canonical, scrubbers), nil
+	})
 	if err != nil {
-		t.Error(fmt.Sprintf("snapshot %q: failed to transform JSON: %v", title, err))
+		t.Error(fmt.Sprintf("snapshot %q: %v", title, err))
 		return
 	}
 
-	snapshots.Snap(t, title, snapshotFormatVersion, transformedJSON)
+	snapshots.SnapWithOptions(t, title, snapshotFormatVersion, scrubbedContent, cfg.snapshotOptions(string(format)))
 }
 
 // Review launches an interactive review session to accept or reject snapshot changes.
@@ -182,6 +543,41 @@ func Review() error {
 	return review.Review()
 }
 
+// ReviewOptions configures ReviewWithOptions.
+type ReviewOptions struct {
+	// Dir limits the review to snapshots under this directory. By default the
+	// whole project is reviewed.
+	Dir string
+
+	// Filter limits the review to snapshots whose title (for example
+	// "TestUsers/admin_case") matches this regular expression.
+	Filter string
+
+	// NonInteractive prints the selected snapshots and their diffs without
+	// prompting, leaving them pending.
+	NonInteractive bool
+
+	// AutoAccept accepts the selected snapshots without prompting.
+	AutoAccept bool
+
+	// DiffTool opens each snapshot in an external diff tool before asking
+	// for a decision: the command in $SHUTTER_DIFFTOOL, or git difftool.
+	DiffTool bool
+}
+
+// ReviewWithOptions reviews the pending snapshots selected by opts. It is
+// meant for tools that run targeted or scripted reviews; Review is
+// equivalent to ReviewWithOptions(ReviewOptions{}).
+func ReviewWithOptions(opts ReviewOptions) error {
+	return review.ReviewWithOptions(review.Options{
+		Dir:            opts.Dir,
+		Filter:         opts.Filter,
+		NonInteractive: opts.NonInteractive,
+		AutoAccept:     opts.AutoAccept,
+		DiffTool:       opts.DiffTool,
+	})
+}
+
 // AcceptAll accepts all pending snapshot changes without review.
 func AcceptAll() error {
 	return review.AcceptAll()
@@ -192,18 +588,39 @@ func RejectAll() error {
 	return review.RejectAll()
 }
 
-// formatValue formats a single value using the configured utter instance.
-func formatValue(v any) string {
-	return utterConfig.Sdump(v)
+// Migrate moves accepted snapshots stored in the legacy flat layout
+// (__snapshots__/<title>.snap) into per-test directories
+// (__snapshots__/<TestName>/<title>.snap). Snapshots recorded without a test
+// name are left in place.
+func Migrate() error {
+	return review.Migrate()
+}
+
+// formatValue formats a single value using the configured utter instance,
+// with times in the zone set by NormalizeTimes, placeholders for the
+// addresses of funcs and channels, and map entries in a canonical order.
+func (c *snapConfig) formatValue(v any) string {
+	config := utterConfig
+	if c.excludeUnexported {
+		copied := *utterConfig
+		copied.IgnoreUnexported = true
+		config = &copied
+	}
+	dump := config.Sdump(v)
+	if c.timeZone != nil {
+		dump = transform.NormalizeTimeValues(dump, config.Indent, c.timeZone)
+	}
+	dump = transform.ReplaceReferences(dump, transform.ReferencePlaceholders(v))
+	return transform.SortMapEntries(dump, config.Indent)
 }
 
 // formatValues formats multiple values using the configured utter instance.
-func formatValues(values ...any) string {
-	var result string
+func (c *snapConfig) formatValues(values ...any) string {
+	var sb strings.Builder
 	for _, v := range values {
-		result += formatValue(v)
+		sb.WriteString(c.formatValue(v))
 	}
-	return result
+	return sb.String()
 }
 
 // separateOptions splits options into scrubbers and ignore patterns.
@@ -214,6 +631,8 @@ func separateOptions(opts []Option) (scrubbers []Scrubber, ignores []IgnorePatte
 			ignores = append(ignores, o)
 		case Scrubber:
 			scrubbers = append(scrubbers, o)
+		case setting:
+			// Settings are resolved separately by newSnapConfig
 		default:
 			// This shouldn't happen if Option interface is properly implemented
 			panic(fmt.Sprintf("unknown option type: %T", opt))
@@ -222,10 +641,20 @@ func separateOptions(opts []Option) (scrubbers []Scrubber, ignores []IgnorePatte
 	return scrubbers, ignores
 }
 
-// applyScrubbers applies all scrubbers to content in sequence.
-func applyScrubbers(content string, scrubbers []Scrubber) string {
+// scrub applies scrubbers to content as applyScrubbers does, keeping the
+// content as it was before for review if KeepRaw is set.
+func (c *snapConfig) scrub(content string, scrubbers []Scrubber) string {
+	if c.keepRaw {
+		c.raw = content
+	}
+	return applyScrubbers(content, scrubbers, c.placeholders)
+}
+
+// applyScrubbers applies all scrubbers to content in sequence, writing
+// built-in placeholders as described by p.
+func applyScrubbers(content string, scrubbers []Scrubber, p placeholders) string {
 	for _, scrubber := range scrubbers {
-		content = scrubber.Scrub(content)
+		content = scrubStyled(scrubber, content, p)
 	}
 	return content
 }
@@ -233,16 +662,17 @@ func applyScrubbers(content string, scrubbers []Scrubber) string {
 // scrubberAdapter adapts a Scrubber to the transform.Scrubber interface.
 type scrubberAdapter struct {
 	scrubber Scrubber
+	p        placeholders
 }
 
 func (s *scrubberAdapter) Scrub(content string) string {
-	return s.scrubber.Scrub(content)
+	return scrubStyled(s.scrubber, content, s.p)
 }
 
-func toTransformScrubbers(scrubbers []Scrubber) []transform.Scrubber {
+func toTransformScrubbers(scrubbers []Scrubber, p placeholders) []transform.Scrubber {
 	result := make([]transform.Scrubber, len(scrubbers))
 	for i, scrubber := range scrubbers {
-		result[i] = &scrubberAdapter{scrubber: scrubber}
+		result[i] = &scrubberAdapter{scrubber: scrubber, p: p}
 	}
 	return result
 }
@@ -256,9 +686,31 @@ func (i *ignoreAdapter) ShouldIgnore(key, value string) bool {
 	return i.ignore.ShouldIgnore(key, value)
 }
 
+// fieldIgnore is an IgnorePattern that decides on the whole field, such as
+// one ignoring values by JSON type.
+type fieldIgnore interface {
+	IgnorePattern
+	shouldIgnoreField(f transform.Field) bool
+}
+
+// fieldIgnoreAdapter adapts a fieldIgnore to the
+// transform.FieldIgnorePattern interface.
+type fieldIgnoreAdapter struct {
+	ignoreAdapter
+	field fieldIgnore
+}
+
+func (i *fieldIgnoreAdapter) ShouldIgnoreField(f transform.Field) bool {
+	return i.field.shouldIgnoreField(f)
+}
+
 func toTransformIgnorePatterns(ignores []IgnorePattern) []transform.IgnorePattern {
 	result := make([]transform.IgnorePattern, len(ignores))
 	for i, ignore := range ignores {
+		if field, ok := ignore.(fieldIgnore); ok {
+			result[i] = &fieldIgnoreAdapter{ignoreAdapter: ignoreAdapter{ignore: ignore}, field: field}
+			continue
+		}
 		result[i] = &ignoreAdapter{ignore: ignore}
 	}
 	return result
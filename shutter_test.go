@@ -3,10 +3,12 @@ package shutter_test
 import (
 	"encoding/json"
 	"fmt"
+	htmltemplate "html/template"
 	"os"
 	"path/filepath"
 	"strings"
 	"testing"
+	"text/template"
 	"time"
 
 	"github.com/ptdewey/shutter"
@@ -25,6 +27,14 @@ func (c CustomStruct) Format() string {
 	return fmt.Sprintf("CustomStruct{Name: %s, Age: %d}", c.Name, c.Age)
 }
 
+func TestSnapEach(t *testing.T) {
+	shutter.SnapEach(t, "Snap Each", []shutter.Case{
+		{Name: "string", Value: "hello"},
+		{Name: "struct", Value: CustomStruct{Name: "Bob", Age: 25}},
+		{Name: "slice", Value: []int{1, 2, 3}},
+	})
+}
+
 func TestSnapCustomType(t *testing.T) {
 	cs := CustomStruct{
 		Name: "Alice",
@@ -550,6 +560,124 @@ func TestSnapJsonMixedTypes(t *testing.T) {
 	shutter.SnapJSON(t, "SnapJSON Mixed Types", jsonStr)
 }
 
+func TestSnapJsonPreserveKeyOrder(t *testing.T) {
+	jsonStr := `{
+		"status": "ok",
+		"data": {"name": "Widget", "price": 9.90, "id": 42},
+		"errors": []
+	}`
+
+	shutter.SnapJSON(t, "SnapJSON Preserve Key Order", jsonStr, shutter.PreserveKeyOrder())
+}
+
+func TestSnapJsonLargeNumbers(t *testing.T) {
+	jsonStr := `{
+		"user_id": 9223372036854775807,
+		"order_id": 9007199254740993,
+		"balance": 12345678901234.567890123,
+		"rate": 0.000000001
+	}`
+
+	shutter.SnapJSON(t, "SnapJSON Large Numbers", jsonStr)
+}
+
+func TestSnapJsonWithComments(t *testing.T) {
+	jsonStr := `{
+		// Editor settings
+		"editor.tabSize": 4,
+		"files.exclude": {
+			"**/node_modules": true, /* dependencies */
+		},
+	}`
+
+	shutter.SnapJSON(t, "SnapJSON With Comments", jsonStr, shutter.AllowJSONC())
+}
+
+func TestSnapJsonBytes(t *testing.T) {
+	shutter.SnapJSONBytes(t, "SnapJSON Bytes", []byte(`{"id": "550e8400-e29b-41d4-a716-446655440000", "name": "Widget"}`),
+		shutter.ScrubUUID(),
+	)
+}
+
+func TestSnapJsonReader(t *testing.T) {
+	body := strings.NewReader(`{"items": [{"sku": "a-1", "qty": 2}, {"sku": "b-2", "qty": 1}]}`)
+	shutter.SnapJSONReader(t, "SnapJSON Reader", body, shutter.CheckDeterminism())
+}
+
+func TestSnapJsonValue(t *testing.T) {
+	type account struct {
+		ID       int64  `json:"id"`
+		Email    string `json:"email"`
+		Password string `json:"password"`
+		Plan     string `json:"plan,omitempty"`
+	}
+
+	shutter.SnapJSONValue(t, "SnapJSON Value", []account{
+		{ID: 9007199254740993, Email: "ada@example.com", Password: "hunter2", Plan: "pro"},
+		{ID: 2, Email: "grace@example.com", Password: "swordfish"},
+	},
+		shutter.IgnoreKey("password"),
+		shutter.ScrubEmail(),
+	)
+}
+
+func TestSnapJsonValueUnsupported(t *testing.T) {
+	rt := &recordingT{T: t}
+	shutter.SnapJSONValue(rt, "Unsupported Value", map[string]any{"ch": make(chan int)})
+
+	if len(rt.errors) != 1 || !strings.Contains(rt.errors[0], "failed to marshal value") {
+		t.Errorf("expected a marshal error, got %v", rt.errors)
+	}
+}
+
+func TestSnapAuto(t *testing.T) {
+	cases := []struct {
+		name string
+		body string
+	}{
+		{"SnapAuto JSON", `{"id": "550e8400-e29b-41d4-a716-446655440000", "status": "ok"}`},
+		{"SnapAuto XML", `<?xml version="1.0"?><order id="550e8400-e29b-41d4-a716-446655440000"><item sku="a-1">2</item><note/></order>`},
+		{"SnapAuto YAML", "id: 550e8400-e29b-41d4-a716-446655440000  \r\nitems:\r\n  - sku: a-1\r\n    qty: 2\r\n"},
+		{"SnapAuto Text", "request 550e8400-e29b-41d4-a716-446655440000 accepted\n"},
+	}
+
+	for _, tc := range cases {
+		shutter.SnapAuto(t, tc.name, tc.body, shutter.ScrubUUID())
+	}
+}
+
+func TestSnapAutoIgnoreNonJSON(t *testing.T) {
+	rt := &recordingT{T: t}
+	shutter.SnapAuto(rt, "SnapAuto Ignore XML", "<user><password>hunter2</password></user>", shutter.IgnoreKey("password"))
+
+	if len(rt.errors) != 1 || !strings.Contains(rt.errors[0], "only supported when SnapAuto detects JSON, not xml") {
+		t.Errorf("expected an unsupported option error, got %v", rt.errors)
+	}
+}
+
+func TestSnapRouteTitle(t *testing.T) {
+	shutter.SnapString(t, "GET /users/:id", "200 OK")
+}
+
+func TestAssertNoSnapshotChange(t *testing.T) {
+	tempProject(t)
+
+	rt := &recordingT{T: t}
+	shutter.SnapString(rt, "Changed", "content")
+
+	// The parent's snapshot is not the subtest's change.
+	t.Run("unchanged", func(t *testing.T) {
+		shutter.AssertNoSnapshotChange(t)
+	})
+
+	shutter.AssertNoSnapshotChange(rt)
+
+	want := filepath.Join("__snapshots__", t.Name(), "changed.snap.new")
+	if len(rt.errors) != 2 || !strings.Contains(rt.errors[1], want) {
+		t.Errorf("expected an error naming %s, got %v", want, rt.errors)
+	}
+}
+
 func TestSnapJsonRealWorldExample(t *testing.T) {
 	jsonStr := `{
 		"success": true,
@@ -630,3 +758,64 @@ func TestSnapJsonRealWorldExample(t *testing.T) {
 }
 
 func ptr[T any](t T) *T { return &t }
+
+type templateUser struct {
+	Name  string
+	Email string
+	Roles []string
+}
+
+func TestSnapTemplateText(t *testing.T) {
+	tmpl := template.Must(template.New("user").Parse(`User: {{.Name}}
+Email: {{.Email}}
+Roles:
+{{- range .Roles}}
+  - {{.}}
+{{- end}}
+`))
+
+	shutter.SnapTemplate(t, "Text Template", tmpl, templateUser{
+		Name:  "Alice",
+		Email: "alice@example.com",
+		Roles: []string{"admin", "user"},
+	}, shutter.ScrubEmail())
+}
+
+func TestSnapTemplateHTML(t *testing.T) {
+	tmpl := htmltemplate.Must(htmltemplate.New("page").Parse(`
+<ul>
+    {{range .Roles}}
+        <li>{{.}}</li>
+    {{end}}
+</ul>
+<p>{{.Name}}</p>
+`))
+
+	shutter.SnapTemplate(t, "HTML Template", tmpl, templateUser{
+		Name:  "<script>alert(1)</script>",
+		Roles: []string{"admin", "user"},
+	}, shutter.CollapseWhitespace())
+}
+
+func TestContentTypeRecorded(t *testing.T) {
+	shutter.SnapString(t, "Plain Output", "done\n")
+	shutter.SnapString(t, "Colored Output", "\033[92mdone\033[0m\n")
+	shutter.SnapJSON(t, "JSON Body", `{"status": "done"}`)
+	shutter.SnapAuto(t, "YAML Body", "status: done\n")
+
+	dir := filepath.Join("__snapshots__", t.Name())
+	for file, want := range map[string]string{
+		"plain_output.snap":   "text",
+		"colored_output.snap": "ansi",
+		"json_body.snap":      "json",
+		"yaml_body.snap":      "yaml",
+	} {
+		data, err := os.ReadFile(filepath.Join(dir, file))
+		if err != nil {
+			t.Fatalf("read %s: %v", file, err)
+		}
+		if !strings.Contains(string(data), "content_type: "+want+"\n") {
+			t.Errorf("expected content_type %s in %s, got:\n%s", want, file, data)
+		}
+	}
+}
@@ -0,0 +1,23 @@
+module github.com/ptdewey/shutter/shuttergrpc
+
+go 1.25.2
+
+require (
+	github.com/ptdewey/shutter v0.2.3
+	google.golang.org/grpc v1.76.0
+	google.golang.org/protobuf v1.36.9
+)
+
+require (
+	github.com/kortschak/utter v1.7.0 // indirect
+	github.com/mattn/go-runewidth v0.0.16 // indirect
+	github.com/rivo/uniseg v0.2.0 // indirect
+	golang.org/x/net v0.42.0 // indirect
+	golang.org/x/sys v0.34.0 // indirect
+	golang.org/x/text v0.27.0 // indirect
+	google.golang.org/genproto/googleapis/rpc v0.0.0-20250804133106-a7a43d27e69b // indirect
+)
+
+// Until a release has the APIs used here, build against the shutter module
+// in this repository.
+replace github.com/ptdewey/shutter => ../
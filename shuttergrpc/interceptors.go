@@ -0,0 +1,170 @@
+package shuttergrpc
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+
+	"github.com/ptdewey/shutter"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/proto"
+)
+
+// UnaryClientInterceptor snapshots every unary call made through a client
+// connection.
+func UnaryClientInterceptor(t shutter.T, opts ...Option) grpc.UnaryClientInterceptor {
+	r := newRecorder(t, opts)
+	return func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, callOpts ...grpc.CallOption) error {
+		md, _ := metadata.FromOutgoingContext(ctx)
+		err := invoker(ctx, method, req, reply, cc, callOpts...)
+
+		call := Call{Method: method, Metadata: md, Requests: []any{req}, Err: err}
+		if err == nil {
+			call.Responses = []any{reply}
+		}
+		r.snap(call)
+		return err
+	}
+}
+
+// UnaryServerInterceptor snapshots every unary call a server handles.
+func UnaryServerInterceptor(t shutter.T, opts ...Option) grpc.UnaryServerInterceptor {
+	r := newRecorder(t, opts)
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		md, _ := metadata.FromIncomingContext(ctx)
+		resp, err := handler(ctx, req)
+
+		call := Call{Method: info.FullMethod, Metadata: md, Requests: []any{req}, Err: err}
+		if err == nil {
+			call.Responses = []any{resp}
+		}
+		r.snap(call)
+		return resp, err
+	}
+}
+
+// StreamClientInterceptor snapshots every streaming call made through a
+// client connection once the stream has finished.
+func StreamClientInterceptor(t shutter.T, opts ...Option) grpc.StreamClientInterceptor {
+	r := newRecorder(t, opts)
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, callOpts ...grpc.CallOption) (grpc.ClientStream, error) {
+		md, _ := metadata.FromOutgoingContext(ctx)
+		call := Call{Method: method, Metadata: md, Stream: true}
+
+		cs, err := streamer(ctx, desc, cc, method, callOpts...)
+		if err != nil {
+			call.Err = err
+			r.snap(call)
+			return nil, err
+		}
+		return &clientStream{ClientStream: cs, r: r, call: call, serverStreams: desc.ServerStreams}, nil
+	}
+}
+
+// clientStream records the messages of a client-side stream. The call is
+// snapshotted when the server has sent its last message: at io.EOF for
+// server streams, and after the single response otherwise.
+type clientStream struct {
+	grpc.ClientStream
+	r             *recorder
+	serverStreams bool
+
+	mu   sync.Mutex
+	call Call
+	done bool
+}
+
+func (s *clientStream) SendMsg(m any) error {
+	err := s.ClientStream.SendMsg(m)
+	if err == nil {
+		s.mu.Lock()
+		s.call.Requests = append(s.call.Requests, cloneMessage(m))
+		s.mu.Unlock()
+	}
+	return err
+}
+
+func (s *clientStream) RecvMsg(m any) error {
+	err := s.ClientStream.RecvMsg(m)
+
+	s.mu.Lock()
+	if s.done {
+		s.mu.Unlock()
+		return err
+	}
+	switch {
+	case err == nil:
+		s.call.Responses = append(s.call.Responses, cloneMessage(m))
+		s.done = !s.serverStreams
+	case errors.Is(err, io.EOF):
+		s.done = true
+	default:
+		s.call.Err = err
+		s.done = true
+	}
+	call, done := s.call, s.done
+	s.mu.Unlock()
+
+	if done {
+		s.r.snap(call)
+	}
+	return err
+}
+
+// StreamServerInterceptor snapshots every streaming call a server handles
+// once the handler has returned.
+func StreamServerInterceptor(t shutter.T, opts ...Option) grpc.StreamServerInterceptor {
+	r := newRecorder(t, opts)
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		md, _ := metadata.FromIncomingContext(ss.Context())
+		stream := &serverStream{ServerStream: ss, call: Call{Method: info.FullMethod, Metadata: md, Stream: true}}
+
+		err := handler(srv, stream)
+
+		stream.mu.Lock()
+		call := stream.call
+		stream.mu.Unlock()
+		call.Err = err
+		r.snap(call)
+		return err
+	}
+}
+
+// serverStream records the messages of a server-side stream.
+type serverStream struct {
+	grpc.ServerStream
+
+	mu   sync.Mutex
+	call Call
+}
+
+func (s *serverStream) SendMsg(m any) error {
+	err := s.ServerStream.SendMsg(m)
+	if err == nil {
+		s.mu.Lock()
+		s.call.Responses = append(s.call.Responses, cloneMessage(m))
+		s.mu.Unlock()
+	}
+	return err
+}
+
+func (s *serverStream) RecvMsg(m any) error {
+	err := s.ServerStream.RecvMsg(m)
+	if err == nil {
+		s.mu.Lock()
+		s.call.Requests = append(s.call.Requests, cloneMessage(m))
+		s.mu.Unlock()
+	}
+	return err
+}
+
+// cloneMessage copies a streamed message before it is recorded, since
+// callers commonly reuse one message value for every RecvMsg.
+func cloneMessage(m any) any {
+	if msg, ok := m.(proto.Message); ok {
+		return proto.Clone(msg)
+	}
+	return m
+}
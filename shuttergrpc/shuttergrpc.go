@@ -0,0 +1,261 @@
+// Package shuttergrpc records gRPC calls as snapshots.
+//
+// The interceptors in this package capture each call's request and response
+// messages, its metadata and its final status, and snapshot them with
+// shutter.SnapJSONBytes under the call's method name. Messages are marshaled
+// with protojson and then formatted like any other JSON snapshot, so the
+// snapshot only changes when the contract does.
+//
+// Example:
+//
+//	conn, err := grpc.NewClient(addr,
+//	    grpc.WithTransportCredentials(insecure.NewCredentials()),
+//	    grpc.WithUnaryInterceptor(shuttergrpc.UnaryClientInterceptor(t)),
+//	    grpc.WithStreamInterceptor(shuttergrpc.StreamClientInterceptor(t)),
+//	)
+package shuttergrpc
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/ptdewey/shutter"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// redacted replaces the values of scrubbed metadata keys. It avoids angle
+// brackets, which JSON snapshots escape.
+const redacted = "[REDACTED]"
+
+// scrubbedKeys are the metadata keys whose values are always redacted.
+var scrubbedKeys = []string{
+	"authorization",
+	"cookie",
+	"proxy-authorization",
+	"set-cookie",
+	"x-api-key",
+}
+
+// marshalOptions keeps field names as they are written in the .proto file,
+// so snapshots read like the contract they record.
+var marshalOptions = protojson.MarshalOptions{UseProtoNames: true}
+
+// Option configures how calls are recorded.
+type Option interface {
+	apply(cfg *config)
+}
+
+type config struct {
+	scrubbed []string
+	snapOpts []shutter.Option
+}
+
+func newConfig(opts []Option) *config {
+	cfg := &config{scrubbed: slices.Clone(scrubbedKeys)}
+	for _, opt := range opts {
+		opt.apply(cfg)
+	}
+	return cfg
+}
+
+type scrubMetadataOption struct {
+	keys []string
+}
+
+func (s *scrubMetadataOption) apply(cfg *config) {
+	for _, key := range s.keys {
+		cfg.scrubbed = append(cfg.scrubbed, strings.ToLower(key))
+	}
+}
+
+// ScrubMetadata redacts the values of the given metadata keys in addition
+// to the credentials and cookies that are always redacted.
+//
+// Example:
+//
+//	shuttergrpc.UnaryClientInterceptor(t, shuttergrpc.ScrubMetadata("x-request-id"))
+func ScrubMetadata(keys ...string) Option {
+	return &scrubMetadataOption{keys: keys}
+}
+
+type snapOptionsOption struct {
+	opts []shutter.Option
+}
+
+func (s *snapOptionsOption) apply(cfg *config) {
+	cfg.snapOpts = append(cfg.snapOpts, s.opts...)
+}
+
+// SnapOptions passes scrubbers, ignore patterns and other snapshot options
+// on to every snapshot taken for a call.
+//
+// Example:
+//
+//	shuttergrpc.UnaryServerInterceptor(t, shuttergrpc.SnapOptions(
+//	    shutter.IgnoreKey("created_at"),
+//	))
+func SnapOptions(opts ...shutter.Option) Option {
+	return &snapOptionsOption{opts: opts}
+}
+
+// Call is a single RPC as it is recorded in a snapshot.
+//
+// The interceptors fill it in for gRPC calls. SnapCall takes one directly,
+// which lets other RPC frameworks, such as Connect, record calls from their
+// own interceptors.
+type Call struct {
+	// Method is the full method name, e.g. "/echo.Echo/Say".
+	Method string
+	// Metadata holds the request metadata. Transport headers are left out
+	// and credentials are redacted.
+	Metadata metadata.MD
+	// Requests and Responses hold the messages sent and received, in order.
+	Requests  []any
+	Responses []any
+	// Stream records requests and responses as lists even when the call
+	// carried a single message.
+	Stream bool
+	// Err is the error the call finished with, if any. It is recorded as a
+	// gRPC status.
+	Err error
+}
+
+// SnapCall snapshots a call under its method name.
+func SnapCall(t shutter.T, call Call, opts ...Option) {
+	t.Helper()
+	snapCall(t, methodTitle(call.Method), call, newConfig(opts))
+}
+
+func snapCall(t shutter.T, title string, call Call, cfg *config) {
+	t.Helper()
+
+	content, err := marshalCall(call, cfg)
+	if err != nil {
+		t.Error(fmt.Sprintf("snapshot %q: %v", title, err))
+		return
+	}
+	shutter.SnapJSONBytes(t, title, content, cfg.snapOpts...)
+}
+
+// methodTitle drops the leading slash gRPC puts in front of method names.
+func methodTitle(method string) string {
+	return strings.TrimPrefix(method, "/")
+}
+
+func marshalCall(call Call, cfg *config) ([]byte, error) {
+	doc := map[string]any{"method": call.Method}
+
+	if md := scrubMetadata(call.Metadata, cfg.scrubbed); len(md) > 0 {
+		doc["metadata"] = md
+	}
+
+	requests, err := marshalMessages(call.Requests)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+	responses, err := marshalMessages(call.Responses)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal response: %w", err)
+	}
+
+	if call.Stream {
+		doc["requests"] = requests
+		doc["responses"] = responses
+	} else {
+		if len(requests) > 0 {
+			doc["request"] = requests[0]
+		}
+		if len(responses) > 0 {
+			doc["response"] = responses[0]
+		}
+	}
+
+	if call.Err != nil {
+		st := status.Convert(call.Err)
+		doc["status"] = map[string]string{
+			"code":    st.Code().String(),
+			"message": st.Message(),
+		}
+	}
+
+	return json.Marshal(doc)
+}
+
+func marshalMessages(msgs []any) ([]json.RawMessage, error) {
+	out := make([]json.RawMessage, 0, len(msgs))
+	for _, msg := range msgs {
+		var (
+			b   []byte
+			err error
+		)
+		if m, ok := msg.(proto.Message); ok {
+			b, err = marshalOptions.Marshal(m)
+		} else {
+			b, err = json.Marshal(msg)
+		}
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, b)
+	}
+	return out, nil
+}
+
+// scrubMetadata drops transport headers, which vary between runs and
+// clients, and redacts the values of scrubbed keys.
+func scrubMetadata(md metadata.MD, scrubbed []string) map[string][]string {
+	out := make(map[string][]string, len(md))
+	for key, values := range md {
+		key = strings.ToLower(key)
+		if transportHeader(key) {
+			continue
+		}
+		if slices.Contains(scrubbed, key) {
+			values = slices.Repeat([]string{redacted}, len(values))
+		}
+		out[key] = append(out[key], values...)
+	}
+	return out
+}
+
+func transportHeader(key string) bool {
+	switch key {
+	case "content-type", "user-agent", "te":
+		return true
+	}
+	return strings.HasPrefix(key, ":") || strings.HasPrefix(key, "grpc-")
+}
+
+// recorder snapshots the calls seen by one interceptor. Repeated calls to a
+// method are numbered so that each gets its own snapshot.
+type recorder struct {
+	t   shutter.T
+	cfg *config
+
+	mu    sync.Mutex
+	calls map[string]int
+}
+
+func newRecorder(t shutter.T, opts []Option) *recorder {
+	return &recorder{t: t, cfg: newConfig(opts), calls: make(map[string]int)}
+}
+
+func (r *recorder) snap(call Call) {
+	r.t.Helper()
+
+	title := methodTitle(call.Method)
+	r.mu.Lock()
+	r.calls[title]++
+	if n := r.calls[title]; n > 1 {
+		title = fmt.Sprintf("%s (call %d)", title, n)
+	}
+	r.mu.Unlock()
+
+	snapCall(r.t, title, call, r.cfg)
+}
@@ -0,0 +1,165 @@
+package shuttergrpc_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"testing"
+
+	"github.com/ptdewey/shutter/shuttergrpc"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/structpb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+// echoService is registered without generated code: Say answers unary
+// calls and Count streams one message per letter of the request.
+var echoService = grpc.ServiceDesc{
+	ServiceName: "echo.Echo",
+	HandlerType: (*any)(nil),
+	Methods: []grpc.MethodDesc{{
+		MethodName: "Say",
+		Handler: func(srv any, ctx context.Context, dec func(any) error, interceptor grpc.UnaryServerInterceptor) (any, error) {
+			req := new(wrapperspb.StringValue)
+			if err := dec(req); err != nil {
+				return nil, err
+			}
+			handler := func(ctx context.Context, req any) (any, error) {
+				return say(req.(*wrapperspb.StringValue))
+			}
+			if interceptor == nil {
+				return handler(ctx, req)
+			}
+			return interceptor(ctx, req, &grpc.UnaryServerInfo{FullMethod: "/echo.Echo/Say"}, handler)
+		},
+	}},
+	Streams: []grpc.StreamDesc{{
+		StreamName:    "Count",
+		ServerStreams: true,
+		Handler: func(srv any, stream grpc.ServerStream) error {
+			req := new(wrapperspb.StringValue)
+			if err := stream.RecvMsg(req); err != nil {
+				return err
+			}
+			for _, r := range req.GetValue() {
+				if err := stream.SendMsg(wrapperspb.String(string(r))); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	}},
+}
+
+func say(req *wrapperspb.StringValue) (*structpb.Struct, error) {
+	if req.GetValue() == "" {
+		return nil, status.Error(codes.InvalidArgument, "nothing to say")
+	}
+	return structpb.NewStruct(map[string]any{
+		"echo":   req.GetValue(),
+		"length": len(req.GetValue()),
+	})
+}
+
+// dial starts an in-memory echo server and returns a connection to it.
+func dial(t *testing.T, serverOpts []grpc.ServerOption, clientOpts ...grpc.DialOption) *grpc.ClientConn {
+	t.Helper()
+
+	lis := bufconn.Listen(1 << 20)
+	srv := grpc.NewServer(serverOpts...)
+	srv.RegisterService(&echoService, nil)
+	go srv.Serve(lis)
+	t.Cleanup(srv.Stop)
+
+	clientOpts = append(clientOpts,
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+	)
+	conn, err := grpc.NewClient("passthrough:///bufnet", clientOpts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+func count(ctx context.Context, conn *grpc.ClientConn, value string) error {
+	stream, err := conn.NewStream(ctx, &echoService.Streams[0], "/echo.Echo/Count")
+	if err != nil {
+		return err
+	}
+	if err := stream.SendMsg(wrapperspb.String(value)); err != nil {
+		return err
+	}
+	if err := stream.CloseSend(); err != nil {
+		return err
+	}
+	resp := new(wrapperspb.StringValue)
+	for {
+		if err := stream.RecvMsg(resp); err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		}
+	}
+}
+
+func TestUnaryClientInterceptor(t *testing.T) {
+	conn := dial(t, nil, grpc.WithUnaryInterceptor(shuttergrpc.UnaryClientInterceptor(t,
+		shuttergrpc.ScrubMetadata("X-Request-Id"),
+	)))
+
+	ctx := metadata.AppendToOutgoingContext(context.Background(),
+		"authorization", "Bearer secret-token",
+		"x-request-id", "4f1c2a",
+		"x-tenant", "acme",
+	)
+	for _, value := range []string{"hello", "goodbye"} {
+		if err := conn.Invoke(ctx, "/echo.Echo/Say", wrapperspb.String(value), new(structpb.Struct)); err != nil {
+			t.Fatalf("Say(%q): %v", value, err)
+		}
+	}
+}
+
+func TestUnaryServerInterceptorError(t *testing.T) {
+	conn := dial(t, []grpc.ServerOption{grpc.UnaryInterceptor(shuttergrpc.UnaryServerInterceptor(t))})
+
+	err := conn.Invoke(context.Background(), "/echo.Echo/Say", wrapperspb.String(""), new(structpb.Struct))
+	if status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("expected InvalidArgument, got %v", err)
+	}
+}
+
+func TestStreamInterceptors(t *testing.T) {
+	t.Run("client", func(t *testing.T) {
+		conn := dial(t, nil, grpc.WithStreamInterceptor(shuttergrpc.StreamClientInterceptor(t)))
+		if err := count(context.Background(), conn, "abc"); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("server", func(t *testing.T) {
+		conn := dial(t, []grpc.ServerOption{grpc.StreamInterceptor(shuttergrpc.StreamServerInterceptor(t))})
+		if err := count(context.Background(), conn, "abc"); err != nil {
+			t.Fatal(err)
+		}
+	})
+}
+
+func TestSnapCall(t *testing.T) {
+	shuttergrpc.SnapCall(t, shuttergrpc.Call{
+		Method:    "/echo.Echo/Say",
+		Metadata:  metadata.Pairs("cookie", "session=abc", "x-tenant", "acme"),
+		Requests:  []any{map[string]string{"value": "hello"}},
+		Responses: []any{map[string]any{"echo": "hello", "length": 5}},
+	})
+}
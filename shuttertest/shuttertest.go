@@ -0,0 +1,147 @@
+// Package shuttertest provides test doubles for testing code built on
+// shutter, such as custom scrubbers, comparators and helpers that wrap the
+// snapshot functions, without writing to the real __snapshots__ directory.
+//
+// FakeT records the failures and logs of a snapshot instead of failing the
+// test, and UseMemoryStorage keeps snapshots in memory for the rest of the
+// test.
+//
+// Example:
+//
+//	func TestScrubOrderID(t *testing.T) {
+//	    store := shuttertest.UseMemoryStorage(t)
+//	    store.SetAccepted("TestOrder", "order", "id: <ORDER_ID>\n")
+//
+//	    ft := shuttertest.NewFakeT("TestOrder")
+//	    shutter.SnapString(ft, "order", "id: ord_8f2k\n", ScrubOrderID())
+//	    if ft.Failed() {
+//	        t.Errorf("expected the order id to be scrubbed: %v", ft.Errors())
+//	    }
+//	}
+package shuttertest
+
+import (
+	"fmt"
+	"slices"
+	"sync"
+	"testing"
+
+	"github.com/ptdewey/shutter/internal/files"
+)
+
+// FakeT is a shutter.T that records what shutter reports instead of
+// failing a test. It is safe for concurrent use.
+type FakeT struct {
+	name string
+
+	mu     sync.Mutex
+	errors []string
+	logs   []string
+}
+
+// NewFakeT returns a FakeT for a test named name. Snapshots taken with it
+// are stored under that name, like those of a real test.
+func NewFakeT(name string) *FakeT {
+	return &FakeT{name: name}
+}
+
+func (f *FakeT) Helper() {}
+
+func (f *FakeT) Name() string {
+	return f.name
+}
+
+func (f *FakeT) Error(args ...any) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.errors = append(f.errors, fmt.Sprint(args...))
+}
+
+func (f *FakeT) Log(args ...any) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.logs = append(f.logs, fmt.Sprint(args...))
+}
+
+// Errors returns the errors reported so far, such as snapshot mismatches.
+func (f *FakeT) Errors() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return slices.Clone(f.errors)
+}
+
+// Logs returns the messages logged so far.
+func (f *FakeT) Logs() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return slices.Clone(f.logs)
+}
+
+// Failed reports whether an error was reported.
+func (f *FakeT) Failed() bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.errors) > 0
+}
+
+// Storage holds the snapshots taken while UseMemoryStorage is in effect.
+type Storage struct {
+	mem *files.MemoryStorage
+}
+
+// UseMemoryStorage keeps snapshots in memory until t ends, instead of in
+// __snapshots__ directories. Nothing is written to disk, including the
+// placeholder numbers of mapped scrubbers. The storage is shared by the
+// whole test binary, so t and the tests running alongside it must not be
+// parallel.
+func UseMemoryStorage(t testing.TB) *Storage {
+	t.Helper()
+	s := &Storage{mem: files.NewMemoryStorage()}
+	prev := files.SetStorage(s.mem)
+	t.Cleanup(func() { files.SetStorage(prev) })
+	return s
+}
+
+// SetAccepted stores content as the accepted snapshot of test and title,
+// the baseline snapshots are compared against.
+func (s *Storage) SetAccepted(test, title, content string) {
+	// Writing to memory does not fail.
+	_ = s.mem.Write(&files.Snapshot{Test: test, Title: title, Content: content}, files.StateAccepted)
+}
+
+// Accepted returns the content of the accepted snapshot of test and title,
+// and false if there is none.
+func (s *Storage) Accepted(test, title string) (string, bool) {
+	return s.read(test, title, files.StateAccepted)
+}
+
+// Pending returns the content of the snapshot of test and title pending
+// review, as left by a new or mismatching snapshot, and false if there is
+// none.
+func (s *Storage) Pending(test, title string) (string, bool) {
+	return s.read(test, title, files.StateNew)
+}
+
+// AcceptAll accepts every pending snapshot, even those that look like they
+// contain secrets, and returns how many there were.
+func (s *Storage) AcceptAll() (int, error) {
+	pending, err := s.mem.List()
+	if err != nil {
+		return 0, err
+	}
+	for i, info := range pending {
+		if err := s.mem.Accept(info, true); err != nil {
+			return i, err
+		}
+	}
+	return len(pending), nil
+}
+
+func (s *Storage) read(test, title string, state files.State) (string, bool) {
+	info := files.SnapshotInfoFor(&files.Snapshot{Test: test, Title: title})
+	snap, err := s.mem.Read(info, state)
+	if err != nil {
+		return "", false
+	}
+	return snap.Content, true
+}
@@ -0,0 +1,50 @@
+package shuttertest_test
+
+import (
+	"io"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/ptdewey/shutter"
+	"github.com/ptdewey/shutter/shuttertest"
+)
+
+func TestMemoryStorage(t *testing.T) {
+	shutter.SetOutput(io.Discard)
+	t.Cleanup(func() { shutter.SetOutput(nil) })
+	store := shuttertest.UseMemoryStorage(t)
+	scrubUser := shutter.ScrubMapped(`user-\d+`, "USER")
+
+	ft := shuttertest.NewFakeT("TestUsers")
+	shutter.SnapString(ft, "users", "user-17 follows user-42\n", scrubUser)
+	if errs := ft.Errors(); len(errs) != 1 || !strings.Contains(errs[0], "new snapshot created") {
+		t.Fatalf("expected a new snapshot error, got %v", errs)
+	}
+	if content, ok := store.Pending("TestUsers", "users"); !ok || content != "<USER_1> follows <USER_2>\n" {
+		t.Errorf("unexpected pending snapshot %q (found %v)", content, ok)
+	}
+	if n, err := store.AcceptAll(); err != nil || n != 1 {
+		t.Fatalf("AcceptAll accepted %d snapshot(s): %v", n, err)
+	}
+
+	ft = shuttertest.NewFakeT("TestUsers")
+	shutter.SnapString(ft, "users", "user-17 follows user-42\n", scrubUser)
+	if ft.Failed() {
+		t.Errorf("expected the accepted snapshot to match, got %v", ft.Errors())
+	}
+
+	store.SetAccepted("TestUsers", "users", "nobody\n")
+	ft = shuttertest.NewFakeT("TestUsers")
+	shutter.SnapString(ft, "users", "user-17 follows user-42\n", scrubUser)
+	if errs := ft.Errors(); len(errs) != 1 || !strings.Contains(errs[0], "snapshot mismatch") {
+		t.Errorf("expected a mismatch, got %v", errs)
+	}
+	if content, ok := store.Accepted("TestUsers", "users"); !ok || content != "nobody\n" {
+		t.Errorf("expected the baseline to be kept, got %q", content)
+	}
+
+	if _, err := os.Stat("__snapshots__"); !os.IsNotExist(err) {
+		t.Errorf("expected nothing to be written to disk, got %v", err)
+	}
+}
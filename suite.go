@@ -0,0 +1,128 @@
+package shutter
+
+import (
+	"io"
+
+	"github.com/ptdewey/shutter/internal/transform"
+)
+
+// SnapSuite takes snapshots that belong to a named suite, such as the
+// snapshots of one API across several test files. Create one with Suite.
+type SnapSuite struct {
+	name string
+	opts []Option
+}
+
+// Suite returns a suite whose Snap methods prefix titles with name and a
+// slash and apply opts before the options of each call. The suite is
+// recorded in the snapshot header, so its snapshots can be reviewed and
+// accepted as a group with the --suite flag of the shutter command.
+//
+// Example:
+//
+//	var billing = shutter.Suite("billing-api", shutter.ScrubUUID(), shutter.ScrubTimestamp())
+//
+//	func TestInvoice(t *testing.T) {
+//	    billing.SnapJSON(t, "invoice", body) // titled "billing-api/invoice"
+//	}
+func Suite(name string, opts ...Option) *SnapSuite {
+	return &SnapSuite{name: name, opts: opts}
+}
+
+// Name returns the name of the suite.
+func (s *SnapSuite) Name() string {
+	return s.name
+}
+
+// title returns the title of a snapshot of the suite.
+func (s *SnapSuite) title(title string) string {
+	return s.name + "/" + title
+}
+
+// options returns the options of a snapshot of the suite: the suite itself,
+// its options, then opts. As with Defaults, the suite's IgnorePattern
+// options only apply to JSON snapshots.
+func (s *SnapSuite) options(opts []Option, json bool) []Option {
+	all := []Option{&suiteSetting{name: s.name}}
+	for _, opt := range s.opts {
+		if _, ok := opt.(IgnorePattern); ok && !json {
+			continue
+		}
+		all = append(all, opt)
+	}
+	return append(all, opts...)
+}
+
+// Snap is like the package-level Snap, within the suite.
+func (s *SnapSuite) Snap(t T, title string, value any, opts ...Option) {
+	t.Helper()
+	Snap(t, s.title(title), value, s.options(opts, false)...)
+}
+
+// SnapMany is like the package-level SnapMany, within the suite.
+func (s *SnapSuite) SnapMany(t T, title string, values []any, opts ...Option) {
+	t.Helper()
+	SnapMany(t, s.title(title), values, s.options(opts, false)...)
+}
+
+// SnapEach is like the package-level SnapEach, within the suite.
+func (s *SnapSuite) SnapEach(t T, title string, cases []Case, opts ...Option) {
+	t.Helper()
+	SnapEach(t, s.title(title), cases, s.options(opts, false)...)
+}
+
+// SnapString is like the package-level SnapString, within the suite.
+func (s *SnapSuite) SnapString(t T, title string, content string, opts ...Option) {
+	t.Helper()
+	SnapString(t, s.title(title), content, s.options(opts, false)...)
+}
+
+// SnapTemplate is like the package-level SnapTemplate, within the suite.
+func (s *SnapSuite) SnapTemplate(t T, title string, tmpl Template, data any, opts ...Option) {
+	t.Helper()
+	SnapTemplate(t, s.title(title), tmpl, data, s.options(opts, false)...)
+}
+
+// SnapJSON is like the package-level SnapJSON, within the suite.
+func (s *SnapSuite) SnapJSON(t T, title string, jsonStr string, opts ...Option) {
+	t.Helper()
+	SnapJSON(t, s.title(title), jsonStr, s.options(opts, true)...)
+}
+
+// SnapJSONBytes is like the package-level SnapJSONBytes, within the suite.
+func (s *SnapSuite) SnapJSONBytes(t T, title string, jsonBytes []byte, opts ...Option) {
+	t.Helper()
+	SnapJSONBytes(t, s.title(title), jsonBytes, s.options(opts, true)...)
+}
+
+// SnapJSONValue is like the package-level SnapJSONValue, within the suite.
+func (s *SnapSuite) SnapJSONValue(t T, title string, v any, opts ...Option) {
+	t.Helper()
+	SnapJSONValue(t, s.title(title), v, s.options(opts, true)...)
+}
+
+// SnapJSONReader is like the package-level SnapJSONReader, within the
+// suite.
+func (s *SnapSuite) SnapJSONReader(t T, title string, r io.Reader, opts ...Option) {
+	t.Helper()
+	SnapJSONReader(t, s.title(title), r, s.options(opts, true)...)
+}
+
+// SnapAuto is like the package-level SnapAuto, within the suite. The
+// suite's IgnorePattern options apply when the content is JSON.
+func (s *SnapSuite) SnapAuto(t T, title string, content string, opts ...Option) {
+	t.Helper()
+	json := transform.Detect(content) == transform.JSON
+	SnapAuto(t, s.title(title), content, s.options(opts, json)...)
+}
+
+// suiteSetting records the suite a snapshot belongs to.
+type suiteSetting struct {
+	name string
+}
+
+func (s *suiteSetting) isOption() {}
+
+func (s *suiteSetting) apply(cfg *snapConfig) {
+	cfg.suite = s.name
+}
@@ -0,0 +1,128 @@
+package shutter
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ptdewey/shutter/internal/transform"
+)
+
+// withinOption scopes scrubbers and ignore patterns to a subtree of a JSON
+// document. It is an IgnorePattern so that, like other ignore patterns, it
+// is rejected by snapshot functions that do not take JSON.
+type withinOption struct {
+	path      string
+	opts      []Option
+	scrubbers []Scrubber
+	ignores   []IgnorePattern
+}
+
+func (w *withinOption) isOption() {}
+
+func (w *withinOption) String() string {
+	names := make([]string, len(w.opts))
+	for i, opt := range w.opts {
+		names[i] = optionName(opt)
+	}
+	return fmt.Sprintf("Within(%s, %s)", quoteArg(w.path), strings.Join(names, ", "))
+}
+
+// ShouldIgnore applies the scoped ignore patterns without regard to scope,
+// as the key and value alone do not tell where a field is. SnapJSON uses
+// shouldIgnoreField instead.
+func (w *withinOption) ShouldIgnore(key, value string) bool {
+	for _, ignore := range w.ignores {
+		if ignore.ShouldIgnore(key, value) {
+			return true
+		}
+	}
+	return false
+}
+
+func (w *withinOption) shouldIgnoreField(f transform.Field) bool {
+	if w.path != "" {
+		rel, ok := strings.CutPrefix(f.Path, w.path+".")
+		if !ok {
+			return false
+		}
+		// Paths given to the scoped options are relative to w.path.
+		f.Path = rel
+	}
+	for _, ignore := range w.ignores {
+		if field, ok := ignore.(fieldIgnore); ok {
+			if field.shouldIgnoreField(f) {
+				return true
+			}
+			continue
+		}
+		if ignore.ShouldIgnore(f.Key, f.ValueString()) {
+			return true
+		}
+	}
+	return false
+}
+
+// scopes returns the transform scopes of the scoped scrubbers, including
+// those of nested Within options, writing built-in placeholders as
+// described by p.
+func (w *withinOption) scopes(p placeholders) []transform.Scope {
+	var scopes []transform.Scope
+	if len(w.scrubbers) > 0 {
+		scopes = append(scopes, transform.Scope{Path: w.path, Scrubbers: toTransformScrubbers(w.scrubbers, p)})
+	}
+	for _, ignore := range w.ignores {
+		if nested, ok := ignore.(*withinOption); ok {
+			for _, scope := range nested.scopes(p) {
+				scope.Path = joinPath(w.path, scope.Path)
+				scopes = append(scopes, scope)
+			}
+		}
+	}
+	return scopes
+}
+
+// joinPath joins two dotted paths, either of which may be empty.
+func joinPath(a, b string) string {
+	if a == "" || b == "" {
+		return a + b
+	}
+	return a + "." + b
+}
+
+// Within scopes scrubbers and ignore patterns to the part of a JSON document
+// at path, so that aggressive rules such as IgnoreNull or
+// ScrubUnixTimestamp leave the rest of the payload alone. path names an
+// object member by its dotted keys from the document root, such as
+// "data.items"; array elements share the path of their array, so the
+// options apply to every item. Scrubbers see the value at path as it is
+// written in the snapshot, and run before unscoped scrubbers.
+//
+// Paths given to the scoped options, as in IgnoreNumbers("stats"), are
+// relative to path, and Within options nest. Settings such as Placeholders
+// apply to the whole snapshot and cannot be scoped; Within panics if given
+// one.
+//
+// This option only works with SnapJSON.
+//
+// Example:
+//
+//	shutter.SnapJSON(t, "response", jsonStr,
+//	    shutter.Within("data.items",
+//	        shutter.IgnoreNull(),
+//	        shutter.ScrubUnixTimestamp(),
+//	    ),
+//	)
+func Within(path string, opts ...Option) IgnorePattern {
+	w := &withinOption{path: path, opts: opts}
+	for _, opt := range opts {
+		switch o := opt.(type) {
+		case IgnorePattern:
+			w.ignores = append(w.ignores, o)
+		case Scrubber:
+			w.scrubbers = append(w.scrubbers, o)
+		default:
+			panic(fmt.Sprintf("Within: %s cannot be scoped", optionName(opt)))
+		}
+	}
+	return w
+}